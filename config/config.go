@@ -3,14 +3,69 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Storage backends.
+const (
+	StorageMongo  = "mongo"
+	StorageMemory = "memory" // no persistence; for demos and local dev
+	StorageSQL    = "sql"    // SQLite file by default, or Postgres
+)
+
 type Config struct {
+	// Production refuses to start with development fallbacks such as the
+	// default JWT secret. Set APP_ENV=production.
+	Production bool
+
+	Storage   string
 	MongoURI  string
+	SQLDriver string // sqlite or postgres, for STORAGE=sql
+	SQLDSN    string
 	JWTSecret string
 	Port      string
+
+	// Access tokens are signed with JWTAlgorithm: HS256 with JWTSecret, or
+	// RS256/EdDSA with the PEM key in JWTPrivateKeyFile, sent as kid
+	// JWTKeyID. JWTVerifyKeys maps kids of keys being rotated out to PEM
+	// public key files; keep them until the access token TTL has passed.
+	JWTAlgorithm      string
+	JWTPrivateKeyFile string
+	JWTKeyID          string
+	JWTVerifyKeys     map[string]string
+	JWTIssuer         string
+	JWTAudience       string
+
+	// Access tokens are short-lived; refresh tokens keep a device logged in
+	// for RefreshTokenTTL after its last refresh.
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// Password reset tokens go through PasswordResetNotifier: "log" writes
+	// them to the server log, "file" appends them to PasswordResetFile and
+	// "none" turns password reset off. Both log and file hand tokens to
+	// whoever reads the server's output, so production only takes "none",
+	// its default.
+	PasswordResetNotifier string
+	PasswordResetFile     string
+	PasswordResetTTL      time.Duration
+
+	// RateLimit turns on the per-IP and per-user request limits set up in
+	// routes.Setup. TrustedProxies lists the proxies whose X-Forwarded-For
+	// is believed; with none, the client IP is the connection's address.
+	RateLimit      bool
+	TrustedProxies []string
+
+	// IdempotencyTTL is how long Idempotency-Key responses are remembered.
+	IdempotencyTTL time.Duration
+
+	// AutoMigrate applies pending schema migrations at startup. When off,
+	// run "gully-backend migrate" before deploying.
+	AutoMigrate bool
 }
 
 func Load() *Config {
@@ -18,20 +73,154 @@ func Load() *Config {
 	_ = godotenv.Load()
 
 	cfg := &Config{
+		Storage:   os.Getenv("STORAGE"),
+		SQLDriver: os.Getenv("SQL_DRIVER"),
+		SQLDSN:    os.Getenv("SQL_DSN"),
 		MongoURI:  os.Getenv("MONGO_URI"),
 		JWTSecret: os.Getenv("JWT_SECRET"),
 		Port:      os.Getenv("PORT"),
+
+		Production: os.Getenv("APP_ENV") == "production",
+
+		JWTAlgorithm:      os.Getenv("JWT_ALG"),
+		JWTPrivateKeyFile: os.Getenv("JWT_PRIVATE_KEY_FILE"),
+		JWTKeyID:          os.Getenv("JWT_KEY_ID"),
+		JWTIssuer:         os.Getenv("JWT_ISSUER"),
+		JWTAudience:       os.Getenv("JWT_AUDIENCE"),
+
+		PasswordResetNotifier: os.Getenv("PASSWORD_RESET_NOTIFIER"),
+		PasswordResetFile:     os.Getenv("PASSWORD_RESET_FILE"),
 	}
 
-	if cfg.MongoURI == "" {
-		log.Fatal("MONGO_URI is required")
+	switch cfg.Storage {
+	case "":
+		cfg.Storage = StorageMongo
+	case StorageMongo, StorageMemory, StorageSQL:
+	default:
+		log.Fatalf("STORAGE must be %s, %s or %s, got %q", StorageMongo, StorageMemory, StorageSQL, cfg.Storage)
+	}
+	if cfg.SQLDriver == "" {
+		cfg.SQLDriver = "sqlite"
+	}
+	if cfg.SQLDSN == "" && cfg.SQLDriver == "sqlite" {
+		cfg.SQLDSN = "gully.db"
 	}
-	if cfg.JWTSecret == "" {
-		cfg.JWTSecret = "default-secret"
+	if cfg.Storage == StorageSQL && cfg.SQLDSN == "" {
+		log.Fatal("SQL_DSN is required for SQL_DRIVER=" + cfg.SQLDriver)
 	}
+	if cfg.Storage == StorageMongo && cfg.MongoURI == "" {
+		log.Fatal("MONGO_URI is required")
+	}
+	loadJWT(cfg)
 	if cfg.Port == "" {
 		cfg.Port = "8080"
 	}
+	switch cfg.PasswordResetNotifier {
+	case "":
+		cfg.PasswordResetNotifier = "log"
+		if cfg.Production {
+			cfg.PasswordResetNotifier = "none"
+		}
+	case "none":
+	case "log", "file":
+		if cfg.Production {
+			log.Fatalf("PASSWORD_RESET_NOTIFIER=%s exposes reset tokens and is not allowed when APP_ENV=production; use none", cfg.PasswordResetNotifier)
+		}
+	default:
+		log.Fatalf("PASSWORD_RESET_NOTIFIER must be log, file or none, got %q", cfg.PasswordResetNotifier)
+	}
+	if cfg.PasswordResetFile == "" {
+		cfg.PasswordResetFile = "password_resets.log"
+	}
+
+	cfg.AccessTokenTTL = durationEnv("ACCESS_TOKEN_TTL", 15*time.Minute)
+	cfg.RefreshTokenTTL = durationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	cfg.PasswordResetTTL = durationEnv("PASSWORD_RESET_TTL", time.Hour)
+	cfg.IdempotencyTTL = durationEnv("IDEMPOTENCY_TTL", 24*time.Hour)
+	cfg.AutoMigrate = boolEnv("AUTO_MIGRATE", true)
+	cfg.RateLimit = boolEnv("RATE_LIMIT", true)
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			cfg.TrustedProxies = append(cfg.TrustedProxies, p)
+		}
+	}
 
 	return cfg
 }
+
+// minJWTSecretLen is the shortest HS256 secret production accepts: 256 bits
+// of base64.
+const minJWTSecretLen = 43
+
+func loadJWT(cfg *Config) {
+	switch cfg.JWTAlgorithm {
+	case "":
+		cfg.JWTAlgorithm = "HS256"
+	case "HS256", "RS256", "EdDSA":
+	default:
+		log.Fatalf("JWT_ALG must be HS256, RS256 or EdDSA, got %q", cfg.JWTAlgorithm)
+	}
+	if cfg.JWTIssuer == "" {
+		cfg.JWTIssuer = "gully-backend"
+	}
+	if cfg.JWTAudience == "" {
+		cfg.JWTAudience = "gully-app"
+	}
+
+	if cfg.JWTAlgorithm == "HS256" {
+		switch {
+		case cfg.JWTSecret == "" && cfg.Production:
+			log.Fatal("JWT_SECRET is required when APP_ENV=production")
+		case cfg.JWTSecret == "":
+			log.Println("JWT_SECRET is not set; using an insecure development secret")
+			cfg.JWTSecret = "default-secret"
+		case cfg.Production && len(cfg.JWTSecret) < minJWTSecretLen:
+			log.Fatalf("JWT_SECRET must be at least %d characters when APP_ENV=production", minJWTSecretLen)
+		}
+		return
+	}
+
+	if cfg.JWTPrivateKeyFile == "" || cfg.JWTKeyID == "" {
+		log.Fatalf("JWT_PRIVATE_KEY_FILE and JWT_KEY_ID are required for JWT_ALG=%s", cfg.JWTAlgorithm)
+	}
+	// JWT_VERIFY_KEYS is a comma-separated list of kid=path pairs.
+	cfg.JWTVerifyKeys = map[string]string{}
+	for _, pair := range strings.Split(os.Getenv("JWT_VERIFY_KEYS"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		kid, path, ok := strings.Cut(pair, "=")
+		if !ok || kid == "" || path == "" {
+			log.Fatalf("JWT_VERIFY_KEYS entries must be kid=path, got %q", pair)
+		}
+		cfg.JWTVerifyKeys[kid] = path
+	}
+}
+
+// durationEnv parses a Go duration string (e.g. "90s", "12h") from the
+// environment, falling back to def when unset.
+func durationEnv(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Fatalf("%s must be a positive duration, got %q", key, v)
+	}
+	return d
+}
+
+// boolEnv parses a boolean ("true", "false", "1", "0") from the environment,
+// falling back to def when unset.
+func boolEnv(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatalf("%s must be true or false, got %q", key, v)
+	}
+	return b
+}
@@ -1,19 +1,23 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"gully-backend/middleware"
 	"gully-backend/services"
 )
 
 type AuthHandler struct {
-	authService *services.AuthService
+	authService     *services.AuthService
+	passwordService *services.PasswordService
 }
 
-func NewAuthHandler(authService *services.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHandler(authService *services.AuthService, passwordService *services.PasswordService) *AuthHandler {
+	return &AuthHandler{authService: authService, passwordService: passwordService}
 }
 
 type registerRequest struct {
@@ -30,19 +34,26 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	user, err := h.authService.Register(c.Request.Context(), req.Username, req.Password)
 	if err != nil {
-		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, services.ErrInvalidUsername), errors.Is(err, services.ErrWeakPassword):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrUsernameTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
-	// Auto-login after registration to return a JWT token
-	token, _, err := h.authService.Login(c.Request.Context(), req.Username, req.Password)
+	// Auto-login after registration to return tokens
+	tokens, _, err := h.authService.Login(actorContext(c), user.Username, req.Password)
 	if err != nil {
 		// Registration succeeded but token generation failed — still return user
 		c.JSON(http.StatusCreated, gin.H{"user": user})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"token": token, "user": user})
+	c.JSON(http.StatusCreated, tokenResponse(tokens, gin.H{"user": user}))
 }
 
 type loginRequest struct {
@@ -50,6 +61,8 @@ type loginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// Login starts a session for the device in X-Device-ID. The access token is
+// returned as "token", next to the refresh token that renews it.
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req loginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -57,11 +70,164 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, user, err := h.authService.Login(c.Request.Context(), req.Username, req.Password)
+	tokens, user, err := h.authService.Login(actorContext(c), req.Username, req.Password)
+	var locked *services.LoginLockedError
+	if errors.As(err, &locked) {
+		middleware.SetRetryAfter(c, locked.RetryAfter)
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token, "user": user})
+	c.JSON(http.StatusOK, tokenResponse(tokens, gin.H{"user": user}))
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Refresh rotates a refresh token: the old one stops working and a new pair
+// is returned.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.authService.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidToken) || errors.Is(err, services.ErrSessionRevoked) ||
+			errors.Is(err, services.ErrRefreshReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenResponse(tokens, gin.H{}))
+}
+
+// Logout ends the session the request's access token belongs to.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	sessionID, err := primitive.ObjectIDFromHex(c.GetString("session_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+	if err := h.authService.Logout(c.Request.Context(), sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// LogoutAll ends every session of the caller, this one included.
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	if err := h.authService.LogoutAll(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "logged out on all devices"})
+}
+
+// ── Passwords ──
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password"     binding:"required"`
+}
+
+// ChangePassword sets a new password. Every session is logged out; the
+// caller gets fresh tokens for this device.
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	var req changePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.passwordService.ChangePassword(actorContext(c), userID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		writePasswordError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tokenResponse(tokens, gin.H{"message": "password changed"}))
+}
+
+type passwordResetRequest struct {
+	Username string `json:"username" binding:"required"`
+}
+
+// RequestPasswordReset sends a reset token to the account's owner. The
+// answer is the same whether or not the account exists.
+func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
+	var req passwordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.passwordService.RequestPasswordReset(c.Request.Context(), req.Username); err != nil {
+		writePasswordError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the account exists, a reset token has been sent"})
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token"        binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ResetPassword redeems a reset token. All sessions are logged out; the
+// user logs in again with the new password.
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req resetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.passwordService.ResetPassword(c.Request.Context(), req.Token, req.NewPassword); err != nil {
+		writePasswordError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "password reset"})
+}
+
+func writePasswordError(c *gin.Context, err error) {
+	var locked *services.LoginLockedError
+	switch {
+	case errors.As(err, &locked):
+		middleware.SetRetryAfter(c, locked.RetryAfter)
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrWrongPassword):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrWeakPassword), errors.Is(err, services.ErrInvalidResetToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPasswordResetOff):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func tokenResponse(tokens *services.TokenPair, body gin.H) gin.H {
+	body["token"] = tokens.AccessToken
+	body["refresh_token"] = tokens.RefreshToken
+	body["expires_in"] = tokens.ExpiresIn
+	return body
 }
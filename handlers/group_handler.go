@@ -1,23 +1,28 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"gully-backend/models"
 	"gully-backend/repositories"
 	"gully-backend/services"
+	ws "gully-backend/websocket"
 )
 
 type GroupHandler struct {
 	groupService  *services.GroupService
 	playerService *services.PlayerService
-	userRepo      *repositories.UserRepo
+	userRepo      repositories.UserRepository
+	hub           *ws.Hub
 }
 
-func NewGroupHandler(groupService *services.GroupService, playerService *services.PlayerService, userRepo *repositories.UserRepo) *GroupHandler {
-	return &GroupHandler{groupService: groupService, playerService: playerService, userRepo: userRepo}
+func NewGroupHandler(groupService *services.GroupService, playerService *services.PlayerService, userRepo repositories.UserRepository, hub *ws.Hub) *GroupHandler {
+	return &GroupHandler{groupService: groupService, playerService: playerService, userRepo: userRepo, hub: hub}
 }
 
 type createGroupRequest struct {
@@ -40,7 +45,7 @@ func (h *GroupHandler) CreateGroup(c *gin.Context) {
 
 	group, err := h.groupService.CreateGroup(c.Request.Context(), req.Name, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeGroupError(c, err)
 		return
 	}
 
@@ -68,9 +73,18 @@ func (h *GroupHandler) JoinGroup(c *gin.Context) {
 		return
 	}
 
-	group, err := h.groupService.JoinGroup(c.Request.Context(), req.Code, userID)
+	group, request, err := h.groupService.JoinGroup(c.Request.Context(), req.Code, userID, c.GetString("username"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "group not found"})
+		writeGroupError(c, err)
+		return
+	}
+	if request != nil {
+		// Not a member yet, so only the group's name is shown.
+		c.JSON(http.StatusAccepted, gin.H{
+			"request": request,
+			"group":   gin.H{"id": group.ID, "name": group.Name},
+		})
+		h.hub.BroadcastToGroup(group.ID.Hex(), gin.H{"type": "join_requested", "request": request})
 		return
 	}
 
@@ -80,22 +94,61 @@ func (h *GroupHandler) JoinGroup(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"group": group})
 }
 
+// GetGroup returns the whole group to members; anyone else gets a summary
+// without its join code or members, and without its settings unless the
+// group is public.
 func (h *GroupHandler) GetGroup(c *gin.Context) {
-	id, err := primitive.ObjectIDFromHex(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group id"})
+	id, userID, ok := groupAndUser(c)
+	if !ok {
 		return
 	}
 
-	group, err := h.groupService.GetGroup(c.Request.Context(), id)
+	group, err := h.groupService.GetMemberGroup(c.Request.Context(), id, userID)
+	if errors.Is(err, services.ErrNotGroupMember) {
+		summary, err := h.groupService.GetGroupSummary(c.Request.Context(), id, userID)
+		if err != nil {
+			writeGroupError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"group": summary})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "group not found"})
+		writeGroupError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"group": group})
 }
 
+// GetPresence lists the group members who currently have the app open.
+func (h *GroupHandler) GetPresence(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	if _, err := h.groupService.GetMemberGroup(c.Request.Context(), groupID, userID); err != nil {
+		writeGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"presence": h.hub.Presence(groupID.Hex())})
+}
+
+// WatchGroup is the group's live feed at /ws/group/:id. Only members can
+// connect; they appear in presence while connected.
+func (h *GroupHandler) WatchGroup(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	if _, err := h.groupService.GetMemberGroup(c.Request.Context(), groupID, userID); err != nil {
+		writeGroupError(c, err)
+		return
+	}
+	h.hub.ServeGroup(c, groupID.Hex(), userID.Hex(), c.GetString("username"))
+}
+
 func (h *GroupHandler) GetUserGroups(c *gin.Context) {
 	userIDStr, _ := c.Get("user_id")
 	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
@@ -113,13 +166,378 @@ func (h *GroupHandler) GetUserGroups(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"groups": groups})
 }
 
-// autoCreatePlayer looks up the user's username and creates a player record
-// in the group if one with that name doesn't already exist.
+// ── Membership ──
+
+// LeaveGroup takes the caller out of the group. If they owned it, it passes
+// to the next member; if they were the last member, it is deleted.
+func (h *GroupHandler) LeaveGroup(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+
+	left, err := h.groupService.LeaveGroup(c.Request.Context(), groupID, userID)
+	if err != nil {
+		writeGroupError(c, err)
+		return
+	}
+
+	if left.Deleted {
+		h.broadcastDeleted(groupID)
+	} else {
+		h.hub.BroadcastToGroup(groupID.Hex(), gin.H{"type": "member_left", "user_id": userID.Hex(), "group": left.Group})
+		h.disconnect(groupID, userID, left.LinksRevoked)
+	}
+	c.JSON(http.StatusOK, left)
+}
+
+// RemoveMember takes another member out of the group (owner-only).
+func (h *GroupHandler) RemoveMember(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	memberID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	removed, err := h.groupService.RemoveMember(c.Request.Context(), groupID, userID, memberID)
+	if err != nil {
+		writeGroupError(c, err)
+		return
+	}
+
+	h.hub.BroadcastToGroup(groupID.Hex(), gin.H{"type": "member_removed", "user_id": memberID.Hex(), "group": removed.Group})
+	h.disconnect(groupID, memberID, removed.LinksRevoked)
+	c.JSON(http.StatusOK, gin.H{"group": removed.Group})
+}
+
+// disconnect closes a departed member's sockets and those watching through
+// their revoked share links.
+func (h *GroupHandler) disconnect(groupID, userID primitive.ObjectID, links []primitive.ObjectID) {
+	h.hub.CloseMember(groupID.Hex(), userID.Hex())
+	for _, linkID := range links {
+		h.hub.CloseShare(groupID.Hex(), linkID.Hex())
+	}
+}
+
+// ── Owner actions ──
+
+type renameGroupRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// RenameGroup changes the group's name (owner-only).
+func (h *GroupHandler) RenameGroup(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	var req renameGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := h.groupService.RenameGroup(c.Request.Context(), groupID, userID, req.Name)
+	if err != nil {
+		writeGroupError(c, err)
+		return
+	}
+
+	h.hub.BroadcastToGroup(groupID.Hex(), gin.H{"type": "group_updated", "group": group})
+	c.JSON(http.StatusOK, gin.H{"group": group})
+}
+
+// DeleteGroup deletes the group with its players and matches (owner-only).
+func (h *GroupHandler) DeleteGroup(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.groupService.DeleteGroup(c.Request.Context(), groupID, userID); err != nil {
+		writeGroupError(c, err)
+		return
+	}
+
+	h.broadcastDeleted(groupID)
+	c.JSON(http.StatusOK, gin.H{"message": "group deleted"})
+}
+
+// ── Join code, invites and join requests (owner-only) ──
+
+// RegenerateJoinCode replaces the group's join code; the old one stops
+// working.
+func (h *GroupHandler) RegenerateJoinCode(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	group, err := h.groupService.RegenerateJoinCode(c.Request.Context(), groupID, userID)
+	if err != nil {
+		writeGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"group": group})
+}
+
+type joinApprovalRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// SetJoinApproval turns approval of new members on or off.
+func (h *GroupHandler) SetJoinApproval(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	var req joinApprovalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	group, err := h.groupService.SetJoinApproval(c.Request.Context(), groupID, userID, *req.Enabled)
+	if err != nil {
+		writeGroupError(c, err)
+		return
+	}
+	h.hub.BroadcastToGroup(groupID.Hex(), gin.H{"type": "group_updated", "group": group})
+	c.JSON(http.StatusOK, gin.H{"group": group})
+}
+
+type createInviteRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxUses   int        `json:"max_uses"`
+}
+
+func (h *GroupHandler) CreateInvite(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	var req createInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	invite, err := h.groupService.CreateInvite(c.Request.Context(), groupID, userID, services.InviteOptions{
+		Name:      req.Name,
+		ExpiresAt: req.ExpiresAt,
+		MaxUses:   req.MaxUses,
+	})
+	if err != nil {
+		writeGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"invite": invite})
+}
+
+func (h *GroupHandler) GetInvites(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	invites, err := h.groupService.ListInvites(c.Request.Context(), groupID, userID)
+	if err != nil {
+		writeGroupError(c, err)
+		return
+	}
+	if invites == nil {
+		invites = []models.Invite{}
+	}
+	c.JSON(http.StatusOK, gin.H{"invites": invites})
+}
+
+func (h *GroupHandler) RevokeInvite(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	inviteID, err := primitive.ObjectIDFromHex(c.Param("inviteId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invite id"})
+		return
+	}
+	invite, err := h.groupService.RevokeInvite(c.Request.Context(), groupID, userID, inviteID)
+	if err != nil {
+		writeGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"invite": invite})
+}
+
+func (h *GroupHandler) GetJoinRequests(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	requests, err := h.groupService.ListJoinRequests(c.Request.Context(), groupID, userID)
+	if err != nil {
+		writeGroupError(c, err)
+		return
+	}
+	if requests == nil {
+		requests = []models.JoinRequest{}
+	}
+	c.JSON(http.StatusOK, gin.H{"requests": requests})
+}
+
+// ApproveJoinRequest lets the requester in and gives them a player.
+func (h *GroupHandler) ApproveJoinRequest(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	requestID, ok := joinRequestParam(c)
+	if !ok {
+		return
+	}
+	group, request, err := h.groupService.ApproveJoinRequest(c.Request.Context(), groupID, userID, requestID)
+	if err != nil {
+		writeGroupError(c, err)
+		return
+	}
+	h.autoCreatePlayer(c, request.UserID, groupID)
+	h.hub.BroadcastToGroup(groupID.Hex(), gin.H{"type": "member_joined", "user_id": request.UserID.Hex(), "group": group})
+	c.JSON(http.StatusOK, gin.H{"group": group})
+}
+
+func (h *GroupHandler) RejectJoinRequest(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	requestID, ok := joinRequestParam(c)
+	if !ok {
+		return
+	}
+	request, err := h.groupService.RejectJoinRequest(c.Request.Context(), groupID, userID, requestID)
+	if err != nil {
+		writeGroupError(c, err)
+		return
+	}
+	h.hub.BroadcastToGroup(groupID.Hex(), gin.H{"type": "join_request_rejected", "request_id": request.ID.Hex()})
+	c.JSON(http.StatusOK, gin.H{"message": "join request rejected"})
+}
+
+// ── Settings ──
+
+// GetSettings returns the group's settings to members, or to anyone when
+// the group is public.
+func (h *GroupHandler) GetSettings(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	settings, err := h.groupService.GetSettings(c.Request.Context(), groupID, userID)
+	if err != nil {
+		writeGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"settings": settings})
+}
+
+type updateSettingsRequest struct {
+	// Version is the settings version the client last read.
+	Version         *int    `json:"version" binding:"required"`
+	Description     *string `json:"description"`
+	Sport           *string `json:"sport"`
+	HomeVenue       *string `json:"home_venue"`
+	Timezone        *string `json:"timezone"`
+	PointsToWin     *int    `json:"points_to_win"`
+	GuestsCanScore  *bool   `json:"guests_can_score"`
+	AllowBackdating *bool   `json:"allow_backdating"`
+	Privacy         *string `json:"privacy"`
+}
+
+// UpdateSettings changes the settings given in the body (owner only).
+// A stale version is a 409; the client should reload and reapply.
+func (h *GroupHandler) UpdateSettings(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	var req updateSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	settings, err := h.groupService.UpdateSettings(c.Request.Context(), groupID, userID, *req.Version, services.SettingsUpdate{
+		Description:     req.Description,
+		Sport:           req.Sport,
+		HomeVenue:       req.HomeVenue,
+		Timezone:        req.Timezone,
+		PointsToWin:     req.PointsToWin,
+		GuestsCanScore:  req.GuestsCanScore,
+		AllowBackdating: req.AllowBackdating,
+		Privacy:         req.Privacy,
+	})
+	if err != nil {
+		writeGroupError(c, err)
+		return
+	}
+	h.hub.BroadcastToGroup(groupID.Hex(), gin.H{"type": "settings_updated", "settings": settings})
+	c.JSON(http.StatusOK, gin.H{"settings": settings})
+}
+
+func joinRequestParam(c *gin.Context) (primitive.ObjectID, bool) {
+	requestID, err := primitive.ObjectIDFromHex(c.Param("requestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request id"})
+		return requestID, false
+	}
+	return requestID, true
+}
+
+// broadcastDeleted tells connected clients the group is gone, then drops
+// their connections.
+func (h *GroupHandler) broadcastDeleted(groupID primitive.ObjectID) {
+	h.hub.BroadcastToGroup(groupID.Hex(), gin.H{"type": "group_deleted", "group_id": groupID.Hex()})
+	h.hub.CloseGroup(groupID.Hex())
+}
+
+func groupAndUser(c *gin.Context) (groupID, userID primitive.ObjectID, ok bool) {
+	groupID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group id"})
+		return groupID, userID, false
+	}
+	userID, ok = currentUser(c)
+	return groupID, userID, ok
+}
+
+func writeGroupError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "group not found"})
+	case errors.Is(err, services.ErrInvalidGroup), errors.Is(err, services.ErrInvalidInvite),
+		errors.Is(err, services.ErrInvalidSettings):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotGroupOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotGroupMember), errors.Is(err, services.ErrInviteNotFound),
+		errors.Is(err, services.ErrJoinRequestNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSettingsConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInviteUnusable):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// autoCreatePlayer creates a player linked to the user, named after their
+// username, unless they already have one in the group.
 func (h *GroupHandler) autoCreatePlayer(c *gin.Context, userID, groupID primitive.ObjectID) {
 	user, err := h.userRepo.FindByID(c.Request.Context(), userID)
 	if err != nil {
 		return // silently skip — user not found
 	}
 	// CreatePlayerIfNotExists is idempotent
-	_, _ = h.playerService.CreatePlayerIfNotExists(c.Request.Context(), user.Username, groupID)
+	_, _ = h.playerService.CreatePlayerIfNotExists(c.Request.Context(), userID, user.Name(), groupID)
 }
@@ -0,0 +1,111 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchGroup_MembersOnly(t *testing.T) {
+	a := newApp(t)
+	ownerToken, ownerID := a.register(t, "owner")
+	strangerToken, _ := a.register(t, "stranger")
+	groupID, _ := a.createGroup(t, ownerToken, "Club")
+
+	_, code := a.dial(t, "/ws/group/"+groupID)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	_, code = a.dial(t, "/ws/group/"+groupID+"?token=forged")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	_, code = a.dial(t, "/ws/group/"+groupID+"?token="+strangerToken)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// Identity comes from the token; query parameters cannot claim another.
+	conn, code := a.dial(t, "/ws/group/"+groupID+"?token="+ownerToken+"&user_id=someone&username=someone")
+	require.Equal(t, http.StatusSwitchingProtocols, code)
+	assert.Equal(t, "presence_join", readType(t, conn))
+	require.Eventually(t, func() bool { return a.hub.IsPresent(groupID, ownerID) }, time.Second, 5*time.Millisecond)
+	assert.False(t, a.hub.IsPresent(groupID, "someone"))
+	assert.Equal(t, "owner", a.hub.Presence(groupID)[0].Username)
+}
+
+func TestRemoveMember_Disconnects(t *testing.T) {
+	a := newApp(t)
+	ownerToken, ownerID := a.register(t, "owner")
+	bobToken, bobID := a.register(t, "bob")
+	groupID, joinCode := a.createGroup(t, ownerToken, "Club")
+	a.join(t, bobToken, joinCode)
+	_, bobShare := a.shareLink(t, bobToken, groupID, gin.H{})
+	_, ownerShare := a.shareLink(t, ownerToken, groupID, gin.H{})
+
+	_, code := a.dial(t, "/ws/group/"+groupID+"?token="+ownerToken)
+	require.Equal(t, http.StatusSwitchingProtocols, code)
+	bob, code := a.dial(t, "/ws/group/"+groupID+"?token="+bobToken)
+	require.Equal(t, http.StatusSwitchingProtocols, code)
+	viewer, code := a.dial(t, "/ws/share/"+bobShare)
+	require.Equal(t, http.StatusSwitchingProtocols, code)
+	require.Eventually(t, func() bool { return a.hub.IsPresent(groupID, bobID) }, time.Second, 5*time.Millisecond)
+
+	code, out := a.do(t, http.MethodDelete, "/api/groups/"+groupID+"/members/"+bobID, ownerToken, nil)
+	require.Equal(t, http.StatusOK, code, out)
+
+	// Bob and whoever watched through his link are cut off, for good.
+	assertClosed(t, bob)
+	assertClosed(t, viewer)
+	_, code = a.dial(t, "/ws/group/"+groupID+"?token="+bobToken)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = a.do(t, http.MethodGet, "/api/share/"+bobShare, "", nil)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// The owner stays connected, and their link keeps working.
+	require.Eventually(t, func() bool { return !a.hub.IsPresent(groupID, bobID) }, time.Second, 5*time.Millisecond)
+	assert.True(t, a.hub.IsPresent(groupID, ownerID))
+	code, _ = a.do(t, http.MethodGet, "/api/share/"+ownerShare, "", nil)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestGetGroup_HidesJoinCodeFromNonMembers(t *testing.T) {
+	a := newApp(t)
+	ownerToken, _ := a.register(t, "owner")
+	strangerToken, _ := a.register(t, "stranger")
+	groupID, joinCode := a.createGroup(t, ownerToken, "Club")
+
+	code, out := a.do(t, http.MethodGet, "/api/groups/"+groupID, ownerToken, nil)
+	require.Equal(t, http.StatusOK, code, out)
+	group := out["group"].(map[string]interface{})
+	assert.Equal(t, joinCode, group["join_code"])
+	assert.Len(t, group["members"], 1)
+
+	code, out = a.do(t, http.MethodGet, "/api/groups/"+groupID, strangerToken, nil)
+	require.Equal(t, http.StatusOK, code, out)
+	group = out["group"].(map[string]interface{})
+	assert.Equal(t, "Club", group["name"])
+	assert.NotContains(t, group, "join_code")
+	assert.NotContains(t, group, "members")
+}
+
+func TestGetGroup_PrivateSettingsHidden(t *testing.T) {
+	a := newApp(t)
+	ownerToken, _ := a.register(t, "owner")
+	strangerToken, _ := a.register(t, "stranger")
+	groupID, _ := a.createGroup(t, ownerToken, "Club")
+
+	// Private by default: a stranger sees the name and nothing else.
+	code, out := a.do(t, http.MethodGet, "/api/groups/"+groupID, strangerToken, nil)
+	require.Equal(t, http.StatusOK, code, out)
+	group := out["group"].(map[string]interface{})
+	assert.Equal(t, "Club", group["name"])
+	assert.NotContains(t, group, "settings")
+	assert.NotContains(t, group, "created_at")
+
+	code, out = a.do(t, http.MethodPatch, "/api/groups/"+groupID+"/settings", ownerToken, gin.H{"version": 0, "privacy": "public"})
+	require.Equal(t, http.StatusOK, code, out)
+	code, out = a.do(t, http.MethodGet, "/api/groups/"+groupID, strangerToken, nil)
+	require.Equal(t, http.StatusOK, code, out)
+	group = out["group"].(map[string]interface{})
+	assert.Equal(t, "public", group["settings"].(map[string]interface{})["privacy"])
+	assert.NotContains(t, group, "join_code")
+}
@@ -0,0 +1,183 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+
+	"gully-backend/config"
+	"gully-backend/handlers"
+	"gully-backend/repositories/memory"
+	"gully-backend/routes"
+	"gully-backend/services"
+	ws "gully-backend/websocket"
+)
+
+// app is the whole API on memory storage, served over HTTP so WebSockets
+// work.
+type app struct {
+	srv    *httptest.Server
+	hub    *ws.Hub
+	routes gin.RoutesInfo
+}
+
+func newApp(t *testing.T) *app {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	users, groups, players := memory.NewUserRepo(), memory.NewGroupRepo(), memory.NewPlayerRepo()
+	matches, events, merges := memory.NewMatchRepo(), memory.NewMatchEventRepo(), memory.NewMergeRecordRepo()
+	invites, joinRequests, shareLinks := memory.NewInviteRepo(), memory.NewJoinRequestRepo(), memory.NewShareLinkRepo()
+	tx := memory.NewTransactor(users, groups, players, matches, events, merges, invites, joinRequests, shareLinks)
+
+	authService := services.NewAuthService(users, memory.NewSessionRepo(), nil, services.NewHMACKeys("test-secret"), 0, 0)
+	passwordService := services.NewPasswordService(users, memory.NewPasswordResetRepo(), authService, services.NewLogNotifier(), 0)
+	playerService := services.NewPlayerService(players, matches, events, merges, tx)
+	groupService := services.NewGroupService(groups, invites, joinRequests, shareLinks, playerService, tx)
+	accountService := services.NewAccountService(users, memory.NewPasswordResetRepo(), groupService, playerService, authService, tx)
+	exportService := services.NewExportService(users, groups, players, matches)
+	matchService := services.NewMatchService(matches, players, events, groups, tx)
+	statsService := services.NewStatsService(matches, players)
+	shareService := services.NewShareService(shareLinks, groups, matches)
+	hub := ws.NewHub()
+
+	r := gin.New()
+	routes.Setup(r, &config.Config{IdempotencyTTL: time.Hour}, authService,
+		handlers.NewAuthHandler(authService, passwordService),
+		handlers.NewUserHandler(accountService, exportService, hub),
+		handlers.NewGroupHandler(groupService, playerService, users, hub),
+		handlers.NewPlayerHandler(playerService, groupService),
+		handlers.NewMatchHandler(matchService, groupService, hub),
+		handlers.NewStatsHandler(statsService, groupService),
+		handlers.NewShareHandler(shareService, hub),
+	)
+	a := &app{srv: httptest.NewServer(r), hub: hub, routes: r.Routes()}
+	t.Cleanup(a.srv.Close)
+	return a
+}
+
+// do sends a JSON request and decodes the JSON response.
+func (a *app) do(t *testing.T, method, path, token string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	return a.doWithHeaders(t, method, path, token, nil, body)
+}
+
+// doWithHeaders is do with extra request headers.
+func (a *app) doWithHeaders(t *testing.T, method, path, token string, headers map[string]string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req, err := http.NewRequest(method, a.srv.URL+path, &buf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	out := map[string]interface{}{}
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+// register signs a user up and returns their access token and user ID.
+func (a *app) register(t *testing.T, username string) (token, userID string) {
+	t.Helper()
+	code, out := a.do(t, http.MethodPost, "/api/auth/register", "", gin.H{"username": username, "password": "password123"})
+	require.Equal(t, http.StatusCreated, code, out)
+	return out["token"].(string), out["user"].(map[string]interface{})["id"].(string)
+}
+
+// createGroup makes a group and returns its ID and join code.
+func (a *app) createGroup(t *testing.T, token, name string) (groupID, joinCode string) {
+	t.Helper()
+	code, out := a.do(t, http.MethodPost, "/api/groups", token, gin.H{"name": name})
+	require.Equal(t, http.StatusCreated, code, out)
+	group := out["group"].(map[string]interface{})
+	return group["id"].(string), group["join_code"].(string)
+}
+
+func (a *app) join(t *testing.T, token, joinCode string) {
+	t.Helper()
+	code, out := a.do(t, http.MethodPost, "/api/groups/join", token, gin.H{"code": joinCode})
+	require.Equal(t, http.StatusOK, code, out)
+}
+
+// createMatch adds players Ann and Ben to the group and starts a singles
+// match between them, returning the match ID. Call it once per group.
+func (a *app) createMatch(t *testing.T, token, groupID string) string {
+	t.Helper()
+	var ids []string
+	for _, name := range []string{"Ann", "Ben"} {
+		code, out := a.do(t, http.MethodPost, "/api/groups/"+groupID+"/players", token, gin.H{"name": name})
+		require.Equal(t, http.StatusCreated, code, out)
+		ids = append(ids, out["player"].(map[string]interface{})["id"].(string))
+	}
+	code, out := a.do(t, http.MethodPost, "/api/matches", token, gin.H{
+		"group_id": groupID, "team1_ids": ids[:1], "team2_ids": ids[1:],
+	})
+	require.Equal(t, http.StatusCreated, code, out)
+	return out["match"].(map[string]interface{})["id"].(string)
+}
+
+// dial opens a WebSocket; it returns the HTTP status when the upgrade is
+// refused.
+func (a *app) dial(t *testing.T, path string) (*websocket.Conn, int) {
+	t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(a.srv.URL, "http")+path, nil)
+	if err != nil {
+		require.NotNil(t, resp, err)
+		return nil, resp.StatusCode
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, http.StatusSwitchingProtocols
+}
+
+// readType reads the next message and returns its type.
+func readType(t *testing.T, conn *websocket.Conn) string {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	var msg struct {
+		Type string `json:"type"`
+	}
+	require.NoError(t, json.Unmarshal(data, &msg))
+	return msg.Type
+}
+
+// assertClosed reads until the server closes the connection, failing if it
+// stays open.
+func assertClosed(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	for {
+		_, _, err := conn.ReadMessage()
+		if err != nil {
+			require.False(t, isTimeout(err), "socket still open")
+			return
+		}
+	}
+}
+
+// isTimeout reports whether a read failed because nothing arrived, rather
+// than because the server closed the connection.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -1,11 +1,19 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"gully-backend/models"
+	"gully-backend/repositories"
 	"gully-backend/services"
 	ws "gully-backend/websocket"
 )
@@ -34,12 +42,28 @@ func (h *MatchHandler) isGroupCreator(c *gin.Context, groupID primitive.ObjectID
 	return group.CreatedBy == userID
 }
 
+// checkScorer rejects the request when another user holds the match's scorer lock.
+func (h *MatchHandler) checkScorer(c *gin.Context, matchID primitive.ObjectID) bool {
+	if err := h.hub.CheckScorer(matchID.Hex(), c.GetString("user_id")); err != nil {
+		body := gin.H{"error": err.Error()}
+		if lock := h.hub.ScorerLockFor(matchID.Hex()); lock != nil {
+			body["scorer"] = lock
+		}
+		c.JSON(http.StatusLocked, body)
+		return false
+	}
+	return true
+}
+
 // ── Create Match ──
 
 type createMatchRequest struct {
-	GroupID  string   `json:"group_id" binding:"required"`
-	Team1IDs []string `json:"team1_ids" binding:"required"`
-	Team2IDs []string `json:"team2_ids" binding:"required"`
+	GroupID     string     `json:"group_id" binding:"required"`
+	Team1IDs    []string   `json:"team1_ids" binding:"required"`
+	Team2IDs    []string   `json:"team2_ids" binding:"required"`
+	Status      string     `json:"status"`       // "live" (default) or "scheduled"
+	ScheduledAt *time.Time `json:"scheduled_at"` // implies status "scheduled"
+	SessionID   string     `json:"session_id"`
 }
 
 func (h *MatchHandler) CreateMatch(c *gin.Context) {
@@ -65,9 +89,18 @@ func (h *MatchHandler) CreateMatch(c *gin.Context) {
 		return
 	}
 
-	match, err := h.matchService.CreateMatch(c.Request.Context(), groupID, t1, t2)
+	var match *models.Match
+	switch {
+	case req.Status == models.MatchStatusScheduled || req.ScheduledAt != nil:
+		match, err = h.matchService.ScheduleMatch(actorContext(c), groupID, t1, t2, req.ScheduledAt, req.SessionID)
+	case req.Status == "" || req.Status == models.MatchStatusLive:
+		match, err = h.matchService.CreateMatch(actorContext(c), groupID, t1, t2, req.SessionID)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be live or scheduled"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeMatchError(c, err)
 		return
 	}
 
@@ -77,27 +110,107 @@ func (h *MatchHandler) CreateMatch(c *gin.Context) {
 
 // ── Get Matches ──
 
+// GetMatches lists the group's matches. Private groups show them to members
+// only.
 func (h *MatchHandler) GetMatches(c *gin.Context) {
-	groupID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	if err := h.groupService.CheckVisible(c.Request.Context(), groupID, userID); err != nil {
+		writeGroupError(c, err)
+		return
+	}
+
+	q, err := parseMatchQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	q.GroupID = groupID
 
-	matches, err := h.matchService.GetMatches(c.Request.Context(), groupID)
+	page, err := h.matchService.ListMatches(c.Request.Context(), q)
 	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"matches": matches})
+	c.JSON(http.StatusOK, gin.H{"matches": page.Matches, "next_cursor": page.NextCursor})
+}
+
+// parseMatchQuery reads the list filters from the query string:
+// status (comma-separated), player_id, from and to (RFC 3339), type,
+// session_id, sort (date or duration), order (asc or desc), cursor and limit.
+func parseMatchQuery(c *gin.Context) (repositories.MatchQuery, error) {
+	q := repositories.MatchQuery{
+		Type:      c.Query("type"),
+		SessionID: c.Query("session_id"),
+		Sort:      c.Query("sort"),
+		Cursor:    c.Query("cursor"),
+	}
+	if v := c.Query("status"); v != "" {
+		q.Statuses = strings.Split(v, ",")
+	}
+	if v := c.Query("player_id"); v != "" {
+		id, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return q, errors.New("invalid player_id")
+		}
+		q.PlayerID = id
+	}
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &q.From}, {"to", &q.To}} {
+		if v := c.Query(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return q, fmt.Errorf("invalid %s: use RFC 3339", p.name)
+			}
+			*p.dst = &t
+		}
+	}
+	switch q.Type {
+	case "", models.MatchType1v1, models.MatchType1v2, models.MatchType2v2:
+	default:
+		return q, errors.New("type must be 1v1, 1v2 or 2v2")
+	}
+	switch q.Sort {
+	case "", repositories.MatchSortDate, repositories.MatchSortDuration:
+	default:
+		return q, errors.New("sort must be date or duration")
+	}
+	switch c.Query("order") {
+	case "", "desc":
+	case "asc":
+		q.Ascending = true
+	default:
+		return q, errors.New("order must be asc or desc")
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return q, errors.New("limit must be a positive number")
+		}
+		q.Limit = n
+	}
+	return q, nil
 }
 
 // ── Update Score ──
 
 type updateScoreRequest struct {
-	Team     int    `json:"team" binding:"required"`      // 1 or 2
-	PlayerID string `json:"player_id" binding:"required"` // hex ID of scorer
+	Team     int    `json:"team"`      // 1 or 2; omitted for a let
+	PlayerID string `json:"player_id"` // hex ID of scorer
+
+	// Optional rally details
+	Outcome       string `json:"outcome"` // smash_winner, net_error, out, service_fault, let
+	RallySecs     int    `json:"rally_secs"`
+	FaultPlayerID string `json:"fault_player_id"`
 }
 
 func (h *MatchHandler) UpdateScore(c *gin.Context) {
@@ -113,16 +226,111 @@ func (h *MatchHandler) UpdateScore(c *gin.Context) {
 		return
 	}
 
-	match, err := h.matchService.UpdateScore(c.Request.Context(), matchID, req.Team, req.PlayerID)
+	if !h.checkScorer(c, matchID) {
+		return
+	}
+
+	if req.Outcome != models.OutcomeLet && req.PlayerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "player_id is required"})
+		return
+	}
+
+	match, err := h.matchService.RecordRally(actorContext(c), matchID, services.Rally{
+		Team:          req.Team,
+		PlayerID:      req.PlayerID,
+		Outcome:       req.Outcome,
+		RallySecs:     req.RallySecs,
+		FaultPlayerID: req.FaultPlayerID,
+	})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeMatchError(c, err)
 		return
 	}
 
 	h.hub.BroadcastToGroup(match.GroupID.Hex(), gin.H{"type": "score_update", "match": match})
+	if match.Status == models.MatchStatusPaused {
+		// The point started the 11-point interval; let spectator screens show it.
+		h.hub.BroadcastToGroup(match.GroupID.Hex(), gin.H{"type": "match_paused", "match": match, "pause": match.OpenPause()})
+	}
 	c.JSON(http.StatusOK, gin.H{"match": match})
 }
 
+// ── Timeline ──
+
+// GetTimeline returns the audit trail of a match, including deleted ones,
+// plus the state rebuilt by replaying it when the timeline is complete. It
+// names who made each change, so it is for group members only, even in
+// public groups.
+func (h *MatchHandler) GetTimeline(c *gin.Context) {
+	matchID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid match id"})
+		return
+	}
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	events, err := h.matchService.GetTimeline(c.Request.Context(), matchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(events) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no timeline for this match"})
+		return
+	}
+	if _, err := h.groupService.GetMemberGroup(c.Request.Context(), events[0].GroupID, userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no timeline for this match"})
+		return
+	}
+
+	resp := gin.H{"events": events}
+	if replayed, deleted, err := services.ReplayMatch(events); err == nil {
+		resp["replayed"] = replayed
+		resp["deleted"] = deleted
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ── Offline Sync ──
+
+type syncRequest struct {
+	DeviceID    string               `json:"device_id" binding:"required"`
+	BaseVersion int                  `json:"base_version"`
+	Events      []services.SyncEvent `json:"events" binding:"required"`
+}
+
+// SyncMatch reconciles rally events a scorer recorded while offline and
+// broadcasts a single score_update with the merged match.
+func (h *MatchHandler) SyncMatch(c *gin.Context) {
+	matchID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid match id"})
+		return
+	}
+
+	var req syncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.checkScorer(c, matchID) {
+		return
+	}
+
+	result, err := h.matchService.SyncEvents(actorContext(c), matchID, req.DeviceID, req.BaseVersion, req.Events)
+	if err != nil {
+		writeMatchError(c, err)
+		return
+	}
+
+	h.hub.BroadcastToGroup(result.Match.GroupID.Hex(), gin.H{"type": "score_update", "match": result.Match})
+	c.JSON(http.StatusOK, result)
+}
+
 // ── Undo Score ──
 
 func (h *MatchHandler) UndoScore(c *gin.Context) {
@@ -132,9 +340,13 @@ func (h *MatchHandler) UndoScore(c *gin.Context) {
 		return
 	}
 
-	match, err := h.matchService.UndoScore(c.Request.Context(), matchID)
+	if !h.checkScorer(c, matchID) {
+		return
+	}
+
+	match, err := h.matchService.UndoScore(actorContext(c), matchID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeMatchError(c, err)
 		return
 	}
 
@@ -142,6 +354,131 @@ func (h *MatchHandler) UndoScore(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"match": match})
 }
 
+// ── Status Transitions ──
+
+func (h *MatchHandler) WarmUpMatch(c *gin.Context) {
+	h.changeStatus(c, func(ctx context.Context, matchID primitive.ObjectID) (*models.Match, error) {
+		return h.matchService.WarmUpMatch(ctx, matchID)
+	})
+}
+
+func (h *MatchHandler) StartMatch(c *gin.Context) {
+	h.changeStatus(c, func(ctx context.Context, matchID primitive.ObjectID) (*models.Match, error) {
+		return h.matchService.StartMatch(ctx, matchID)
+	})
+}
+
+type abandonRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (h *MatchHandler) AbandonMatch(c *gin.Context) {
+	var req abandonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.changeStatus(c, func(ctx context.Context, matchID primitive.ObjectID) (*models.Match, error) {
+		return h.matchService.AbandonMatch(ctx, matchID, req.Reason)
+	})
+}
+
+type walkoverRequest struct {
+	WinnerTeam int    `json:"winner_team" binding:"required"`
+	Reason     string `json:"reason"` // e.g. "no-show", "retired injured"
+}
+
+func (h *MatchHandler) WalkoverMatch(c *gin.Context) {
+	var req walkoverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.changeStatus(c, func(ctx context.Context, matchID primitive.ObjectID) (*models.Match, error) {
+		return h.matchService.WalkoverMatch(ctx, matchID, req.WinnerTeam, req.Reason)
+	})
+}
+
+// changeStatus runs a status transition and tells the group about it. Once a
+// match is over its scorer lock is released.
+func (h *MatchHandler) changeStatus(c *gin.Context, apply func(context.Context, primitive.ObjectID) (*models.Match, error)) {
+	matchID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid match id"})
+		return
+	}
+
+	if !h.checkScorer(c, matchID) {
+		return
+	}
+
+	match, err := apply(actorContext(c), matchID)
+	if err != nil {
+		writeMatchError(c, err)
+		return
+	}
+
+	if models.IsTerminalStatus(match.Status) {
+		_ = h.hub.ReleaseScorer(matchID.Hex(), "", true)
+	}
+	h.hub.BroadcastToGroup(match.GroupID.Hex(), gin.H{"type": "match_status", "match": match})
+	c.JSON(http.StatusOK, gin.H{"match": match})
+}
+
+// ── Pause / Resume ──
+
+type pauseRequest struct {
+	Reason string `json:"reason" binding:"required"` // break, injury, interval, game_interval
+}
+
+func (h *MatchHandler) PauseMatch(c *gin.Context) {
+	matchID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid match id"})
+		return
+	}
+
+	var req pauseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.checkScorer(c, matchID) {
+		return
+	}
+
+	match, err := h.matchService.PauseMatch(actorContext(c), matchID, req.Reason)
+	if err != nil {
+		writeMatchError(c, err)
+		return
+	}
+
+	h.hub.BroadcastToGroup(match.GroupID.Hex(), gin.H{"type": "match_paused", "match": match, "pause": match.OpenPause()})
+	c.JSON(http.StatusOK, gin.H{"match": match})
+}
+
+func (h *MatchHandler) ResumeMatch(c *gin.Context) {
+	matchID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid match id"})
+		return
+	}
+
+	if !h.checkScorer(c, matchID) {
+		return
+	}
+
+	match, err := h.matchService.ResumeMatch(actorContext(c), matchID)
+	if err != nil {
+		writeMatchError(c, err)
+		return
+	}
+
+	h.hub.BroadcastToGroup(match.GroupID.Hex(), gin.H{"type": "match_resumed", "match": match})
+	c.JSON(http.StatusOK, gin.H{"match": match})
+}
+
 // ── Finish Match ──
 
 func (h *MatchHandler) FinishMatch(c *gin.Context) {
@@ -151,12 +488,17 @@ func (h *MatchHandler) FinishMatch(c *gin.Context) {
 		return
 	}
 
-	match, err := h.matchService.FinishMatch(c.Request.Context(), matchID)
+	if !h.checkScorer(c, matchID) {
+		return
+	}
+
+	match, err := h.matchService.FinishMatch(actorContext(c), matchID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeMatchError(c, err)
 		return
 	}
 
+	_ = h.hub.ReleaseScorer(matchID.Hex(), "", true)
 	h.hub.BroadcastToGroup(match.GroupID.Hex(), gin.H{"type": "match_finished", "match": match})
 	c.JSON(http.StatusOK, gin.H{"match": match})
 }
@@ -181,11 +523,12 @@ func (h *MatchHandler) DeleteMatch(c *gin.Context) {
 		return
 	}
 
-	if err := h.matchService.DeleteMatch(c.Request.Context(), matchID); err != nil {
+	if err := h.matchService.DeleteMatch(actorContext(c), matchID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	_ = h.hub.ReleaseScorer(matchID.Hex(), "", true)
 	h.hub.BroadcastToGroup(match.GroupID.Hex(), gin.H{"type": "match_deleted", "match_id": matchID.Hex()})
 	c.JSON(http.StatusOK, gin.H{"message": "match deleted"})
 }
@@ -221,9 +564,13 @@ func (h *MatchHandler) EditScore(c *gin.Context) {
 		return
 	}
 
-	updated, err := h.matchService.EditScore(c.Request.Context(), matchID, req.Score1, req.Score2)
+	if !h.checkScorer(c, matchID) {
+		return
+	}
+
+	updated, err := h.matchService.EditScore(actorContext(c), matchID, req.Score1, req.Score2)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeMatchError(c, err)
 		return
 	}
 
@@ -231,14 +578,119 @@ func (h *MatchHandler) EditScore(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"match": updated})
 }
 
+// ── Scorer Lock ──
+
+// ClaimScorer makes the current user the only one allowed to score a live match.
+func (h *MatchHandler) ClaimScorer(c *gin.Context) {
+	matchID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid match id"})
+		return
+	}
+
+	match, err := h.matchService.GetMatch(c.Request.Context(), matchID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "match not found"})
+		return
+	}
+	if models.IsTerminalStatus(match.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "match is already over"})
+		return
+	}
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	if _, err := h.groupService.GetMemberGroup(c.Request.Context(), match.GroupID, userID); err != nil {
+		writeGroupError(c, err)
+		return
+	}
+
+	lock, err := h.hub.ClaimScorer(match.GroupID.Hex(), matchID.Hex(), c.GetString("user_id"))
+	if err != nil {
+		writeScorerError(c, err)
+		return
+	}
+
+	h.hub.BroadcastToGroup(match.GroupID.Hex(), gin.H{"type": "scorer_lock", "lock": lock})
+	c.JSON(http.StatusOK, gin.H{"lock": lock})
+}
+
+type handOverScorerRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+}
+
+// HandOverScorer passes the scorer lock to another connected group member.
+func (h *MatchHandler) HandOverScorer(c *gin.Context) {
+	matchID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid match id"})
+		return
+	}
+
+	var req handOverScorerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lock, err := h.hub.HandOverScorer(matchID.Hex(), c.GetString("user_id"), req.UserID)
+	if err != nil {
+		writeScorerError(c, err)
+		return
+	}
+
+	h.hub.BroadcastToGroup(lock.GroupID, gin.H{"type": "scorer_lock", "lock": lock})
+	c.JSON(http.StatusOK, gin.H{"lock": lock})
+}
+
+// ReleaseScorer drops the scorer lock. The group creator may release anyone's lock.
+func (h *MatchHandler) ReleaseScorer(c *gin.Context) {
+	matchID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid match id"})
+		return
+	}
+
+	match, err := h.matchService.GetMatch(c.Request.Context(), matchID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "match not found"})
+		return
+	}
+
+	force := h.isGroupCreator(c, match.GroupID)
+	if err := h.hub.ReleaseScorer(matchID.Hex(), c.GetString("user_id"), force); err != nil {
+		writeScorerError(c, err)
+		return
+	}
+
+	h.hub.BroadcastToGroup(match.GroupID.Hex(), gin.H{"type": "scorer_lock_released", "match_id": matchID.Hex()})
+	c.JSON(http.StatusOK, gin.H{"message": "scorer lock released"})
+}
+
+func writeScorerError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ws.ErrScorerLocked):
+		c.JSON(http.StatusLocked, gin.H{"error": err.Error()})
+	case errors.Is(err, ws.ErrNotScorerHolder):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ws.ErrNotPresent):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 // ── Add Result (past match) ──
 
 type addResultRequest struct {
-	GroupID  string   `json:"group_id" binding:"required"`
-	Team1IDs []string `json:"team1_ids" binding:"required"`
-	Team2IDs []string `json:"team2_ids" binding:"required"`
-	Score1   int      `json:"score1"`
-	Score2   int      `json:"score2"`
+	GroupID   string     `json:"group_id" binding:"required"`
+	Team1IDs  []string   `json:"team1_ids" binding:"required"`
+	Team2IDs  []string   `json:"team2_ids" binding:"required"`
+	Score1    int        `json:"score1"`
+	Score2    int        `json:"score2"`
+	SessionID string     `json:"session_id"`
+	PlayedAt  *time.Time `json:"played_at"` // back-dating needs the group's permission
 }
 
 func (h *MatchHandler) AddResult(c *gin.Context) {
@@ -264,9 +716,9 @@ func (h *MatchHandler) AddResult(c *gin.Context) {
 		return
 	}
 
-	match, err := h.matchService.AddResult(c.Request.Context(), groupID, t1, t2, req.Score1, req.Score2)
+	match, err := h.matchService.AddResult(actorContext(c), groupID, t1, t2, req.Score1, req.Score2, req.SessionID, req.PlayedAt)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeMatchError(c, err)
 		return
 	}
 
@@ -275,6 +727,37 @@ func (h *MatchHandler) AddResult(c *gin.Context) {
 
 // ── Utility ──
 
+// actorContext attaches the authenticated user and their device (from the
+// optional X-Device-ID header) to the request context.
+func actorContext(c *gin.Context) context.Context {
+	return services.WithActor(c.Request.Context(), services.Actor{
+		UserID:   c.GetString("user_id"),
+		Username: c.GetString("username"),
+		DeviceID: c.GetHeader("X-Device-ID"),
+	})
+}
+
+// writeMatchError maps service errors to responses. Requests that can never
+// succeed get 400; those refused by the match's state or a concurrent change
+// get 409, and anything unrecognised 500, neither of which is replayed to
+// a retry.
+func writeMatchError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "match not found"})
+	case errors.Is(err, services.ErrMatchConflict), errors.Is(err, services.ErrMatchState):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotGroupMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group members can change this group's matches"})
+	case errors.Is(err, services.ErrBackdatingDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidResult), errors.Is(err, services.ErrInvalidMatchRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 func parseObjectIDs(hexIDs []string) ([]primitive.ObjectID, error) {
 	ids := make([]primitive.ObjectID, len(hexIDs))
 	for i, h := range hexIDs {
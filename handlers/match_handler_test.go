@@ -0,0 +1,137 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupPrivacy_Reads(t *testing.T) {
+	a := newApp(t)
+	ownerToken, _ := a.register(t, "owner")
+	memberToken, _ := a.register(t, "member")
+	outsiderToken, _ := a.register(t, "outsider")
+	groupID, joinCode := a.createGroup(t, ownerToken, "Club")
+	a.join(t, memberToken, joinCode)
+	matchID := a.createMatch(t, ownerToken, groupID)
+
+	reads := []string{
+		"/api/groups/" + groupID + "/matches",
+		"/api/groups/" + groupID + "/players",
+		"/api/groups/" + groupID + "/stats",
+		"/api/matches/" + matchID + "/timeline",
+	}
+	for _, path := range reads {
+		code, _ := a.do(t, http.MethodGet, path, memberToken, nil)
+		assert.Equal(t, http.StatusOK, code, path)
+		code, _ = a.do(t, http.MethodGet, path, outsiderToken, nil)
+		assert.Equal(t, http.StatusNotFound, code, path)
+	}
+
+	// A public group opens everything but the timeline, which names who
+	// made each change.
+	code, out := a.do(t, http.MethodPatch, "/api/groups/"+groupID+"/settings", ownerToken, gin.H{"version": 0, "privacy": "public"})
+	require.Equal(t, http.StatusOK, code, out)
+	for _, path := range reads[:3] {
+		code, _ := a.do(t, http.MethodGet, path, outsiderToken, nil)
+		assert.Equal(t, http.StatusOK, code, path)
+	}
+	code, _ = a.do(t, http.MethodGet, reads[3], outsiderToken, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestGuestsCanScore_OnlyScoring(t *testing.T) {
+	a := newApp(t)
+	ownerToken, _ := a.register(t, "owner")
+	guestToken, _ := a.register(t, "guest")
+	groupID, _ := a.createGroup(t, ownerToken, "Club")
+	matchID := a.createMatch(t, ownerToken, groupID)
+	code, out := a.do(t, http.MethodGet, "/api/groups/"+groupID+"/players", ownerToken, nil)
+	require.Equal(t, http.StatusOK, code, out)
+	scorer := out["players"].([]interface{})[0].(map[string]interface{})["id"].(string)
+	point := gin.H{"team": 1, "player_id": scorer}
+
+	code, _ = a.do(t, http.MethodPost, "/api/matches/"+matchID+"/score", guestToken, point)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, out = a.do(t, http.MethodPatch, "/api/groups/"+groupID+"/settings", ownerToken, gin.H{"version": 0, "guests_can_score": true})
+	require.Equal(t, http.StatusOK, code, out)
+	code, out = a.do(t, http.MethodPost, "/api/matches/"+matchID+"/score", guestToken, point)
+	require.Equal(t, http.StatusOK, code, out)
+	code, _ = a.do(t, http.MethodPost, "/api/matches/"+matchID+"/undo", guestToken, nil)
+	assert.Equal(t, http.StatusOK, code)
+
+	// Running the match stays with members.
+	code, _ = a.do(t, http.MethodPost, "/api/matches/"+matchID+"/pause", guestToken, gin.H{"reason": "break"})
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = a.do(t, http.MethodPost, "/api/matches/"+matchID+"/finish", guestToken, nil)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = a.do(t, http.MethodPost, "/api/matches", guestToken, gin.H{
+		"group_id": groupID, "team1_ids": []string{scorer}, "team2_ids": []string{scorer},
+	})
+	assert.Equal(t, http.StatusForbidden, code)
+}
+
+func TestUpdateScore_PausedIsNotReplayed(t *testing.T) {
+	a := newApp(t)
+	token, _ := a.register(t, "owner")
+	groupID, _ := a.createGroup(t, token, "Club")
+	matchID := a.createMatch(t, token, groupID)
+	key := map[string]string{"Idempotency-Key": "rally-1"}
+
+	code, out := a.do(t, http.MethodPost, "/api/matches/"+matchID+"/pause", token, gin.H{"reason": "break"})
+	require.Equal(t, http.StatusOK, code, out)
+	code, out = a.doWithHeaders(t, http.MethodPost, "/api/matches/"+matchID+"/score", token, key, gin.H{"team": 1, "player_id": "p1"})
+	assert.Equal(t, http.StatusConflict, code, out)
+
+	// Once play resumes, the same rally goes through instead of replaying 409.
+	code, out = a.do(t, http.MethodPost, "/api/matches/"+matchID+"/resume", token, nil)
+	require.Equal(t, http.StatusOK, code, out)
+	code, out = a.doWithHeaders(t, http.MethodPost, "/api/matches/"+matchID+"/score", token, key, gin.H{"team": 1, "player_id": "p1"})
+	require.Equal(t, http.StatusOK, code, out)
+	assert.EqualValues(t, 1, out["match"].(map[string]interface{})["score1"])
+
+	// An invalid rally stays invalid, so its answer is replayed.
+	bad := map[string]string{"Idempotency-Key": "rally-2"}
+	code, _ = a.doWithHeaders(t, http.MethodPost, "/api/matches/"+matchID+"/score", token, bad, gin.H{"team": 3, "player_id": "p1"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = a.doWithHeaders(t, http.MethodPost, "/api/matches/"+matchID+"/score", token, bad, gin.H{"team": 1, "player_id": "p1"})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestScorerLock_BlocksOthers(t *testing.T) {
+	a := newApp(t)
+	ownerToken, _ := a.register(t, "owner")
+	bobToken, bobID := a.register(t, "bob")
+	groupID, joinCode := a.createGroup(t, ownerToken, "Club")
+	a.join(t, bobToken, joinCode)
+	matchID := a.createMatch(t, ownerToken, groupID)
+
+	// Claiming the lock takes an open connection to the group.
+	_, code := a.dial(t, "/ws/group/"+groupID+"?token="+bobToken)
+	require.Equal(t, http.StatusSwitchingProtocols, code)
+	require.Eventually(t, func() bool { return a.hub.IsPresent(groupID, bobID) }, time.Second, 5*time.Millisecond)
+	code, out := a.do(t, http.MethodPost, "/api/matches/"+matchID+"/scorer", bobToken, nil)
+	require.Equal(t, http.StatusOK, code, out)
+
+	// While Bob keeps score, nobody else may change it, the owner included.
+	for _, req := range []struct {
+		method, path string
+		body         interface{}
+	}{
+		{http.MethodPost, "/score", gin.H{"team": 1, "player_id": "p1"}},
+		{http.MethodPost, "/undo", nil},
+		{http.MethodPost, "/finish", nil},
+		{http.MethodPut, "/score", gin.H{"score1": 5, "score2": 3}},
+	} {
+		code, out := a.do(t, req.method, "/api/matches/"+matchID+req.path, ownerToken, req.body)
+		assert.Equal(t, http.StatusLocked, code, "%s %s: %v", req.method, req.path, out)
+	}
+
+	code, out = a.do(t, http.MethodPost, "/api/matches/"+matchID+"/finish", bobToken, nil)
+	assert.Equal(t, http.StatusOK, code, out)
+}
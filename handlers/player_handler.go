@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"gully-backend/models"
 	"gully-backend/services"
 )
 
@@ -37,21 +39,26 @@ func (h *PlayerHandler) CreatePlayer(c *gin.Context) {
 
 	player, err := h.playerService.CreatePlayer(c.Request.Context(), req.Name, groupID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writePlayerError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{"player": player})
 }
 
+// GetPlayers lists the group's players for pickers; ?include_archived=true
+// adds archived ones. Private groups show them to members only.
 func (h *PlayerHandler) GetPlayers(c *gin.Context) {
-	groupID, err := primitive.ObjectIDFromHex(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group id"})
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	if err := h.groupService.CheckVisible(c.Request.Context(), groupID, userID); err != nil {
+		writeGroupError(c, err)
 		return
 	}
 
-	players, err := h.playerService.GetPlayers(c.Request.Context(), groupID)
+	players, err := h.playerService.GetPlayers(c.Request.Context(), groupID, c.Query("include_archived") == "true")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -60,100 +67,382 @@ func (h *PlayerHandler) GetPlayers(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"players": players})
 }
 
-// DeletePlayer removes a player from the group (creator-only).
-func (h *PlayerHandler) DeletePlayer(c *gin.Context) {
-	groupID, err := primitive.ObjectIDFromHex(c.Param("id"))
+// ── Player profile ──
+
+type updatePlayerRequest struct {
+	Name          *string `json:"name"`
+	Nickname      *string `json:"nickname"`
+	Hand          *string `json:"hand"`
+	Position      *string `json:"position"`
+	RenameMatches bool    `json:"rename_matches"`
+}
+
+// UpdatePlayer edits a player's name and profile: the creator, or the user
+// linked to the player. With rename_matches a new name is also written into
+// the player's past matches.
+func (h *PlayerHandler) UpdatePlayer(c *gin.Context) {
+	group, userID, ok := h.loadGroup(c)
+	if !ok {
+		return
+	}
+	playerID, ok := playerParam(c)
+	if !ok {
+		return
+	}
+	var req updatePlayerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	player, err := h.playerService.GetPlayer(c.Request.Context(), group.ID, playerID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group id"})
+		writePlayerError(c, err)
+		return
+	}
+	if group.CreatedBy != userID && !player.LinkedTo(userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group creator or the linked user can edit a player"})
 		return
 	}
 
-	playerID, err := primitive.ObjectIDFromHex(c.Param("playerId"))
+	player, renamed, err := h.playerService.UpdatePlayer(actorContext(c), group.ID, playerID, services.PlayerUpdate{
+		Name:          req.Name,
+		Nickname:      req.Nickname,
+		Hand:          req.Hand,
+		Position:      req.Position,
+		RenameMatches: req.RenameMatches,
+	})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player id"})
+		writePlayerError(c, err)
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"player": player, "matches_renamed": renamed})
+}
 
-	// Check creator
-	userIDStr, _ := c.Get("user_id")
-	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+// DeletePlayer archives a player (creator-only). Archived players leave the
+// pickers but keep their matches and stats; RestorePlayer brings them back.
+func (h *PlayerHandler) DeletePlayer(c *gin.Context) {
+	groupID, ok := h.creatorGroup(c, "only group creator can remove players")
+	if !ok {
+		return
+	}
+	playerID, ok := playerParam(c)
+	if !ok {
 		return
 	}
 
-	group, err := h.groupService.GetGroup(c.Request.Context(), groupID)
+	player, err := h.playerService.ArchivePlayer(c.Request.Context(), groupID, playerID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "group not found"})
+		writePlayerError(c, err)
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": "player archived", "player": player})
+}
 
-	if group.CreatedBy != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "only group creator can remove players"})
+// RestorePlayer un-archives a player (creator-only).
+func (h *PlayerHandler) RestorePlayer(c *gin.Context) {
+	groupID, ok := h.creatorGroup(c, "only group creator can restore players")
+	if !ok {
 		return
 	}
-
-	if err := h.playerService.DeletePlayer(c.Request.Context(), playerID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	playerID, ok := playerParam(c)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "player deleted"})
+	player, err := h.playerService.RestorePlayer(c.Request.Context(), groupID, playerID)
+	if err != nil {
+		writePlayerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"player": player})
 }
 
 // ── Merge Player (creator-only) ──
 
 type mergePlayerRequest struct {
-	TargetPlayerID string `json:"target_player_id" binding:"required"`
-	SourcePlayerID string `json:"source_player_id" binding:"required"`
+	TargetPlayerID string `json:"target_player_id" form:"target_player_id" binding:"required"`
+	SourcePlayerID string `json:"source_player_id" form:"source_player_id" binding:"required"`
+}
+
+// PreviewMerge shows what merging would change without changing anything.
+// The player IDs come from the query string.
+func (h *PlayerHandler) PreviewMerge(c *gin.Context) {
+	groupID, ok := h.creatorGroup(c, "only group creator can merge players")
+	if !ok {
+		return
+	}
+	var req mergePlayerRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	targetID, sourceID, ok := req.ids(c)
+	if !ok {
+		return
+	}
+
+	preview, err := h.playerService.PreviewMerge(c.Request.Context(), groupID, targetID, sourceID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"preview": preview})
 }
 
 func (h *PlayerHandler) MergePlayer(c *gin.Context) {
+	groupID, ok := h.creatorGroup(c, "only group creator can merge players")
+	if !ok {
+		return
+	}
+	var req mergePlayerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	targetID, sourceID, ok := req.ids(c)
+	if !ok {
+		return
+	}
+
+	merge, err := h.playerService.MergePlayer(actorContext(c), groupID, targetID, sourceID)
+	if err != nil {
+		writeMergeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "players merged successfully", "merge": merge})
+}
+
+// GetMerges lists the group's merges, newest first, with their undo deadlines.
+func (h *PlayerHandler) GetMerges(c *gin.Context) {
+	groupID, ok := h.creatorGroup(c, "only group creator can view merges")
+	if !ok {
+		return
+	}
+	merges, err := h.playerService.GetMerges(c.Request.Context(), groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if merges == nil {
+		merges = []models.MergeRecord{}
+	}
+	c.JSON(http.StatusOK, gin.H{"merges": merges})
+}
+
+// UndoMerge reverses a merge within its undo window.
+func (h *PlayerHandler) UndoMerge(c *gin.Context) {
+	groupID, ok := h.creatorGroup(c, "only group creator can undo merges")
+	if !ok {
+		return
+	}
+	mergeID, err := primitive.ObjectIDFromHex(c.Param("mergeId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid merge id"})
+		return
+	}
+
+	merge, player, err := h.playerService.UndoMerge(actorContext(c), groupID, mergeID)
+	if err != nil {
+		writeMergeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "merge undone", "merge": merge, "player": player})
+}
+
+// creatorGroup parses the group ID and checks the caller created the group,
+// writing the error response if not.
+func (h *PlayerHandler) creatorGroup(c *gin.Context, forbidden string) (primitive.ObjectID, bool) {
+	group, userID, ok := h.loadGroup(c)
+	if !ok {
+		return primitive.NilObjectID, false
+	}
+	if group.CreatedBy != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": forbidden})
+		return group.ID, false
+	}
+	return group.ID, true
+}
+
+// loadGroup fetches the group named in the path and the caller's user ID,
+// writing the error response on failure.
+func (h *PlayerHandler) loadGroup(c *gin.Context) (*models.Group, primitive.ObjectID, bool) {
 	groupID, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group id"})
-		return
+		return nil, primitive.NilObjectID, false
 	}
 
-	// Check creator
 	userIDStr, _ := c.Get("user_id")
 	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
-		return
+		return nil, primitive.NilObjectID, false
 	}
 
 	group, err := h.groupService.GetGroup(c.Request.Context(), groupID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "group not found"})
+		return nil, primitive.NilObjectID, false
+	}
+	return group, userID, true
+}
+
+func (r mergePlayerRequest) ids(c *gin.Context) (targetID, sourceID primitive.ObjectID, ok bool) {
+	targetID, err := primitive.ObjectIDFromHex(r.TargetPlayerID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid target_player_id"})
+		return targetID, sourceID, false
+	}
+	sourceID, err = primitive.ObjectIDFromHex(r.SourcePlayerID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid source_player_id"})
+		return targetID, sourceID, false
+	}
+	return targetID, sourceID, true
+}
+
+func writeMergeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrMergeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMergeUndoExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMergeConflict), errors.Is(err, services.ErrMergeLinked), errors.Is(err, services.ErrMergeStale),
+		errors.Is(err, services.ErrMergeUndone), errors.Is(err, services.ErrMatchConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// ── Player ↔ user links ──
+
+// ClaimPlayer asks for a guest player to be linked to the caller. The group
+// creator's own claims are linked at once; anyone else's wait for approval.
+func (h *PlayerHandler) ClaimPlayer(c *gin.Context) {
+	group, userID, ok := h.loadGroup(c)
+	if !ok {
 		return
 	}
-	if group.CreatedBy != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "only group creator can merge players"})
+	if !group.HasMember(userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group members can claim players"})
+		return
+	}
+	playerID, ok := playerParam(c)
+	if !ok {
 		return
 	}
 
-	var req mergePlayerRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	player, err := h.playerService.ClaimPlayer(c.Request.Context(), group.ID, playerID, userID, group.CreatedBy == userID)
+	if err != nil {
+		writePlayerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"player": player})
+}
+
+// ApproveClaim links a claimed player to its claimant (creator-only). The
+// claimant's previous player in the group, if any, is unlinked and returned
+// as "released".
+func (h *PlayerHandler) ApproveClaim(c *gin.Context) {
+	groupID, ok := h.creatorGroup(c, "only group creator can approve claims")
+	if !ok {
+		return
+	}
+	playerID, ok := playerParam(c)
+	if !ok {
 		return
 	}
 
-	targetID, err := primitive.ObjectIDFromHex(req.TargetPlayerID)
+	player, released, err := h.playerService.ApproveClaim(c.Request.Context(), groupID, playerID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid target_player_id"})
+		writePlayerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"player": player, "released": released})
+}
+
+// RejectClaim drops a pending claim: the creator rejecting it or the
+// claimant withdrawing it.
+func (h *PlayerHandler) RejectClaim(c *gin.Context) {
+	group, userID, ok := h.loadGroup(c)
+	if !ok {
+		return
+	}
+	playerID, ok := playerParam(c)
+	if !ok {
 		return
 	}
-	sourceID, err := primitive.ObjectIDFromHex(req.SourcePlayerID)
+	player, err := h.playerService.GetPlayer(c.Request.Context(), group.ID, playerID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid source_player_id"})
+		writePlayerError(c, err)
+		return
+	}
+	claimant := player.ClaimedBy != nil && *player.ClaimedBy == userID
+	if group.CreatedBy != userID && !claimant {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group creator or the claimant can drop a claim"})
 		return
 	}
 
-	if err := h.playerService.MergePlayer(c.Request.Context(), groupID, targetID, sourceID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	player, err = h.playerService.RejectClaim(c.Request.Context(), group.ID, playerID)
+	if err != nil {
+		writePlayerError(c, err)
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"player": player})
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "players merged successfully"})
+// UnlinkPlayer turns a linked player back into a guest: the linked user
+// letting go of it, or the creator removing the link.
+func (h *PlayerHandler) UnlinkPlayer(c *gin.Context) {
+	group, userID, ok := h.loadGroup(c)
+	if !ok {
+		return
+	}
+	playerID, ok := playerParam(c)
+	if !ok {
+		return
+	}
+	player, err := h.playerService.GetPlayer(c.Request.Context(), group.ID, playerID)
+	if err != nil {
+		writePlayerError(c, err)
+		return
+	}
+	if group.CreatedBy != userID && !player.LinkedTo(userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group creator or the linked user can unlink a player"})
+		return
+	}
+
+	player, err = h.playerService.UnlinkPlayer(c.Request.Context(), group.ID, playerID)
+	if err != nil {
+		writePlayerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"player": player})
+}
+
+func playerParam(c *gin.Context) (primitive.ObjectID, bool) {
+	playerID, err := primitive.ObjectIDFromHex(c.Param("playerId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player id"})
+		return playerID, false
+	}
+	return playerID, true
+}
+
+func writePlayerError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPlayerNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidPlayer):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPlayerLinked), errors.Is(err, services.ErrClaimPending),
+		errors.Is(err, services.ErrNoClaim), errors.Is(err, services.ErrPlayerUnlinked),
+		errors.Is(err, services.ErrPlayerNameTaken), errors.Is(err, services.ErrPlayerArchived),
+		errors.Is(err, services.ErrPlayerActive), errors.Is(err, services.ErrMatchConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
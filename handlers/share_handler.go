@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+	"gully-backend/services"
+	ws "gully-backend/websocket"
+)
+
+// ShareHandler serves share links: members make and revoke them, and anyone
+// holding a token can read the shared matches and watch them live. The
+// public side only reads; scoring stays behind MatchHandler and login.
+type ShareHandler struct {
+	shareService *services.ShareService
+	hub          *ws.Hub
+}
+
+func NewShareHandler(shareService *services.ShareService, hub *ws.Hub) *ShareHandler {
+	return &ShareHandler{shareService: shareService, hub: hub}
+}
+
+type createShareLinkRequest struct {
+	MatchID   string     `json:"match_id"` // empty shares the whole group
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateShareLink makes a share link. The token is in this response only.
+func (h *ShareHandler) CreateShareLink(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	var req createShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var matchID *primitive.ObjectID
+	if req.MatchID != "" {
+		id, err := primitive.ObjectIDFromHex(req.MatchID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid match id"})
+			return
+		}
+		matchID = &id
+	}
+	link, token, err := h.shareService.CreateShareLink(c.Request.Context(), groupID, userID, matchID, req.ExpiresAt)
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"share_link": link, "token": token})
+}
+
+func (h *ShareHandler) GetShareLinks(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	links, err := h.shareService.ListShareLinks(c.Request.Context(), groupID, userID)
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+	if links == nil {
+		links = []models.ShareLink{}
+	}
+	c.JSON(http.StatusOK, gin.H{"share_links": links})
+}
+
+// RevokeShareLink stops the link working and disconnects its viewers.
+func (h *ShareHandler) RevokeShareLink(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	linkID, err := primitive.ObjectIDFromHex(c.Param("linkId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid share link id"})
+		return
+	}
+	link, err := h.shareService.RevokeShareLink(c.Request.Context(), groupID, userID, linkID)
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+	h.hub.CloseShare(groupID.Hex(), linkID.Hex())
+	c.JSON(http.StatusOK, gin.H{"share_link": link})
+}
+
+// ── Public ──
+
+// GetShared returns what the token in the path shows: one match, or the
+// group's active matches. No login needed.
+func (h *ShareHandler) GetShared(c *gin.Context) {
+	link, err := h.shareService.Resolve(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+	view, err := h.shareService.View(c.Request.Context(), link)
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"shared": view})
+}
+
+// WatchShared is the WebSocket at /ws/share/:token. It streams the shared
+// match events until the link expires or is revoked.
+func (h *ShareHandler) WatchShared(c *gin.Context) {
+	link, err := h.shareService.Resolve(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+	// Check the group and match are still there before upgrading.
+	if _, err := h.shareService.View(c.Request.Context(), link); err != nil {
+		writeShareError(c, err)
+		return
+	}
+	var matchID string
+	if link.MatchID != nil {
+		matchID = link.MatchID.Hex()
+	}
+	h.hub.ServeShare(c, link.GroupID.Hex(), matchID, link.ID.Hex(), link.ExpiresAt)
+}
+
+func writeShareError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "group not found"})
+	case errors.Is(err, services.ErrInvalidShareLink):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotGroupOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotGroupMember), errors.Is(err, services.ErrShareLinkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
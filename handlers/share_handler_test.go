@@ -0,0 +1,116 @@
+package handlers_test
+
+import (
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// shareLink makes a link as the given member and returns its ID and token.
+func (a *app) shareLink(t *testing.T, token, groupID string, body gin.H) (linkID, shareToken string) {
+	t.Helper()
+	code, out := a.do(t, http.MethodPost, "/api/groups/"+groupID+"/share-links", token, body)
+	require.Equal(t, http.StatusCreated, code, out)
+	return out["share_link"].(map[string]interface{})["id"].(string), out["token"].(string)
+}
+
+func TestGetShared_HidesGroup(t *testing.T) {
+	a := newApp(t)
+	token, _ := a.register(t, "owner")
+	groupID, joinCode := a.createGroup(t, token, "Club")
+	matchID := a.createMatch(t, token, groupID)
+
+	for _, body := range []gin.H{{}, {"match_id": matchID}} {
+		_, shareToken := a.shareLink(t, token, groupID, body)
+		req, err := http.NewRequest(http.MethodGet, a.srv.URL+"/api/share/"+shareToken, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		var raw strings.Builder
+		_, _ = io.Copy(&raw, resp.Body)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode, raw.String())
+		assert.Contains(t, raw.String(), matchID)
+		assert.NotContains(t, raw.String(), groupID)
+		assert.NotContains(t, raw.String(), joinCode)
+	}
+
+	code, _ := a.do(t, http.MethodGet, "/api/share/"+primitive.NewObjectID().Hex()+".nope", "", nil)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestShareToken_CannotMutateMatches(t *testing.T) {
+	a := newApp(t)
+	token, _ := a.register(t, "owner")
+	groupID, _ := a.createGroup(t, token, "Club")
+	matchID := a.createMatch(t, token, groupID)
+	_, shareToken := a.shareLink(t, token, groupID, gin.H{"match_id": matchID})
+
+	param := regexp.MustCompile(`:[A-Za-z]+`)
+	checked := 0
+	for _, route := range a.routes {
+		if !strings.Contains(route.Handler, "(*MatchHandler)") {
+			continue
+		}
+		path := param.ReplaceAllString(route.Path, matchID)
+		code, out := a.do(t, route.Method, path, shareToken, gin.H{"team": 1})
+		assert.Equal(t, http.StatusUnauthorized, code, "%s %s: %v", route.Method, route.Path, out)
+		code, _ = a.do(t, route.Method, path+"?token="+shareToken, "", gin.H{"team": 1})
+		assert.Equal(t, http.StatusUnauthorized, code, "%s %s with ?token=", route.Method, route.Path)
+		checked++
+	}
+	assert.Greater(t, checked, 10)
+
+	// The public share routes only read.
+	for _, route := range a.routes {
+		if strings.Contains(route.Path, "/share/") {
+			assert.Equal(t, http.MethodGet, route.Method, route.Path)
+			assert.Contains(t, route.Handler, "(*ShareHandler)", route.Path)
+		}
+	}
+
+	// Nor can the token open the group's own feed.
+	_, code := a.dial(t, "/ws/group/"+groupID+"?token="+shareToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestWatchShared_RevokeAndExpiry(t *testing.T) {
+	a := newApp(t)
+	token, _ := a.register(t, "owner")
+	groupID, _ := a.createGroup(t, token, "Club")
+	matchID := a.createMatch(t, token, groupID)
+	linkID, shareToken := a.shareLink(t, token, groupID, gin.H{"match_id": matchID})
+
+	conn, code := a.dial(t, "/ws/share/"+shareToken)
+	require.Equal(t, http.StatusSwitchingProtocols, code)
+	code, out := a.do(t, http.MethodPost, "/api/matches/"+matchID+"/pause", token, gin.H{"reason": "break"})
+	require.Equal(t, http.StatusOK, code, out)
+	assert.Equal(t, "match_paused", readType(t, conn))
+
+	code, out = a.do(t, http.MethodDelete, "/api/groups/"+groupID+"/share-links/"+linkID, token, nil)
+	require.Equal(t, http.StatusOK, code, out)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, _, err := conn.ReadMessage()
+	require.Error(t, err)
+	assert.False(t, isTimeout(err), "revoking closes the stream")
+	_, code = a.dial(t, "/ws/share/"+shareToken)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// A link's stream ends when it expires.
+	expires := time.Now().Add(300 * time.Millisecond)
+	_, shareToken = a.shareLink(t, token, groupID, gin.H{"expires_at": expires})
+	conn, code = a.dial(t, "/ws/share/"+shareToken)
+	require.Equal(t, http.StatusSwitchingProtocols, code)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, _, err = conn.ReadMessage()
+	require.Error(t, err)
+	assert.False(t, isTimeout(err), "expiry closes the stream")
+}
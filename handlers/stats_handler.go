@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/services"
+)
+
+type StatsHandler struct {
+	statsService *services.StatsService
+	groupService *services.GroupService
+}
+
+func NewStatsHandler(statsService *services.StatsService, groupService *services.GroupService) *StatsHandler {
+	return &StatsHandler{statsService: statsService, groupService: groupService}
+}
+
+// GetGroupStats returns per-player results and rally stats for a group: the
+// leaderboard. Private groups show it to members only.
+func (h *StatsHandler) GetGroupStats(c *gin.Context) {
+	groupID, userID, ok := groupAndUser(c)
+	if !ok {
+		return
+	}
+	if err := h.groupService.CheckVisible(c.Request.Context(), groupID, userID); err != nil {
+		writeGroupError(c, err)
+		return
+	}
+
+	stats, err := h.statsService.GroupStats(c.Request.Context(), groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
+
+// GetMyStats returns the caller's stats in each group they have a linked
+// player in, and overall.
+func (h *StatsHandler) GetMyStats(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	stats, err := h.statsService.UserStats(c.Request.Context(), userID, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
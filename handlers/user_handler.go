@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/repositories"
+	"gully-backend/services"
+	"gully-backend/websocket"
+)
+
+type UserHandler struct {
+	accountService *services.AccountService
+	exportService  *services.ExportService
+	hub            *websocket.Hub
+}
+
+func NewUserHandler(accountService *services.AccountService, exportService *services.ExportService, hub *websocket.Hub) *UserHandler {
+	return &UserHandler{accountService: accountService, exportService: exportService, hub: hub}
+}
+
+// GetMe returns the caller's account and profile.
+func (h *UserHandler) GetMe(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	user, err := h.accountService.GetProfile(c.Request.Context(), userID)
+	if err != nil {
+		writeAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+type updateMeRequest struct {
+	DisplayName *string `json:"display_name"`
+	Phone       *string `json:"phone"`
+}
+
+// UpdateMe edits the caller's profile. Omitted fields are left alone; empty
+// strings clear them.
+func (h *UserHandler) UpdateMe(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req updateMeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, err := h.accountService.UpdateProfile(c.Request.Context(), userID, services.ProfileUpdate{
+		DisplayName: req.DisplayName,
+		Phone:       req.Phone,
+	})
+	if err != nil {
+		writeAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+type deleteMeRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// DeleteMe deletes the caller's account once they confirm their password.
+// Groups they created are handed on or deleted, and their players are
+// anonymised; the response says which.
+func (h *UserHandler) DeleteMe(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req deleteMeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	deleted, err := h.accountService.DeleteAccount(c.Request.Context(), userID, req.Password)
+	if err != nil {
+		writeAccountError(c, err)
+		return
+	}
+
+	// Members are told as if the user had left each group themselves.
+	for _, groupID := range deleted.GroupsDeleted {
+		h.hub.BroadcastToGroup(groupID.Hex(), gin.H{"type": "group_deleted", "group_id": groupID.Hex()})
+		h.hub.CloseGroup(groupID.Hex())
+	}
+	for _, left := range deleted.GroupsLeft {
+		groupID := left.Group.ID.Hex()
+		h.hub.BroadcastToGroup(groupID, gin.H{"type": "member_left", "user_id": userID.Hex(), "group": left.Group})
+		h.hub.CloseMember(groupID, userID.Hex())
+		for _, linkID := range left.LinksRevoked {
+			h.hub.CloseShare(groupID, linkID.Hex())
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "account deleted", "deletion": deleted})
+}
+
+// ExportMe streams everything stored about the caller as a JSON download.
+func (h *UserHandler) ExportMe(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	w := &downloadWriter{c: c, filename: fmt.Sprintf("gully-export-%s.json", time.Now().UTC().Format("2006-01-02"))}
+	err := h.exportService.Export(c.Request.Context(), userID, w)
+	if err == nil {
+		return
+	}
+	if !w.started {
+		writeAccountError(c, err)
+		return
+	}
+	// The status is already sent. The document is left unterminated, so the
+	// client cannot mistake it for a complete export.
+	log.Printf("export for user %s failed: %v", userID.Hex(), err)
+	c.Abort()
+}
+
+// downloadWriter sends the download headers with the first write, so an
+// export that fails before writing can still answer with an error status.
+type downloadWriter struct {
+	c        *gin.Context
+	filename string
+	started  bool
+}
+
+func (w *downloadWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Type", "application/json; charset=utf-8")
+		w.c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", w.filename))
+		w.c.Status(http.StatusOK)
+	}
+	return w.c.Writer.Write(p)
+}
+
+func currentUser(c *gin.Context) (primitive.ObjectID, bool) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
+
+func writeAccountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	case errors.Is(err, services.ErrInvalidProfile):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrWrongPassword):
+		c.JSON(http.StatusForbidden, gin.H{"error": "password is incorrect"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,78 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gully-backend/services"
+)
+
+func TestDeleteMe_TellsGroups(t *testing.T) {
+	a := newApp(t)
+	bobToken, _ := a.register(t, "bob")
+	aliceToken, _ := a.register(t, "alice")
+	clubID, clubCode := a.createGroup(t, bobToken, "Club")
+	a.join(t, aliceToken, clubCode)
+	soloID, _ := a.createGroup(t, aliceToken, "Solo")
+
+	bob, code := a.dial(t, "/ws/group/"+clubID+"?token="+bobToken)
+	require.Equal(t, http.StatusSwitchingProtocols, code)
+	assert.Equal(t, "presence_join", readType(t, bob))
+	alice, code := a.dial(t, "/ws/group/"+soloID+"?token="+aliceToken)
+	require.Equal(t, http.StatusSwitchingProtocols, code)
+	assert.Equal(t, "presence_join", readType(t, alice))
+	aliceClub, code := a.dial(t, "/ws/group/"+clubID+"?token="+aliceToken)
+	require.Equal(t, http.StatusSwitchingProtocols, code)
+	assert.Equal(t, "presence_join", readType(t, bob))
+	_, shareToken := a.shareLink(t, aliceToken, clubID, gin.H{})
+	viewer, code := a.dial(t, "/ws/share/"+shareToken)
+	require.Equal(t, http.StatusSwitchingProtocols, code)
+
+	code, out := a.do(t, http.MethodDelete, "/api/user/me", aliceToken, gin.H{"password": "password123"})
+	require.Equal(t, http.StatusOK, code, out)
+
+	assert.Equal(t, "member_left", readType(t, bob))
+
+	// The group that went with the account is closed after saying so.
+	assert.Equal(t, "group_deleted", readType(t, alice))
+	assertClosed(t, alice)
+
+	// In the group that carries on, their socket and share links close.
+	assertClosed(t, aliceClub)
+	assertClosed(t, viewer)
+	code, _ = a.do(t, http.MethodGet, "/api/share/"+shareToken, "", nil)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestChangePassword_LockedOut(t *testing.T) {
+	a := newApp(t)
+	token, _ := a.register(t, "alice")
+	wrong := gin.H{"current_password": "wrong", "new_password": "newpassword1"}
+
+	for i := 1; i < services.LoginLockoutThreshold; i++ {
+		code, out := a.do(t, http.MethodPost, "/api/auth/change-password", token, wrong)
+		require.Equal(t, http.StatusForbidden, code, out)
+	}
+
+	body, err := json.Marshal(wrong)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, a.srv.URL+"/api/auth/change-password", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "60", resp.Header.Get("Retry-After"))
+
+	// Logging in is locked too: it is the same count.
+	code, _ := a.do(t, http.MethodPost, "/api/auth/login", "", gin.H{"username": "alice", "password": "password123"})
+	assert.Equal(t, http.StatusTooManyRequests, code)
+}
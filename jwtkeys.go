@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"gully-backend/config"
+	"gully-backend/services"
+)
+
+// loadJWTKeys builds the access token keys from the JWT_* settings.
+func loadJWTKeys(cfg *config.Config) *services.JWTKeys {
+	var keys *services.JWTKeys
+	if cfg.JWTAlgorithm == services.JWTAlgHS256 {
+		keys = services.NewHMACKeys(cfg.JWTSecret)
+	} else {
+		private, err := os.ReadFile(cfg.JWTPrivateKeyFile)
+		if err != nil {
+			log.Fatalf("JWT private key: %v", err)
+		}
+		keys, err = services.ParseJWTKeys(cfg.JWTAlgorithm, cfg.JWTKeyID, private)
+		if err != nil {
+			log.Fatalf("JWT private key: %v", err)
+		}
+		for kid, path := range cfg.JWTVerifyKeys {
+			public, err := os.ReadFile(path)
+			if err != nil {
+				log.Fatalf("JWT verify key %q: %v", kid, err)
+			}
+			if err := keys.AddVerifyKey(kid, public); err != nil {
+				log.Fatalf("JWT verify key: %v", err)
+			}
+		}
+		log.Printf("Signing access tokens with %s key %q; %d older key(s) accepted", keys.Alg(), cfg.JWTKeyID, len(cfg.JWTVerifyKeys))
+	}
+	keys.Issuer = cfg.JWTIssuer
+	keys.Audience = cfg.JWTAudience
+	return keys
+}
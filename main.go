@@ -1,18 +1,15 @@
 package main
 
 import (
-	"context"
 	"log"
+	"os"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"gully-backend/config"
 	"gully-backend/handlers"
-	"gully-backend/repositories"
 	"gully-backend/routes"
 	"gully-backend/services"
 	ws "gully-backend/websocket"
@@ -22,62 +19,68 @@ func main() {
 	// 1. Load config
 	cfg := config.Load()
 
-	// 2. Connect to MongoDB
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURI))
-	if err != nil {
-		log.Fatalf("MongoDB connect error: %v", err)
-	}
-	defer func() {
-		if err := client.Disconnect(context.Background()); err != nil {
-			log.Printf("MongoDB disconnect error: %v", err)
-		}
-	}()
-
-	if err := client.Ping(ctx, nil); err != nil {
-		log.Fatalf("MongoDB ping error: %v", err)
+	// "gully-backend migrate [status]" manages the schema and exits.
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(cfg, os.Args[2:])
+		return
 	}
-	log.Println("Connected to MongoDB")
 
-	db := client.Database("gullybadminton")
+	// 2. Open storage and init repos
+	store := openStorage(cfg)
+	defer store.close()
+	userRepo := store.users
+	groupRepo := store.groups
+	playerRepo := store.players
+	matchRepo := store.matches
+	matchEventRepo := store.matchEvents
 
-	// 3. Init repos
-	userRepo := repositories.NewUserRepo(db)
-	groupRepo := repositories.NewGroupRepo(db)
-	playerRepo := repositories.NewPlayerRepo(db)
-	matchRepo := repositories.NewMatchRepo(db)
-
-	// 4. Init services
-	authService := services.NewAuthService(userRepo, cfg.JWTSecret)
-	groupService := services.NewGroupService(groupRepo)
-	playerService := services.NewPlayerService(playerRepo, matchRepo)
-	matchService := services.NewMatchService(matchRepo, playerRepo)
+	// 3. Init services
+	authService := services.NewAuthService(userRepo, store.sessions, nil, loadJWTKeys(cfg), cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	var resetNotifier services.PasswordResetNotifier
+	switch cfg.PasswordResetNotifier {
+	case "log":
+		resetNotifier = services.NewLogNotifier()
+	case "file":
+		resetNotifier = services.NewFileNotifier(cfg.PasswordResetFile)
+	}
+	passwordService := services.NewPasswordService(userRepo, store.resets, authService, resetNotifier, cfg.PasswordResetTTL)
+	playerService := services.NewPlayerService(playerRepo, matchRepo, matchEventRepo, store.mergeRecords, store.tx)
+	groupService := services.NewGroupService(groupRepo, store.invites, store.joinRequests, store.shareLinks, playerService, store.tx)
+	accountService := services.NewAccountService(userRepo, store.resets, groupService, playerService, authService, store.tx)
+	exportService := services.NewExportService(userRepo, groupRepo, playerRepo, matchRepo)
+	matchService := services.NewMatchService(matchRepo, playerRepo, matchEventRepo, groupRepo, store.tx)
+	statsService := services.NewStatsService(matchRepo, playerRepo)
+	shareService := services.NewShareService(store.shareLinks, groupRepo, matchRepo)
 
-	// 5. Init WebSocket hub
+	// 4. Init WebSocket hub
 	hub := ws.NewHub()
 
-	// 6. Init handlers
-	authHandler := handlers.NewAuthHandler(authService)
-	groupHandler := handlers.NewGroupHandler(groupService, playerService, userRepo)
+	// 5. Init handlers
+	authHandler := handlers.NewAuthHandler(authService, passwordService)
+	userHandler := handlers.NewUserHandler(accountService, exportService, hub)
+	groupHandler := handlers.NewGroupHandler(groupService, playerService, userRepo, hub)
 	playerHandler := handlers.NewPlayerHandler(playerService, groupService)
 	matchHandler := handlers.NewMatchHandler(matchService, groupService, hub)
+	statsHandler := handlers.NewStatsHandler(statsService, groupService)
+	shareHandler := handlers.NewShareHandler(shareService, hub)
 
-	// 7. Setup Gin
+	// 6. Setup Gin
 	r := gin.Default()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("TRUSTED_PROXIES: %v", err)
+	}
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Idempotency-Key", "X-Device-ID"},
+		ExposeHeaders:    []string{"Content-Length", "Idempotent-Replayed"},
 		AllowCredentials: false,
 		MaxAge:           12 * time.Hour,
 	}))
 
-	routes.Setup(r, cfg.JWTSecret, authHandler, groupHandler, playerHandler, matchHandler, hub)
+	routes.Setup(r, cfg, authService, authHandler, userHandler, groupHandler, playerHandler, matchHandler, statsHandler, shareHandler)
 
-	// 8. Start server
+	// 7. Start server
 	log.Printf("Server starting on :%s", cfg.Port)
 	if err := r.Run(":" + cfg.Port); err != nil {
 		log.Fatalf("Server error: %v", err)
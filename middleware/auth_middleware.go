@@ -1,16 +1,31 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+
+	"gully-backend/services"
 )
 
-func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+// TokenVerifier checks an access token, including that its session is live.
+type TokenVerifier interface {
+	VerifyAccessToken(ctx context.Context, token string) (*services.AccessClaims, error)
+}
+
+// AuthMiddleware requires a valid access token, normally as a Bearer
+// Authorization header. Browsers cannot set headers on a WebSocket, so
+// upgrade requests may pass it as ?token= instead.
+func AuthMiddleware(tokens TokenVerifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" && websocket.IsWebSocketUpgrade(c.Request) && c.Query("token") != "" {
+			authHeader = "Bearer " + c.Query("token")
+		}
 		if authHeader == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization header required"})
 			return
@@ -22,23 +37,19 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 			return
 		}
 
-		tokenStr := parts[1]
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			return []byte(jwtSecret), nil
-		})
-		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		claims, err := tokens.VerifyAccessToken(c.Request.Context(), parts[1])
+		if errors.Is(err, services.ErrSessionRevoked) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
 		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			return
 		}
 
-		c.Set("user_id", claims["user_id"])
-		c.Set("username", claims["username"])
+		c.Set("user_id", claims.UserID)
+		c.Set("username", claims.Username)
+		c.Set("session_id", claims.SessionID)
 		c.Next()
 	}
 }
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"gully-backend/services"
+)
+
+// fakeTokens accepts the token "good" as user u1.
+type fakeTokens struct{}
+
+func (fakeTokens) VerifyAccessToken(_ context.Context, token string) (*services.AccessClaims, error) {
+	if token != "good" {
+		return nil, errors.New("bad token")
+	}
+	return &services.AccessClaims{UserID: "u1", Username: "alice"}, nil
+}
+
+func authServer() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/me", AuthMiddleware(fakeTokens{}), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("user_id"))
+	})
+	return r
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	r := authServer()
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	upgrade := http.Header{
+		"Connection":            {"Upgrade"},
+		"Upgrade":               {"websocket"},
+		"Sec-Websocket-Version": {"13"},
+		"Sec-Websocket-Key":     {"dGhlIHNhbXBsZSBub25jZQ=="},
+	}
+
+	w := get("/me", http.Header{"Authorization": {"Bearer good"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "u1", w.Body.String())
+
+	assert.Equal(t, http.StatusUnauthorized, get("/me", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, get("/me", http.Header{"Authorization": {"Bearer bad"}}).Code)
+	assert.Equal(t, http.StatusUnauthorized, get("/me", http.Header{"Authorization": {"good"}}).Code)
+
+	// ?token= is only read on WebSocket upgrades, and must be valid there.
+	assert.Equal(t, http.StatusUnauthorized, get("/me?token=good", nil).Code)
+	assert.Equal(t, http.StatusOK, get("/me?token=good", upgrade).Code)
+	assert.Equal(t, http.StatusUnauthorized, get("/me?token=bad", upgrade).Code)
+}
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyHeader is the request header clients set to make retries safe.
+const IdempotencyHeader = "Idempotency-Key"
+
+const maxIdempotencyKeyLen = 255
+
+// StoredResponse is the first response produced for an idempotency key.
+type StoredResponse struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// IdempotencyStore remembers responses per idempotency key.
+type IdempotencyStore interface {
+	// Reserve claims key for a new request. It returns the stored response
+	// if the key already completed, or reserved=false if it is in flight.
+	Reserve(key string, ttl time.Duration) (resp *StoredResponse, reserved bool)
+	// Complete stores the response for a reserved key.
+	Complete(key string, resp StoredResponse, ttl time.Duration)
+	// Release frees a reserved key without storing anything, so it can be retried.
+	Release(key string)
+}
+
+// rememberedStatuses are the client errors a retry would get again, so they
+// are replayed like successes: requests that are invalid as sent. Others,
+// such as 409 for a conflicting edit or a paused match, 423 for a scorer
+// lock or any 5xx, may pass on retry.
+var rememberedStatuses = map[int]bool{
+	http.StatusBadRequest:          true,
+	http.StatusUnprocessableEntity: true,
+}
+
+// Idempotency replays the original response when a request is retried with the
+// same Idempotency-Key, instead of applying the change again. Keys are scoped to
+// the authenticated user and the request path (which carries the match ID).
+// Only successes and rememberedStatuses are kept; anything else, including
+// a panic, frees the key so the client can retry.
+func Idempotency(store IdempotencyStore, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "idempotency key too long"})
+			return
+		}
+
+		scoped := c.GetString("user_id") + "|" + c.Request.Method + "|" + c.Request.URL.Path + "|" + key
+		stored, reserved := store.Reserve(scoped, ttl)
+		if stored != nil {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(stored.Status, stored.ContentType, stored.Body)
+			c.Abort()
+			return
+		}
+		if !reserved {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "a request with this idempotency key is still being processed"})
+			return
+		}
+
+		completed := false
+		defer func() {
+			if !completed {
+				store.Release(scoped)
+			}
+		}()
+
+		rec := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = rec
+		c.Next()
+
+		status := rec.Status()
+		if (status < http.StatusOK || status >= http.StatusMultipleChoices) && !rememberedStatuses[status] {
+			return
+		}
+		store.Complete(scoped, StoredResponse{
+			Status:      status,
+			ContentType: rec.Header().Get("Content-Type"),
+			Body:        rec.body.Bytes(),
+		}, ttl)
+		completed = true
+	}
+}
+
+// recordingWriter tees the response body so it can be stored.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// ── Memory store ──
+
+type idempotencyEntry struct {
+	resp      *StoredResponse // nil while the request is in flight
+	expiresAt time.Time
+}
+
+// MemoryIdempotencyStore keeps keys in process memory. It suits a single
+// instance; run several instances behind a shared store instead.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+	sweptAt time.Time
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{entries: make(map[string]*idempotencyEntry)}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(key string, ttl time.Duration) (*StoredResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now, ttl)
+	if e, ok := s.entries[key]; ok && now.Before(e.expiresAt) {
+		return e.resp, false
+	}
+	s.entries[key] = &idempotencyEntry{expiresAt: now.Add(ttl)}
+	return nil, true
+}
+
+func (s *MemoryIdempotencyStore) Complete(key string, resp StoredResponse, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = &idempotencyEntry{resp: &resp, expiresAt: time.Now().Add(ttl)}
+}
+
+func (s *MemoryIdempotencyStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+}
+
+// sweep drops expired entries at most once per TTL. Caller holds s.mu.
+func (s *MemoryIdempotencyStore) sweep(now time.Time, ttl time.Duration) {
+	if now.Sub(s.sweptAt) < ttl {
+		return
+	}
+	for k, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, k)
+		}
+	}
+	s.sweptAt = now
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// idempotentServer answers POST /do with the status in ?status= and counts
+// the calls that reach it. ?panic=1 panics instead.
+func idempotentServer(calls *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.CustomRecovery(func(c *gin.Context, _ interface{}) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	r.POST("/do", func(c *gin.Context) {
+		c.Set("user_id", c.Query("user"))
+		c.Next()
+	}, Idempotency(NewMemoryIdempotencyStore(), time.Hour), func(c *gin.Context) {
+		*calls++
+		if c.Query("panic") != "" {
+			panic("boom")
+		}
+		status := http.StatusOK
+		switch c.Query("status") {
+		case "400":
+			status = http.StatusBadRequest
+		case "409":
+			status = http.StatusConflict
+		case "423":
+			status = http.StatusLocked
+		case "500":
+			status = http.StatusInternalServerError
+		}
+		c.JSON(status, gin.H{"call": *calls})
+	})
+	return r
+}
+
+func post(r *gin.Engine, query, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/do?"+query, nil)
+	if key != "" {
+		req.Header.Set(IdempotencyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_ReplaysSuccess(t *testing.T) {
+	calls := 0
+	r := idempotentServer(&calls)
+
+	first := post(r, "user=u1", "k1")
+	require.Equal(t, http.StatusOK, first.Code)
+	again := post(r, "user=u1", "k1")
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, first.Body.String(), again.Body.String())
+	assert.Equal(t, "true", again.Header().Get("Idempotent-Replayed"))
+
+	// Keys belong to one user, and requests without one always run.
+	post(r, "user=u2", "k1")
+	assert.Equal(t, 2, calls)
+	post(r, "user=u1", "")
+	post(r, "user=u1", "")
+	assert.Equal(t, 4, calls)
+}
+
+func TestIdempotency_TransientFailuresRetry(t *testing.T) {
+	for _, query := range []string{"status=409", "status=423", "status=500", "panic=1"} {
+		t.Run(query, func(t *testing.T) {
+			calls := 0
+			r := idempotentServer(&calls)
+			first := post(r, "user=u1&"+query, "k")
+			assert.NotEqual(t, http.StatusOK, first.Code)
+
+			// The same key runs again once the problem has passed.
+			again := post(r, "user=u1", "k")
+			assert.Equal(t, http.StatusOK, again.Code)
+			assert.Equal(t, 2, calls)
+			assert.Empty(t, again.Header().Get("Idempotent-Replayed"))
+		})
+	}
+}
+
+func TestIdempotency_ReplaysBadRequest(t *testing.T) {
+	calls := 0
+	r := idempotentServer(&calls)
+	post(r, "user=u1&status=400", "k")
+	again := post(r, "user=u1", "k")
+	assert.Equal(t, http.StatusBadRequest, again.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	s := NewMemoryIdempotencyStore()
+	_, reserved := s.Reserve("k", time.Hour)
+	require.True(t, reserved)
+	resp, reserved := s.Reserve("k", time.Hour)
+	assert.Nil(t, resp)
+	assert.False(t, reserved, "still in flight")
+
+	s.Complete("k", StoredResponse{Status: http.StatusCreated}, time.Hour)
+	resp, _ = s.Reserve("k", time.Hour)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusCreated, resp.Status)
+
+	s.Complete("short", StoredResponse{Status: http.StatusOK}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	resp, reserved = s.Reserve("short", time.Hour)
+	assert.Nil(t, resp, "expired")
+	assert.True(t, reserved)
+}
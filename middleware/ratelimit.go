@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limit is a token bucket: up to Burst requests at once, refilled at Burst
+// per Per. The zero Limit does not limit anything.
+type Limit struct {
+	Burst int
+	Per   time.Duration
+}
+
+func (l Limit) enabled() bool { return l.Burst > 0 && l.Per > 0 }
+
+// RateLimitRule is the pair of buckets a route group draws from. PerUser
+// only applies behind AuthMiddleware, where the user is known.
+type RateLimitRule struct {
+	PerIP   Limit
+	PerUser Limit
+}
+
+// RateLimitStore keeps token buckets by key.
+type RateLimitStore interface {
+	// Take removes one token from the bucket for key. When the bucket is
+	// empty it returns false and how long until a token is available.
+	Take(key string, limit Limit) (allowed bool, retryAfter time.Duration)
+}
+
+// RateLimit answers 429 with Retry-After once the caller's IP or user has
+// used up its bucket. Buckets are named by group, so each route group set
+// up with its own name is limited separately.
+func RateLimit(store RateLimitStore, group string, rule RateLimitRule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var wait time.Duration
+		if rule.PerIP.enabled() {
+			if ok, retry := store.Take(group+"|ip|"+c.ClientIP(), rule.PerIP); !ok {
+				wait = retry
+			}
+		}
+		if userID := c.GetString("user_id"); userID != "" && rule.PerUser.enabled() {
+			if ok, retry := store.Take(group+"|user|"+userID, rule.PerUser); !ok && retry > wait {
+				wait = retry
+			}
+		}
+		if wait > 0 {
+			SetRetryAfter(c, wait)
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many requests"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// SetRetryAfter sets the Retry-After header, in whole seconds rounded up.
+func SetRetryAfter(c *gin.Context, wait time.Duration) {
+	secs := int(math.Ceil(wait.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(secs))
+}
+
+// ── Memory store ──
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	full      time.Duration // time to refill from empty; idle this long means full
+}
+
+// MemoryRateLimitStore keeps buckets in process memory. Like the memory
+// idempotency store it suits a single instance; several instances need a
+// shared store so a client cannot spread its requests across them.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweptAt time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, limit Limit) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updatedAt: now, full: limit.Per}
+		s.buckets[key] = b
+	}
+
+	rate := float64(limit.Burst) / limit.Per.Seconds() // tokens per second
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.updatedAt).Seconds()*rate)
+	b.updatedAt = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+// sweep drops buckets that have refilled completely, at most once a
+// minute. They are indistinguishable from new ones. Caller holds s.mu.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.sweptAt) < time.Minute {
+		return
+	}
+	for k, b := range s.buckets {
+		if now.Sub(b.updatedAt) >= b.full {
+			delete(s.buckets, k)
+		}
+	}
+	s.sweptAt = now
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// limitedServer serves /a and /b, limited separately by rule. The caller's
+// user, if any, is taken from the X-User header, standing in for
+// AuthMiddleware.
+func limitedServer(store RateLimitStore, rule RateLimitRule) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	asUser := func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			c.Set("user_id", user)
+		}
+	}
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/a", asUser, RateLimit(store, "a", rule), ok)
+	r.GET("/b", asUser, RateLimit(store, "b", rule), ok)
+	return r
+}
+
+func limitedGet(r *gin.Engine, path, ip, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = ip + ":1234"
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit_PerIP(t *testing.T) {
+	r := limitedServer(NewMemoryRateLimitStore(), RateLimitRule{PerIP: Limit{Burst: 2, Per: time.Minute}})
+
+	assert.Equal(t, http.StatusOK, limitedGet(r, "/a", "10.0.0.1", "").Code)
+	assert.Equal(t, http.StatusOK, limitedGet(r, "/a", "10.0.0.1", "").Code)
+	w := limitedGet(r, "/a", "10.0.0.1", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"), "one token comes back every 30s")
+
+	// Other IPs and other route groups have buckets of their own.
+	assert.Equal(t, http.StatusOK, limitedGet(r, "/a", "10.0.0.2", "").Code)
+	assert.Equal(t, http.StatusOK, limitedGet(r, "/b", "10.0.0.1", "").Code)
+}
+
+func TestRateLimit_PerUser(t *testing.T) {
+	r := limitedServer(NewMemoryRateLimitStore(), RateLimitRule{
+		PerIP:   Limit{Burst: 10, Per: time.Minute},
+		PerUser: Limit{Burst: 1, Per: time.Minute},
+	})
+
+	assert.Equal(t, http.StatusOK, limitedGet(r, "/a", "10.0.0.1", "u1").Code)
+	// A user cannot get round their limit by changing address.
+	w := limitedGet(r, "/a", "10.0.0.2", "u1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	// Others behind the same address are not held up.
+	assert.Equal(t, http.StatusOK, limitedGet(r, "/a", "10.0.0.1", "u2").Code)
+	// Without a user only the IP bucket applies.
+	assert.Equal(t, http.StatusOK, limitedGet(r, "/a", "10.0.0.1", "").Code)
+}
+
+func TestMemoryRateLimitStore_Refills(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	limit := Limit{Burst: 2, Per: 200 * time.Millisecond}
+
+	for i := 0; i < 2; i++ {
+		ok, _ := store.Take("k", limit)
+		require.True(t, ok)
+	}
+	ok, retry := store.Take("k", limit)
+	require.False(t, ok)
+	assert.Greater(t, retry, time.Duration(0))
+	assert.LessOrEqual(t, retry, 100*time.Millisecond)
+
+	time.Sleep(retry + 10*time.Millisecond)
+	ok, _ = store.Take("k", limit)
+	assert.True(t, ok, "a token has come back")
+	ok, _ = store.Take("k", limit)
+	assert.False(t, ok, "but only one")
+}
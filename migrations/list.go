@@ -0,0 +1,238 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// All is every migration, in the order they are applied. Append new steps
+// with the next version; never renumber or edit one that has shipped. When
+// models.Match (or another model) gains a field that old documents need,
+// add a backfill here.
+var All = []Migration{
+	{Version: 1, Name: "user_group_player_indexes", Up: userGroupPlayerIndexes},
+	{Version: 2, Name: "match_indexes", Up: matchIndexes},
+	{Version: 3, Name: "backfill_match_fields", Up: backfillMatchFields},
+	{Version: 4, Name: "player_merge_indexes", Up: playerMergeIndexes},
+	{Version: 5, Name: "link_players_to_users", Up: linkPlayersToUsers},
+	{Version: 6, Name: "session_indexes", Up: sessionIndexes},
+	{Version: 7, Name: "password_reset_indexes", Up: passwordResetIndexes},
+	{Version: 8, Name: "case_insensitive_usernames", Up: caseInsensitiveUsernames},
+	{Version: 9, Name: "invite_indexes", Up: inviteIndexes},
+	{Version: 10, Name: "share_link_indexes", Up: shareLinkIndexes},
+}
+
+// userGroupPlayerIndexes backs FindByUsername, FindByJoinCode, FindByMember
+// and FindByNameAndGroupID, and makes usernames and join codes unique. It
+// fails if existing data already has duplicates; fix those by hand first.
+func userGroupPlayerIndexes(ctx context.Context, db *mongo.Database) error {
+	if err := ensureIndexes(ctx, db, "users",
+		mongo.IndexModel{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true)},
+	); err != nil {
+		return err
+	}
+	if err := ensureIndexes(ctx, db, "groups",
+		mongo.IndexModel{Keys: bson.D{{Key: "join_code", Value: 1}}, Options: options.Index().SetUnique(true)},
+		mongo.IndexModel{Keys: bson.D{{Key: "members", Value: 1}}},
+	); err != nil {
+		return err
+	}
+	return ensureIndexes(ctx, db, "players",
+		mongo.IndexModel{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "name", Value: 1}}},
+	)
+}
+
+// matchIndexes backs the match list filters and sorts, and timeline reads.
+func matchIndexes(ctx context.Context, db *mongo.Database) error {
+	if err := ensureIndexes(ctx, db, "matches",
+		mongo.IndexModel{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "duration_secs", Value: -1}, {Key: "_id", Value: -1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "team1_ids", Value: 1}, {Key: "created_at", Value: -1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "team2_ids", Value: 1}, {Key: "created_at", Value: -1}}},
+		mongo.IndexModel{
+			Keys:    bson.D{{Key: "group_id", Value: 1}, {Key: "session_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetSparse(true),
+		},
+	); err != nil {
+		return err
+	}
+	return ensureIndexes(ctx, db, "match_events",
+		mongo.IndexModel{Keys: bson.D{{Key: "match_id", Value: 1}, {Key: "version", Value: 1}, {Key: "index", Value: 1}}},
+	)
+}
+
+// backfillMatchFields fills in fields added to models.Match after the first
+// release: version, type, pauses and paused_secs.
+func backfillMatchFields(ctx context.Context, db *mongo.Database) error {
+	col := db.Collection("matches")
+	missing := func(field string) bson.M { return bson.M{field: bson.M{"$exists": false}} }
+
+	if _, err := col.UpdateMany(ctx, missing("version"), bson.M{"$set": bson.M{"version": 1}}); err != nil {
+		return err
+	}
+	if _, err := col.UpdateMany(ctx, missing("pauses"), bson.M{"$set": bson.M{"pauses": bson.A{}}}); err != nil {
+		return err
+	}
+	if _, err := col.UpdateMany(ctx, missing("paused_secs"), bson.M{"$set": bson.M{"paused_secs": 0}}); err != nil {
+		return err
+	}
+
+	// type is derived from the team sizes: 1v1, 2v2, otherwise 1v2.
+	size := func(field string) bson.M { return bson.M{"$size": bson.M{"$ifNull": bson.A{field, bson.A{}}}} }
+	matchType := bson.M{"$switch": bson.M{
+		"branches": bson.A{
+			bson.M{"case": bson.M{"$and": bson.A{
+				bson.M{"$eq": bson.A{size("$team1_ids"), 1}},
+				bson.M{"$eq": bson.A{size("$team2_ids"), 1}},
+			}}, "then": "1v1"},
+			bson.M{"case": bson.M{"$and": bson.A{
+				bson.M{"$eq": bson.A{size("$team1_ids"), 2}},
+				bson.M{"$eq": bson.A{size("$team2_ids"), 2}},
+			}}, "then": "2v2"},
+		},
+		"default": "1v2",
+	}}
+	_, err := col.UpdateMany(ctx, missing("type"), mongo.Pipeline{{{Key: "$set", Value: bson.M{"type": matchType}}}})
+	return err
+}
+
+// playerMergeIndexes backs the merge history list. Creating the index also
+// creates the collection, which older servers require before a transaction
+// can insert into it.
+func playerMergeIndexes(ctx context.Context, db *mongo.Database) error {
+	return ensureIndexes(ctx, db, "player_merges",
+		mongo.IndexModel{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "merged_at", Value: -1}}},
+	)
+}
+
+// linkPlayersToUsers indexes the player–user link and fills it in for
+// existing data, which matched players to users by name. A member is linked
+// to the player named after their username only when exactly one unlinked
+// player in the group has that name; anything ambiguous is left for the
+// member to claim.
+func linkPlayersToUsers(ctx context.Context, db *mongo.Database) error {
+	players := db.Collection("players")
+	if err := ensureIndexes(ctx, db, "players",
+		mongo.IndexModel{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "group_id", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	); err != nil {
+		return err
+	}
+
+	usernames := map[primitive.ObjectID]string{}
+	cursor, err := db.Collection("users").Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	var users []struct {
+		ID       primitive.ObjectID `bson:"_id"`
+		Username string             `bson:"username"`
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		return err
+	}
+	for _, u := range users {
+		usernames[u.ID] = u.Username
+	}
+
+	cursor, err = db.Collection("groups").Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	var groups []struct {
+		ID      primitive.ObjectID   `bson:"_id"`
+		Members []primitive.ObjectID `bson:"members"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return err
+	}
+	for _, g := range groups {
+		for _, member := range g.Members {
+			name, ok := usernames[member]
+			if !ok {
+				continue
+			}
+			linked, err := players.CountDocuments(ctx, bson.M{"group_id": g.ID, "user_id": member})
+			if err != nil {
+				return err
+			}
+			if linked > 0 {
+				continue
+			}
+			filter := bson.M{"group_id": g.ID, "name": name, "user_id": bson.M{"$exists": false}}
+			n, err := players.CountDocuments(ctx, filter)
+			if err != nil {
+				return err
+			}
+			if n != 1 {
+				continue
+			}
+			if _, err := players.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"user_id": member}}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sessionIndexes backs "log out all devices" and lets Mongo delete sessions
+// once they expire.
+func sessionIndexes(ctx context.Context, db *mongo.Database) error {
+	return ensureIndexes(ctx, db, "sessions",
+		mongo.IndexModel{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	)
+}
+
+// passwordResetIndexes lets a reset spend the user's other tokens, and Mongo
+// delete them once they expire.
+func passwordResetIndexes(ctx context.Context, db *mongo.Database) error {
+	return ensureIndexes(ctx, db, "password_resets",
+		mongo.IndexModel{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	)
+}
+
+// caseInsensitiveUsernames makes usernames unique ignoring case, alongside
+// the exact-match index from version 1. Like that one, it fails if users
+// already differ only in case; rename one of them by hand first.
+func caseInsensitiveUsernames(ctx context.Context, db *mongo.Database) error {
+	return ensureIndexes(ctx, db, "users",
+		mongo.IndexModel{
+			Keys: bson.D{{Key: "username", Value: 1}},
+			Options: options.Index().SetName("username_ci").SetUnique(true).
+				SetCollation(&options.Collation{Locale: "en", Strength: 2}),
+		},
+	)
+}
+
+// inviteIndexes makes invite codes unique and allows one pending join
+// request per user and group.
+func inviteIndexes(ctx context.Context, db *mongo.Database) error {
+	if err := ensureIndexes(ctx, db, "invites",
+		mongo.IndexModel{Keys: bson.D{{Key: "code", Value: 1}}, Options: options.Index().SetUnique(true)},
+		mongo.IndexModel{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "created_at", Value: 1}}},
+	); err != nil {
+		return err
+	}
+	return ensureIndexes(ctx, db, "join_requests",
+		mongo.IndexModel{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		mongo.IndexModel{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	)
+}
+
+// shareLinkIndexes backs the group's link list and lets Mongo delete links
+// once they expire.
+func shareLinkIndexes(ctx context.Context, db *mongo.Database) error {
+	return ensureIndexes(ctx, db, "share_links",
+		mongo.IndexModel{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	)
+}
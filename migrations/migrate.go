@@ -0,0 +1,114 @@
+// Package migrations keeps the MongoDB schema in step with the models:
+// indexes and data backfills are applied once each, in version order, and
+// recorded in the "migrations" collection.
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const collection = "migrations"
+
+// Migration is one schema step. Up must be safe to re-run: a crash after Up
+// succeeds but before it is recorded will run it again on the next start.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+}
+
+// Record is a migration that has been applied.
+type Record struct {
+	Version   int       `bson:"_id"        json:"version"`
+	Name      string    `bson:"name"       json:"name"`
+	AppliedAt time.Time `bson:"applied_at" json:"applied_at"`
+}
+
+// Run applies every migration that has not been recorded yet.
+func Run(ctx context.Context, db *mongo.Database) error {
+	if err := validate(All); err != nil {
+		return err
+	}
+	pending, err := Pending(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	col := db.Collection(collection)
+	for _, m := range pending {
+		start := time.Now()
+		if err := m.Up(ctx, db); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		rec := Record{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}
+		if _, err := col.InsertOne(ctx, rec); err != nil && !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("record migration %d: %w", m.Version, err)
+		}
+		log.Printf("Applied migration %d (%s) in %s", m.Version, m.Name, time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
+
+// Applied lists the migrations recorded in the database, oldest first.
+func Applied(ctx context.Context, db *mongo.Database) ([]Record, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := db.Collection(collection).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Pending lists the migrations that Run would apply.
+func Pending(ctx context.Context, db *mongo.Database) ([]Migration, error) {
+	applied, err := Applied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[int]bool, len(applied))
+	for _, r := range applied {
+		done[r.Version] = true
+	}
+	var pending []Migration
+	for _, m := range All {
+		if !done[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// validate checks versions are unique and listed in ascending order, so a
+// badly merged list fails loudly instead of applying steps out of order.
+func validate(all []Migration) error {
+	if !sort.SliceIsSorted(all, func(i, j int) bool { return all[i].Version < all[j].Version }) {
+		return fmt.Errorf("migrations are not in version order")
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].Version == all[i-1].Version {
+			return fmt.Errorf("duplicate migration version %d", all[i].Version)
+		}
+	}
+	return nil
+}
+
+// ensureIndexes creates indexes on a collection; existing identical indexes
+// are left alone.
+func ensureIndexes(ctx context.Context, db *mongo.Database, name string, indexes ...mongo.IndexModel) error {
+	_, err := db.Collection(name).Indexes().CreateMany(ctx, indexes)
+	return err
+}
@@ -13,4 +13,20 @@ type Group struct {
 	CreatedBy primitive.ObjectID   `bson:"created_by"    json:"created_by"`
 	Members   []primitive.ObjectID `bson:"members"       json:"members"`
 	CreatedAt time.Time            `bson:"created_at"    json:"created_at"`
+
+	// JoinApproval holds joins, by join code or invite, until the owner
+	// approves them.
+	JoinApproval bool `bson:"join_approval" json:"join_approval"`
+
+	Settings GroupSettings `bson:"settings" json:"settings"`
+}
+
+// HasMember reports whether the user is on the member list.
+func (g *Group) HasMember(userID primitive.ObjectID) bool {
+	for _, m := range g.Members {
+		if m == userID {
+			return true
+		}
+	}
+	return false
 }
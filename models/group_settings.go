@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Sports a group can play. Scoring and intervals follow badminton rules
+// whatever the sport; the sport is for display and filtering.
+const (
+	SportBadminton   = "badminton"
+	SportTableTennis = "table_tennis"
+	SportSquash      = "squash"
+	SportPickleball  = "pickleball"
+	SportOther       = "other"
+)
+
+// Group privacy. A private group's matches, players and leaderboard are for
+// members only; a public group's can be read by any signed-in user.
+const (
+	PrivacyPrivate = "private"
+	PrivacyPublic  = "public"
+)
+
+// Points a game can be played to. Games are won by two clear points.
+const (
+	PointsToWin11 = 11
+	PointsToWin15 = 15
+	PointsToWin21 = 21
+)
+
+// GroupSettings is the group's settings document. Groups that never saved
+// settings have Version 0 and zero fields; WithDefaults fills them in.
+type GroupSettings struct {
+	Description string `bson:"description" json:"description"`
+	Sport       string `bson:"sport"       json:"sport"`
+	HomeVenue   string `bson:"home_venue"  json:"home_venue"`
+	Timezone    string `bson:"timezone"    json:"timezone"` // IANA name, e.g. "Europe/London"
+
+	// PointsToWin is the default game length for new matches.
+	PointsToWin int `bson:"points_to_win" json:"points_to_win"`
+
+	// GuestsCanScore lets signed-in users who are not members record and
+	// undo rallies in the group's matches, e.g. a friend keeping score from
+	// the side of the court. Everything else (creating, editing, pausing,
+	// finishing and deleting matches) stays with members.
+	GuestsCanScore bool `bson:"guests_can_score" json:"guests_can_score"`
+	// AllowBackdating lets results be added with a past played_at.
+	AllowBackdating bool   `bson:"allow_backdating" json:"allow_backdating"`
+	Privacy         string `bson:"privacy"          json:"privacy"`
+
+	// Version is bumped on every save; saves only apply if it still matches.
+	Version   int                 `bson:"version"              json:"version"`
+	UpdatedAt *time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	UpdatedBy *primitive.ObjectID `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+}
+
+// WithDefaults returns the settings with unset fields given their defaults.
+func (s GroupSettings) WithDefaults() GroupSettings {
+	if s.Sport == "" {
+		s.Sport = SportBadminton
+	}
+	if s.Timezone == "" {
+		s.Timezone = "UTC"
+	}
+	if s.PointsToWin == 0 {
+		s.PointsToWin = PointsToWin21
+	}
+	if s.Privacy == "" {
+		s.Privacy = PrivacyPrivate
+	}
+	return s
+}
+
+// ValidSport reports whether s is a known sport.
+func ValidSport(s string) bool {
+	switch s {
+	case SportBadminton, SportTableTennis, SportSquash, SportPickleball, SportOther:
+		return true
+	}
+	return false
+}
+
+// ValidPointsToWin reports whether n is a supported game length.
+func ValidPointsToWin(n int) bool {
+	return n == PointsToWin11 || n == PointsToWin15 || n == PointsToWin21
+}
+
+// IntervalAt returns the score that starts the mid-game interval for a game
+// to pointsToWin: 11 in a game to 21, 8 to 15 and 6 to 11. Zero means a
+// game to 21.
+func IntervalAt(pointsToWin int) int {
+	if pointsToWin == 0 {
+		return IntervalPoints
+	}
+	return pointsToWin/2 + 1
+}
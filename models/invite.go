@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Invite is a named code for joining a group, alongside the group's own
+// join code. It can expire, be limited to a number of uses, or be revoked.
+type Invite struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"        json:"id"`
+	GroupID   primitive.ObjectID `bson:"group_id"             json:"group_id"`
+	Code      string             `bson:"code"                 json:"code"`
+	Name      string             `bson:"name"                 json:"name"`
+	CreatedBy primitive.ObjectID `bson:"created_by"           json:"created_by"`
+	CreatedAt time.Time          `bson:"created_at"           json:"created_at"`
+	ExpiresAt *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	MaxUses   int                `bson:"max_uses"             json:"max_uses"` // 0 is unlimited
+	Uses      int                `bson:"uses"                 json:"uses"`
+	RevokedAt *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+// Usable reports whether the invite can still be used at now.
+func (i *Invite) Usable(now time.Time) bool {
+	return i.RevokedAt == nil &&
+		(i.ExpiresAt == nil || now.Before(*i.ExpiresAt)) &&
+		(i.MaxUses == 0 || i.Uses < i.MaxUses)
+}
+
+// JoinRequest is a user waiting for the group owner to let them in, for
+// groups that require approval.
+type JoinRequest struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty"       json:"id"`
+	GroupID   primitive.ObjectID  `bson:"group_id"            json:"group_id"`
+	UserID    primitive.ObjectID  `bson:"user_id"             json:"user_id"`
+	Username  string              `bson:"username"            json:"username"`
+	InviteID  *primitive.ObjectID `bson:"invite_id,omitempty" json:"invite_id,omitempty"`
+	CreatedAt time.Time           `bson:"created_at"          json:"created_at"`
+}
@@ -7,14 +7,121 @@ import (
 )
 
 const (
-	MatchStatusLive     = "live"
-	MatchStatusFinished = "finished"
+	MatchStatusScheduled = "scheduled" // teams named, waiting for a court
+	MatchStatusWarmup    = "warmup"    // on court, knocking up
+	MatchStatusLive      = "live"
+	MatchStatusPaused    = "paused"
+	MatchStatusFinished  = "finished"
+	MatchStatusAbandoned = "abandoned" // stopped with no result (rain, court lost)
+	MatchStatusWalkover  = "walkover"  // decided without (full) play: no-show or retirement
 )
 
+// matchTransitions lists the statuses each status may move to. Finished,
+// abandoned and walkover are terminal.
+var matchTransitions = map[string][]string{
+	MatchStatusScheduled: {MatchStatusWarmup, MatchStatusLive, MatchStatusAbandoned, MatchStatusWalkover},
+	MatchStatusWarmup:    {MatchStatusLive, MatchStatusAbandoned, MatchStatusWalkover},
+	MatchStatusLive:      {MatchStatusPaused, MatchStatusFinished, MatchStatusAbandoned, MatchStatusWalkover},
+	MatchStatusPaused:    {MatchStatusLive, MatchStatusFinished, MatchStatusAbandoned, MatchStatusWalkover},
+}
+
+// CanTransition reports whether a match may move from one status to another.
+func CanTransition(from, to string) bool {
+	for _, s := range matchTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminalStatus reports whether no further changes of status are possible.
+func IsTerminalStatus(status string) bool {
+	_, ok := matchTransitions[status]
+	return !ok
+}
+
+// Match types by team size. 1v2 covers either side being the pair.
+const (
+	MatchType1v1 = "1v1"
+	MatchType1v2 = "1v2"
+	MatchType2v2 = "2v2"
+)
+
+// MatchTypeFor returns the match type for the given team sizes.
+func MatchTypeFor(team1, team2 int) string {
+	switch {
+	case team1 == 1 && team2 == 1:
+		return MatchType1v1
+	case team1 == 2 && team2 == 2:
+		return MatchType2v2
+	default:
+		return MatchType1v2
+	}
+}
+
+// Why play stopped. Intervals follow badminton rules: 60 seconds when the
+// leading side reaches 11 in a game to 21 (recorded automatically; see
+// IntervalAt for shorter games) and 120 seconds between games (a match
+// here is one game, so that one is paused manually).
+const (
+	PauseReasonInterval     = "interval"
+	PauseReasonGameInterval = "game_interval"
+	PauseReasonBreak        = "break"
+	PauseReasonInjury       = "injury"
+
+	IntervalPoints   = 11
+	IntervalSecs     = 60
+	GameIntervalSecs = 120
+)
+
+// Pause is a stretch of time with no play. Auto pauses are interval markers
+// the server inserts itself; they end when the next rally is recorded.
+type Pause struct {
+	Reason    string     `bson:"reason"            json:"reason"`
+	Auto      bool       `bson:"auto"              json:"auto"`
+	StartedAt time.Time  `bson:"started_at"        json:"started_at"`
+	EndsAt    *time.Time `bson:"ends_at,omitempty" json:"ends_at,omitempty"` // planned end for intervals
+	EndedAt   *time.Time `bson:"ended_at"          json:"ended_at"`
+}
+
+// How a rally ended. Error outcomes are charged to the player who made them.
+const (
+	OutcomeSmashWinner  = "smash_winner"
+	OutcomeNetError     = "net_error"
+	OutcomeOut          = "out"
+	OutcomeServiceFault = "service_fault"
+	OutcomeLet          = "let" // replayed rally; no point awarded
+)
+
+// ValidOutcome reports whether o is a known rally outcome ("" means unspecified).
+func ValidOutcome(o string) bool {
+	switch o {
+	case "", OutcomeSmashWinner, OutcomeNetError, OutcomeOut, OutcomeServiceFault, OutcomeLet:
+		return true
+	}
+	return false
+}
+
+// IsErrorOutcome reports whether the rally was lost to an unforced error.
+func IsErrorOutcome(o string) bool {
+	return o == OutcomeNetError || o == OutcomeOut || o == OutcomeServiceFault
+}
+
 // ScoreEvent records who scored each point.
 type ScoreEvent struct {
-	Team     int    `bson:"team"      json:"team"`      // 1 or 2
+	Team     int    `bson:"team"      json:"team"`      // 1 or 2 (0 for a let)
 	PlayerID string `bson:"player_id" json:"player_id"` // hex ObjectID of scorer
+
+	// Optional rally details
+	Outcome       string `bson:"outcome,omitempty"         json:"outcome,omitempty"`
+	RallySecs     int    `bson:"rally_secs,omitempty"      json:"rally_secs,omitempty"`
+	FaultPlayerID string `bson:"fault_player_id,omitempty" json:"fault_player_id,omitempty"` // who made the error
+
+	At       time.Time `bson:"at"                  json:"at"`                  // server time the rally ended
+	DeviceID string    `bson:"device_id,omitempty" json:"device_id,omitempty"` // device that recorded it
+	EventID  string    `bson:"event_id,omitempty"  json:"event_id,omitempty"`  // client-side ID for synced events
+	Version  int       `bson:"version,omitempty"   json:"version,omitempty"`   // match version that added it
 }
 
 type Match struct {
@@ -26,6 +133,15 @@ type Match struct {
 	Team2IDs   []primitive.ObjectID `bson:"team2_ids"   json:"team2_ids"`
 	Team1Names []string             `bson:"team1_names" json:"team1_names"`
 	Team2Names []string             `bson:"team2_names" json:"team2_names"`
+	Type       string               `bson:"type"        json:"type"` // 1v1, 1v2 or 2v2
+
+	// PointsToWin is the game length, from the group's settings when the
+	// match was created. Zero on older matches, which were played to 21.
+	PointsToWin int `bson:"points_to_win,omitempty" json:"points_to_win,omitempty"`
+
+	// SessionID groups the matches played in one sitting (e.g. "sat-evening").
+	// It is chosen by the client and optional.
+	SessionID string `bson:"session_id,omitempty" json:"session_id,omitempty"`
 
 	// Scores
 	Score1       int          `bson:"score1"        json:"score1"`
@@ -41,10 +157,26 @@ type Match struct {
 	Team2Positions []string `bson:"team2_positions" json:"team2_positions"`
 
 	// Match lifecycle
-	Status       string     `bson:"status"        json:"status"`
-	StartedAt    time.Time  `bson:"started_at"    json:"started_at"`
-	FinishedAt   *time.Time `bson:"finished_at"   json:"finished_at"`
-	DurationSecs int        `bson:"duration_secs" json:"duration_secs"`
-	CreatedAt    time.Time  `bson:"created_at"    json:"created_at"`
-	UpdatedAt    time.Time  `bson:"updated_at"    json:"updated_at"`
+	Status       string     `bson:"status"                 json:"status"`
+	ScheduledAt  *time.Time `bson:"scheduled_at,omitempty" json:"scheduled_at,omitempty"`
+	StartedAt    time.Time  `bson:"started_at"             json:"started_at"` // zero until play begins
+	FinishedAt   *time.Time `bson:"finished_at"            json:"finished_at"`
+	DurationSecs int        `bson:"duration_secs"          json:"duration_secs"` // active play only
+	Pauses       []Pause    `bson:"pauses"                 json:"pauses"`
+	PausedSecs   int        `bson:"paused_secs"            json:"paused_secs"`
+	WinnerTeam   int        `bson:"winner_team,omitempty"  json:"winner_team,omitempty"` // set for walkovers
+	EndReason    string     `bson:"end_reason,omitempty"   json:"end_reason,omitempty"`  // why it was abandoned or walked over
+	CreatedAt    time.Time  `bson:"created_at"             json:"created_at"`
+	UpdatedAt    time.Time  `bson:"updated_at"             json:"updated_at"`
+
+	// Version is bumped on every write; updates only apply if it still matches.
+	Version int `bson:"version" json:"version"`
+}
+
+// OpenPause returns the pause in progress, or nil when play is running.
+func (m *Match) OpenPause() *Pause {
+	if n := len(m.Pauses); n > 0 && m.Pauses[n-1].EndedAt == nil {
+		return &m.Pauses[n-1]
+	}
+	return nil
 }
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MatchEventCreated       = "created"
+	MatchEventPoint         = "point"
+	MatchEventUndo          = "undo"
+	MatchEventEdit          = "edit"
+	MatchEventFinish        = "finish"
+	MatchEventDelete        = "delete"
+	MatchEventPlayerMerge   = "player_merge"
+	MatchEventPlayerUnmerge = "player_unmerge" // a merge undone; Snapshot is the restored match
+	MatchEventPlayerRename  = "player_rename"  // Merge has the same source and target
+	MatchEventPause         = "pause"
+	MatchEventResume        = "resume"
+	MatchEventStatus        = "status" // scheduled → warmup → live, abandon, walkover
+)
+
+// MatchEvent is an immutable record of one change to a match. Replaying a
+// match's events in (Version, Index) order rebuilds its current state.
+type MatchEvent struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	MatchID primitive.ObjectID `bson:"match_id"      json:"match_id"`
+	GroupID primitive.ObjectID `bson:"group_id"      json:"group_id"`
+	Type    string             `bson:"type"          json:"type"`
+
+	// Version is the match version the change produced; Index orders several
+	// events written by one update (e.g. an offline sync batch).
+	Version int `bson:"version" json:"version"`
+	Index   int `bson:"index"   json:"index"`
+
+	ActorID   string    `bson:"actor_id,omitempty"   json:"actor_id,omitempty"`
+	ActorName string    `bson:"actor_name,omitempty" json:"actor_name,omitempty"`
+	DeviceID  string    `bson:"device_id,omitempty"  json:"device_id,omitempty"`
+	At        time.Time `bson:"at"                   json:"at"`
+
+	// EventID is the client-side ID of the synced undo that made this change.
+	// Synced points carry theirs in Point.
+	EventID string `bson:"event_id,omitempty" json:"event_id,omitempty"`
+
+	Before *MatchState `bson:"before,omitempty" json:"before,omitempty"`
+	After  *MatchState `bson:"after,omitempty"  json:"after,omitempty"`
+
+	// Type-specific payloads
+	Snapshot *Match       `bson:"snapshot,omitempty" json:"snapshot,omitempty"` // created, player_unmerge
+	Point    *ScoreEvent  `bson:"point,omitempty"    json:"point,omitempty"`    // point; on undo, the point removed
+	Merge    *PlayerMerge `bson:"merge,omitempty"    json:"merge,omitempty"`    // player_merge, player_unmerge, player_rename
+	Pause    *Pause       `bson:"pause,omitempty"    json:"pause,omitempty"`    // pause, resume; on undo, a cancelled interval
+}
+
+// MatchState is the scalar part of a match that events change.
+type MatchState struct {
+	Score1          int        `bson:"score1"            json:"score1"`
+	Score2          int        `bson:"score2"            json:"score2"`
+	Status          string     `bson:"status"            json:"status"`
+	ServingTeam     int        `bson:"serving_team"      json:"serving_team"`
+	ServingPlayerID string     `bson:"serving_player_id" json:"serving_player_id"`
+	FinishedAt      *time.Time `bson:"finished_at"       json:"finished_at"`
+	DurationSecs    int        `bson:"duration_secs"     json:"duration_secs"`
+	PausedSecs      int        `bson:"paused_secs"       json:"paused_secs"`
+	StartedAt       time.Time  `bson:"started_at"        json:"started_at"`
+	WinnerTeam      int        `bson:"winner_team"       json:"winner_team"`
+	EndReason       string     `bson:"end_reason"        json:"end_reason"`
+}
+
+// PlayerMerge records that one player's references were rewritten to another.
+type PlayerMerge struct {
+	SourceID   primitive.ObjectID `bson:"source_id"   json:"source_id"`
+	TargetID   primitive.ObjectID `bson:"target_id"   json:"target_id"`
+	SourceName string             `bson:"source_name" json:"source_name"`
+	TargetName string             `bson:"target_name" json:"target_name"`
+}
+
+// State captures the match's current MatchState.
+func (m *Match) State() MatchState {
+	return MatchState{
+		Score1:          m.Score1,
+		Score2:          m.Score2,
+		Status:          m.Status,
+		ServingTeam:     m.ServingTeam,
+		ServingPlayerID: m.ServingPlayerID,
+		FinishedAt:      m.FinishedAt,
+		DurationSecs:    m.DurationSecs,
+		PausedSecs:      m.PausedSecs,
+		StartedAt:       m.StartedAt,
+		WinnerTeam:      m.WinnerTeam,
+		EndReason:       m.EndReason,
+	}
+}
+
+// ApplyState overwrites the match's scalar state.
+func (m *Match) ApplyState(s MatchState) {
+	m.Score1 = s.Score1
+	m.Score2 = s.Score2
+	m.Status = s.Status
+	m.ServingTeam = s.ServingTeam
+	m.ServingPlayerID = s.ServingPlayerID
+	m.FinishedAt = s.FinishedAt
+	m.DurationSecs = s.DurationSecs
+	m.PausedSecs = s.PausedSecs
+	m.StartedAt = s.StartedAt
+	m.WinnerTeam = s.WinnerTeam
+	m.EndReason = s.EndReason
+}
+
+// ReplacePlayer rewrites every reference to sourceID (teams, names, scorers
+// and faults in the history, positions and serve) to point at targetID. It
+// reports whether the match referenced the source at all.
+func (m *Match) ReplacePlayer(sourceID, targetID primitive.ObjectID, targetName string) bool {
+	sourceHex := sourceID.Hex()
+	targetHex := targetID.Hex()
+	found := false
+
+	// Replace in team IDs and names
+	for i, id := range m.Team1IDs {
+		if id == sourceID {
+			found = true
+			m.Team1IDs[i] = targetID
+			if i < len(m.Team1Names) {
+				m.Team1Names[i] = targetName
+			}
+		}
+	}
+	for i, id := range m.Team2IDs {
+		if id == sourceID {
+			found = true
+			m.Team2IDs[i] = targetID
+			if i < len(m.Team2Names) {
+				m.Team2Names[i] = targetName
+			}
+		}
+	}
+	// Replace in score history
+	for i, ev := range m.ScoreHistory {
+		if ev.PlayerID == sourceHex {
+			m.ScoreHistory[i].PlayerID = targetHex
+		}
+		if ev.FaultPlayerID == sourceHex {
+			m.ScoreHistory[i].FaultPlayerID = targetHex
+		}
+	}
+	// Replace in positions
+	for i, p := range m.Team1Positions {
+		if p == sourceHex {
+			m.Team1Positions[i] = targetHex
+		}
+	}
+	for i, p := range m.Team2Positions {
+		if p == sourceHex {
+			m.Team2Positions[i] = targetHex
+		}
+	}
+	// Replace serving player
+	if m.ServingPlayerID == sourceHex {
+		m.ServingPlayerID = targetHex
+	}
+	return found
+}
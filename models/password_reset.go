@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PasswordReset is an outstanding request to reset a user's password. Only
+// the token's hash is kept; the token itself goes to the user through a
+// notifier.
+type PasswordReset struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"     json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id"           json:"user_id"`
+	TokenHash string             `bson:"token_hash"        json:"-"`
+	CreatedAt time.Time          `bson:"created_at"        json:"created_at"`
+	ExpiresAt time.Time          `bson:"expires_at"        json:"expires_at"`
+	UsedAt    *time.Time         `bson:"used_at,omitempty" json:"used_at,omitempty"`
+}
+
+// Usable reports whether the reset can still be redeemed at now.
+func (r *PasswordReset) Usable(now time.Time) bool {
+	return r.UsedAt == nil && now.Before(r.ExpiresAt)
+}
@@ -1,14 +1,76 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
+	"unicode"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Dominant hands and preferred doubles positions. Empty means not set.
+const (
+	HandLeft  = "left"
+	HandRight = "right"
+
+	PositionFront = "front"
+	PositionBack  = "back"
+)
+
+// Player is someone who plays in a group. A player with no UserID is a guest;
+// a user can claim one, and the group creator approves the link.
 type Player struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name      string             `bson:"name"          json:"name"`
-	GroupID   primitive.ObjectID `bson:"group_id"      json:"group_id"`
-	CreatedAt time.Time          `bson:"created_at"    json:"created_at"`
+	ID        primitive.ObjectID  `bson:"_id,omitempty"     json:"id"`
+	Name      string              `bson:"name"              json:"name"`
+	GroupID   primitive.ObjectID  `bson:"group_id"          json:"group_id"`
+	UserID    *primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"` // linked account
+	CreatedAt time.Time           `bson:"created_at"        json:"created_at"`
+
+	// Profile, all optional.
+	Nickname string `bson:"nickname,omitempty" json:"nickname,omitempty"`
+	Hand     string `bson:"hand,omitempty"     json:"hand,omitempty"`     // HandLeft or HandRight
+	Position string `bson:"position,omitempty" json:"position,omitempty"` // PositionFront or PositionBack
+
+	// ArchivedAt hides the player from pickers; their matches and stats stay.
+	ArchivedAt *time.Time `bson:"archived_at,omitempty" json:"archived_at,omitempty"`
+
+	// Pending claim, waiting for the group creator to approve or reject it.
+	ClaimedBy *primitive.ObjectID `bson:"claimed_by,omitempty" json:"claimed_by,omitempty"`
+	ClaimedAt *time.Time          `bson:"claimed_at,omitempty" json:"claimed_at,omitempty"`
+}
+
+// LinkedTo reports whether the player is linked to the given user.
+func (p *Player) LinkedTo(userID primitive.ObjectID) bool {
+	return p.UserID != nil && *p.UserID == userID
+}
+
+// Archived reports whether the player has been archived.
+func (p *Player) Archived() bool {
+	return p.ArchivedAt != nil
+}
+
+// Initials is what an avatar shows: the first letters of the first and last
+// words of the name ("Amit Kumar Shah" → "AS"), or of the one word.
+func (p *Player) Initials() string {
+	words := strings.FieldsFunc(p.Name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	initial := func(w string) string { return string(unicode.ToUpper([]rune(w)[0])) }
+	if len(words) == 1 {
+		return initial(words[0])
+	}
+	return initial(words[0]) + initial(words[len(words)-1])
+}
+
+// MarshalJSON adds the avatar initials to the stored fields.
+func (p Player) MarshalJSON() ([]byte, error) {
+	type stored Player
+	return json.Marshal(struct {
+		stored
+		Initials string `json:"initials"`
+	}{stored(p), p.Initials()})
 }
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MergeRecord is kept for every player merge so it can be undone while
+// UndoUntil has not passed.
+type MergeRecord struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	GroupID    primitive.ObjectID `bson:"group_id"      json:"group_id"`
+	SourceID   primitive.ObjectID `bson:"source_id"     json:"source_id"`
+	TargetID   primitive.ObjectID `bson:"target_id"     json:"target_id"`
+	SourceName string             `bson:"source_name"   json:"source_name"`
+	TargetName string             `bson:"target_name"   json:"target_name"`
+
+	// Source is the deleted player, re-created on undo.
+	Source  Player        `bson:"source"  json:"source"`
+	Matches []MergedMatch `bson:"matches" json:"matches"`
+	// LinkMoved is set when the source's user link passed to the target.
+	LinkMoved bool `bson:"link_moved,omitempty" json:"link_moved,omitempty"`
+
+	MergedBy  string    `bson:"merged_by,omitempty" json:"merged_by,omitempty"` // user ID
+	MergedAt  time.Time `bson:"merged_at"           json:"merged_at"`
+	UndoUntil time.Time `bson:"undo_until"          json:"undo_until"`
+
+	UndoneAt   *time.Time         `bson:"undone_at"             json:"undone_at"`
+	UndoneBy   string             `bson:"undone_by,omitempty"   json:"undone_by,omitempty"`
+	RestoredID primitive.ObjectID `bson:"restored_id,omitempty" json:"restored_id,omitempty"` // the source's ID after undo
+}
+
+// MergedMatch is one match a merge rewrote: the match as it was before, and
+// the version the merge wrote. Undo only restores it if that version is
+// still current.
+type MergedMatch struct {
+	MatchID primitive.ObjectID `bson:"match_id" json:"match_id"`
+	Before  Match              `bson:"before"   json:"before"`
+	Version int                `bson:"version"  json:"version"`
+}
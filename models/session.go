@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Why a session was revoked.
+const (
+	RevokedLogout    = "logout"
+	RevokedLogoutAll = "logout_all"
+	RevokedReuse     = "refresh_reuse" // an already-rotated refresh token came back
+	RevokedPassword  = "password"      // the password was changed or reset
+	RevokedDeleted   = "account_deleted"
+)
+
+// MaxRotatedHashes is how many earlier refresh token hashes a session keeps
+// to spot reuse. Older tokens are simply invalid.
+const MaxRotatedHashes = 32
+
+// Session is one login on one device. Its refresh token rotates on every
+// use. The hashes of recently rotated tokens are kept, so one of those
+// coming back means it leaked, and the whole session is revoked.
+type Session struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"        json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id"              json:"user_id"`
+	TokenHash string             `bson:"token_hash"           json:"-"`
+	DeviceID  string             `bson:"device_id,omitempty"  json:"device_id,omitempty"`
+
+	// RotatedHashes are the hashes of the last MaxRotatedHashes tokens
+	// this session has rotated away from, oldest first.
+	RotatedHashes []string `bson:"rotated_hashes,omitempty" json:"-"`
+
+	CreatedAt  time.Time `bson:"created_at"   json:"created_at"`
+	LastUsedAt time.Time `bson:"last_used_at" json:"last_used_at"`
+	ExpiresAt  time.Time `bson:"expires_at"   json:"expires_at"`
+
+	RevokedAt     *time.Time `bson:"revoked_at,omitempty"     json:"revoked_at,omitempty"`
+	RevokedReason string     `bson:"revoked_reason,omitempty" json:"revoked_reason,omitempty"`
+}
+
+// Active reports whether the session can still be used at now.
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// WasRotated reports whether hash belongs to a token the session has
+// already rotated away from.
+func (s *Session) WasRotated(hash string) bool {
+	for _, h := range s.RotatedHashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// AddRotated returns hashes with hash appended, keeping the last
+// MaxRotatedHashes.
+func AddRotated(hashes []string, hash string) []string {
+	hashes = append(hashes, hash)
+	if len(hashes) > MaxRotatedHashes {
+		hashes = hashes[len(hashes)-MaxRotatedHashes:]
+	}
+	return hashes
+}
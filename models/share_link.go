@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShareLink lets people without an account watch a group's matches, or one
+// match, read-only. Only the token's hash is kept; the token is shown once,
+// when the link is made. Links always expire.
+type ShareLink struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty"        json:"id"`
+	GroupID   primitive.ObjectID  `bson:"group_id"             json:"group_id"`
+	MatchID   *primitive.ObjectID `bson:"match_id,omitempty"   json:"match_id,omitempty"` // nil shares the whole group
+	TokenHash string              `bson:"token_hash"           json:"-"`
+	CreatedBy primitive.ObjectID  `bson:"created_by"           json:"created_by"`
+	CreatedAt time.Time           `bson:"created_at"           json:"created_at"`
+	ExpiresAt time.Time           `bson:"expires_at"           json:"expires_at"`
+	RevokedAt *time.Time          `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+// Usable reports whether the link still grants access at now.
+func (l *ShareLink) Usable(now time.Time) bool {
+	return l.RevokedAt == nil && now.Before(l.ExpiresAt)
+}
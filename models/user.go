@@ -11,4 +11,16 @@ type User struct {
 	Username  string             `bson:"username"      json:"username"`
 	Password  string             `bson:"password"      json:"-"`
 	CreatedAt time.Time          `bson:"created_at"    json:"created_at"`
+
+	// Profile; both optional. The phone is only shown to the user.
+	DisplayName string `bson:"display_name,omitempty" json:"display_name,omitempty"`
+	Phone       string `bson:"phone,omitempty"        json:"phone,omitempty"`
+}
+
+// Name is what other members see: the display name, else the username.
+func (u *User) Name() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return u.Username
 }
@@ -0,0 +1,26 @@
+package repositories
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrVersionConflict is returned by conditional updates when the stored
+// document changed since it was read.
+var ErrVersionConflict = errors.New("document was modified concurrently")
+
+// ErrNotFound is returned by single-document lookups that match nothing. It
+// is the Mongo driver's sentinel, so every backend can be checked the same way.
+var ErrNotFound = mongo.ErrNoDocuments
+
+// ErrDuplicateKey is returned when a write would break a uniqueness rule
+// (usernames, join codes). Mongo reports these as write exceptions instead;
+// use IsDuplicateKey to check either.
+var ErrDuplicateKey = errors.New("duplicate key")
+
+// IsDuplicateKey reports whether err is a uniqueness violation from any
+// backend.
+func IsDuplicateKey(err error) bool {
+	return errors.Is(err, ErrDuplicateKey) || mongo.IsDuplicateKeyError(err)
+}
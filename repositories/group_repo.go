@@ -64,3 +64,80 @@ func (r *GroupRepo) AddMember(ctx context.Context, groupID, userID primitive.Obj
 	_, err := r.col.UpdateByID(ctx, groupID, bson.M{"$addToSet": bson.M{"members": userID}})
 	return err
 }
+
+// RemoveMember takes a user off the member list (idempotent via $pull).
+func (r *GroupRepo) RemoveMember(ctx context.Context, groupID, userID primitive.ObjectID) error {
+	_, err := r.col.UpdateByID(ctx, groupID, bson.M{"$pull": bson.M{"members": userID}})
+	return err
+}
+
+func (r *GroupRepo) SetCreator(ctx context.Context, groupID, userID primitive.ObjectID) error {
+	res, err := r.col.UpdateByID(ctx, groupID, bson.M{"$set": bson.M{"created_by": userID}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *GroupRepo) Rename(ctx context.Context, groupID primitive.ObjectID, name string) error {
+	res, err := r.col.UpdateByID(ctx, groupID, bson.M{"$set": bson.M{"name": name}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *GroupRepo) SetJoinCode(ctx context.Context, groupID primitive.ObjectID, code string) error {
+	return r.set(ctx, groupID, bson.M{"join_code": code})
+}
+
+func (r *GroupRepo) SetJoinApproval(ctx context.Context, groupID primitive.ObjectID, on bool) error {
+	return r.set(ctx, groupID, bson.M{"join_approval": on})
+}
+
+func (r *GroupRepo) UpdateSettings(ctx context.Context, groupID primitive.ObjectID, settings *models.GroupSettings) error {
+	filter := bson.M{"_id": groupID, "settings.version": settings.Version}
+	if settings.Version == 0 {
+		// Groups created before settings have no settings document.
+		filter["settings.version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+	stored := *settings
+	stored.Version++
+	res, err := r.col.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"settings": stored}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrVersionConflict
+	}
+	settings.Version = stored.Version
+	return nil
+}
+
+func (r *GroupRepo) set(ctx context.Context, groupID primitive.ObjectID, fields bson.M) error {
+	res, err := r.col.UpdateByID(ctx, groupID, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *GroupRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
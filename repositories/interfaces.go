@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
@@ -10,9 +11,19 @@ import (
 
 // UserRepository defines the interface for user persistence.
 type UserRepository interface {
+	// Create fails with ErrDuplicateKey if the username is taken in any
+	// letter case.
 	Create(ctx context.Context, user *models.User) error
+	// FindByUsername matches the username ignoring case.
 	FindByUsername(ctx context.Context, username string) (*models.User, error)
 	FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	// UpdatePassword stores a new password hash; a missing user is ErrNotFound.
+	UpdatePassword(ctx context.Context, id primitive.ObjectID, hash string) error
+	// UpdateProfile stores the display name and phone; a missing user is
+	// ErrNotFound.
+	UpdateProfile(ctx context.Context, user *models.User) error
+	// Delete removes the user; a missing user is ErrNotFound.
+	Delete(ctx context.Context, id primitive.ObjectID) error
 }
 
 // GroupRepository defines the interface for group persistence.
@@ -22,6 +33,27 @@ type GroupRepository interface {
 	FindByJoinCode(ctx context.Context, code string) (*models.Group, error)
 	FindByMember(ctx context.Context, userID primitive.ObjectID) ([]models.Group, error)
 	AddMember(ctx context.Context, groupID, userID primitive.ObjectID) error
+	// RemoveMember takes a user off the member list. Like AddMember it is
+	// idempotent and a missing group is not an error.
+	RemoveMember(ctx context.Context, groupID, userID primitive.ObjectID) error
+	// SetCreator hands the group to another user; a missing group is
+	// ErrNotFound.
+	SetCreator(ctx context.Context, groupID, userID primitive.ObjectID) error
+	// Rename sets the group's name; a missing group is ErrNotFound.
+	Rename(ctx context.Context, groupID primitive.ObjectID, name string) error
+	// SetJoinCode replaces the join code. A code another group has is a
+	// duplicate key error; a missing group is ErrNotFound.
+	SetJoinCode(ctx context.Context, groupID primitive.ObjectID, code string) error
+	// SetJoinApproval turns join approval on or off; a missing group is
+	// ErrNotFound.
+	SetJoinApproval(ctx context.Context, groupID primitive.ObjectID, on bool) error
+	// UpdateSettings stores the group's settings if the stored ones are
+	// still at settings.Version, and bumps settings.Version. Otherwise, and
+	// for a missing group, it returns ErrVersionConflict.
+	UpdateSettings(ctx context.Context, groupID primitive.ObjectID, settings *models.GroupSettings) error
+	// Delete removes the group record only; see the DeleteByGroupID methods
+	// for its data. A missing group is ErrNotFound.
+	Delete(ctx context.Context, id primitive.ObjectID) error
 }
 
 // PlayerRepository defines the interface for player persistence.
@@ -31,7 +63,10 @@ type PlayerRepository interface {
 	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Player, error)
 	Delete(ctx context.Context, id primitive.ObjectID) error
 	FindByNameAndGroupID(ctx context.Context, name string, groupID primitive.ObjectID) (*models.Player, error)
+	FindByUserAndGroupID(ctx context.Context, userID, groupID primitive.ObjectID) (*models.Player, error)
+	FindByUserID(ctx context.Context, userID primitive.ObjectID) ([]models.Player, error)
 	Update(ctx context.Context, player *models.Player) error
+	DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error
 }
 
 // MatchRepository defines the interface for match persistence.
@@ -39,7 +74,111 @@ type MatchRepository interface {
 	Create(ctx context.Context, match *models.Match) error
 	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Match, error)
 	FindByGroupID(ctx context.Context, groupID primitive.ObjectID) ([]models.Match, error)
+	FindPage(ctx context.Context, q MatchQuery) (*MatchPage, error)
 	Update(ctx context.Context, match *models.Match) error
 	Delete(ctx context.Context, id primitive.ObjectID) error
-	ReplacePlayerInMatches(ctx context.Context, groupID, sourceID, targetID primitive.ObjectID, sourceName, targetName string) error
+	ReplacePlayerInMatches(ctx context.Context, groupID, sourceID, targetID primitive.ObjectID, sourceName, targetName string) ([]models.Match, error)
+	DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error
+}
+
+// MatchEventRepository defines the interface for the match timeline. Events
+// are added, not edited: Replace only scrubs a deleted user, and events go
+// only with their group.
+type MatchEventRepository interface {
+	Append(ctx context.Context, events ...models.MatchEvent) error
+	FindByMatchID(ctx context.Context, matchID primitive.ObjectID) ([]models.MatchEvent, error)
+	FindByGroupID(ctx context.Context, groupID primitive.ObjectID) ([]models.MatchEvent, error)
+	FindByActorID(ctx context.Context, actorID string) ([]models.MatchEvent, error)
+	// Replace overwrites a stored event. It is only used to remove a deleted
+	// user's details.
+	Replace(ctx context.Context, event *models.MatchEvent) error
+	DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error
+}
+
+// MergeRecordRepository stores player merges so they can be undone.
+type MergeRecordRepository interface {
+	Create(ctx context.Context, record *models.MergeRecord) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.MergeRecord, error)
+	FindByGroupID(ctx context.Context, groupID primitive.ObjectID) ([]models.MergeRecord, error)
+	// MarkUndone sets the undo fields on a record that has not been undone
+	// yet; otherwise it returns ErrVersionConflict.
+	MarkUndone(ctx context.Context, record *models.MergeRecord) error
+	// Replace overwrites a stored record. It is only used to remove a
+	// deleted user's details.
+	Replace(ctx context.Context, record *models.MergeRecord) error
+	DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error
+}
+
+// SessionRepository stores login sessions and their refresh token hashes.
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Session, error)
+	// Rotate swaps the token hash and extends the session, only if the
+	// stored hash is still oldHash and the session is not revoked;
+	// otherwise it returns ErrVersionConflict. oldHash is added to the
+	// session's RotatedHashes.
+	Rotate(ctx context.Context, id primitive.ObjectID, oldHash, newHash string, usedAt, expiresAt time.Time) error
+	// Revoke revokes one session; revoking it again is not an error.
+	Revoke(ctx context.Context, id primitive.ObjectID, reason string) error
+	// RevokeByUserID revokes every active session of the user.
+	RevokeByUserID(ctx context.Context, userID primitive.ObjectID, reason string) error
+}
+
+// PasswordResetRepository stores password reset tokens by hash.
+type PasswordResetRepository interface {
+	Create(ctx context.Context, reset *models.PasswordReset) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.PasswordReset, error)
+	// MarkUsed sets UsedAt on an unused reset; otherwise it returns
+	// ErrVersionConflict, so each token works once.
+	MarkUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error
+	// MarkUsedByUserID spends every unused reset of the user.
+	MarkUsedByUserID(ctx context.Context, userID primitive.ObjectID, usedAt time.Time) error
+}
+
+// InviteRepository stores named group invites. Codes are unique.
+type InviteRepository interface {
+	Create(ctx context.Context, invite *models.Invite) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Invite, error)
+	FindByCode(ctx context.Context, code string) (*models.Invite, error)
+	// FindByGroupID returns the group's invites, oldest first.
+	FindByGroupID(ctx context.Context, groupID primitive.ObjectID) ([]models.Invite, error)
+	// Use counts one use of the invite, only if it is usable at now (see
+	// models.Invite.Usable); otherwise it returns ErrNotFound. Concurrent
+	// uses cannot go over MaxUses.
+	Use(ctx context.Context, id primitive.ObjectID, now time.Time) error
+	// Revoke sets RevokedAt; a missing invite is ErrNotFound.
+	Revoke(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error
+}
+
+// JoinRequestRepository stores pending join requests. A user has at most
+// one per group; a second is a duplicate key error.
+type JoinRequestRepository interface {
+	Create(ctx context.Context, request *models.JoinRequest) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.JoinRequest, error)
+	FindByGroupAndUser(ctx context.Context, groupID, userID primitive.ObjectID) (*models.JoinRequest, error)
+	// FindByGroupID returns the group's requests, oldest first.
+	FindByGroupID(ctx context.Context, groupID primitive.ObjectID) ([]models.JoinRequest, error)
+	// Delete removes a request; a missing one is ErrNotFound.
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error
+	DeleteByUserID(ctx context.Context, userID primitive.ObjectID) error
+}
+
+// Transactor runs fn atomically: either everything fn writes through the
+// repositories (using the ctx it is given) is kept, or none of it is.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// ShareLinkRepository stores read-only share links.
+type ShareLinkRepository interface {
+	Create(ctx context.Context, link *models.ShareLink) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.ShareLink, error)
+	// FindByGroupID returns the group's links, newest first. Expired links
+	// may have been deleted already.
+	FindByGroupID(ctx context.Context, groupID primitive.ObjectID) ([]models.ShareLink, error)
+	// Revoke sets RevokedAt; a missing link is ErrNotFound.
+	Revoke(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error
 }
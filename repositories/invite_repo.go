@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"gully-backend/models"
+)
+
+type InviteRepo struct {
+	col *mongo.Collection
+}
+
+func NewInviteRepo(db *mongo.Database) *InviteRepo {
+	return &InviteRepo{col: db.Collection("invites")}
+}
+
+func (r *InviteRepo) Create(ctx context.Context, invite *models.Invite) error {
+	invite.ID = primitive.NewObjectID()
+	_, err := r.col.InsertOne(ctx, invite)
+	return err
+}
+
+func (r *InviteRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Invite, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *InviteRepo) FindByCode(ctx context.Context, code string) (*models.Invite, error) {
+	return r.findOne(ctx, bson.M{"code": code})
+}
+
+func (r *InviteRepo) findOne(ctx context.Context, filter bson.M) (*models.Invite, error) {
+	var invite models.Invite
+	if err := r.col.FindOne(ctx, filter).Decode(&invite); err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (r *InviteRepo) FindByGroupID(ctx context.Context, groupID primitive.ObjectID) ([]models.Invite, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.col.Find(ctx, bson.M{"group_id": groupID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var invites []models.Invite
+	if err := cursor.All(ctx, &invites); err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+// Use counts a use in the same update that checks the invite is still
+// usable, so racing joins cannot overshoot MaxUses.
+func (r *InviteRepo) Use(ctx context.Context, id primitive.ObjectID, now time.Time) error {
+	filter := bson.M{
+		"_id":        id,
+		"revoked_at": nil,
+		"$and": bson.A{
+			bson.M{"$or": bson.A{bson.M{"expires_at": nil}, bson.M{"expires_at": bson.M{"$gt": now}}}},
+			bson.M{"$or": bson.A{bson.M{"max_uses": 0}, bson.M{"$expr": bson.M{"$lt": bson.A{"$uses", "$max_uses"}}}}},
+		},
+	}
+	res, err := r.col.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"uses": 1}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *InviteRepo) Revoke(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	res, err := r.col.UpdateByID(ctx, id, bson.M{"$set": bson.M{"revoked_at": at}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *InviteRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	_, err := r.col.DeleteMany(ctx, bson.M{"group_id": groupID})
+	return err
+}
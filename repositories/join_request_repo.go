@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"gully-backend/models"
+)
+
+type JoinRequestRepo struct {
+	col *mongo.Collection
+}
+
+func NewJoinRequestRepo(db *mongo.Database) *JoinRequestRepo {
+	return &JoinRequestRepo{col: db.Collection("join_requests")}
+}
+
+func (r *JoinRequestRepo) Create(ctx context.Context, request *models.JoinRequest) error {
+	request.ID = primitive.NewObjectID()
+	_, err := r.col.InsertOne(ctx, request)
+	return err
+}
+
+func (r *JoinRequestRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.JoinRequest, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *JoinRequestRepo) FindByGroupAndUser(ctx context.Context, groupID, userID primitive.ObjectID) (*models.JoinRequest, error) {
+	return r.findOne(ctx, bson.M{"group_id": groupID, "user_id": userID})
+}
+
+func (r *JoinRequestRepo) findOne(ctx context.Context, filter bson.M) (*models.JoinRequest, error) {
+	var request models.JoinRequest
+	if err := r.col.FindOne(ctx, filter).Decode(&request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *JoinRequestRepo) FindByGroupID(ctx context.Context, groupID primitive.ObjectID) ([]models.JoinRequest, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.col.Find(ctx, bson.M{"group_id": groupID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var requests []models.JoinRequest
+	if err := cursor.All(ctx, &requests); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+func (r *JoinRequestRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *JoinRequestRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	_, err := r.col.DeleteMany(ctx, bson.M{"group_id": groupID})
+	return err
+}
+
+func (r *JoinRequestRepo) DeleteByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.col.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
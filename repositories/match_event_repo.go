@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"gully-backend/models"
+)
+
+type MatchEventRepo struct {
+	col *mongo.Collection
+}
+
+func NewMatchEventRepo(db *mongo.Database) *MatchEventRepo {
+	return &MatchEventRepo{col: db.Collection("match_events")}
+}
+
+// Append inserts events.
+func (r *MatchEventRepo) Append(ctx context.Context, events ...models.MatchEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, len(events))
+	for i := range events {
+		events[i].ID = primitive.NewObjectID()
+		docs[i] = events[i]
+	}
+	_, err := r.col.InsertMany(ctx, docs)
+	return err
+}
+
+// FindByMatchID returns a match's events in replay order.
+func (r *MatchEventRepo) FindByMatchID(ctx context.Context, matchID primitive.ObjectID) ([]models.MatchEvent, error) {
+	return r.find(ctx, bson.M{"match_id": matchID})
+}
+
+// FindByGroupID returns the events of all the group's matches.
+func (r *MatchEventRepo) FindByGroupID(ctx context.Context, groupID primitive.ObjectID) ([]models.MatchEvent, error) {
+	return r.find(ctx, bson.M{"group_id": groupID})
+}
+
+// FindByActorID returns the events the user made, in any group.
+func (r *MatchEventRepo) FindByActorID(ctx context.Context, actorID string) ([]models.MatchEvent, error) {
+	return r.find(ctx, bson.M{"actor_id": actorID})
+}
+
+// Replace overwrites a stored event.
+func (r *MatchEventRepo) Replace(ctx context.Context, event *models.MatchEvent) error {
+	res, err := r.col.ReplaceOne(ctx, bson.M{"_id": event.ID}, event)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *MatchEventRepo) find(ctx context.Context, filter bson.M) ([]models.MatchEvent, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "match_id", Value: 1}, {Key: "version", Value: 1}, {Key: "index", Value: 1}})
+	cursor, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []models.MatchEvent
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *MatchEventRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	_, err := r.col.DeleteMany(ctx, bson.M{"group_id": groupID})
+	return err
+}
@@ -0,0 +1,263 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+)
+
+// Match list sort keys.
+const (
+	MatchSortDate     = "date" // created_at
+	MatchSortDuration = "duration"
+)
+
+const (
+	DefaultMatchPageSize = 50
+	MaxMatchPageSize     = 200
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// MatchQuery selects one page of a group's matches. Zero-valued filters are
+// ignored.
+type MatchQuery struct {
+	GroupID   primitive.ObjectID
+	Statuses  []string
+	PlayerID  primitive.ObjectID // on either team
+	From      *time.Time         // created at or after
+	To        *time.Time         // created before
+	Type      string
+	SessionID string
+
+	Sort      string // MatchSortDate (default) or MatchSortDuration
+	Ascending bool
+	Cursor    string // NextCursor of the previous page
+	Limit     int
+}
+
+// MatchPage is one page of matches. NextCursor is empty on the last page.
+type MatchPage struct {
+	Matches    []models.Match `json:"matches"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// matchCursor is the position after the last match of a page: its sort key
+// and ID, which breaks ties.
+type matchCursor struct {
+	Key int64              `json:"k"`
+	ID  primitive.ObjectID `json:"id"`
+}
+
+// normalize fills in defaults and rejects unknown sort keys.
+func (q *MatchQuery) normalize() error {
+	switch q.Sort {
+	case "":
+		q.Sort = MatchSortDate
+	case MatchSortDate, MatchSortDuration:
+	default:
+		return errors.New("sort must be date or duration")
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultMatchPageSize
+	}
+	if q.Limit > MaxMatchPageSize {
+		q.Limit = MaxMatchPageSize
+	}
+	return nil
+}
+
+// sortField is the document field the query orders by.
+func (q MatchQuery) sortField() string {
+	if q.Sort == MatchSortDuration {
+		return "duration_secs"
+	}
+	return "created_at"
+}
+
+// sortKey is m's value for the query's sort. Dates are compared at
+// millisecond precision, which is what Mongo stores.
+func (q MatchQuery) sortKey(m *models.Match) int64 {
+	if q.Sort == MatchSortDuration {
+		return int64(m.DurationSecs)
+	}
+	return m.CreatedAt.UnixMilli()
+}
+
+// Includes reports whether m passes the query's filters (not the cursor).
+func (q MatchQuery) Includes(m *models.Match) bool {
+	if m.GroupID != q.GroupID {
+		return false
+	}
+	if len(q.Statuses) > 0 && !containsString(q.Statuses, m.Status) {
+		return false
+	}
+	if !q.PlayerID.IsZero() && !containsID(m.Team1IDs, q.PlayerID) && !containsID(m.Team2IDs, q.PlayerID) {
+		return false
+	}
+	if q.From != nil && m.CreatedAt.Before(*q.From) {
+		return false
+	}
+	if q.To != nil && !m.CreatedAt.Before(*q.To) {
+		return false
+	}
+	if q.Type != "" && m.Type != q.Type {
+		return false
+	}
+	if q.SessionID != "" && m.SessionID != q.SessionID {
+		return false
+	}
+	return true
+}
+
+// Before reports whether a sorts ahead of b in the query's order.
+func (q MatchQuery) Before(a, b *models.Match) bool {
+	ka, kb := q.sortKey(a), q.sortKey(b)
+	if ka == kb {
+		return idBefore(a.ID, b.ID, q.Ascending)
+	}
+	return (ka < kb) == q.Ascending
+}
+
+// after reports whether m comes after cursor c in the query's order.
+func (q MatchQuery) after(m *models.Match, c *matchCursor) bool {
+	k := q.sortKey(m)
+	if k == c.Key {
+		return idBefore(c.ID, m.ID, q.Ascending)
+	}
+	return (c.Key < k) == q.Ascending
+}
+
+func idBefore(a, b primitive.ObjectID, ascending bool) bool {
+	cmp := compareIDs(a, b)
+	if ascending {
+		return cmp < 0
+	}
+	return cmp > 0
+}
+
+func compareIDs(a, b primitive.ObjectID) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// MatchPlan is a MatchQuery ready to run: defaults applied and the cursor
+// decoded. Backends that build their own queries start from it.
+type MatchPlan struct {
+	query    MatchQuery
+	Sort     string
+	Limit    int
+	AfterKey *int64             // sort key of the previous page's last match
+	AfterID  primitive.ObjectID // its ID, which breaks ties on AfterKey
+}
+
+// Plan validates q and decodes its cursor.
+func (q MatchQuery) Plan() (*MatchPlan, error) {
+	if err := q.normalize(); err != nil {
+		return nil, err
+	}
+	cursor, err := decodeMatchCursor(q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	p := &MatchPlan{query: q, Sort: q.Sort, Limit: q.Limit}
+	if cursor != nil {
+		p.AfterKey, p.AfterID = &cursor.Key, cursor.ID
+	}
+	return p, nil
+}
+
+// Page turns up to Limit+1 matches, already in order, into a page.
+func (p *MatchPlan) Page(matches []models.Match) *MatchPage {
+	return p.query.page(matches)
+}
+
+func (q MatchQuery) encodeCursor(m *models.Match) string {
+	raw, _ := json.Marshal(matchCursor{Key: q.sortKey(m), ID: m.ID})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeMatchCursor(s string) (*matchCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c matchCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// PageMatches applies q to matches already held in memory, with the same
+// semantics as MatchRepo.FindPage.
+func PageMatches(matches []models.Match, q MatchQuery) (*MatchPage, error) {
+	if err := q.normalize(); err != nil {
+		return nil, err
+	}
+	cursor, err := decodeMatchCursor(q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make([]models.Match, 0, q.Limit)
+	for i := range matches {
+		m := &matches[i]
+		if q.Includes(m) && (cursor == nil || q.after(m, cursor)) {
+			selected = append(selected, *m)
+		}
+	}
+	sortMatches(selected, q)
+	return q.page(selected), nil
+}
+
+func sortMatches(matches []models.Match, q MatchQuery) {
+	sort.Slice(matches, func(i, j int) bool { return q.Before(&matches[i], &matches[j]) })
+}
+
+// page trims a sorted result fetched with Limit+1 and sets NextCursor.
+func (q MatchQuery) page(matches []models.Match) *MatchPage {
+	out := &MatchPage{Matches: matches}
+	if len(matches) > q.Limit {
+		out.Matches = matches[:q.Limit]
+		out.NextCursor = q.encodeCursor(&out.Matches[q.Limit-1])
+	}
+	if out.Matches == nil {
+		out.Matches = []models.Match{}
+	}
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsID(list []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, v := range list {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -24,6 +24,7 @@ func (r *MatchRepo) Create(ctx context.Context, match *models.Match) error {
 	match.ID = primitive.NewObjectID()
 	match.CreatedAt = time.Now()
 	match.UpdatedAt = match.CreatedAt
+	match.Version = 1
 	_, err := r.col.InsertOne(ctx, match)
 	return err
 }
@@ -52,10 +53,98 @@ func (r *MatchRepo) FindByGroupID(ctx context.Context, groupID primitive.ObjectI
 	return matches, nil
 }
 
+// FindPage returns one page of a group's matches, filtered and sorted as q
+// asks. Pages are keyed on the sort value and ID of the last match returned,
+// so inserts between requests do not shift later pages.
+func (r *MatchRepo) FindPage(ctx context.Context, q MatchQuery) (*MatchPage, error) {
+	if err := q.normalize(); err != nil {
+		return nil, err
+	}
+	cursor, err := decodeMatchCursor(q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	and := bson.A{bson.M{"group_id": q.GroupID}}
+	if len(q.Statuses) > 0 {
+		and = append(and, bson.M{"status": bson.M{"$in": q.Statuses}})
+	}
+	if !q.PlayerID.IsZero() {
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"team1_ids": q.PlayerID},
+			bson.M{"team2_ids": q.PlayerID},
+		}})
+	}
+	if q.From != nil {
+		and = append(and, bson.M{"created_at": bson.M{"$gte": *q.From}})
+	}
+	if q.To != nil {
+		and = append(and, bson.M{"created_at": bson.M{"$lt": *q.To}})
+	}
+	if q.Type != "" {
+		and = append(and, bson.M{"type": q.Type})
+	}
+	if q.SessionID != "" {
+		and = append(and, bson.M{"session_id": q.SessionID})
+	}
+
+	field, dir, cmp := q.sortField(), -1, "$lt"
+	if q.Ascending {
+		dir, cmp = 1, "$gt"
+	}
+	if cursor != nil {
+		var key interface{} = cursor.Key
+		if q.Sort == MatchSortDate {
+			key = time.UnixMilli(cursor.Key)
+		}
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{field: bson.M{cmp: key}},
+			bson.M{field: key, "_id": bson.M{cmp: cursor.ID}},
+		}})
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: field, Value: dir}, {Key: "_id", Value: dir}}).
+		SetLimit(int64(q.Limit + 1))
+	cur, err := r.col.Find(ctx, bson.M{"$and": and}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var matches []models.Match
+	if err := cur.All(ctx, &matches); err != nil {
+		return nil, err
+	}
+	return q.page(matches), nil
+}
+
+// Update replaces the match only if its stored version still equals
+// match.Version, then bumps the version. A mismatch yields ErrVersionConflict.
 func (r *MatchRepo) Update(ctx context.Context, match *models.Match) error {
+	return r.replaceVersioned(ctx, match)
+}
+
+func (r *MatchRepo) replaceVersioned(ctx context.Context, match *models.Match) error {
+	filter := bson.M{"_id": match.ID, "version": match.Version}
+	if match.Version == 0 {
+		// Documents written before versioning have no version field.
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	prevVersion, prevUpdatedAt := match.Version, match.UpdatedAt
+	match.Version++
 	match.UpdatedAt = time.Now()
-	_, err := r.col.ReplaceOne(ctx, bson.M{"_id": match.ID}, match)
-	return err
+
+	res, err := r.col.ReplaceOne(ctx, filter, match)
+	if err == nil && res.MatchedCount == 0 {
+		err = ErrVersionConflict
+	}
+	if err != nil {
+		match.Version, match.UpdatedAt = prevVersion, prevUpdatedAt
+		return err
+	}
+	return nil
 }
 
 func (r *MatchRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
@@ -63,9 +152,15 @@ func (r *MatchRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
 	return err
 }
 
+func (r *MatchRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	_, err := r.col.DeleteMany(ctx, bson.M{"group_id": groupID})
+	return err
+}
+
 // ReplacePlayerInMatches replaces all occurrences of sourceID with targetID
-// in team arrays and score history for matches in a group.
-func (r *MatchRepo) ReplacePlayerInMatches(ctx context.Context, groupID, sourceID, targetID primitive.ObjectID, sourceName, targetName string) error {
+// in team arrays and score history for matches in a group, returning the
+// updated matches.
+func (r *MatchRepo) ReplacePlayerInMatches(ctx context.Context, groupID, sourceID, targetID primitive.ObjectID, sourceName, targetName string) ([]models.Match, error) {
 	// Find all matches in this group that reference sourceID
 	filter := bson.M{
 		"group_id": groupID,
@@ -77,63 +172,21 @@ func (r *MatchRepo) ReplacePlayerInMatches(ctx context.Context, groupID, sourceI
 
 	cursor, err := r.col.Find(ctx, filter)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer cursor.Close(ctx)
 
 	var matches []models.Match
 	if err := cursor.All(ctx, &matches); err != nil {
-		return err
+		return nil, err
 	}
 
-	sourceHex := sourceID.Hex()
-	targetHex := targetID.Hex()
-
-	for _, m := range matches {
-		// Replace in team IDs and names
-		for i, id := range m.Team1IDs {
-			if id == sourceID {
-				m.Team1IDs[i] = targetID
-				if i < len(m.Team1Names) {
-					m.Team1Names[i] = targetName
-				}
-			}
-		}
-		for i, id := range m.Team2IDs {
-			if id == sourceID {
-				m.Team2IDs[i] = targetID
-				if i < len(m.Team2Names) {
-					m.Team2Names[i] = targetName
-				}
-			}
-		}
-		// Replace in score history
-		for i, ev := range m.ScoreHistory {
-			if ev.PlayerID == sourceHex {
-				m.ScoreHistory[i].PlayerID = targetHex
-			}
-		}
-		// Replace in positions
-		for i, p := range m.Team1Positions {
-			if p == sourceHex {
-				m.Team1Positions[i] = targetHex
-			}
-		}
-		for i, p := range m.Team2Positions {
-			if p == sourceHex {
-				m.Team2Positions[i] = targetHex
-			}
-		}
-		// Replace serving player
-		if m.ServingPlayerID == sourceHex {
-			m.ServingPlayerID = targetHex
-		}
-
-		m.UpdatedAt = time.Now()
-		if _, err := r.col.ReplaceOne(ctx, bson.M{"_id": m.ID}, m); err != nil {
-			return err
+	for i := range matches {
+		matches[i].ReplacePlayer(sourceID, targetID, targetName)
+		if err := r.replaceVersioned(ctx, &matches[i]); err != nil {
+			return nil, err
 		}
 	}
 
-	return nil
+	return matches, nil
 }
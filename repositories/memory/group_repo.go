@@ -0,0 +1,187 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+type GroupRepo struct {
+	txGuard
+	mu     sync.RWMutex
+	groups []models.Group
+}
+
+func NewGroupRepo() *GroupRepo {
+	return &GroupRepo{}
+}
+
+func (r *GroupRepo) snapshot() func() { return snapshotOf(&r.mu, &r.groups) }
+
+func (r *GroupRepo) Create(ctx context.Context, group *models.Group) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, g := range r.groups {
+		if g.JoinCode == group.JoinCode {
+			return duplicateKey("groups", "join_code", group.JoinCode)
+		}
+	}
+	group.ID = primitive.NewObjectID()
+	group.CreatedAt = time.Now()
+	r.groups = append(r.groups, *clone(group))
+	return nil
+}
+
+func (r *GroupRepo) FindByID(_ context.Context, id primitive.ObjectID) (*models.Group, error) {
+	return r.find(func(g *models.Group) bool { return g.ID == id })
+}
+
+func (r *GroupRepo) FindByJoinCode(_ context.Context, code string) (*models.Group, error) {
+	return r.find(func(g *models.Group) bool { return g.JoinCode == code })
+}
+
+// FindByMember returns all groups where the given user is a member.
+func (r *GroupRepo) FindByMember(_ context.Context, userID primitive.ObjectID) ([]models.Group, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []models.Group
+	for i := range r.groups {
+		if containsID(r.groups[i].Members, userID) {
+			out = append(out, *clone(&r.groups[i]))
+		}
+	}
+	return out, nil
+}
+
+// AddMember adds a user to the group's member list. Like $addToSet it is
+// idempotent, and like UpdateByID a missing group is not an error.
+func (r *GroupRepo) AddMember(ctx context.Context, groupID, userID primitive.ObjectID) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.groups {
+		if r.groups[i].ID == groupID && !containsID(r.groups[i].Members, userID) {
+			r.groups[i].Members = append(r.groups[i].Members, userID)
+		}
+	}
+	return nil
+}
+
+// RemoveMember is idempotent, and a missing group is not an error.
+func (r *GroupRepo) RemoveMember(ctx context.Context, groupID, userID primitive.ObjectID) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.groups {
+		if r.groups[i].ID == groupID {
+			r.groups[i].Members = removeWhere(r.groups[i].Members, func(m *primitive.ObjectID) bool { return *m == userID })
+		}
+	}
+	return nil
+}
+
+func (r *GroupRepo) SetCreator(ctx context.Context, groupID, userID primitive.ObjectID) error {
+	return r.update(ctx, groupID, func(g *models.Group) error {
+		g.CreatedBy = userID
+		return nil
+	})
+}
+
+func (r *GroupRepo) Rename(ctx context.Context, groupID primitive.ObjectID, name string) error {
+	return r.update(ctx, groupID, func(g *models.Group) error {
+		g.Name = name
+		return nil
+	})
+}
+
+func (r *GroupRepo) SetJoinCode(ctx context.Context, groupID primitive.ObjectID, code string) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, g := range r.groups {
+		if g.JoinCode == code && g.ID != groupID {
+			return duplicateKey("groups", "join_code", code)
+		}
+	}
+	return r.updateLocked(groupID, func(g *models.Group) error {
+		g.JoinCode = code
+		return nil
+	})
+}
+
+func (r *GroupRepo) SetJoinApproval(ctx context.Context, groupID primitive.ObjectID, on bool) error {
+	return r.update(ctx, groupID, func(g *models.Group) error {
+		g.JoinApproval = on
+		return nil
+	})
+}
+
+func (r *GroupRepo) UpdateSettings(ctx context.Context, groupID primitive.ObjectID, settings *models.GroupSettings) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.groups {
+		if g := &r.groups[i]; g.ID == groupID && g.Settings.Version == settings.Version {
+			g.Settings = *clone(settings)
+			g.Settings.Version++
+			settings.Version++
+			return nil
+		}
+	}
+	return repositories.ErrVersionConflict
+}
+
+// update applies fn to the stored group; a missing group is ErrNotFound.
+func (r *GroupRepo) update(ctx context.Context, id primitive.ObjectID, fn func(*models.Group) error) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.updateLocked(id, fn)
+}
+
+func (r *GroupRepo) updateLocked(id primitive.ObjectID, fn func(*models.Group) error) error {
+	for i := range r.groups {
+		if r.groups[i].ID == id {
+			return fn(&r.groups[i])
+		}
+	}
+	return repositories.ErrNotFound
+}
+
+func (r *GroupRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := len(r.groups)
+	r.groups = removeWhere(r.groups, func(g *models.Group) bool { return g.ID == id })
+	if len(r.groups) == n {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+func (r *GroupRepo) find(match func(*models.Group) bool) (*models.Group, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := range r.groups {
+		if match(&r.groups[i]) {
+			return clone(&r.groups[i]), nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+type InviteRepo struct {
+	txGuard
+	mu      sync.RWMutex
+	invites []models.Invite
+}
+
+func NewInviteRepo() *InviteRepo {
+	return &InviteRepo{}
+}
+
+func (r *InviteRepo) snapshot() func() { return snapshotOf(&r.mu, &r.invites) }
+
+func (r *InviteRepo) Create(ctx context.Context, invite *models.Invite) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, inv := range r.invites {
+		if inv.Code == invite.Code {
+			return duplicateKey("invites", "code", invite.Code)
+		}
+	}
+	invite.ID = primitive.NewObjectID()
+	r.invites = append(r.invites, *clone(invite))
+	return nil
+}
+
+func (r *InviteRepo) FindByID(_ context.Context, id primitive.ObjectID) (*models.Invite, error) {
+	return r.find(func(inv *models.Invite) bool { return inv.ID == id })
+}
+
+func (r *InviteRepo) FindByCode(_ context.Context, code string) (*models.Invite, error) {
+	return r.find(func(inv *models.Invite) bool { return inv.Code == code })
+}
+
+func (r *InviteRepo) FindByGroupID(_ context.Context, groupID primitive.ObjectID) ([]models.Invite, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []models.Invite
+	for i := range r.invites {
+		if r.invites[i].GroupID == groupID {
+			out = append(out, *clone(&r.invites[i]))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *InviteRepo) Use(ctx context.Context, id primitive.ObjectID, now time.Time) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.invites {
+		if inv := &r.invites[i]; inv.ID == id && inv.Usable(now) {
+			inv.Uses++
+			return nil
+		}
+	}
+	return repositories.ErrNotFound
+}
+
+func (r *InviteRepo) Revoke(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.invites {
+		if inv := &r.invites[i]; inv.ID == id {
+			inv.RevokedAt = &at
+			return nil
+		}
+	}
+	return repositories.ErrNotFound
+}
+
+func (r *InviteRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.invites = removeWhere(r.invites, func(inv *models.Invite) bool { return inv.GroupID == groupID })
+	return nil
+}
+
+func (r *InviteRepo) find(match func(*models.Invite) bool) (*models.Invite, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := range r.invites {
+		if match(&r.invites[i]) {
+			return clone(&r.invites[i]), nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
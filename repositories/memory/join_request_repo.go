@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+type JoinRequestRepo struct {
+	txGuard
+	mu       sync.RWMutex
+	requests []models.JoinRequest
+}
+
+func NewJoinRequestRepo() *JoinRequestRepo {
+	return &JoinRequestRepo{}
+}
+
+func (r *JoinRequestRepo) snapshot() func() { return snapshotOf(&r.mu, &r.requests) }
+
+func (r *JoinRequestRepo) Create(ctx context.Context, request *models.JoinRequest) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, jr := range r.requests {
+		if jr.GroupID == request.GroupID && jr.UserID == request.UserID {
+			return duplicateKey("join_requests", "user_id", request.UserID.Hex())
+		}
+	}
+	request.ID = primitive.NewObjectID()
+	r.requests = append(r.requests, *clone(request))
+	return nil
+}
+
+func (r *JoinRequestRepo) FindByID(_ context.Context, id primitive.ObjectID) (*models.JoinRequest, error) {
+	return r.find(func(jr *models.JoinRequest) bool { return jr.ID == id })
+}
+
+func (r *JoinRequestRepo) FindByGroupAndUser(_ context.Context, groupID, userID primitive.ObjectID) (*models.JoinRequest, error) {
+	return r.find(func(jr *models.JoinRequest) bool { return jr.GroupID == groupID && jr.UserID == userID })
+}
+
+func (r *JoinRequestRepo) FindByGroupID(_ context.Context, groupID primitive.ObjectID) ([]models.JoinRequest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []models.JoinRequest
+	for i := range r.requests {
+		if r.requests[i].GroupID == groupID {
+			out = append(out, *clone(&r.requests[i]))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *JoinRequestRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := len(r.requests)
+	r.requests = removeWhere(r.requests, func(jr *models.JoinRequest) bool { return jr.ID == id })
+	if len(r.requests) == n {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+func (r *JoinRequestRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = removeWhere(r.requests, func(jr *models.JoinRequest) bool { return jr.GroupID == groupID })
+	return nil
+}
+
+func (r *JoinRequestRepo) DeleteByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = removeWhere(r.requests, func(jr *models.JoinRequest) bool { return jr.UserID == userID })
+	return nil
+}
+
+func (r *JoinRequestRepo) find(match func(*models.JoinRequest) bool) (*models.JoinRequest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := range r.requests {
+		if match(&r.requests[i]) {
+			return clone(&r.requests[i]), nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
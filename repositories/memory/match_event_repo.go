@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+type MatchEventRepo struct {
+	txGuard
+	mu     sync.RWMutex
+	events []models.MatchEvent
+}
+
+func NewMatchEventRepo() *MatchEventRepo {
+	return &MatchEventRepo{}
+}
+
+func (r *MatchEventRepo) snapshot() func() { return snapshotOf(&r.mu, &r.events) }
+
+// Append inserts events.
+func (r *MatchEventRepo) Append(ctx context.Context, events ...models.MatchEvent) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range events {
+		events[i].ID = primitive.NewObjectID()
+		r.events = append(r.events, *clone(&events[i]))
+	}
+	return nil
+}
+
+// FindByMatchID returns a match's events in replay order.
+func (r *MatchEventRepo) FindByMatchID(_ context.Context, matchID primitive.ObjectID) ([]models.MatchEvent, error) {
+	return r.find(func(e *models.MatchEvent) bool { return e.MatchID == matchID }), nil
+}
+
+// FindByGroupID returns the events of all the group's matches.
+func (r *MatchEventRepo) FindByGroupID(_ context.Context, groupID primitive.ObjectID) ([]models.MatchEvent, error) {
+	return r.find(func(e *models.MatchEvent) bool { return e.GroupID == groupID }), nil
+}
+
+// FindByActorID returns the events the user made, in any group.
+func (r *MatchEventRepo) FindByActorID(_ context.Context, actorID string) ([]models.MatchEvent, error) {
+	return r.find(func(e *models.MatchEvent) bool { return e.ActorID == actorID }), nil
+}
+
+// Replace overwrites a stored event.
+func (r *MatchEventRepo) Replace(ctx context.Context, event *models.MatchEvent) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.events {
+		if r.events[i].ID == event.ID {
+			r.events[i] = *clone(event)
+			return nil
+		}
+	}
+	return repositories.ErrNotFound
+}
+
+// find returns the matching events, each match's in replay order.
+func (r *MatchEventRepo) find(match func(*models.MatchEvent) bool) []models.MatchEvent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []models.MatchEvent
+	for i := range r.events {
+		if match(&r.events[i]) {
+			out = append(out, *clone(&r.events[i]))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].MatchID != out[j].MatchID {
+			return bytes.Compare(out[i].MatchID[:], out[j].MatchID[:]) < 0
+		}
+		if out[i].Version != out[j].Version {
+			return out[i].Version < out[j].Version
+		}
+		return out[i].Index < out[j].Index
+	})
+	return out
+}
+
+func (r *MatchEventRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = removeWhere(r.events, func(e *models.MatchEvent) bool { return e.GroupID == groupID })
+	return nil
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+type MatchRepo struct {
+	txGuard
+	mu      sync.RWMutex
+	matches []models.Match
+}
+
+func NewMatchRepo() *MatchRepo {
+	return &MatchRepo{}
+}
+
+func (r *MatchRepo) snapshot() func() { return snapshotOf(&r.mu, &r.matches) }
+
+func (r *MatchRepo) Create(ctx context.Context, match *models.Match) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	match.ID = primitive.NewObjectID()
+	match.CreatedAt = time.Now()
+	match.UpdatedAt = match.CreatedAt
+	match.Version = 1
+	r.matches = append(r.matches, *clone(match))
+	return nil
+}
+
+func (r *MatchRepo) FindByID(_ context.Context, id primitive.ObjectID) (*models.Match, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if i := r.index(id); i >= 0 {
+		return clone(&r.matches[i]), nil
+	}
+	return nil, repositories.ErrNotFound
+}
+
+// FindByGroupID returns the group's matches, newest first.
+func (r *MatchRepo) FindByGroupID(_ context.Context, groupID primitive.ObjectID) ([]models.Match, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []models.Match
+	for i := range r.matches {
+		if r.matches[i].GroupID == groupID {
+			out = append(out, *clone(&r.matches[i]))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *MatchRepo) FindPage(_ context.Context, q repositories.MatchQuery) (*repositories.MatchPage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return repositories.PageMatches(cloneAll(r.matches), q)
+}
+
+// Update replaces the match only if its stored version still equals
+// match.Version, then bumps the version. A mismatch yields ErrVersionConflict.
+func (r *MatchRepo) Update(ctx context.Context, match *models.Match) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.index(match.ID)
+	if i < 0 || r.matches[i].Version != match.Version {
+		return repositories.ErrVersionConflict
+	}
+	match.Version++
+	match.UpdatedAt = time.Now()
+	r.matches[i] = *clone(match)
+	return nil
+}
+
+func (r *MatchRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i := r.index(id); i >= 0 {
+		r.matches = append(r.matches[:i], r.matches[i+1:]...)
+	}
+	return nil
+}
+
+func (r *MatchRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.matches = removeWhere(r.matches, func(m *models.Match) bool { return m.GroupID == groupID })
+	return nil
+}
+
+// ReplacePlayerInMatches replaces all occurrences of sourceID with targetID
+// in matches of a group where sourceID is on a team, returning the updated
+// matches.
+func (r *MatchRepo) ReplacePlayerInMatches(ctx context.Context, groupID, sourceID, targetID primitive.ObjectID, _, targetName string) ([]models.Match, error) {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var updated []models.Match
+	for i := range r.matches {
+		m := &r.matches[i]
+		if m.GroupID != groupID || (!containsID(m.Team1IDs, sourceID) && !containsID(m.Team2IDs, sourceID)) {
+			continue
+		}
+		m.ReplacePlayer(sourceID, targetID, targetName)
+		m.Version++
+		m.UpdatedAt = time.Now()
+		*m = *clone(m)
+		updated = append(updated, *clone(m))
+	}
+	return updated, nil
+}
+
+func (r *MatchRepo) index(id primitive.ObjectID) int {
+	for i := range r.matches {
+		if r.matches[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,141 @@
+// Package memory implements the repository interfaces in process memory, for
+// tests and for running the server without MongoDB (STORAGE=memory).
+//
+// Documents are copied through BSON on the way in and out, so callers see
+// exactly what a Mongo round trip would give them: times truncated to
+// milliseconds in UTC, nil slices for null arrays, and no shared state
+// between what was stored and what was returned. Lookups that miss return
+// repositories.ErrNotFound and unique-key clashes wrap
+// repositories.ErrDuplicateKey.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"gully-backend/repositories"
+)
+
+// clone deep-copies v through its BSON encoding.
+func clone[T any](v *T) *T {
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("memory: encode %T: %v", v, err))
+	}
+	var out T
+	if err := bson.Unmarshal(raw, &out); err != nil {
+		panic(fmt.Sprintf("memory: decode %T: %v", v, err))
+	}
+	return &out
+}
+
+// cloneAll copies a slice of stored documents for returning to a caller.
+func cloneAll[T any](docs []T) []T {
+	if len(docs) == 0 {
+		return nil
+	}
+	out := make([]T, len(docs))
+	for i := range docs {
+		out[i] = *clone(&docs[i])
+	}
+	return out
+}
+
+func duplicateKey(collection, field string, value interface{}) error {
+	return fmt.Errorf("%w: %s.%s %q", repositories.ErrDuplicateKey, collection, field, value)
+}
+
+// Restorable is a repository whose contents a Transactor can put back.
+type Restorable interface {
+	snapshot() (restore func())
+	bind(t *Transactor)
+}
+
+// snapshotOf copies docs; restore puts the copy back.
+func snapshotOf[T any](mu *sync.RWMutex, docs *[]T) func() {
+	mu.RLock()
+	saved := cloneAll(*docs)
+	mu.RUnlock()
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		*docs = saved
+	}
+}
+
+// txGuard is embedded in each repository. Writes from outside a
+// transaction wait for any open one on the repository's Transactor, so a
+// rollback never wipes them.
+type txGuard struct {
+	tx *Transactor
+}
+
+func (g *txGuard) bind(t *Transactor) { g.tx = t }
+
+// enter holds a write back while a transaction it is not part of is open.
+// The returned func ends the write.
+func (g *txGuard) enter(ctx context.Context) (leave func()) {
+	if g.tx == nil || ctx.Value(txKey{g.tx}) != nil {
+		return func() {}
+	}
+	g.tx.mu.RLock()
+	return g.tx.mu.RUnlock
+}
+
+// Transactor runs transactions one at a time. When fn fails, the repos it
+// was given are put back as they were before fn ran. Writes to them from
+// outside the transaction wait until it is over, so none are lost.
+type Transactor struct {
+	// mu is held by a transaction, and shared by writes outside one.
+	mu    sync.RWMutex
+	repos []Restorable
+}
+
+// NewTransactor returns a Transactor that rolls back repos. Writes to
+// repositories not listed are kept when a transaction fails. A repository
+// belongs to one Transactor at most.
+func NewTransactor(repos ...Restorable) *Transactor {
+	t := &Transactor{repos: repos}
+	for _, r := range repos {
+		r.bind(t)
+	}
+	return t
+}
+
+type txKey struct{ t *Transactor }
+
+// WithTransaction runs fn, undoing its writes if it fails. Inside another
+// transaction it joins that one instead.
+func (t *Transactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{t}) != nil {
+		return fn(ctx)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	restore := make([]func(), len(t.repos))
+	for i, r := range t.repos {
+		restore[i] = r.snapshot()
+	}
+	err := fn(context.WithValue(ctx, txKey{t}, true))
+	if err != nil {
+		for _, undo := range restore {
+			undo()
+		}
+	}
+	return err
+}
+
+// removeWhere drops the documents match selects, in place.
+func removeWhere[T any](docs []T, match func(*T) bool) []T {
+	kept := docs[:0]
+	for i := range docs {
+		if !match(&docs[i]) {
+			kept = append(kept, docs[i])
+		}
+	}
+	clear(docs[len(kept):])
+	return kept
+}
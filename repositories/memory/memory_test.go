@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+	"gully-backend/repositories/repotest"
+)
+
+func TestContract(t *testing.T) {
+	repotest.Run(t, func(*testing.T) repotest.Repos {
+		users, groups, players := NewUserRepo(), NewGroupRepo(), NewPlayerRepo()
+		matches, events, merges := NewMatchRepo(), NewMatchEventRepo(), NewMergeRecordRepo()
+		sessions, resets, invites := NewSessionRepo(), NewPasswordResetRepo(), NewInviteRepo()
+		joinRequests, shareLinks := NewJoinRequestRepo(), NewShareLinkRepo()
+		return repotest.Repos{
+			Users:          users,
+			Groups:         groups,
+			Players:        players,
+			Matches:        matches,
+			MatchEvents:    events,
+			MergeRecords:   merges,
+			Sessions:       sessions,
+			PasswordResets: resets,
+			Invites:        invites,
+			JoinRequests:   joinRequests,
+			ShareLinks:     shareLinks,
+			Tx: NewTransactor(users, groups, players, matches, events, merges,
+				sessions, resets, invites, joinRequests, shareLinks),
+		}
+	})
+}
+
+// A transaction rolling back must not take writes made outside it with it.
+func TestTransactor_RollbackKeepsOutsideWrites(t *testing.T) {
+	ctx := context.Background()
+	users := NewUserRepo()
+	tx := NewTransactor(users)
+	inside, release := make(chan struct{}), make(chan struct{})
+	failed := make(chan error)
+	go func() {
+		failed <- tx.WithTransaction(ctx, func(ctx context.Context) error {
+			if err := users.Create(ctx, &models.User{Username: "inside"}); err != nil {
+				return err
+			}
+			close(inside)
+			<-release
+			return errors.New("boom")
+		})
+	}()
+	<-inside
+
+	written := make(chan error)
+	go func() { written <- users.Create(ctx, &models.User{Username: "outside"}) }()
+	select {
+	case <-written:
+		t.Fatal("write outside the transaction went ahead while it was open")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	require.Error(t, <-failed)
+	require.NoError(t, <-written)
+
+	_, err := users.FindByUsername(ctx, "inside")
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	_, err = users.FindByUsername(ctx, "outside")
+	assert.NoError(t, err)
+}
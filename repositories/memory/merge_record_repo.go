@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+type MergeRecordRepo struct {
+	txGuard
+	mu      sync.RWMutex
+	records []models.MergeRecord
+}
+
+func NewMergeRecordRepo() *MergeRecordRepo {
+	return &MergeRecordRepo{}
+}
+
+func (r *MergeRecordRepo) snapshot() func() { return snapshotOf(&r.mu, &r.records) }
+
+func (r *MergeRecordRepo) Create(ctx context.Context, record *models.MergeRecord) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record.ID = primitive.NewObjectID()
+	r.records = append(r.records, *clone(record))
+	return nil
+}
+
+func (r *MergeRecordRepo) FindByID(_ context.Context, id primitive.ObjectID) (*models.MergeRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := range r.records {
+		if r.records[i].ID == id {
+			return clone(&r.records[i]), nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+// FindByGroupID returns a group's merges, newest first.
+func (r *MergeRecordRepo) FindByGroupID(_ context.Context, groupID primitive.ObjectID) ([]models.MergeRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []models.MergeRecord
+	for i := range r.records {
+		if r.records[i].GroupID == groupID {
+			out = append(out, *clone(&r.records[i]))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if !out[i].MergedAt.Equal(out[j].MergedAt) {
+			return out[i].MergedAt.After(out[j].MergedAt)
+		}
+		return bytes.Compare(out[i].ID[:], out[j].ID[:]) > 0
+	})
+	return out, nil
+}
+
+// MarkUndone stores the record's undo fields, only if it was not undone
+// already.
+func (r *MergeRecordRepo) MarkUndone(ctx context.Context, record *models.MergeRecord) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.records {
+		stored := &r.records[i]
+		if stored.ID != record.ID {
+			continue
+		}
+		if stored.UndoneAt != nil {
+			return repositories.ErrVersionConflict
+		}
+		updated := *clone(stored)
+		updated.UndoneAt, updated.UndoneBy, updated.RestoredID = record.UndoneAt, record.UndoneBy, record.RestoredID
+		*stored = *clone(&updated)
+		return nil
+	}
+	return repositories.ErrVersionConflict
+}
+
+// Replace overwrites a stored record.
+func (r *MergeRecordRepo) Replace(ctx context.Context, record *models.MergeRecord) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.records {
+		if r.records[i].ID == record.ID {
+			r.records[i] = *clone(record)
+			return nil
+		}
+	}
+	return repositories.ErrNotFound
+}
+
+func (r *MergeRecordRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = removeWhere(r.records, func(m *models.MergeRecord) bool { return m.GroupID == groupID })
+	return nil
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+type PasswordResetRepo struct {
+	txGuard
+	mu     sync.RWMutex
+	resets []models.PasswordReset
+}
+
+func NewPasswordResetRepo() *PasswordResetRepo {
+	return &PasswordResetRepo{}
+}
+
+func (r *PasswordResetRepo) snapshot() func() { return snapshotOf(&r.mu, &r.resets) }
+
+func (r *PasswordResetRepo) Create(ctx context.Context, reset *models.PasswordReset) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reset.ID = primitive.NewObjectID()
+	r.resets = append(r.resets, *clone(reset))
+	return nil
+}
+
+func (r *PasswordResetRepo) FindByID(_ context.Context, id primitive.ObjectID) (*models.PasswordReset, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := range r.resets {
+		if r.resets[i].ID == id {
+			return clone(&r.resets[i]), nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+// MarkUsed spends the reset, only if it was not used already.
+func (r *PasswordResetRepo) MarkUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.resets {
+		if rs := &r.resets[i]; rs.ID == id && rs.UsedAt == nil {
+			rs.UsedAt = &usedAt
+			return nil
+		}
+	}
+	return repositories.ErrVersionConflict
+}
+
+func (r *PasswordResetRepo) MarkUsedByUserID(ctx context.Context, userID primitive.ObjectID, usedAt time.Time) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.resets {
+		if rs := &r.resets[i]; rs.UserID == userID && rs.UsedAt == nil {
+			at := usedAt
+			rs.UsedAt = &at
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+type PlayerRepo struct {
+	txGuard
+	mu      sync.RWMutex
+	players []models.Player
+}
+
+func NewPlayerRepo() *PlayerRepo {
+	return &PlayerRepo{}
+}
+
+func (r *PlayerRepo) snapshot() func() { return snapshotOf(&r.mu, &r.players) }
+
+func (r *PlayerRepo) Create(ctx context.Context, player *models.Player) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	player.ID = primitive.NewObjectID()
+	player.CreatedAt = time.Now()
+	r.players = append(r.players, *clone(player))
+	return nil
+}
+
+func (r *PlayerRepo) FindByGroupID(_ context.Context, groupID primitive.ObjectID) ([]models.Player, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []models.Player
+	for i := range r.players {
+		if r.players[i].GroupID == groupID {
+			out = append(out, *clone(&r.players[i]))
+		}
+	}
+	return out, nil
+}
+
+func (r *PlayerRepo) FindByID(_ context.Context, id primitive.ObjectID) (*models.Player, error) {
+	return r.find(func(p *models.Player) bool { return p.ID == id })
+}
+
+func (r *PlayerRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i := r.index(id); i >= 0 {
+		r.players = append(r.players[:i], r.players[i+1:]...)
+	}
+	return nil
+}
+
+func (r *PlayerRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.players = removeWhere(r.players, func(p *models.Player) bool { return p.GroupID == groupID })
+	return nil
+}
+
+func (r *PlayerRepo) FindByNameAndGroupID(_ context.Context, name string, groupID primitive.ObjectID) (*models.Player, error) {
+	return r.find(func(p *models.Player) bool { return p.Name == name && p.GroupID == groupID })
+}
+
+func (r *PlayerRepo) FindByUserAndGroupID(_ context.Context, userID, groupID primitive.ObjectID) (*models.Player, error) {
+	return r.find(func(p *models.Player) bool { return p.LinkedTo(userID) && p.GroupID == groupID })
+}
+
+// FindByUserID returns the players linked to a user, one per group at most.
+func (r *PlayerRepo) FindByUserID(_ context.Context, userID primitive.ObjectID) ([]models.Player, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []models.Player
+	for i := range r.players {
+		if r.players[i].LinkedTo(userID) {
+			out = append(out, *clone(&r.players[i]))
+		}
+	}
+	return out, nil
+}
+
+// Update replaces the stored player; like ReplaceOne, a missing player is
+// not an error.
+func (r *PlayerRepo) Update(ctx context.Context, player *models.Player) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i := r.index(player.ID); i >= 0 {
+		r.players[i] = *clone(player)
+	}
+	return nil
+}
+
+func (r *PlayerRepo) index(id primitive.ObjectID) int {
+	for i := range r.players {
+		if r.players[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *PlayerRepo) find(match func(*models.Player) bool) (*models.Player, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := range r.players {
+		if match(&r.players[i]) {
+			return clone(&r.players[i]), nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
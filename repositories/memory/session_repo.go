@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+type SessionRepo struct {
+	txGuard
+	mu       sync.RWMutex
+	sessions []models.Session
+}
+
+func NewSessionRepo() *SessionRepo {
+	return &SessionRepo{}
+}
+
+func (r *SessionRepo) snapshot() func() { return snapshotOf(&r.mu, &r.sessions) }
+
+func (r *SessionRepo) Create(ctx context.Context, session *models.Session) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session.ID = primitive.NewObjectID()
+	r.sessions = append(r.sessions, *clone(session))
+	return nil
+}
+
+func (r *SessionRepo) FindByID(_ context.Context, id primitive.ObjectID) (*models.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := range r.sessions {
+		if r.sessions[i].ID == id {
+			return clone(&r.sessions[i]), nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+// Rotate swaps the token hash only if it is still oldHash and the session
+// is not revoked, and adds oldHash to the rotated hashes.
+func (r *SessionRepo) Rotate(ctx context.Context, id primitive.ObjectID, oldHash, newHash string, usedAt, expiresAt time.Time) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.sessions {
+		s := &r.sessions[i]
+		if s.ID == id && s.TokenHash == oldHash && s.RevokedAt == nil {
+			s.TokenHash, s.LastUsedAt, s.ExpiresAt = newHash, usedAt, expiresAt
+			s.RotatedHashes = models.AddRotated(s.RotatedHashes, oldHash)
+			return nil
+		}
+	}
+	return repositories.ErrVersionConflict
+}
+
+func (r *SessionRepo) Revoke(ctx context.Context, id primitive.ObjectID, reason string) error {
+	r.revoke(ctx, func(s *models.Session) bool { return s.ID == id }, reason)
+	return nil
+}
+
+func (r *SessionRepo) RevokeByUserID(ctx context.Context, userID primitive.ObjectID, reason string) error {
+	r.revoke(ctx, func(s *models.Session) bool { return s.UserID == userID }, reason)
+	return nil
+}
+
+func (r *SessionRepo) revoke(ctx context.Context, match func(*models.Session) bool, reason string) {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for i := range r.sessions {
+		if s := &r.sessions[i]; match(s) && s.RevokedAt == nil {
+			revokedAt := now
+			s.RevokedAt, s.RevokedReason = &revokedAt, reason
+		}
+	}
+}
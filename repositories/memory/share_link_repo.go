@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+type ShareLinkRepo struct {
+	txGuard
+	mu    sync.RWMutex
+	links []models.ShareLink
+}
+
+func NewShareLinkRepo() *ShareLinkRepo {
+	return &ShareLinkRepo{}
+}
+
+func (r *ShareLinkRepo) snapshot() func() { return snapshotOf(&r.mu, &r.links) }
+
+func (r *ShareLinkRepo) Create(ctx context.Context, link *models.ShareLink) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	link.ID = primitive.NewObjectID()
+	r.links = append(r.links, *clone(link))
+	return nil
+}
+
+func (r *ShareLinkRepo) FindByID(_ context.Context, id primitive.ObjectID) (*models.ShareLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := range r.links {
+		if r.links[i].ID == id {
+			return clone(&r.links[i]), nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+func (r *ShareLinkRepo) FindByGroupID(_ context.Context, groupID primitive.ObjectID) ([]models.ShareLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []models.ShareLink
+	for i := range r.links {
+		if r.links[i].GroupID == groupID {
+			out = append(out, *clone(&r.links[i]))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *ShareLinkRepo) Revoke(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.links {
+		if r.links[i].ID == id {
+			r.links[i].RevokedAt = &at
+			return nil
+		}
+	}
+	return repositories.ErrNotFound
+}
+
+func (r *ShareLinkRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.links = removeWhere(r.links, func(link *models.ShareLink) bool { return link.GroupID == groupID })
+	return nil
+}
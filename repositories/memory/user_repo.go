@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+type UserRepo struct {
+	txGuard
+	mu    sync.RWMutex
+	users []models.User
+}
+
+func NewUserRepo() *UserRepo {
+	return &UserRepo{}
+}
+
+func (r *UserRepo) snapshot() func() { return snapshotOf(&r.mu, &r.users) }
+
+func (r *UserRepo) Create(ctx context.Context, user *models.User) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if strings.EqualFold(u.Username, user.Username) {
+			return duplicateKey("users", "username", user.Username)
+		}
+	}
+	user.ID = primitive.NewObjectID()
+	user.CreatedAt = time.Now()
+	r.users = append(r.users, *clone(user))
+	return nil
+}
+
+func (r *UserRepo) FindByUsername(_ context.Context, username string) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return strings.EqualFold(u.Username, username) })
+}
+
+func (r *UserRepo) FindByID(_ context.Context, id primitive.ObjectID) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.ID == id })
+}
+
+func (r *UserRepo) UpdatePassword(ctx context.Context, id primitive.ObjectID, hash string) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.users {
+		if r.users[i].ID == id {
+			r.users[i].Password = hash
+			return nil
+		}
+	}
+	return repositories.ErrNotFound
+}
+
+func (r *UserRepo) UpdateProfile(ctx context.Context, user *models.User) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.users {
+		if r.users[i].ID == user.ID {
+			r.users[i].DisplayName = user.DisplayName
+			r.users[i].Phone = user.Phone
+			return nil
+		}
+	}
+	return repositories.ErrNotFound
+}
+
+func (r *UserRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	defer r.enter(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := len(r.users)
+	r.users = removeWhere(r.users, func(u *models.User) bool { return u.ID == id })
+	if len(r.users) == n {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+func (r *UserRepo) find(match func(*models.User) bool) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := range r.users {
+		if match(&r.users[i]) {
+			return clone(&r.users[i]), nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
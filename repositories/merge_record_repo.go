@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"gully-backend/models"
+)
+
+type MergeRecordRepo struct {
+	col *mongo.Collection
+}
+
+func NewMergeRecordRepo(db *mongo.Database) *MergeRecordRepo {
+	return &MergeRecordRepo{col: db.Collection("player_merges")}
+}
+
+func (r *MergeRecordRepo) Create(ctx context.Context, record *models.MergeRecord) error {
+	record.ID = primitive.NewObjectID()
+	_, err := r.col.InsertOne(ctx, record)
+	return err
+}
+
+func (r *MergeRecordRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.MergeRecord, error) {
+	var record models.MergeRecord
+	if err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// FindByGroupID returns a group's merges, newest first.
+func (r *MergeRecordRepo) FindByGroupID(ctx context.Context, groupID primitive.ObjectID) ([]models.MergeRecord, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "merged_at", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := r.col.Find(ctx, bson.M{"group_id": groupID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []models.MergeRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// MarkUndone stores the record's undo fields, only if it was not undone
+// already.
+func (r *MergeRecordRepo) MarkUndone(ctx context.Context, record *models.MergeRecord) error {
+	res, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": record.ID, "undone_at": nil},
+		bson.M{"$set": bson.M{
+			"undone_at":   record.UndoneAt,
+			"undone_by":   record.UndoneBy,
+			"restored_id": record.RestoredID,
+		}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// Replace overwrites a stored record.
+func (r *MergeRecordRepo) Replace(ctx context.Context, record *models.MergeRecord) error {
+	res, err := r.col.ReplaceOne(ctx, bson.M{"_id": record.ID}, record)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *MergeRecordRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	_, err := r.col.DeleteMany(ctx, bson.M{"group_id": groupID})
+	return err
+}
@@ -0,0 +1,56 @@
+package repositories_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"gully-backend/migrations"
+	"gully-backend/repositories"
+	"gully-backend/repositories/repotest"
+)
+
+// TestMongoContract runs the repository contract against a real MongoDB,
+// which must be a replica set. Set MONGO_TEST_URI (e.g.
+// mongodb://localhost:27017/?replicaSet=rs0) to enable it; each subtest
+// uses a throwaway database.
+func TestMongoContract(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+	require.NoError(t, client.Ping(ctx, nil))
+
+	repotest.Run(t, func(t *testing.T) repotest.Repos {
+		db := client.Database("gully_contract_" + primitive.NewObjectID().Hex())
+		t.Cleanup(func() { _ = db.Drop(context.Background()) })
+		require.NoError(t, migrations.Run(context.Background(), db))
+		tx, err := repositories.NewMongoTransactor(context.Background(), db)
+		require.NoError(t, err)
+		return repotest.Repos{
+			Users:          repositories.NewUserRepo(db),
+			Groups:         repositories.NewGroupRepo(db),
+			Players:        repositories.NewPlayerRepo(db),
+			Matches:        repositories.NewMatchRepo(db),
+			MatchEvents:    repositories.NewMatchEventRepo(db),
+			MergeRecords:   repositories.NewMergeRecordRepo(db),
+			Sessions:       repositories.NewSessionRepo(db),
+			PasswordResets: repositories.NewPasswordResetRepo(db),
+			Invites:        repositories.NewInviteRepo(db),
+			JoinRequests:   repositories.NewJoinRequestRepo(db),
+			ShareLinks:     repositories.NewShareLinkRepo(db),
+			Tx:             tx,
+		}
+	})
+}
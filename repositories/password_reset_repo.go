@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"gully-backend/models"
+)
+
+type PasswordResetRepo struct {
+	col *mongo.Collection
+}
+
+func NewPasswordResetRepo(db *mongo.Database) *PasswordResetRepo {
+	return &PasswordResetRepo{col: db.Collection("password_resets")}
+}
+
+func (r *PasswordResetRepo) Create(ctx context.Context, reset *models.PasswordReset) error {
+	reset.ID = primitive.NewObjectID()
+	_, err := r.col.InsertOne(ctx, reset)
+	return err
+}
+
+func (r *PasswordResetRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.PasswordReset, error) {
+	var reset models.PasswordReset
+	if err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&reset); err != nil {
+		return nil, err
+	}
+	return &reset, nil
+}
+
+// MarkUsed spends the reset, only if it was not used already.
+func (r *PasswordResetRepo) MarkUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	res, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": id, "used_at": nil},
+		bson.M{"$set": bson.M{"used_at": usedAt}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (r *PasswordResetRepo) MarkUsedByUserID(ctx context.Context, userID primitive.ObjectID, usedAt time.Time) error {
+	_, err := r.col.UpdateMany(ctx,
+		bson.M{"user_id": userID, "used_at": nil},
+		bson.M{"$set": bson.M{"used_at": usedAt}})
+	return err
+}
@@ -54,6 +54,11 @@ func (r *PlayerRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
 	return err
 }
 
+func (r *PlayerRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	_, err := r.col.DeleteMany(ctx, bson.M{"group_id": groupID})
+	return err
+}
+
 func (r *PlayerRepo) FindByNameAndGroupID(ctx context.Context, name string, groupID primitive.ObjectID) (*models.Player, error) {
 	var player models.Player
 	err := r.col.FindOne(ctx, bson.M{"name": name, "group_id": groupID}).Decode(&player)
@@ -63,6 +68,30 @@ func (r *PlayerRepo) FindByNameAndGroupID(ctx context.Context, name string, grou
 	return &player, nil
 }
 
+func (r *PlayerRepo) FindByUserAndGroupID(ctx context.Context, userID, groupID primitive.ObjectID) (*models.Player, error) {
+	var player models.Player
+	err := r.col.FindOne(ctx, bson.M{"user_id": userID, "group_id": groupID}).Decode(&player)
+	if err != nil {
+		return nil, err
+	}
+	return &player, nil
+}
+
+// FindByUserID returns the players linked to a user, one per group at most.
+func (r *PlayerRepo) FindByUserID(ctx context.Context, userID primitive.ObjectID) ([]models.Player, error) {
+	cursor, err := r.col.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var players []models.Player
+	if err := cursor.All(ctx, &players); err != nil {
+		return nil, err
+	}
+	return players, nil
+}
+
 func (r *PlayerRepo) Update(ctx context.Context, player *models.Player) error {
 	_, err := r.col.ReplaceOne(ctx, bson.M{"_id": player.ID}, player)
 	return err
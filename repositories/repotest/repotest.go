@@ -0,0 +1,988 @@
+// Package repotest is the contract every repository implementation must
+// meet. Each backend's tests call Run with a constructor for fresh, empty
+// repositories; the same assertions then hold for Mongo, memory and any
+// other storage.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+// Repos is one backend's set of repositories sharing a single empty store.
+type Repos struct {
+	Users          repositories.UserRepository
+	Groups         repositories.GroupRepository
+	Players        repositories.PlayerRepository
+	Matches        repositories.MatchRepository
+	MatchEvents    repositories.MatchEventRepository
+	MergeRecords   repositories.MergeRecordRepository
+	Sessions       repositories.SessionRepository
+	PasswordResets repositories.PasswordResetRepository
+	Invites        repositories.InviteRepository
+	JoinRequests   repositories.JoinRequestRepository
+	ShareLinks     repositories.ShareLinkRepository
+	Tx             repositories.Transactor
+}
+
+// Run runs the contract suite. newRepos is called once per subtest.
+func Run(t *testing.T, newRepos func(t *testing.T) Repos) {
+	t.Run("Users", func(t *testing.T) { testUsers(t, newRepos(t)) })
+	t.Run("Groups", func(t *testing.T) { testGroups(t, newRepos(t)) })
+	t.Run("Players", func(t *testing.T) { testPlayers(t, newRepos(t)) })
+	t.Run("PlayerLinks", func(t *testing.T) { testPlayerLinks(t, newRepos(t)) })
+	t.Run("Matches", func(t *testing.T) { testMatches(t, newRepos(t)) })
+	t.Run("MatchPages", func(t *testing.T) { testMatchPages(t, newRepos(t)) })
+	t.Run("ReplacePlayer", func(t *testing.T) { testReplacePlayer(t, newRepos(t)) })
+	t.Run("MatchEvents", func(t *testing.T) { testMatchEvents(t, newRepos(t)) })
+	t.Run("MergeRecords", func(t *testing.T) { testMergeRecords(t, newRepos(t)) })
+	t.Run("Sessions", func(t *testing.T) { testSessions(t, newRepos(t)) })
+	t.Run("PasswordResets", func(t *testing.T) { testPasswordResets(t, newRepos(t)) })
+	t.Run("Invites", func(t *testing.T) { testInvites(t, newRepos(t)) })
+	t.Run("JoinRequests", func(t *testing.T) { testJoinRequests(t, newRepos(t)) })
+	t.Run("ShareLinks", func(t *testing.T) { testShareLinks(t, newRepos(t)) })
+	t.Run("GroupData", func(t *testing.T) { testGroupData(t, newRepos(t)) })
+	t.Run("Transaction", func(t *testing.T) { testTransaction(t, newRepos(t)) })
+}
+
+// ── Users ──
+
+func testUsers(t *testing.T, r Repos) {
+	ctx := context.Background()
+
+	user := &models.User{Username: "amit", Password: "hash"}
+	require.NoError(t, r.Users.Create(ctx, user))
+	assert.False(t, user.ID.IsZero())
+	assert.False(t, user.CreatedAt.IsZero())
+
+	got, err := r.Users.FindByUsername(ctx, "amit")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, got.ID)
+	assert.Equal(t, "hash", got.Password)
+
+	got, err = r.Users.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "amit", got.Username)
+
+	_, err = r.Users.FindByUsername(ctx, "nobody")
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	_, err = r.Users.FindByID(ctx, primitive.NewObjectID())
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+
+	err = r.Users.Create(ctx, &models.User{Username: "amit", Password: "other"})
+	assert.True(t, repositories.IsDuplicateKey(err), "duplicate username: %v", err)
+
+	require.NoError(t, r.Users.UpdatePassword(ctx, user.ID, "new-hash"))
+	got, err = r.Users.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "new-hash", got.Password)
+	assert.ErrorIs(t, r.Users.UpdatePassword(ctx, primitive.NewObjectID(), "x"), repositories.ErrNotFound)
+
+	// Usernames are unique and found ignoring case.
+	got, err = r.Users.FindByUsername(ctx, "AMIT")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, got.ID)
+	assert.Equal(t, "amit", got.Username, "stored as registered")
+	err = r.Users.Create(ctx, &models.User{Username: "Amit", Password: "other"})
+	assert.True(t, repositories.IsDuplicateKey(err), "username differing in case: %v", err)
+
+	user.DisplayName, user.Phone = "Amit K", "+91 98765 43210"
+	require.NoError(t, r.Users.UpdateProfile(ctx, user))
+	got, err = r.Users.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Amit K", got.DisplayName)
+	assert.Equal(t, "+91 98765 43210", got.Phone)
+	assert.Equal(t, "new-hash", got.Password, "profile updates leave the password alone")
+	assert.ErrorIs(t, r.Users.UpdateProfile(ctx, &models.User{ID: primitive.NewObjectID()}), repositories.ErrNotFound)
+
+	require.NoError(t, r.Users.Delete(ctx, user.ID))
+	_, err = r.Users.FindByID(ctx, user.ID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	assert.ErrorIs(t, r.Users.Delete(ctx, user.ID), repositories.ErrNotFound)
+	require.NoError(t, r.Users.Create(ctx, &models.User{Username: "Amit", Password: "hash"}), "a deleted username is free again")
+}
+
+// ── Groups ──
+
+func testGroups(t *testing.T, r Repos) {
+	ctx := context.Background()
+	owner, member := primitive.NewObjectID(), primitive.NewObjectID()
+
+	group := &models.Group{Name: "Sunday Smash", JoinCode: "ABC123", CreatedBy: owner, Members: []primitive.ObjectID{owner}}
+	require.NoError(t, r.Groups.Create(ctx, group))
+	assert.False(t, group.ID.IsZero())
+	other := &models.Group{Name: "Office", JoinCode: "XYZ789", CreatedBy: member, Members: []primitive.ObjectID{member}}
+	require.NoError(t, r.Groups.Create(ctx, other))
+
+	got, err := r.Groups.FindByJoinCode(ctx, "ABC123")
+	require.NoError(t, err)
+	assert.Equal(t, group.ID, got.ID)
+	_, err = r.Groups.FindByJoinCode(ctx, "abc123")
+	assert.ErrorIs(t, err, repositories.ErrNotFound, "join codes are case-sensitive")
+	_, err = r.Groups.FindByID(ctx, primitive.NewObjectID())
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+
+	require.NoError(t, r.Groups.AddMember(ctx, group.ID, member))
+	require.NoError(t, r.Groups.AddMember(ctx, group.ID, member))
+	got, err = r.Groups.FindByID(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{owner, member}, got.Members)
+	assert.NoError(t, r.Groups.AddMember(ctx, primitive.NewObjectID(), member), "missing group is not an error")
+
+	groups, err := r.Groups.FindByMember(ctx, member)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []primitive.ObjectID{group.ID, other.ID}, groupIDs(groups))
+	groups, err = r.Groups.FindByMember(ctx, primitive.NewObjectID())
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+
+	err = r.Groups.Create(ctx, &models.Group{Name: "Copy", JoinCode: "ABC123"})
+	assert.True(t, repositories.IsDuplicateKey(err), "duplicate join code: %v", err)
+
+	require.NoError(t, r.Groups.SetCreator(ctx, group.ID, member))
+	require.NoError(t, r.Groups.RemoveMember(ctx, group.ID, owner))
+	require.NoError(t, r.Groups.RemoveMember(ctx, group.ID, owner))
+	got, err = r.Groups.FindByID(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Equal(t, member, got.CreatedBy)
+	assert.Equal(t, []primitive.ObjectID{member}, got.Members)
+	assert.NoError(t, r.Groups.RemoveMember(ctx, primitive.NewObjectID(), member), "missing group is not an error")
+	assert.ErrorIs(t, r.Groups.SetCreator(ctx, primitive.NewObjectID(), member), repositories.ErrNotFound)
+
+	require.NoError(t, r.Groups.Rename(ctx, group.ID, "Renamed"))
+	got, err = r.Groups.FindByID(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", got.Name)
+	assert.ErrorIs(t, r.Groups.Rename(ctx, primitive.NewObjectID(), "Nope"), repositories.ErrNotFound)
+
+	require.NoError(t, r.Groups.SetJoinCode(ctx, group.ID, "NEW123"))
+	require.NoError(t, r.Groups.SetJoinApproval(ctx, group.ID, true))
+	got, err = r.Groups.FindByJoinCode(ctx, "NEW123")
+	require.NoError(t, err)
+	assert.Equal(t, group.ID, got.ID)
+	assert.True(t, got.JoinApproval)
+	_, err = r.Groups.FindByJoinCode(ctx, "ABC123")
+	assert.ErrorIs(t, err, repositories.ErrNotFound, "old code no longer joins")
+	err = r.Groups.SetJoinCode(ctx, other.ID, "NEW123")
+	assert.True(t, repositories.IsDuplicateKey(err), "code taken: %v", err)
+	assert.ErrorIs(t, r.Groups.SetJoinCode(ctx, primitive.NewObjectID(), "NOPE00"), repositories.ErrNotFound)
+	assert.ErrorIs(t, r.Groups.SetJoinApproval(ctx, primitive.NewObjectID(), true), repositories.ErrNotFound)
+
+	assert.Zero(t, got.Settings.Version, "no settings saved yet")
+	updatedAt := time.Now().Truncate(time.Millisecond)
+	settings := models.GroupSettings{
+		Description:    "Tuesdays and Thursdays",
+		Sport:          models.SportBadminton,
+		HomeVenue:      "Leisure centre",
+		Timezone:       "Europe/London",
+		PointsToWin:    models.PointsToWin15,
+		GuestsCanScore: true,
+		Privacy:        models.PrivacyPublic,
+		UpdatedAt:      &updatedAt,
+		UpdatedBy:      &member,
+	}
+	require.NoError(t, r.Groups.UpdateSettings(ctx, group.ID, &settings))
+	assert.Equal(t, 1, settings.Version)
+	got, err = r.Groups.FindByID(ctx, group.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.Settings.UpdatedAt)
+	assert.True(t, updatedAt.Equal(*got.Settings.UpdatedAt))
+	got.Settings.UpdatedAt = &updatedAt
+	assert.Equal(t, settings, got.Settings)
+	stale := settings
+	stale.Version = 0
+	assert.ErrorIs(t, r.Groups.UpdateSettings(ctx, group.ID, &stale), repositories.ErrVersionConflict)
+	assert.Zero(t, stale.Version, "failed save leaves the version alone")
+	settings.Description = ""
+	require.NoError(t, r.Groups.UpdateSettings(ctx, group.ID, &settings))
+	assert.Equal(t, 2, settings.Version)
+	assert.ErrorIs(t, r.Groups.UpdateSettings(ctx, primitive.NewObjectID(), &models.GroupSettings{}), repositories.ErrVersionConflict)
+
+	require.NoError(t, r.Groups.Delete(ctx, group.ID))
+	_, err = r.Groups.FindByID(ctx, group.ID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	assert.ErrorIs(t, r.Groups.Delete(ctx, group.ID), repositories.ErrNotFound)
+	groups, err = r.Groups.FindByMember(ctx, member)
+	require.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{other.ID}, groupIDs(groups))
+}
+
+func groupIDs(groups []models.Group) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, len(groups))
+	for i, g := range groups {
+		ids[i] = g.ID
+	}
+	return ids
+}
+
+// ── Players ──
+
+func testPlayers(t *testing.T, r Repos) {
+	ctx := context.Background()
+	groupID := primitive.NewObjectID()
+
+	alice := &models.Player{Name: "Alice", GroupID: groupID}
+	require.NoError(t, r.Players.Create(ctx, alice))
+	assert.False(t, alice.ID.IsZero())
+	bob := &models.Player{Name: "Bob", GroupID: groupID}
+	require.NoError(t, r.Players.Create(ctx, bob))
+	require.NoError(t, r.Players.Create(ctx, &models.Player{Name: "Alice", GroupID: primitive.NewObjectID()}))
+
+	players, err := r.Players.FindByGroupID(ctx, groupID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Alice", "Bob"}, []string{players[0].Name, players[1].Name})
+
+	got, err := r.Players.FindByNameAndGroupID(ctx, "Alice", groupID)
+	require.NoError(t, err)
+	assert.Equal(t, alice.ID, got.ID)
+	_, err = r.Players.FindByNameAndGroupID(ctx, "alice", groupID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound, "names match exactly")
+
+	archivedAt := time.Now()
+	alice.Name, alice.Nickname, alice.Hand, alice.Position = "Alice K", "Ace", models.HandLeft, models.PositionFront
+	alice.ArchivedAt = &archivedAt
+	require.NoError(t, r.Players.Update(ctx, alice))
+	got, err = r.Players.FindByID(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice K", got.Name)
+	assert.Equal(t, "Ace", got.Nickname)
+	assert.Equal(t, models.HandLeft, got.Hand)
+	assert.Equal(t, models.PositionFront, got.Position)
+	require.NotNil(t, got.ArchivedAt)
+	assert.WithinDuration(t, archivedAt, *got.ArchivedAt, time.Millisecond)
+
+	require.NoError(t, r.Players.Delete(ctx, bob.ID))
+	_, err = r.Players.FindByID(ctx, bob.ID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	assert.NoError(t, r.Players.Delete(ctx, bob.ID), "deleting twice is not an error")
+
+	players, err = r.Players.FindByGroupID(ctx, primitive.NewObjectID())
+	require.NoError(t, err)
+	assert.Empty(t, players)
+}
+
+func testPlayerLinks(t *testing.T, r Repos) {
+	ctx := context.Background()
+	groupID, otherGroup := primitive.NewObjectID(), primitive.NewObjectID()
+	userID, claimant := primitive.NewObjectID(), primitive.NewObjectID()
+
+	linked := &models.Player{Name: "Amit", GroupID: groupID, UserID: &userID}
+	require.NoError(t, r.Players.Create(ctx, linked))
+	elsewhere := &models.Player{Name: "Amit", GroupID: otherGroup, UserID: &userID}
+	require.NoError(t, r.Players.Create(ctx, elsewhere))
+	guest := &models.Player{Name: "Amit", GroupID: groupID}
+	require.NoError(t, r.Players.Create(ctx, guest))
+
+	got, err := r.Players.FindByUserAndGroupID(ctx, userID, groupID)
+	require.NoError(t, err)
+	assert.Equal(t, linked.ID, got.ID)
+	require.NotNil(t, got.UserID)
+	assert.Equal(t, userID, *got.UserID)
+	_, err = r.Players.FindByUserAndGroupID(ctx, claimant, groupID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+
+	players, err := r.Players.FindByUserID(ctx, userID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []primitive.ObjectID{linked.ID, elsewhere.ID}, []primitive.ObjectID{players[0].ID, players[1].ID})
+
+	claimedAt := time.Now()
+	guest.ClaimedBy, guest.ClaimedAt = &claimant, &claimedAt
+	require.NoError(t, r.Players.Update(ctx, guest))
+	got, err = r.Players.FindByID(ctx, guest.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.UserID)
+	require.NotNil(t, got.ClaimedBy)
+	assert.Equal(t, claimant, *got.ClaimedBy)
+	assert.WithinDuration(t, claimedAt, *got.ClaimedAt, time.Millisecond)
+
+	linked.UserID = nil
+	require.NoError(t, r.Players.Update(ctx, linked))
+	players, err = r.Players.FindByUserID(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, players, 1)
+	assert.Equal(t, elsewhere.ID, players[0].ID)
+}
+
+// ── Matches ──
+
+func newMatch(groupID primitive.ObjectID, t1, t2 []primitive.ObjectID) *models.Match {
+	return &models.Match{
+		GroupID:        groupID,
+		Team1IDs:       t1,
+		Team2IDs:       t2,
+		Team1Names:     []string{"Alice", "Bob"}[:len(t1)],
+		Team2Names:     []string{"Charlie", "Dave"}[:len(t2)],
+		Type:           models.MatchTypeFor(len(t1), len(t2)),
+		ScoreHistory:   []models.ScoreEvent{},
+		Pauses:         []models.Pause{},
+		ServingTeam:    1,
+		Team1Positions: []string{},
+		Team2Positions: []string{},
+		Status:         models.MatchStatusLive,
+		StartedAt:      time.Now(),
+		PointsToWin:    models.PointsToWin15,
+	}
+}
+
+// createMatches stores n matches a few milliseconds apart, so their
+// created_at values are distinct even at Mongo's precision.
+func createMatches(t *testing.T, r Repos, groupID primitive.ObjectID, n int) []*models.Match {
+	t.Helper()
+	out := make([]*models.Match, n)
+	for i := range out {
+		out[i] = newMatch(groupID, []primitive.ObjectID{primitive.NewObjectID()}, []primitive.ObjectID{primitive.NewObjectID()})
+		require.NoError(t, r.Matches.Create(context.Background(), out[i]))
+		time.Sleep(3 * time.Millisecond)
+	}
+	return out
+}
+
+func testMatches(t *testing.T, r Repos) {
+	ctx := context.Background()
+	groupID := primitive.NewObjectID()
+	matches := createMatches(t, r, groupID, 3)
+	createMatches(t, r, primitive.NewObjectID(), 1)
+
+	first := matches[0]
+	assert.False(t, first.ID.IsZero())
+	assert.Equal(t, 1, first.Version)
+
+	list, err := r.Matches.FindByGroupID(ctx, groupID)
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Equal(t, []primitive.ObjectID{matches[2].ID, matches[1].ID, matches[0].ID}, matchIDs(list), "newest first")
+
+	got, err := r.Matches.FindByID(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PointsToWin15, got.PointsToWin)
+	got.Score1 = 5
+	got.ScoreHistory = append(got.ScoreHistory, models.ScoreEvent{Team: 1, PlayerID: first.Team1IDs[0].Hex(), At: time.Now()})
+	require.NoError(t, r.Matches.Update(ctx, got))
+	assert.Equal(t, 2, got.Version)
+
+	stored, err := r.Matches.FindByID(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 5, stored.Score1)
+	assert.Len(t, stored.ScoreHistory, 1)
+	assert.Equal(t, 2, stored.Version)
+
+	// first still holds version 1.
+	first.Score2 = 3
+	assert.ErrorIs(t, r.Matches.Update(ctx, first), repositories.ErrVersionConflict)
+	assert.Equal(t, 1, first.Version, "failed update leaves the version alone")
+	missing := newMatch(groupID, first.Team1IDs, first.Team2IDs)
+	missing.ID = primitive.NewObjectID()
+	missing.Version = 1
+	assert.ErrorIs(t, r.Matches.Update(ctx, missing), repositories.ErrVersionConflict)
+
+	require.NoError(t, r.Matches.Delete(ctx, first.ID))
+	_, err = r.Matches.FindByID(ctx, first.ID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	assert.NoError(t, r.Matches.Delete(ctx, first.ID))
+
+	list, err = r.Matches.FindByGroupID(ctx, primitive.NewObjectID())
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func testMatchPages(t *testing.T, r Repos) {
+	ctx := context.Background()
+	groupID := primitive.NewObjectID()
+	matches := createMatches(t, r, groupID, 5)
+	durations := []int{600, 300, 900, 300, 0}
+	for i, m := range matches {
+		m.DurationSecs = durations[i]
+		if i%2 == 0 {
+			m.Status = models.MatchStatusFinished
+			m.SessionID = "sat-evening"
+		}
+		require.NoError(t, r.Matches.Update(ctx, m))
+	}
+
+	// Walk every page of the default (newest first) order.
+	var got []primitive.ObjectID
+	q := repositories.MatchQuery{GroupID: groupID, Limit: 2}
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 5)
+		page, err := r.Matches.FindPage(ctx, q)
+		require.NoError(t, err)
+		got = append(got, matchIDs(page.Matches)...)
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []primitive.ObjectID{matches[4].ID, matches[3].ID, matches[2].ID, matches[1].ID, matches[0].ID}, got)
+
+	// Duration ascending, ties by ID, across a page break inside the tie.
+	q = repositories.MatchQuery{GroupID: groupID, Sort: repositories.MatchSortDuration, Ascending: true, Limit: 2}
+	page, err := r.Matches.FindPage(ctx, q)
+	require.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{matches[4].ID, matches[1].ID}, matchIDs(page.Matches))
+	q.Cursor = page.NextCursor
+	page, err = r.Matches.FindPage(ctx, q)
+	require.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{matches[3].ID, matches[0].ID}, matchIDs(page.Matches))
+
+	page, err = r.Matches.FindPage(ctx, repositories.MatchQuery{
+		GroupID: groupID, Statuses: []string{models.MatchStatusFinished}, SessionID: "sat-evening",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{matches[4].ID, matches[2].ID, matches[0].ID}, matchIDs(page.Matches))
+	assert.Empty(t, page.NextCursor)
+
+	from, to := matches[1].CreatedAt, matches[3].CreatedAt
+	page, err = r.Matches.FindPage(ctx, repositories.MatchQuery{
+		GroupID: groupID, PlayerID: matches[2].Team2IDs[0], From: &from, To: &to, Type: models.MatchType1v1,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{matches[2].ID}, matchIDs(page.Matches))
+
+	page, err = r.Matches.FindPage(ctx, repositories.MatchQuery{GroupID: groupID, Type: models.MatchType2v2})
+	require.NoError(t, err)
+	assert.NotNil(t, page.Matches)
+	assert.Empty(t, page.Matches)
+
+	_, err = r.Matches.FindPage(ctx, repositories.MatchQuery{GroupID: groupID, Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, repositories.ErrInvalidCursor)
+}
+
+func testReplacePlayer(t *testing.T, r Repos) {
+	ctx := context.Background()
+	groupID := primitive.NewObjectID()
+	source, target, other := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	withSource := newMatch(groupID, []primitive.ObjectID{source}, []primitive.ObjectID{other})
+	withSource.ScoreHistory = []models.ScoreEvent{{Team: 1, PlayerID: source.Hex()}}
+	withSource.Team1Positions = []string{source.Hex()}
+	require.NoError(t, r.Matches.Create(ctx, withSource))
+	without := newMatch(groupID, []primitive.ObjectID{other}, []primitive.ObjectID{primitive.NewObjectID()})
+	require.NoError(t, r.Matches.Create(ctx, without))
+	otherGroup := newMatch(primitive.NewObjectID(), []primitive.ObjectID{source}, []primitive.ObjectID{other})
+	require.NoError(t, r.Matches.Create(ctx, otherGroup))
+
+	updated, err := r.Matches.ReplacePlayerInMatches(ctx, groupID, source, target, "alice", "Alice")
+	require.NoError(t, err)
+	require.Len(t, updated, 1)
+	assert.Equal(t, withSource.ID, updated[0].ID)
+	assert.Equal(t, 2, updated[0].Version)
+
+	stored, err := r.Matches.FindByID(ctx, withSource.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{target}, stored.Team1IDs)
+	assert.Equal(t, []string{"Alice"}, stored.Team1Names)
+	assert.Equal(t, target.Hex(), stored.ScoreHistory[0].PlayerID)
+	assert.Equal(t, []string{target.Hex()}, stored.Team1Positions)
+	assert.Equal(t, 2, stored.Version)
+
+	untouched, err := r.Matches.FindByID(ctx, without.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, untouched.Version)
+	untouched, err = r.Matches.FindByID(ctx, otherGroup.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{source}, untouched.Team1IDs)
+
+	// Replacing a player with itself renames it.
+	updated, err = r.Matches.ReplacePlayerInMatches(ctx, groupID, target, target, "Alice", "Alice K")
+	require.NoError(t, err)
+	require.Len(t, updated, 1)
+	stored, err = r.Matches.FindByID(ctx, withSource.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{target}, stored.Team1IDs)
+	assert.Equal(t, []string{"Alice K"}, stored.Team1Names)
+	assert.Equal(t, 3, stored.Version)
+}
+
+func matchIDs(matches []models.Match) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, len(matches))
+	for i, m := range matches {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+// ── Match events ──
+
+func testMatchEvents(t *testing.T, r Repos) {
+	ctx := context.Background()
+	matchID := primitive.NewObjectID()
+
+	require.NoError(t, r.MatchEvents.Append(ctx))
+	events := []models.MatchEvent{
+		{MatchID: matchID, Type: models.MatchEventPoint, Version: 2, Index: 1},
+		{MatchID: matchID, Type: models.MatchEventPause, Version: 2, Index: 2},
+	}
+	require.NoError(t, r.MatchEvents.Append(ctx, events...))
+	assert.False(t, events[0].ID.IsZero())
+	require.NoError(t, r.MatchEvents.Append(ctx,
+		models.MatchEvent{MatchID: matchID, Type: models.MatchEventCreated, Version: 1},
+		models.MatchEvent{MatchID: primitive.NewObjectID(), Type: models.MatchEventCreated, Version: 1},
+	))
+
+	got, err := r.MatchEvents.FindByMatchID(ctx, matchID)
+	require.NoError(t, err)
+	types := make([]string, len(got))
+	for i, ev := range got {
+		types[i] = ev.Type
+	}
+	assert.Equal(t, []string{models.MatchEventCreated, models.MatchEventPoint, models.MatchEventPause}, types)
+
+	got, err = r.MatchEvents.FindByMatchID(ctx, primitive.NewObjectID())
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	groupID := primitive.NewObjectID()
+	mine := []models.MatchEvent{
+		{MatchID: matchID, GroupID: groupID, Type: models.MatchEventEdit, Version: 3, ActorID: "u1", ActorName: "alice"},
+		{MatchID: primitive.NewObjectID(), GroupID: groupID, Type: models.MatchEventEdit, Version: 1, ActorID: "u2"},
+	}
+	require.NoError(t, r.MatchEvents.Append(ctx, mine...))
+	got, err = r.MatchEvents.FindByGroupID(ctx, groupID)
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+	got, err = r.MatchEvents.FindByActorID(ctx, "u1")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, mine[0].ID, got[0].ID)
+
+	got[0].ActorID, got[0].ActorName = "", ""
+	require.NoError(t, r.MatchEvents.Replace(ctx, &got[0]))
+	got, err = r.MatchEvents.FindByActorID(ctx, "u1")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+	got, err = r.MatchEvents.FindByMatchID(ctx, matchID)
+	require.NoError(t, err)
+	require.Len(t, got, 4)
+	assert.Equal(t, mine[0].ID, got[3].ID)
+	assert.Empty(t, got[3].ActorName)
+	assert.ErrorIs(t, r.MatchEvents.Replace(ctx, &models.MatchEvent{ID: primitive.NewObjectID()}), repositories.ErrNotFound)
+}
+
+// ── Merge records ──
+
+func testMergeRecords(t *testing.T, r Repos) {
+	ctx := context.Background()
+	groupID := primitive.NewObjectID()
+	source := models.Player{ID: primitive.NewObjectID(), Name: "alice", GroupID: groupID}
+	match := newMatch(groupID, []primitive.ObjectID{source.ID}, []primitive.ObjectID{primitive.NewObjectID()})
+	match.ID = primitive.NewObjectID()
+
+	now := time.Now()
+	older := &models.MergeRecord{
+		GroupID: groupID, SourceID: source.ID, TargetID: primitive.NewObjectID(),
+		SourceName: "alice", TargetName: "Alice", Source: source,
+		Matches:  []models.MergedMatch{{MatchID: match.ID, Before: *match, Version: 2}},
+		MergedBy: "u1", MergedAt: now.Add(-time.Hour), UndoUntil: now.Add(23 * time.Hour),
+	}
+	require.NoError(t, r.MergeRecords.Create(ctx, older))
+	assert.False(t, older.ID.IsZero())
+	newer := &models.MergeRecord{GroupID: groupID, MergedAt: now, UndoUntil: now.Add(24 * time.Hour)}
+	require.NoError(t, r.MergeRecords.Create(ctx, newer))
+	require.NoError(t, r.MergeRecords.Create(ctx, &models.MergeRecord{GroupID: primitive.NewObjectID(), MergedAt: now}))
+
+	got, err := r.MergeRecords.FindByID(ctx, older.ID)
+	require.NoError(t, err)
+	assert.Equal(t, source.ID, got.Source.ID)
+	require.Len(t, got.Matches, 1)
+	assert.Equal(t, match.ID, got.Matches[0].MatchID)
+	assert.Equal(t, []primitive.ObjectID{source.ID}, got.Matches[0].Before.Team1IDs)
+	assert.Equal(t, 2, got.Matches[0].Version)
+	assert.Nil(t, got.UndoneAt)
+
+	_, err = r.MergeRecords.FindByID(ctx, primitive.NewObjectID())
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+
+	list, err := r.MergeRecords.FindByGroupID(ctx, groupID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, newer.ID, list[0].ID)
+	assert.Equal(t, older.ID, list[1].ID)
+
+	undoneAt := time.Now()
+	got.UndoneAt, got.UndoneBy, got.RestoredID = &undoneAt, "u2", primitive.NewObjectID()
+	require.NoError(t, r.MergeRecords.MarkUndone(ctx, got))
+	assert.ErrorIs(t, r.MergeRecords.MarkUndone(ctx, got), repositories.ErrVersionConflict)
+
+	stored, err := r.MergeRecords.FindByID(ctx, older.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.UndoneAt)
+	assert.Equal(t, "u2", stored.UndoneBy)
+	assert.Equal(t, got.RestoredID, stored.RestoredID)
+	assert.Len(t, stored.Matches, 1)
+
+	stored.SourceName, stored.Source.Name = "Former member", "Former member"
+	require.NoError(t, r.MergeRecords.Replace(ctx, stored))
+	replaced, err := r.MergeRecords.FindByID(ctx, older.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Former member", replaced.SourceName)
+	assert.Equal(t, "Former member", replaced.Source.Name)
+	assert.NotNil(t, replaced.UndoneAt)
+	assert.ErrorIs(t, r.MergeRecords.Replace(ctx, &models.MergeRecord{ID: primitive.NewObjectID()}), repositories.ErrNotFound)
+}
+
+// ── Group data ──
+
+func testGroupData(t *testing.T, r Repos) {
+	ctx := context.Background()
+	groupID, otherGroup := primitive.NewObjectID(), primitive.NewObjectID()
+	for _, g := range []primitive.ObjectID{groupID, otherGroup} {
+		require.NoError(t, r.Players.Create(ctx, &models.Player{Name: "Alice", GroupID: g}))
+		match := createMatches(t, r, g, 1)[0]
+		require.NoError(t, r.MatchEvents.Append(ctx, models.MatchEvent{MatchID: match.ID, GroupID: g, Type: models.MatchEventCreated, Version: 1}))
+		require.NoError(t, r.MergeRecords.Create(ctx, &models.MergeRecord{GroupID: g, MergedAt: time.Now()}))
+	}
+	// A deleted match's events stay in the timeline until the group goes.
+	gone := createMatches(t, r, groupID, 1)[0]
+	require.NoError(t, r.MatchEvents.Append(ctx, models.MatchEvent{MatchID: gone.ID, GroupID: groupID, Type: models.MatchEventDelete, Version: 2}))
+	require.NoError(t, r.Matches.Delete(ctx, gone.ID))
+
+	require.NoError(t, r.MatchEvents.DeleteByGroupID(ctx, groupID))
+	require.NoError(t, r.Matches.DeleteByGroupID(ctx, groupID))
+	require.NoError(t, r.Players.DeleteByGroupID(ctx, groupID))
+	require.NoError(t, r.MergeRecords.DeleteByGroupID(ctx, groupID))
+
+	count := func(g primitive.ObjectID) (players, matches, events, merges int) {
+		p, err := r.Players.FindByGroupID(ctx, g)
+		require.NoError(t, err)
+		m, err := r.Matches.FindByGroupID(ctx, g)
+		require.NoError(t, err)
+		for _, match := range append(m, *gone) {
+			e, err := r.MatchEvents.FindByMatchID(ctx, match.ID)
+			require.NoError(t, err)
+			events += len(e)
+		}
+		mr, err := r.MergeRecords.FindByGroupID(ctx, g)
+		require.NoError(t, err)
+		return len(p), len(m), events, len(mr)
+	}
+	players, matches, events, merges := count(groupID)
+	assert.Zero(t, players+matches+events+merges, "group data is gone")
+	players, matches, events, merges = count(otherGroup)
+	assert.Equal(t, []int{1, 1, 1, 1}, []int{players, matches, events, merges}, "other groups are untouched")
+}
+
+// ── Sessions ──
+
+func testSessions(t *testing.T, r Repos) {
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	now := time.Now()
+	newSession := func(user primitive.ObjectID) *models.Session {
+		s := &models.Session{UserID: user, TokenHash: "h1", DeviceID: "phone", CreatedAt: now, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)}
+		require.NoError(t, r.Sessions.Create(ctx, s))
+		return s
+	}
+	phone, tablet, other := newSession(userID), newSession(userID), newSession(primitive.NewObjectID())
+	assert.False(t, phone.ID.IsZero())
+
+	got, err := r.Sessions.FindByID(ctx, phone.ID)
+	require.NoError(t, err)
+	assert.Equal(t, userID, got.UserID)
+	assert.Equal(t, "h1", got.TokenHash)
+	assert.Equal(t, "phone", got.DeviceID)
+	assert.WithinDuration(t, now.Add(time.Hour), got.ExpiresAt, time.Millisecond)
+	assert.True(t, got.Active(now))
+	_, err = r.Sessions.FindByID(ctx, primitive.NewObjectID())
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+
+	later := now.Add(time.Minute)
+	require.NoError(t, r.Sessions.Rotate(ctx, phone.ID, "h1", "h2", later, later.Add(time.Hour)))
+	assert.ErrorIs(t, r.Sessions.Rotate(ctx, phone.ID, "h1", "h3", later, later), repositories.ErrVersionConflict, "old hash")
+	got, err = r.Sessions.FindByID(ctx, phone.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "h2", got.TokenHash)
+	assert.WithinDuration(t, later, got.LastUsedAt, time.Millisecond)
+	assert.Equal(t, []string{"h1"}, got.RotatedHashes)
+	assert.True(t, got.WasRotated("h1"))
+	prev := "h1"
+	for i := 0; i <= models.MaxRotatedHashes; i++ {
+		next := fmt.Sprint("t", i)
+		require.NoError(t, r.Sessions.Rotate(ctx, tablet.ID, prev, next, later, later.Add(time.Hour)))
+		prev = next
+	}
+	got, err = r.Sessions.FindByID(ctx, tablet.ID)
+	require.NoError(t, err)
+	assert.Len(t, got.RotatedHashes, models.MaxRotatedHashes)
+	assert.False(t, got.WasRotated("h1"), "the oldest is dropped")
+	assert.True(t, got.WasRotated("t0"))
+
+	require.NoError(t, r.Sessions.Revoke(ctx, phone.ID, models.RevokedLogout))
+	require.NoError(t, r.Sessions.Revoke(ctx, phone.ID, models.RevokedReuse), "revoking twice")
+	got, err = r.Sessions.FindByID(ctx, phone.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.RevokedAt)
+	assert.Equal(t, models.RevokedLogout, got.RevokedReason, "the first reason stays")
+	assert.ErrorIs(t, r.Sessions.Rotate(ctx, phone.ID, "h2", "h3", later, later), repositories.ErrVersionConflict, "revoked")
+
+	require.NoError(t, r.Sessions.RevokeByUserID(ctx, userID, models.RevokedLogoutAll))
+	got, err = r.Sessions.FindByID(ctx, tablet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.RevokedLogoutAll, got.RevokedReason)
+	got, err = r.Sessions.FindByID(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.RevokedAt)
+}
+
+// ── Password resets ──
+
+func testPasswordResets(t *testing.T, r Repos) {
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	now := time.Now()
+	newReset := func(user primitive.ObjectID) *models.PasswordReset {
+		reset := &models.PasswordReset{UserID: user, TokenHash: "h", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+		require.NoError(t, r.PasswordResets.Create(ctx, reset))
+		return reset
+	}
+	first, second, other := newReset(userID), newReset(userID), newReset(primitive.NewObjectID())
+	assert.False(t, first.ID.IsZero())
+
+	got, err := r.PasswordResets.FindByID(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, userID, got.UserID)
+	assert.Equal(t, "h", got.TokenHash)
+	assert.WithinDuration(t, now.Add(time.Hour), got.ExpiresAt, time.Millisecond)
+	assert.True(t, got.Usable(now))
+	_, err = r.PasswordResets.FindByID(ctx, primitive.NewObjectID())
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+
+	require.NoError(t, r.PasswordResets.MarkUsed(ctx, first.ID, now))
+	assert.ErrorIs(t, r.PasswordResets.MarkUsed(ctx, first.ID, now), repositories.ErrVersionConflict)
+	got, err = r.PasswordResets.FindByID(ctx, first.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.UsedAt)
+	assert.False(t, got.Usable(now))
+
+	require.NoError(t, r.PasswordResets.MarkUsedByUserID(ctx, userID, now))
+	got, err = r.PasswordResets.FindByID(ctx, second.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, got.UsedAt)
+	got, err = r.PasswordResets.FindByID(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.UsedAt)
+}
+
+// ── Invites ──
+
+func testInvites(t *testing.T, r Repos) {
+	ctx := context.Background()
+	groupID, otherGroup := primitive.NewObjectID(), primitive.NewObjectID()
+	now := time.Now()
+	expires := now.Add(time.Hour)
+
+	limited := &models.Invite{GroupID: groupID, Code: "LIMIT001", Name: "Flyer", CreatedAt: now, ExpiresAt: &expires, MaxUses: 2}
+	require.NoError(t, r.Invites.Create(ctx, limited))
+	assert.False(t, limited.ID.IsZero())
+	open := &models.Invite{GroupID: groupID, Code: "OPEN0001", Name: "Chat", CreatedAt: now.Add(time.Second)}
+	require.NoError(t, r.Invites.Create(ctx, open))
+	other := &models.Invite{GroupID: otherGroup, Code: "OTHER001", Name: "Other", CreatedAt: now}
+	require.NoError(t, r.Invites.Create(ctx, other))
+	err := r.Invites.Create(ctx, &models.Invite{GroupID: otherGroup, Code: "OPEN0001", CreatedAt: now})
+	assert.True(t, repositories.IsDuplicateKey(err), "duplicate code: %v", err)
+
+	got, err := r.Invites.FindByCode(ctx, "LIMIT001")
+	require.NoError(t, err)
+	assert.Equal(t, limited.ID, got.ID)
+	assert.Equal(t, "Flyer", got.Name)
+	assert.Equal(t, 2, got.MaxUses)
+	require.NotNil(t, got.ExpiresAt)
+	assert.WithinDuration(t, expires, *got.ExpiresAt, time.Millisecond)
+	assert.True(t, got.Usable(now))
+	_, err = r.Invites.FindByCode(ctx, "MISSING1")
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	_, err = r.Invites.FindByID(ctx, primitive.NewObjectID())
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+
+	invites, err := r.Invites.FindByGroupID(ctx, groupID)
+	require.NoError(t, err)
+	require.Len(t, invites, 2)
+	assert.Equal(t, limited.ID, invites[0].ID, "oldest first")
+
+	// Uses stop at MaxUses and at expiry.
+	require.NoError(t, r.Invites.Use(ctx, limited.ID, now))
+	assert.ErrorIs(t, r.Invites.Use(ctx, limited.ID, expires.Add(time.Second)), repositories.ErrNotFound, "expired")
+	require.NoError(t, r.Invites.Use(ctx, limited.ID, now))
+	assert.ErrorIs(t, r.Invites.Use(ctx, limited.ID, now), repositories.ErrNotFound, "used up")
+	got, err = r.Invites.FindByID(ctx, limited.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.Uses)
+	assert.False(t, got.Usable(now))
+
+	// Unlimited invites work until revoked.
+	for i := 0; i < 3; i++ {
+		require.NoError(t, r.Invites.Use(ctx, open.ID, now))
+	}
+	require.NoError(t, r.Invites.Revoke(ctx, open.ID, now))
+	assert.ErrorIs(t, r.Invites.Use(ctx, open.ID, now), repositories.ErrNotFound, "revoked")
+	got, err = r.Invites.FindByID(ctx, open.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.Uses)
+	assert.NotNil(t, got.RevokedAt)
+	assert.ErrorIs(t, r.Invites.Revoke(ctx, primitive.NewObjectID(), now), repositories.ErrNotFound)
+	assert.ErrorIs(t, r.Invites.Use(ctx, primitive.NewObjectID(), now), repositories.ErrNotFound)
+
+	require.NoError(t, r.Invites.DeleteByGroupID(ctx, groupID))
+	invites, err = r.Invites.FindByGroupID(ctx, groupID)
+	require.NoError(t, err)
+	assert.Empty(t, invites)
+	_, err = r.Invites.FindByID(ctx, other.ID)
+	assert.NoError(t, err, "other groups untouched")
+}
+
+func testJoinRequests(t *testing.T, r Repos) {
+	ctx := context.Background()
+	groupID, otherGroup := primitive.NewObjectID(), primitive.NewObjectID()
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+	inviteID := primitive.NewObjectID()
+	now := time.Now()
+
+	first := &models.JoinRequest{GroupID: groupID, UserID: alice, Username: "alice", InviteID: &inviteID, CreatedAt: now}
+	require.NoError(t, r.JoinRequests.Create(ctx, first))
+	assert.False(t, first.ID.IsZero())
+	second := &models.JoinRequest{GroupID: groupID, UserID: bob, Username: "bob", CreatedAt: now.Add(time.Second)}
+	require.NoError(t, r.JoinRequests.Create(ctx, second))
+	elsewhere := &models.JoinRequest{GroupID: otherGroup, UserID: alice, Username: "alice", CreatedAt: now}
+	require.NoError(t, r.JoinRequests.Create(ctx, elsewhere))
+	err := r.JoinRequests.Create(ctx, &models.JoinRequest{GroupID: groupID, UserID: alice, Username: "alice", CreatedAt: now})
+	assert.True(t, repositories.IsDuplicateKey(err), "one request per user and group: %v", err)
+
+	got, err := r.JoinRequests.FindByGroupAndUser(ctx, groupID, alice)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, got.ID)
+	assert.Equal(t, "alice", got.Username)
+	require.NotNil(t, got.InviteID)
+	assert.Equal(t, inviteID, *got.InviteID)
+	got, err = r.JoinRequests.FindByID(ctx, second.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.InviteID)
+	_, err = r.JoinRequests.FindByGroupAndUser(ctx, otherGroup, bob)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+
+	requests, err := r.JoinRequests.FindByGroupID(ctx, groupID)
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	assert.Equal(t, first.ID, requests[0].ID, "oldest first")
+
+	require.NoError(t, r.JoinRequests.Delete(ctx, second.ID))
+	assert.ErrorIs(t, r.JoinRequests.Delete(ctx, second.ID), repositories.ErrNotFound)
+
+	require.NoError(t, r.JoinRequests.DeleteByUserID(ctx, alice))
+	_, err = r.JoinRequests.FindByID(ctx, elsewhere.ID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+
+	third := &models.JoinRequest{GroupID: groupID, UserID: bob, Username: "bob", CreatedAt: now}
+	require.NoError(t, r.JoinRequests.Create(ctx, third))
+	require.NoError(t, r.JoinRequests.DeleteByGroupID(ctx, groupID))
+	requests, err = r.JoinRequests.FindByGroupID(ctx, groupID)
+	require.NoError(t, err)
+	assert.Empty(t, requests)
+}
+
+// ── Transactions ──
+
+// testTransaction checks that writes made inside a transaction are visible
+// within it, kept on commit and undone on failure, nested transactions
+// included.
+func testTransaction(t *testing.T, r Repos) {
+	ctx := context.Background()
+	groupID := primitive.NewObjectID()
+
+	var player models.Player
+	err := r.Tx.WithTransaction(ctx, func(ctx context.Context) error {
+		player = models.Player{Name: "Alice", GroupID: groupID}
+		if err := r.Players.Create(ctx, &player); err != nil {
+			return err
+		}
+		_, err := r.Players.FindByID(ctx, player.ID)
+		return err
+	})
+	require.NoError(t, err)
+
+	got, err := r.Players.FindByID(ctx, player.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", got.Name)
+
+	var undone models.Player
+	var match models.Match
+	failed := errors.New("failed")
+	err = r.Tx.WithTransaction(ctx, func(ctx context.Context) error {
+		undone = models.Player{Name: "Bob", GroupID: groupID}
+		if err := r.Players.Create(ctx, &undone); err != nil {
+			return err
+		}
+		// A nested transaction joins this one.
+		return r.Tx.WithTransaction(ctx, func(ctx context.Context) error {
+			match = models.Match{GroupID: groupID, Team1IDs: []primitive.ObjectID{undone.ID}, Team1Names: []string{"Bob"}, Status: models.MatchStatusLive}
+			if err := r.Matches.Create(ctx, &match); err != nil {
+				return err
+			}
+			return failed
+		})
+	})
+	require.ErrorIs(t, err, failed)
+
+	_, err = r.Players.FindByID(ctx, undone.ID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	_, err = r.Matches.FindByID(ctx, match.ID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	_, err = r.Players.FindByID(ctx, player.ID)
+	assert.NoError(t, err)
+}
+
+// ── Share links ──
+
+func testShareLinks(t *testing.T, r Repos) {
+	ctx := context.Background()
+	groupID, matchID := primitive.NewObjectID(), primitive.NewObjectID()
+	now := time.Now()
+
+	group := &models.ShareLink{GroupID: groupID, TokenHash: "hash-1", CreatedBy: primitive.NewObjectID(), CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	require.NoError(t, r.ShareLinks.Create(ctx, group))
+	assert.False(t, group.ID.IsZero())
+	match := &models.ShareLink{GroupID: groupID, MatchID: &matchID, TokenHash: "hash-2", CreatedBy: group.CreatedBy, CreatedAt: now.Add(time.Second), ExpiresAt: now.Add(time.Hour)}
+	require.NoError(t, r.ShareLinks.Create(ctx, match))
+	require.NoError(t, r.ShareLinks.Create(ctx, &models.ShareLink{GroupID: primitive.NewObjectID(), TokenHash: "hash-3", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}))
+
+	got, err := r.ShareLinks.FindByID(ctx, match.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "hash-2", got.TokenHash)
+	require.NotNil(t, got.MatchID)
+	assert.Equal(t, matchID, *got.MatchID)
+	assert.WithinDuration(t, match.ExpiresAt, got.ExpiresAt, time.Millisecond)
+	assert.True(t, got.Usable(now))
+	got, err = r.ShareLinks.FindByID(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.MatchID)
+	_, err = r.ShareLinks.FindByID(ctx, primitive.NewObjectID())
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+
+	links, err := r.ShareLinks.FindByGroupID(ctx, groupID)
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	assert.Equal(t, match.ID, links[0].ID, "newest first")
+	assert.Equal(t, group.ID, links[1].ID)
+
+	require.NoError(t, r.ShareLinks.Revoke(ctx, group.ID, now))
+	got, err = r.ShareLinks.FindByID(ctx, group.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.RevokedAt)
+	assert.False(t, got.Usable(now))
+	assert.ErrorIs(t, r.ShareLinks.Revoke(ctx, primitive.NewObjectID(), now), repositories.ErrNotFound)
+
+	require.NoError(t, r.ShareLinks.DeleteByGroupID(ctx, groupID))
+	links, err = r.ShareLinks.FindByGroupID(ctx, groupID)
+	require.NoError(t, err)
+	assert.Empty(t, links)
+	_, err = r.ShareLinks.FindByID(ctx, match.ID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+}
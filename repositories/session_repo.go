@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"gully-backend/models"
+)
+
+type SessionRepo struct {
+	col *mongo.Collection
+}
+
+func NewSessionRepo(db *mongo.Database) *SessionRepo {
+	return &SessionRepo{col: db.Collection("sessions")}
+}
+
+func (r *SessionRepo) Create(ctx context.Context, session *models.Session) error {
+	session.ID = primitive.NewObjectID()
+	_, err := r.col.InsertOne(ctx, session)
+	return err
+}
+
+func (r *SessionRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Session, error) {
+	var session models.Session
+	if err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Rotate swaps the token hash only if it is still oldHash and the session
+// is not revoked, and adds oldHash to the rotated hashes.
+func (r *SessionRepo) Rotate(ctx context.Context, id primitive.ObjectID, oldHash, newHash string, usedAt, expiresAt time.Time) error {
+	res, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": id, "token_hash": oldHash, "revoked_at": nil},
+		bson.M{
+			"$set":  bson.M{"token_hash": newHash, "last_used_at": usedAt, "expires_at": expiresAt},
+			"$push": bson.M{"rotated_hashes": bson.M{"$each": bson.A{oldHash}, "$slice": -models.MaxRotatedHashes}},
+		})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (r *SessionRepo) Revoke(ctx context.Context, id primitive.ObjectID, reason string) error {
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": id, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now(), "revoked_reason": reason}})
+	return err
+}
+
+func (r *SessionRepo) RevokeByUserID(ctx context.Context, userID primitive.ObjectID, reason string) error {
+	_, err := r.col.UpdateMany(ctx,
+		bson.M{"user_id": userID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now(), "revoked_reason": reason}})
+	return err
+}
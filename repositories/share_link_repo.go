@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"gully-backend/models"
+)
+
+type ShareLinkRepo struct {
+	col *mongo.Collection
+}
+
+func NewShareLinkRepo(db *mongo.Database) *ShareLinkRepo {
+	return &ShareLinkRepo{col: db.Collection("share_links")}
+}
+
+func (r *ShareLinkRepo) Create(ctx context.Context, link *models.ShareLink) error {
+	link.ID = primitive.NewObjectID()
+	_, err := r.col.InsertOne(ctx, link)
+	return err
+}
+
+func (r *ShareLinkRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.ShareLink, error) {
+	var link models.ShareLink
+	if err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&link); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *ShareLinkRepo) FindByGroupID(ctx context.Context, groupID primitive.ObjectID) ([]models.ShareLink, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := r.col.Find(ctx, bson.M{"group_id": groupID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var links []models.ShareLink
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (r *ShareLinkRepo) Revoke(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	res, err := r.col.UpdateByID(ctx, id, bson.M{"$set": bson.M{"revoked_at": at}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *ShareLinkRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	_, err := r.col.DeleteMany(ctx, bson.M{"group_id": groupID})
+	return err
+}
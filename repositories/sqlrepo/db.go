@@ -0,0 +1,266 @@
+// Package sqlrepo implements the repository interfaces on SQL databases:
+// embedded SQLite by default, or Postgres. Queries are written once with
+// "?" placeholders and rewritten for Postgres.
+//
+// IDs stay ObjectID hex strings and times are stored as Unix milliseconds,
+// so documents look the same as they do coming out of Mongo.
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" driver
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"gully-backend/repositories"
+)
+
+// Supported drivers.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// DB is an open SQL database with the schema applied.
+type DB struct {
+	sql    *sql.DB
+	driver string
+}
+
+// Open connects to the database and brings its schema up to date.
+func Open(ctx context.Context, driver, dsn string) (*DB, error) {
+	var name string
+	switch driver {
+	case DriverSQLite:
+		name = "sqlite"
+	case DriverPostgres:
+		name = "pgx"
+	default:
+		return nil, fmt.Errorf("unknown SQL driver %q", driver)
+	}
+	conn, err := sql.Open(name, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if driver == DriverSQLite {
+		// SQLite allows one writer at a time; a single connection queues
+		// writers in Go instead of failing with SQLITE_BUSY.
+		conn.SetMaxOpenConns(1)
+	}
+	db := &DB{sql: conn, driver: driver}
+	if err := conn.PingContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := db.migrate(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("sql schema: %w", err)
+	}
+	return db, nil
+}
+
+func (db *DB) Close() error {
+	return db.sql.Close()
+}
+
+// querier is what both *sql.DB and *sql.Tx offer.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// conn wraps a querier so every query is rebound for the driver.
+type conn struct {
+	q      querier
+	driver string
+}
+
+func (c conn) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.q.ExecContext(ctx, c.rebind(query), args...)
+}
+
+func (c conn) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return c.q.QueryContext(ctx, c.rebind(query), args...)
+}
+
+func (c conn) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return c.q.QueryRowContext(ctx, c.rebind(query), args...)
+}
+
+// rebind turns "?" placeholders into Postgres's "$1", "$2", ...
+func (c conn) rebind(query string) string {
+	if c.driver != DriverPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// txKey marks a context carrying a transaction opened by WithTransaction.
+type txKey struct{ db *DB }
+
+// conn returns the transaction ctx carries, or the database.
+func (db *DB) conn(ctx context.Context) conn {
+	if tx, ok := ctx.Value(txKey{db}).(*sql.Tx); ok {
+		return conn{q: tx, driver: db.driver}
+	}
+	return conn{q: db.sql, driver: db.driver}
+}
+
+// inTx runs fn in a transaction, committing if it returns nil. Inside
+// WithTransaction it joins the outer transaction instead.
+func (db *DB) inTx(ctx context.Context, fn func(c conn) error) error {
+	if tx, ok := ctx.Value(txKey{db}).(*sql.Tx); ok {
+		return fn(conn{q: tx, driver: db.driver})
+	}
+	tx, err := db.sql.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(conn{q: tx, driver: db.driver}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// WithTransaction runs fn in a transaction that every repository call made
+// with fn's ctx takes part in. It commits if fn returns nil.
+func (db *DB) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{db}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+	tx, err := db.sql.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(context.WithValue(ctx, txKey{db}, tx)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return translate(tx.Commit())
+}
+
+// affectedOne checks that a write keyed by ID found its row; no row is
+// ErrNotFound.
+func affectedOne(res sql.Result, err error) error {
+	if err != nil {
+		return translate(err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+// translate maps driver errors onto the repository sentinels.
+func translate(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, sql.ErrNoRows):
+		return repositories.ErrNotFound
+	case isUniqueViolation(err):
+		return fmt.Errorf("%w: %v", repositories.ErrDuplicateKey, err)
+	}
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505"
+	}
+	var liteErr *sqlite.Error
+	if errors.As(err, &liteErr) {
+		return liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
+}
+
+// ── Value helpers ──
+
+func toMillis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func fromMillis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms).UTC()
+}
+
+func toNullMillis(t *time.Time) sql.NullInt64 {
+	if t == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixMilli(), Valid: true}
+}
+
+func fromNullMillis(ms sql.NullInt64) *time.Time {
+	if !ms.Valid {
+		return nil
+	}
+	t := time.UnixMilli(ms.Int64).UTC()
+	return &t
+}
+
+func toNullID(id *primitive.ObjectID) sql.NullString {
+	if id == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: id.Hex(), Valid: true}
+}
+
+func fromNullID(hex sql.NullString) *primitive.ObjectID {
+	if !hex.Valid {
+		return nil
+	}
+	id := mustID(hex.String)
+	return &id
+}
+
+// rowScanner is a *sql.Row or *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// placeholders returns "?, ?, ?" for n values.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// mustID parses an ID this package wrote itself.
+func mustID(hex string) primitive.ObjectID {
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		panic(fmt.Sprintf("sqlrepo: stored id %q: %v", hex, err))
+	}
+	return id
+}
+
+// errNoRows stands in for sql.ErrNoRows when a multi-row query finds nothing.
+var errNoRows = sql.ErrNoRows
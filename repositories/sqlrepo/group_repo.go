@@ -0,0 +1,196 @@
+package sqlrepo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+type GroupRepo struct {
+	db *DB
+}
+
+func NewGroupRepo(db *DB) *GroupRepo {
+	return &GroupRepo{db: db}
+}
+
+func (r *GroupRepo) Create(ctx context.Context, group *models.Group) error {
+	id, createdAt := primitive.NewObjectID(), time.Now()
+	settings, err := json.Marshal(group.Settings)
+	if err != nil {
+		return err
+	}
+	err = r.db.inTx(ctx, func(c conn) error {
+		if _, err := c.exec(ctx,
+			`INSERT INTO groups (id, name, join_code, created_by, created_at, join_approval, settings, settings_version)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			id.Hex(), group.Name, group.JoinCode, group.CreatedBy.Hex(), toMillis(createdAt), group.JoinApproval,
+			string(settings), group.Settings.Version); err != nil {
+			return err
+		}
+		for i, member := range group.Members {
+			if _, err := c.exec(ctx,
+				`INSERT INTO group_members (group_id, user_id, position) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`,
+				id.Hex(), member.Hex(), i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return translate(err)
+	}
+	group.ID, group.CreatedAt = id, createdAt
+	return nil
+}
+
+func (r *GroupRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Group, error) {
+	return r.findOne(ctx, `id = ?`, id.Hex())
+}
+
+func (r *GroupRepo) FindByJoinCode(ctx context.Context, code string) (*models.Group, error) {
+	return r.findOne(ctx, `join_code = ?`, code)
+}
+
+// FindByMember returns all groups where the given user is a member.
+func (r *GroupRepo) FindByMember(ctx context.Context, userID primitive.ObjectID) ([]models.Group, error) {
+	return r.find(ctx, `id IN (SELECT group_id FROM group_members WHERE user_id = ?)`, userID.Hex())
+}
+
+// AddMember adds a user to the end of the group's member list. It is
+// idempotent, and a missing group is not an error.
+func (r *GroupRepo) AddMember(ctx context.Context, groupID, userID primitive.ObjectID) error {
+	_, err := r.db.conn(ctx).exec(ctx,
+		`INSERT INTO group_members (group_id, user_id, position)
+		 SELECT g.id, ?, (SELECT COALESCE(MAX(position), -1) + 1 FROM group_members WHERE group_id = g.id)
+		 FROM groups g WHERE g.id = ?
+		 ON CONFLICT DO NOTHING`,
+		userID.Hex(), groupID.Hex())
+	return translate(err)
+}
+
+// RemoveMember is idempotent, and a missing group is not an error.
+func (r *GroupRepo) RemoveMember(ctx context.Context, groupID, userID primitive.ObjectID) error {
+	_, err := r.db.conn(ctx).exec(ctx,
+		`DELETE FROM group_members WHERE group_id = ? AND user_id = ?`, groupID.Hex(), userID.Hex())
+	return translate(err)
+}
+
+func (r *GroupRepo) SetCreator(ctx context.Context, groupID, userID primitive.ObjectID) error {
+	res, err := r.db.conn(ctx).exec(ctx, `UPDATE groups SET created_by = ? WHERE id = ?`, userID.Hex(), groupID.Hex())
+	return affectedOne(res, err)
+}
+
+func (r *GroupRepo) Rename(ctx context.Context, groupID primitive.ObjectID, name string) error {
+	res, err := r.db.conn(ctx).exec(ctx, `UPDATE groups SET name = ? WHERE id = ?`, name, groupID.Hex())
+	return affectedOne(res, err)
+}
+
+func (r *GroupRepo) SetJoinCode(ctx context.Context, groupID primitive.ObjectID, code string) error {
+	res, err := r.db.conn(ctx).exec(ctx, `UPDATE groups SET join_code = ? WHERE id = ?`, code, groupID.Hex())
+	return affectedOne(res, err)
+}
+
+func (r *GroupRepo) SetJoinApproval(ctx context.Context, groupID primitive.ObjectID, on bool) error {
+	res, err := r.db.conn(ctx).exec(ctx, `UPDATE groups SET join_approval = ? WHERE id = ?`, on, groupID.Hex())
+	return affectedOne(res, err)
+}
+
+func (r *GroupRepo) UpdateSettings(ctx context.Context, groupID primitive.ObjectID, settings *models.GroupSettings) error {
+	body, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.conn(ctx).exec(ctx,
+		`UPDATE groups SET settings = ?, settings_version = settings_version + 1 WHERE id = ? AND settings_version = ?`,
+		string(body), groupID.Hex(), settings.Version)
+	if err := affectedOne(res, err); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return repositories.ErrVersionConflict
+		}
+		return err
+	}
+	settings.Version++
+	return nil
+}
+
+func (r *GroupRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return translate(r.db.inTx(ctx, func(c conn) error {
+		if _, err := c.exec(ctx, `DELETE FROM group_members WHERE group_id = ?`, id.Hex()); err != nil {
+			return err
+		}
+		res, err := c.exec(ctx, `DELETE FROM groups WHERE id = ?`, id.Hex())
+		return affectedOne(res, err)
+	}))
+}
+
+func (r *GroupRepo) findOne(ctx context.Context, where string, arg interface{}) (*models.Group, error) {
+	groups, err := r.find(ctx, where, arg)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, translate(errNoRows)
+	}
+	return &groups[0], nil
+}
+
+func (r *GroupRepo) find(ctx context.Context, where string, arg interface{}) ([]models.Group, error) {
+	c := r.db.conn(ctx)
+	rows, err := c.query(ctx,
+		`SELECT id, name, join_code, created_by, created_at, join_approval, settings, settings_version FROM groups WHERE `+where+` ORDER BY created_at, id`, arg)
+	if err != nil {
+		return nil, err
+	}
+	var groups []models.Group
+	index := map[string]int{}
+	for rows.Next() {
+		var g models.Group
+		var id, createdBy, settings string
+		var createdAt int64
+		var settingsVersion int
+		if err := rows.Scan(&id, &g.Name, &g.JoinCode, &createdBy, &createdAt, &g.JoinApproval, &settings, &settingsVersion); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(settings), &g.Settings); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		g.Settings.Version = settingsVersion
+		g.ID, g.CreatedBy, g.CreatedAt = mustID(id), mustID(createdBy), fromMillis(createdAt)
+		index[id] = len(groups)
+		groups = append(groups, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(groups) == 0 {
+		return groups, err
+	}
+
+	ids := make([]interface{}, 0, len(groups))
+	for id := range index {
+		ids = append(ids, id)
+	}
+	rows, err = c.query(ctx,
+		`SELECT group_id, user_id FROM group_members WHERE group_id IN (`+placeholders(len(ids))+`) ORDER BY group_id, position`,
+		ids...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var groupID, userID string
+		if err := rows.Scan(&groupID, &userID); err != nil {
+			return nil, err
+		}
+		g := &groups[index[groupID]]
+		g.Members = append(g.Members, mustID(userID))
+	}
+	return groups, rows.Err()
+}
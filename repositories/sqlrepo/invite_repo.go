@@ -0,0 +1,104 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+)
+
+type InviteRepo struct {
+	db *DB
+}
+
+func NewInviteRepo(db *DB) *InviteRepo {
+	return &InviteRepo{db: db}
+}
+
+const inviteColumns = `id, group_id, code, name, created_by, created_at, expires_at, max_uses, uses, revoked_at`
+
+func (r *InviteRepo) Create(ctx context.Context, invite *models.Invite) error {
+	id := primitive.NewObjectID()
+	_, err := r.db.conn(ctx).exec(ctx,
+		`INSERT INTO invites (`+inviteColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id.Hex(), invite.GroupID.Hex(), invite.Code, invite.Name, invite.CreatedBy.Hex(), toMillis(invite.CreatedAt),
+		toNullMillis(invite.ExpiresAt), invite.MaxUses, invite.Uses, toNullMillis(invite.RevokedAt))
+	if err != nil {
+		return translate(err)
+	}
+	invite.ID = id
+	return nil
+}
+
+func (r *InviteRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Invite, error) {
+	return r.findOne(ctx, `id = ?`, id.Hex())
+}
+
+func (r *InviteRepo) FindByCode(ctx context.Context, code string) (*models.Invite, error) {
+	return r.findOne(ctx, `code = ?`, code)
+}
+
+func (r *InviteRepo) findOne(ctx context.Context, where string, arg interface{}) (*models.Invite, error) {
+	invite, err := scanInvite(r.db.conn(ctx).queryRow(ctx, `SELECT `+inviteColumns+` FROM invites WHERE `+where, arg))
+	if err != nil {
+		return nil, translate(err)
+	}
+	return invite, nil
+}
+
+func (r *InviteRepo) FindByGroupID(ctx context.Context, groupID primitive.ObjectID) ([]models.Invite, error) {
+	rows, err := r.db.conn(ctx).query(ctx,
+		`SELECT `+inviteColumns+` FROM invites WHERE group_id = ? ORDER BY created_at, id`, groupID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invites []models.Invite
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, *invite)
+	}
+	return invites, rows.Err()
+}
+
+// Use counts a use in the same statement that checks the invite is still
+// usable, so racing joins cannot overshoot max_uses.
+func (r *InviteRepo) Use(ctx context.Context, id primitive.ObjectID, now time.Time) error {
+	res, err := r.db.conn(ctx).exec(ctx,
+		`UPDATE invites SET uses = uses + 1
+		 WHERE id = ? AND revoked_at IS NULL
+		   AND (expires_at IS NULL OR expires_at > ?)
+		   AND (max_uses = 0 OR uses < max_uses)`,
+		id.Hex(), toMillis(now))
+	return affectedOne(res, err)
+}
+
+func (r *InviteRepo) Revoke(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	res, err := r.db.conn(ctx).exec(ctx, `UPDATE invites SET revoked_at = ? WHERE id = ?`, toMillis(at), id.Hex())
+	return affectedOne(res, err)
+}
+
+func (r *InviteRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	_, err := r.db.conn(ctx).exec(ctx, `DELETE FROM invites WHERE group_id = ?`, groupID.Hex())
+	return translate(err)
+}
+
+func scanInvite(row rowScanner) (*models.Invite, error) {
+	var inv models.Invite
+	var id, groupID, createdBy string
+	var createdAt int64
+	var expiresAt, revokedAt sql.NullInt64
+	if err := row.Scan(&id, &groupID, &inv.Code, &inv.Name, &createdBy, &createdAt, &expiresAt, &inv.MaxUses, &inv.Uses, &revokedAt); err != nil {
+		return nil, err
+	}
+	inv.ID, inv.GroupID, inv.CreatedBy = mustID(id), mustID(groupID), mustID(createdBy)
+	inv.CreatedAt, inv.ExpiresAt, inv.RevokedAt = fromMillis(createdAt), fromNullMillis(expiresAt), fromNullMillis(revokedAt)
+	return &inv, nil
+}
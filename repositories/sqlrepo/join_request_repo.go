@@ -0,0 +1,95 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+)
+
+type JoinRequestRepo struct {
+	db *DB
+}
+
+func NewJoinRequestRepo(db *DB) *JoinRequestRepo {
+	return &JoinRequestRepo{db: db}
+}
+
+const joinRequestColumns = `id, group_id, user_id, username, invite_id, created_at`
+
+func (r *JoinRequestRepo) Create(ctx context.Context, request *models.JoinRequest) error {
+	id := primitive.NewObjectID()
+	_, err := r.db.conn(ctx).exec(ctx,
+		`INSERT INTO join_requests (`+joinRequestColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		id.Hex(), request.GroupID.Hex(), request.UserID.Hex(), request.Username, toNullID(request.InviteID), toMillis(request.CreatedAt))
+	if err != nil {
+		return translate(err)
+	}
+	request.ID = id
+	return nil
+}
+
+func (r *JoinRequestRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.JoinRequest, error) {
+	return r.findOne(ctx, `id = ?`, id.Hex())
+}
+
+func (r *JoinRequestRepo) FindByGroupAndUser(ctx context.Context, groupID, userID primitive.ObjectID) (*models.JoinRequest, error) {
+	return r.findOne(ctx, `group_id = ? AND user_id = ?`, groupID.Hex(), userID.Hex())
+}
+
+func (r *JoinRequestRepo) findOne(ctx context.Context, where string, args ...interface{}) (*models.JoinRequest, error) {
+	request, err := scanJoinRequest(r.db.conn(ctx).queryRow(ctx, `SELECT `+joinRequestColumns+` FROM join_requests WHERE `+where, args...))
+	if err != nil {
+		return nil, translate(err)
+	}
+	return request, nil
+}
+
+func (r *JoinRequestRepo) FindByGroupID(ctx context.Context, groupID primitive.ObjectID) ([]models.JoinRequest, error) {
+	rows, err := r.db.conn(ctx).query(ctx,
+		`SELECT `+joinRequestColumns+` FROM join_requests WHERE group_id = ? ORDER BY created_at, id`, groupID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []models.JoinRequest
+	for rows.Next() {
+		request, err := scanJoinRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, *request)
+	}
+	return requests, rows.Err()
+}
+
+func (r *JoinRequestRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.db.conn(ctx).exec(ctx, `DELETE FROM join_requests WHERE id = ?`, id.Hex())
+	return affectedOne(res, err)
+}
+
+func (r *JoinRequestRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	_, err := r.db.conn(ctx).exec(ctx, `DELETE FROM join_requests WHERE group_id = ?`, groupID.Hex())
+	return translate(err)
+}
+
+func (r *JoinRequestRepo) DeleteByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.db.conn(ctx).exec(ctx, `DELETE FROM join_requests WHERE user_id = ?`, userID.Hex())
+	return translate(err)
+}
+
+func scanJoinRequest(row rowScanner) (*models.JoinRequest, error) {
+	var jr models.JoinRequest
+	var id, groupID, userID string
+	var inviteID sql.NullString
+	var createdAt int64
+	if err := row.Scan(&id, &groupID, &userID, &jr.Username, &inviteID, &createdAt); err != nil {
+		return nil, err
+	}
+	jr.ID, jr.GroupID, jr.UserID = mustID(id), mustID(groupID), mustID(userID)
+	jr.InviteID, jr.CreatedAt = fromNullID(inviteID), fromMillis(createdAt)
+	return &jr, nil
+}
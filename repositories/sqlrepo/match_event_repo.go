@@ -0,0 +1,94 @@
+package sqlrepo
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+)
+
+type MatchEventRepo struct {
+	db *DB
+}
+
+func NewMatchEventRepo(db *DB) *MatchEventRepo {
+	return &MatchEventRepo{db: db}
+}
+
+// Append inserts events.
+func (r *MatchEventRepo) Append(ctx context.Context, events ...models.MatchEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return translate(r.db.inTx(ctx, func(c conn) error {
+		for i := range events {
+			events[i].ID = primitive.NewObjectID()
+			body, err := json.Marshal(events[i])
+			if err != nil {
+				return err
+			}
+			if _, err := c.exec(ctx,
+				`INSERT INTO match_events (id, match_id, group_id, version, idx, body) VALUES (?, ?, ?, ?, ?, ?)`,
+				events[i].ID.Hex(), events[i].MatchID.Hex(), events[i].GroupID.Hex(), events[i].Version, events[i].Index, string(body)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+// FindByMatchID returns a match's events in replay order.
+func (r *MatchEventRepo) FindByMatchID(ctx context.Context, matchID primitive.ObjectID) ([]models.MatchEvent, error) {
+	return r.find(ctx, `match_id = ?`, matchID.Hex())
+}
+
+// FindByGroupID returns the events of all the group's matches.
+func (r *MatchEventRepo) FindByGroupID(ctx context.Context, groupID primitive.ObjectID) ([]models.MatchEvent, error) {
+	return r.find(ctx, `group_id = ?`, groupID.Hex())
+}
+
+// FindByActorID returns the events the user made, in any group. The actor
+// is only in the body; this is used when an account is deleted, which is
+// rare enough to scan for.
+func (r *MatchEventRepo) FindByActorID(ctx context.Context, actorID string) ([]models.MatchEvent, error) {
+	return r.find(ctx, `body LIKE ?`, `%"actor_id":"`+actorID+`"%`)
+}
+
+// Replace overwrites a stored event.
+func (r *MatchEventRepo) Replace(ctx context.Context, event *models.MatchEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return affectedOne(r.db.conn(ctx).exec(ctx, `UPDATE match_events SET body = ? WHERE id = ?`, string(body), event.ID.Hex()))
+}
+
+func (r *MatchEventRepo) find(ctx context.Context, where string, args ...interface{}) ([]models.MatchEvent, error) {
+	rows, err := r.db.conn(ctx).query(ctx,
+		`SELECT body FROM match_events WHERE `+where+` ORDER BY match_id, version, idx`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.MatchEvent
+	for rows.Next() {
+		var body string
+		if err := rows.Scan(&body); err != nil {
+			return nil, err
+		}
+		var ev models.MatchEvent
+		if err := json.Unmarshal([]byte(body), &ev); err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
+
+func (r *MatchEventRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	_, err := r.db.conn(ctx).exec(ctx, `DELETE FROM match_events WHERE group_id = ?`, groupID.Hex())
+	return translate(err)
+}
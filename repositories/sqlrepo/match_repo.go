@@ -0,0 +1,376 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+type MatchRepo struct {
+	db *DB
+}
+
+func NewMatchRepo(db *DB) *MatchRepo {
+	return &MatchRepo{db: db}
+}
+
+const matchColumns = `id, group_id, type, session_id, team1_names, team2_names, score1, score2,
+	serving_team, serving_player_id, team1_positions, team2_positions, status, scheduled_at,
+	started_at, finished_at, duration_secs, pauses, paused_secs, winner_team, end_reason,
+	created_at, updated_at, version, points_to_win`
+
+func (r *MatchRepo) Create(ctx context.Context, match *models.Match) error {
+	m := *match
+	m.ID = primitive.NewObjectID()
+	m.CreatedAt = time.Now()
+	m.UpdatedAt = m.CreatedAt
+	m.Version = 1
+	err := r.db.inTx(ctx, func(c conn) error {
+		args, err := matchArgs(&m)
+		if err != nil {
+			return err
+		}
+		if _, err := c.exec(ctx, `INSERT INTO matches (`+matchColumns+`) VALUES (`+placeholders(len(args))+`)`, args...); err != nil {
+			return err
+		}
+		return writeMatchChildren(ctx, c, &m)
+	})
+	if err != nil {
+		return translate(err)
+	}
+	*match = m
+	return nil
+}
+
+func (r *MatchRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Match, error) {
+	return findMatch(ctx, r.db.conn(ctx), id)
+}
+
+// FindByGroupID returns the group's matches, newest first.
+func (r *MatchRepo) FindByGroupID(ctx context.Context, groupID primitive.ObjectID) ([]models.Match, error) {
+	return queryMatches(ctx, r.db.conn(ctx), `WHERE group_id = ? ORDER BY created_at DESC, id DESC`, groupID.Hex())
+}
+
+// FindPage returns one page of a group's matches, filtered and sorted as q
+// asks, keyed on the sort value and ID of the last match returned.
+func (r *MatchRepo) FindPage(ctx context.Context, q repositories.MatchQuery) (*repositories.MatchPage, error) {
+	plan, err := q.Plan()
+	if err != nil {
+		return nil, err
+	}
+
+	where := []string{"group_id = ?"}
+	args := []interface{}{q.GroupID.Hex()}
+	if len(q.Statuses) > 0 {
+		where = append(where, "status IN ("+placeholders(len(q.Statuses))+")")
+		for _, s := range q.Statuses {
+			args = append(args, s)
+		}
+	}
+	if !q.PlayerID.IsZero() {
+		where = append(where, "EXISTS (SELECT 1 FROM match_players mp WHERE mp.match_id = matches.id AND mp.player_id = ?)")
+		args = append(args, q.PlayerID.Hex())
+	}
+	if q.From != nil {
+		where = append(where, "created_at >= ?")
+		args = append(args, q.From.UnixMilli())
+	}
+	if q.To != nil {
+		where = append(where, "created_at < ?")
+		args = append(args, q.To.UnixMilli())
+	}
+	if q.Type != "" {
+		where = append(where, "type = ?")
+		args = append(args, q.Type)
+	}
+	if q.SessionID != "" {
+		where = append(where, "session_id = ?")
+		args = append(args, q.SessionID)
+	}
+
+	field, dir, cmp := "created_at", "DESC", "<"
+	if plan.Sort == repositories.MatchSortDuration {
+		field = "duration_secs"
+	}
+	if q.Ascending {
+		dir, cmp = "ASC", ">"
+	}
+	if plan.AfterKey != nil {
+		where = append(where, "("+field+" "+cmp+" ? OR ("+field+" = ? AND id "+cmp+" ?))")
+		args = append(args, *plan.AfterKey, *plan.AfterKey, plan.AfterID.Hex())
+	}
+	args = append(args, plan.Limit+1)
+
+	matches, err := queryMatches(ctx, r.db.conn(ctx),
+		"WHERE "+strings.Join(where, " AND ")+" ORDER BY "+field+" "+dir+", id "+dir+" LIMIT ?", args...)
+	if err != nil {
+		return nil, err
+	}
+	return plan.Page(matches), nil
+}
+
+// Update replaces the match only if its stored version still equals
+// match.Version, then bumps the version. A mismatch yields ErrVersionConflict.
+func (r *MatchRepo) Update(ctx context.Context, match *models.Match) error {
+	m := *match
+	m.Version++
+	m.UpdatedAt = time.Now()
+	err := r.db.inTx(ctx, func(c conn) error {
+		return updateMatch(ctx, c, &m, match.Version)
+	})
+	if err != nil {
+		return translate(err)
+	}
+	*match = m
+	return nil
+}
+
+func (r *MatchRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return translate(r.db.inTx(ctx, func(c conn) error {
+		for _, table := range []string{"score_events", "match_players"} {
+			if _, err := c.exec(ctx, `DELETE FROM `+table+` WHERE match_id = ?`, id.Hex()); err != nil {
+				return err
+			}
+		}
+		_, err := c.exec(ctx, `DELETE FROM matches WHERE id = ?`, id.Hex())
+		return err
+	}))
+}
+
+func (r *MatchRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	return translate(r.db.inTx(ctx, func(c conn) error {
+		for _, table := range []string{"score_events", "match_players"} {
+			if _, err := c.exec(ctx,
+				`DELETE FROM `+table+` WHERE match_id IN (SELECT id FROM matches WHERE group_id = ?)`,
+				groupID.Hex()); err != nil {
+				return err
+			}
+		}
+		_, err := c.exec(ctx, `DELETE FROM matches WHERE group_id = ?`, groupID.Hex())
+		return err
+	}))
+}
+
+// ReplacePlayerInMatches replaces all occurrences of sourceID with targetID
+// in matches of a group where sourceID is on a team, returning the updated
+// matches. All matches change in one transaction, or none do.
+func (r *MatchRepo) ReplacePlayerInMatches(ctx context.Context, groupID, sourceID, targetID primitive.ObjectID, _, targetName string) ([]models.Match, error) {
+	var updated []models.Match
+	err := r.db.inTx(ctx, func(c conn) error {
+		matches, err := queryMatches(ctx, c,
+			`WHERE group_id = ? AND id IN (SELECT match_id FROM match_players WHERE player_id = ?) ORDER BY created_at, id`,
+			groupID.Hex(), sourceID.Hex())
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for i := range matches {
+			m := &matches[i]
+			prev := m.Version
+			m.ReplacePlayer(sourceID, targetID, targetName)
+			m.Version++
+			m.UpdatedAt = now
+			if err := updateMatch(ctx, c, m, prev); err != nil {
+				return err
+			}
+		}
+		updated = matches
+		return nil
+	})
+	if err != nil {
+		return nil, translate(err)
+	}
+	return updated, nil
+}
+
+// ── Row mapping ──
+
+func findMatch(ctx context.Context, c conn, id primitive.ObjectID) (*models.Match, error) {
+	matches, err := queryMatches(ctx, c, `WHERE id = ?`, id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, translate(errNoRows)
+	}
+	return &matches[0], nil
+}
+
+// updateMatch writes m over the stored row if that row is still at version
+// prev.
+func updateMatch(ctx context.Context, c conn, m *models.Match, prev int) error {
+	args, err := matchArgs(m)
+	if err != nil {
+		return err
+	}
+	cols := strings.Split(matchColumns, ",")
+	set := make([]string, 0, len(cols)-1)
+	for _, col := range cols[1:] {
+		set = append(set, strings.TrimSpace(col)+" = ?")
+	}
+	args = append(args[1:], m.ID.Hex(), prev)
+	res, err := c.exec(ctx, `UPDATE matches SET `+strings.Join(set, ", ")+` WHERE id = ? AND version = ?`, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repositories.ErrVersionConflict
+	}
+	return writeMatchChildren(ctx, c, m)
+}
+
+// matchArgs returns m's column values in matchColumns order.
+func matchArgs(m *models.Match) ([]interface{}, error) {
+	var encoded [5]string
+	for i, v := range []interface{}{m.Team1Names, m.Team2Names, m.Team1Positions, m.Team2Positions, m.Pauses} {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = string(raw)
+	}
+	return []interface{}{
+		m.ID.Hex(), m.GroupID.Hex(), m.Type, m.SessionID, encoded[0], encoded[1], m.Score1, m.Score2,
+		m.ServingTeam, m.ServingPlayerID, encoded[2], encoded[3], m.Status, toNullMillis(m.ScheduledAt),
+		toMillis(m.StartedAt), toNullMillis(m.FinishedAt), m.DurationSecs, encoded[4], m.PausedSecs, m.WinnerTeam, m.EndReason,
+		toMillis(m.CreatedAt), toMillis(m.UpdatedAt), m.Version, m.PointsToWin,
+	}, nil
+}
+
+// writeMatchChildren replaces the match's team and score history rows.
+func writeMatchChildren(ctx context.Context, c conn, m *models.Match) error {
+	for _, table := range []string{"score_events", "match_players"} {
+		if _, err := c.exec(ctx, `DELETE FROM `+table+` WHERE match_id = ?`, m.ID.Hex()); err != nil {
+			return err
+		}
+	}
+	for team, ids := range [][]primitive.ObjectID{m.Team1IDs, m.Team2IDs} {
+		for slot, id := range ids {
+			if _, err := c.exec(ctx,
+				`INSERT INTO match_players (match_id, team, slot, player_id) VALUES (?, ?, ?, ?)`,
+				m.ID.Hex(), team+1, slot, id.Hex()); err != nil {
+				return err
+			}
+		}
+	}
+	for seq, ev := range m.ScoreHistory {
+		if _, err := c.exec(ctx,
+			`INSERT INTO score_events (match_id, seq, team, player_id, outcome, rally_secs, fault_player_id, at, device_id, event_id, version)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			m.ID.Hex(), seq, ev.Team, ev.PlayerID, ev.Outcome, ev.RallySecs, ev.FaultPlayerID,
+			toMillis(ev.At), ev.DeviceID, ev.EventID, ev.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queryMatches loads the matches selected by tail (a WHERE/ORDER/LIMIT
+// clause) together with their teams and score history.
+func queryMatches(ctx context.Context, c conn, tail string, args ...interface{}) ([]models.Match, error) {
+	rows, err := c.query(ctx, `SELECT `+matchColumns+` FROM matches `+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	var matches []models.Match
+	index := map[string]int{}
+	for rows.Next() {
+		m, err := scanMatch(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[m.ID.Hex()] = len(matches)
+		matches = append(matches, *m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(matches) == 0 {
+		return matches, err
+	}
+
+	ids := make([]interface{}, 0, len(matches))
+	for _, m := range matches {
+		ids = append(ids, m.ID.Hex())
+	}
+	in := `(` + placeholders(len(ids)) + `)`
+
+	rows, err = c.query(ctx, `SELECT match_id, team, player_id FROM match_players WHERE match_id IN `+in+` ORDER BY match_id, team, slot`, ids...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var matchID, playerID string
+		var team int
+		if err := rows.Scan(&matchID, &team, &playerID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m := &matches[index[matchID]]
+		if team == 1 {
+			m.Team1IDs = append(m.Team1IDs, mustID(playerID))
+		} else {
+			m.Team2IDs = append(m.Team2IDs, mustID(playerID))
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = c.query(ctx,
+		`SELECT match_id, team, player_id, outcome, rally_secs, fault_player_id, at, device_id, event_id, version
+		 FROM score_events WHERE match_id IN `+in+` ORDER BY match_id, seq`, ids...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var matchID string
+		var ev models.ScoreEvent
+		var at int64
+		if err := rows.Scan(&matchID, &ev.Team, &ev.PlayerID, &ev.Outcome, &ev.RallySecs, &ev.FaultPlayerID,
+			&at, &ev.DeviceID, &ev.EventID, &ev.Version); err != nil {
+			return nil, err
+		}
+		ev.At = fromMillis(at)
+		m := &matches[index[matchID]]
+		m.ScoreHistory = append(m.ScoreHistory, ev)
+	}
+	return matches, rows.Err()
+}
+
+func scanMatch(rows *sql.Rows) (*models.Match, error) {
+	var m models.Match
+	var id, groupID string
+	var names1, names2, pos1, pos2, pauses string
+	var scheduledAt, finishedAt sql.NullInt64
+	var startedAt, createdAt, updatedAt int64
+	if err := rows.Scan(&id, &groupID, &m.Type, &m.SessionID, &names1, &names2, &m.Score1, &m.Score2,
+		&m.ServingTeam, &m.ServingPlayerID, &pos1, &pos2, &m.Status, &scheduledAt,
+		&startedAt, &finishedAt, &m.DurationSecs, &pauses, &m.PausedSecs, &m.WinnerTeam, &m.EndReason,
+		&createdAt, &updatedAt, &m.Version, &m.PointsToWin); err != nil {
+		return nil, err
+	}
+	m.ID, m.GroupID = mustID(id), mustID(groupID)
+	m.ScheduledAt, m.FinishedAt = fromNullMillis(scheduledAt), fromNullMillis(finishedAt)
+	m.StartedAt, m.CreatedAt, m.UpdatedAt = fromMillis(startedAt), fromMillis(createdAt), fromMillis(updatedAt)
+	for _, f := range []struct {
+		raw string
+		dst interface{}
+	}{{names1, &m.Team1Names}, {names2, &m.Team2Names}, {pos1, &m.Team1Positions}, {pos2, &m.Team2Positions}, {pauses, &m.Pauses}} {
+		if err := json.Unmarshal([]byte(f.raw), f.dst); err != nil {
+			return nil, err
+		}
+	}
+	m.ScoreHistory = []models.ScoreEvent{}
+	return &m, nil
+}
@@ -0,0 +1,128 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+// MergeRecordRepo keeps each record as a JSON body, with the columns it is
+// looked up and guarded by alongside.
+type MergeRecordRepo struct {
+	db *DB
+}
+
+func NewMergeRecordRepo(db *DB) *MergeRecordRepo {
+	return &MergeRecordRepo{db: db}
+}
+
+func (r *MergeRecordRepo) Create(ctx context.Context, record *models.MergeRecord) error {
+	stored := *record
+	stored.ID = primitive.NewObjectID()
+	body, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.conn(ctx).exec(ctx,
+		`INSERT INTO player_merges (id, group_id, merged_at, undone_at, body) VALUES (?, ?, ?, ?, ?)`,
+		stored.ID.Hex(), stored.GroupID.Hex(), toMillis(stored.MergedAt), toNullMillis(stored.UndoneAt), string(body))
+	if err != nil {
+		return translate(err)
+	}
+	record.ID = stored.ID
+	return nil
+}
+
+func (r *MergeRecordRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.MergeRecord, error) {
+	records, err := r.find(ctx, `id = ?`, id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, translate(errNoRows)
+	}
+	return &records[0], nil
+}
+
+// FindByGroupID returns a group's merges, newest first.
+func (r *MergeRecordRepo) FindByGroupID(ctx context.Context, groupID primitive.ObjectID) ([]models.MergeRecord, error) {
+	return r.find(ctx, `group_id = ?`, groupID.Hex())
+}
+
+// MarkUndone stores the record's undo fields, only if it was not undone
+// already.
+func (r *MergeRecordRepo) MarkUndone(ctx context.Context, record *models.MergeRecord) error {
+	return translate(r.db.inTx(ctx, func(c conn) error {
+		var body string
+		err := c.queryRow(ctx, `SELECT body FROM player_merges WHERE id = ? AND undone_at IS NULL`, record.ID.Hex()).Scan(&body)
+		if errors.Is(err, sql.ErrNoRows) {
+			return repositories.ErrVersionConflict
+		}
+		if err != nil {
+			return err
+		}
+		var stored models.MergeRecord
+		if err := json.Unmarshal([]byte(body), &stored); err != nil {
+			return err
+		}
+		stored.UndoneAt, stored.UndoneBy, stored.RestoredID = record.UndoneAt, record.UndoneBy, record.RestoredID
+		updated, err := json.Marshal(stored)
+		if err != nil {
+			return err
+		}
+		res, err := c.exec(ctx, `UPDATE player_merges SET undone_at = ?, body = ? WHERE id = ? AND undone_at IS NULL`,
+			toNullMillis(stored.UndoneAt), string(updated), record.ID.Hex())
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return repositories.ErrVersionConflict
+		}
+		return nil
+	}))
+}
+
+// Replace overwrites a stored record.
+func (r *MergeRecordRepo) Replace(ctx context.Context, record *models.MergeRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return affectedOne(r.db.conn(ctx).exec(ctx,
+		`UPDATE player_merges SET merged_at = ?, undone_at = ?, body = ? WHERE id = ?`,
+		toMillis(record.MergedAt), toNullMillis(record.UndoneAt), string(body), record.ID.Hex()))
+}
+
+func (r *MergeRecordRepo) find(ctx context.Context, where string, args ...interface{}) ([]models.MergeRecord, error) {
+	rows, err := r.db.conn(ctx).query(ctx,
+		`SELECT body FROM player_merges WHERE `+where+` ORDER BY merged_at DESC, id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []models.MergeRecord
+	for rows.Next() {
+		var body string
+		if err := rows.Scan(&body); err != nil {
+			return nil, err
+		}
+		var record models.MergeRecord
+		if err := json.Unmarshal([]byte(body), &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func (r *MergeRecordRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	_, err := r.db.conn(ctx).exec(ctx, `DELETE FROM player_merges WHERE group_id = ?`, groupID.Hex())
+	return translate(err)
+}
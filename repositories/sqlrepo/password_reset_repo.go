@@ -0,0 +1,67 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+type PasswordResetRepo struct {
+	db *DB
+}
+
+func NewPasswordResetRepo(db *DB) *PasswordResetRepo {
+	return &PasswordResetRepo{db: db}
+}
+
+func (r *PasswordResetRepo) Create(ctx context.Context, reset *models.PasswordReset) error {
+	id := primitive.NewObjectID()
+	_, err := r.db.conn(ctx).exec(ctx,
+		`INSERT INTO password_resets (id, user_id, token_hash, created_at, expires_at, used_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		id.Hex(), reset.UserID.Hex(), reset.TokenHash, toMillis(reset.CreatedAt), toMillis(reset.ExpiresAt), toNullMillis(reset.UsedAt))
+	if err != nil {
+		return translate(err)
+	}
+	reset.ID = id
+	return nil
+}
+
+func (r *PasswordResetRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.PasswordReset, error) {
+	var reset models.PasswordReset
+	var userID string
+	var createdAt, expiresAt int64
+	var usedAt sql.NullInt64
+	err := r.db.conn(ctx).queryRow(ctx,
+		`SELECT user_id, token_hash, created_at, expires_at, used_at FROM password_resets WHERE id = ?`, id.Hex()).
+		Scan(&userID, &reset.TokenHash, &createdAt, &expiresAt, &usedAt)
+	if err != nil {
+		return nil, translate(err)
+	}
+	reset.ID, reset.UserID = id, mustID(userID)
+	reset.CreatedAt, reset.ExpiresAt, reset.UsedAt = fromMillis(createdAt), fromMillis(expiresAt), fromNullMillis(usedAt)
+	return &reset, nil
+}
+
+// MarkUsed spends the reset, only if it was not used already.
+func (r *PasswordResetRepo) MarkUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	res, err := r.db.conn(ctx).exec(ctx,
+		`UPDATE password_resets SET used_at = ? WHERE id = ? AND used_at IS NULL`, toMillis(usedAt), id.Hex())
+	if err != nil {
+		return translate(err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return repositories.ErrVersionConflict
+	}
+	return nil
+}
+
+func (r *PasswordResetRepo) MarkUsedByUserID(ctx context.Context, userID primitive.ObjectID, usedAt time.Time) error {
+	_, err := r.db.conn(ctx).exec(ctx,
+		`UPDATE password_resets SET used_at = ? WHERE user_id = ? AND used_at IS NULL`, toMillis(usedAt), userID.Hex())
+	return translate(err)
+}
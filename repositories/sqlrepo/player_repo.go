@@ -0,0 +1,115 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+)
+
+type PlayerRepo struct {
+	db *DB
+}
+
+func NewPlayerRepo(db *DB) *PlayerRepo {
+	return &PlayerRepo{db: db}
+}
+
+func (r *PlayerRepo) Create(ctx context.Context, player *models.Player) error {
+	id, createdAt := primitive.NewObjectID(), time.Now()
+	_, err := r.db.conn(ctx).exec(ctx,
+		`INSERT INTO players (id, name, group_id, user_id, nickname, hand, position, archived_at, claimed_by, claimed_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id.Hex(), player.Name, player.GroupID.Hex(), toNullID(player.UserID),
+		player.Nickname, player.Hand, player.Position, toNullMillis(player.ArchivedAt),
+		toNullID(player.ClaimedBy), toNullMillis(player.ClaimedAt), toMillis(createdAt))
+	if err != nil {
+		return translate(err)
+	}
+	player.ID, player.CreatedAt = id, createdAt
+	return nil
+}
+
+func (r *PlayerRepo) FindByGroupID(ctx context.Context, groupID primitive.ObjectID) ([]models.Player, error) {
+	return r.find(ctx, `group_id = ?`, groupID.Hex())
+}
+
+func (r *PlayerRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Player, error) {
+	return r.findOne(ctx, `id = ?`, id.Hex())
+}
+
+func (r *PlayerRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.db.conn(ctx).exec(ctx, `DELETE FROM players WHERE id = ?`, id.Hex())
+	return translate(err)
+}
+
+func (r *PlayerRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	_, err := r.db.conn(ctx).exec(ctx, `DELETE FROM players WHERE group_id = ?`, groupID.Hex())
+	return translate(err)
+}
+
+func (r *PlayerRepo) FindByNameAndGroupID(ctx context.Context, name string, groupID primitive.ObjectID) (*models.Player, error) {
+	return r.findOne(ctx, `group_id = ? AND name = ?`, groupID.Hex(), name)
+}
+
+func (r *PlayerRepo) FindByUserAndGroupID(ctx context.Context, userID, groupID primitive.ObjectID) (*models.Player, error) {
+	return r.findOne(ctx, `user_id = ? AND group_id = ?`, userID.Hex(), groupID.Hex())
+}
+
+// FindByUserID returns the players linked to a user, one per group at most.
+func (r *PlayerRepo) FindByUserID(ctx context.Context, userID primitive.ObjectID) ([]models.Player, error) {
+	return r.find(ctx, `user_id = ?`, userID.Hex())
+}
+
+// Update replaces the stored player; a missing player is not an error.
+func (r *PlayerRepo) Update(ctx context.Context, player *models.Player) error {
+	_, err := r.db.conn(ctx).exec(ctx,
+		`UPDATE players SET name = ?, group_id = ?, user_id = ?, nickname = ?, hand = ?, position = ?, archived_at = ?,
+		claimed_by = ?, claimed_at = ?, created_at = ? WHERE id = ?`,
+		player.Name, player.GroupID.Hex(), toNullID(player.UserID),
+		player.Nickname, player.Hand, player.Position, toNullMillis(player.ArchivedAt),
+		toNullID(player.ClaimedBy), toNullMillis(player.ClaimedAt), toMillis(player.CreatedAt), player.ID.Hex())
+	return translate(err)
+}
+
+func (r *PlayerRepo) findOne(ctx context.Context, where string, args ...interface{}) (*models.Player, error) {
+	players, err := r.find(ctx, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(players) == 0 {
+		return nil, translate(errNoRows)
+	}
+	return &players[0], nil
+}
+
+func (r *PlayerRepo) find(ctx context.Context, where string, args ...interface{}) ([]models.Player, error) {
+	rows, err := r.db.conn(ctx).query(ctx,
+		`SELECT id, name, group_id, user_id, nickname, hand, position, archived_at, claimed_by, claimed_at, created_at
+		FROM players WHERE `+where+` ORDER BY created_at, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var players []models.Player
+	for rows.Next() {
+		var p models.Player
+		var id, groupID string
+		var userID, claimedBy sql.NullString
+		var archivedAt, claimedAt sql.NullInt64
+		var createdAt int64
+		if err := rows.Scan(&id, &p.Name, &groupID, &userID, &p.Nickname, &p.Hand, &p.Position, &archivedAt,
+			&claimedBy, &claimedAt, &createdAt); err != nil {
+			return nil, err
+		}
+		p.ID, p.GroupID, p.CreatedAt = mustID(id), mustID(groupID), fromMillis(createdAt)
+		p.UserID, p.ArchivedAt = fromNullID(userID), fromNullMillis(archivedAt)
+		p.ClaimedBy, p.ClaimedAt = fromNullID(claimedBy), fromNullMillis(claimedAt)
+		players = append(players, p)
+	}
+	return players, rows.Err()
+}
@@ -0,0 +1,259 @@
+package sqlrepo
+
+import (
+	"context"
+	"time"
+)
+
+// schema is applied in order; each step runs once and is recorded in
+// schema_migrations. Append new steps, never edit shipped ones. The DDL is
+// kept to what SQLite and Postgres both accept.
+var schema = []struct {
+	version int
+	stmts   []string
+}{
+	{1, []string{
+		`CREATE TABLE users (
+			id         TEXT PRIMARY KEY,
+			username   TEXT NOT NULL UNIQUE,
+			password   TEXT NOT NULL,
+			created_at BIGINT NOT NULL
+		)`,
+		`CREATE TABLE groups (
+			id         TEXT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			join_code  TEXT NOT NULL UNIQUE,
+			created_by TEXT NOT NULL,
+			created_at BIGINT NOT NULL
+		)`,
+		`CREATE TABLE group_members (
+			group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+			user_id  TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			PRIMARY KEY (group_id, user_id)
+		)`,
+		`CREATE INDEX group_members_user ON group_members (user_id)`,
+		`CREATE TABLE players (
+			id         TEXT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			group_id   TEXT NOT NULL,
+			created_at BIGINT NOT NULL
+		)`,
+		`CREATE INDEX players_group_name ON players (group_id, name)`,
+		`CREATE TABLE matches (
+			id                TEXT PRIMARY KEY,
+			group_id          TEXT NOT NULL,
+			type              TEXT NOT NULL,
+			session_id        TEXT NOT NULL DEFAULT '',
+			team1_names       TEXT NOT NULL,
+			team2_names       TEXT NOT NULL,
+			score1            INTEGER NOT NULL,
+			score2            INTEGER NOT NULL,
+			serving_team      INTEGER NOT NULL,
+			serving_player_id TEXT NOT NULL,
+			team1_positions   TEXT NOT NULL,
+			team2_positions   TEXT NOT NULL,
+			status            TEXT NOT NULL,
+			scheduled_at      BIGINT,
+			started_at        BIGINT NOT NULL,
+			finished_at       BIGINT,
+			duration_secs     INTEGER NOT NULL,
+			pauses            TEXT NOT NULL,
+			paused_secs       INTEGER NOT NULL,
+			winner_team       INTEGER NOT NULL,
+			end_reason        TEXT NOT NULL,
+			created_at        BIGINT NOT NULL,
+			updated_at        BIGINT NOT NULL,
+			version           INTEGER NOT NULL
+		)`,
+		`CREATE INDEX matches_group_created ON matches (group_id, created_at, id)`,
+		`CREATE INDEX matches_group_duration ON matches (group_id, duration_secs, id)`,
+		`CREATE INDEX matches_group_session ON matches (group_id, session_id)`,
+		`CREATE TABLE match_players (
+			match_id  TEXT NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+			team      INTEGER NOT NULL,
+			slot      INTEGER NOT NULL,
+			player_id TEXT NOT NULL,
+			PRIMARY KEY (match_id, team, slot)
+		)`,
+		`CREATE INDEX match_players_player ON match_players (player_id)`,
+		`CREATE TABLE score_events (
+			match_id        TEXT NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+			seq             INTEGER NOT NULL,
+			team            INTEGER NOT NULL,
+			player_id       TEXT NOT NULL,
+			outcome         TEXT NOT NULL,
+			rally_secs      INTEGER NOT NULL,
+			fault_player_id TEXT NOT NULL,
+			at              BIGINT NOT NULL,
+			device_id       TEXT NOT NULL,
+			event_id        TEXT NOT NULL,
+			version         INTEGER NOT NULL,
+			PRIMARY KEY (match_id, seq)
+		)`,
+		`CREATE TABLE match_events (
+			id       TEXT PRIMARY KEY,
+			match_id TEXT NOT NULL,
+			version  INTEGER NOT NULL,
+			idx      INTEGER NOT NULL,
+			body     TEXT NOT NULL
+		)`,
+		`CREATE INDEX match_events_match ON match_events (match_id, version, idx)`,
+	}},
+	{2, []string{
+		`CREATE TABLE player_merges (
+			id        TEXT PRIMARY KEY,
+			group_id  TEXT NOT NULL,
+			merged_at BIGINT NOT NULL,
+			undone_at BIGINT,
+			body      TEXT NOT NULL
+		)`,
+		`CREATE INDEX player_merges_group ON player_merges (group_id, merged_at)`,
+	}},
+	{3, []string{
+		`ALTER TABLE players ADD COLUMN user_id TEXT`,
+		`ALTER TABLE players ADD COLUMN claimed_by TEXT`,
+		`ALTER TABLE players ADD COLUMN claimed_at BIGINT`,
+		`CREATE INDEX players_user_group ON players (user_id, group_id)`,
+	}},
+	{4, []string{
+		`ALTER TABLE players ADD COLUMN nickname TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE players ADD COLUMN hand TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE players ADD COLUMN position TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE players ADD COLUMN archived_at BIGINT`,
+	}},
+	{5, []string{
+		`CREATE TABLE sessions (
+			id             TEXT PRIMARY KEY,
+			user_id        TEXT NOT NULL,
+			token_hash     TEXT NOT NULL,
+			device_id      TEXT NOT NULL DEFAULT '',
+			created_at     BIGINT NOT NULL,
+			last_used_at   BIGINT NOT NULL,
+			expires_at     BIGINT NOT NULL,
+			revoked_at     BIGINT,
+			revoked_reason TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE INDEX sessions_user ON sessions (user_id)`,
+	}},
+	{6, []string{
+		`CREATE TABLE password_resets (
+			id         TEXT PRIMARY KEY,
+			user_id    TEXT NOT NULL,
+			token_hash TEXT NOT NULL,
+			created_at BIGINT NOT NULL,
+			expires_at BIGINT NOT NULL,
+			used_at    BIGINT
+		)`,
+		`CREATE INDEX password_resets_user ON password_resets (user_id)`,
+	}},
+	{7, []string{
+		// Usernames are unique ignoring case. This fails if existing users
+		// differ only in case; rename one of them by hand first.
+		`CREATE UNIQUE INDEX users_username_ci ON users (lower(username))`,
+		`ALTER TABLE users ADD COLUMN display_name TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN phone TEXT NOT NULL DEFAULT ''`,
+		// Events of matches deleted before this step keep an empty group.
+		`ALTER TABLE match_events ADD COLUMN group_id TEXT NOT NULL DEFAULT ''`,
+		`UPDATE match_events SET group_id = COALESCE(
+			(SELECT group_id FROM matches WHERE matches.id = match_events.match_id), '')`,
+		`CREATE INDEX match_events_group ON match_events (group_id)`,
+	}},
+	{8, []string{
+		`ALTER TABLE groups ADD COLUMN join_approval BOOLEAN NOT NULL DEFAULT FALSE`,
+		`CREATE TABLE invites (
+			id         TEXT PRIMARY KEY,
+			group_id   TEXT NOT NULL,
+			code       TEXT NOT NULL UNIQUE,
+			name       TEXT NOT NULL,
+			created_by TEXT NOT NULL,
+			created_at BIGINT NOT NULL,
+			expires_at BIGINT,
+			max_uses   INTEGER NOT NULL,
+			uses       INTEGER NOT NULL DEFAULT 0,
+			revoked_at BIGINT
+		)`,
+		`CREATE INDEX invites_group ON invites (group_id, created_at)`,
+		`CREATE TABLE join_requests (
+			id         TEXT PRIMARY KEY,
+			group_id   TEXT NOT NULL,
+			user_id    TEXT NOT NULL,
+			username   TEXT NOT NULL,
+			invite_id  TEXT,
+			created_at BIGINT NOT NULL,
+			UNIQUE (group_id, user_id)
+		)`,
+		`CREATE INDEX join_requests_user ON join_requests (user_id)`,
+	}},
+	{9, []string{
+		// Settings are one JSON document; the version lives in its own
+		// column so saves can be made conditional on it.
+		`ALTER TABLE groups ADD COLUMN settings TEXT NOT NULL DEFAULT '{}'`,
+		`ALTER TABLE groups ADD COLUMN settings_version INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE matches ADD COLUMN points_to_win INTEGER NOT NULL DEFAULT 0`,
+	}},
+	{10, []string{
+		`CREATE TABLE share_links (
+			id         TEXT PRIMARY KEY,
+			group_id   TEXT NOT NULL,
+			match_id   TEXT,
+			token_hash TEXT NOT NULL,
+			created_by TEXT NOT NULL,
+			created_at BIGINT NOT NULL,
+			expires_at BIGINT NOT NULL,
+			revoked_at BIGINT
+		)`,
+		`CREATE INDEX share_links_group ON share_links (group_id, created_at)`,
+	}},
+	{11, []string{
+		`ALTER TABLE sessions ADD COLUMN rotated_hashes TEXT NOT NULL DEFAULT '[]'`,
+	}},
+}
+
+func (db *DB) migrate(ctx context.Context) error {
+	c := db.conn(ctx)
+	if _, err := c.exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at BIGINT NOT NULL
+	)`); err != nil {
+		return err
+	}
+
+	applied := map[int]bool{}
+	rows, err := c.query(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[v] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, step := range schema {
+		if applied[step.version] {
+			continue
+		}
+		err := db.inTx(ctx, func(c conn) error {
+			for _, stmt := range step.stmts {
+				if _, err := c.exec(ctx, stmt); err != nil {
+					return err
+				}
+			}
+			_, err := c.exec(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`,
+				step.version, time.Now().UnixMilli())
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
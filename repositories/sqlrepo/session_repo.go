@@ -0,0 +1,108 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+type SessionRepo struct {
+	db *DB
+}
+
+func NewSessionRepo(db *DB) *SessionRepo {
+	return &SessionRepo{db: db}
+}
+
+func (r *SessionRepo) Create(ctx context.Context, session *models.Session) error {
+	id := primitive.NewObjectID()
+	_, err := r.db.conn(ctx).exec(ctx,
+		`INSERT INTO sessions (id, user_id, token_hash, device_id, created_at, last_used_at, expires_at, revoked_at, revoked_reason)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id.Hex(), session.UserID.Hex(), session.TokenHash, session.DeviceID,
+		toMillis(session.CreatedAt), toMillis(session.LastUsedAt), toMillis(session.ExpiresAt),
+		toNullMillis(session.RevokedAt), session.RevokedReason)
+	if err != nil {
+		return translate(err)
+	}
+	session.ID = id
+	return nil
+}
+
+func (r *SessionRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Session, error) {
+	var s models.Session
+	var userID string
+	var createdAt, lastUsedAt, expiresAt int64
+	var revokedAt sql.NullInt64
+	var rotated string
+	err := r.db.conn(ctx).queryRow(ctx,
+		`SELECT user_id, token_hash, device_id, created_at, last_used_at, expires_at, revoked_at, revoked_reason, rotated_hashes
+		FROM sessions WHERE id = ?`, id.Hex()).
+		Scan(&userID, &s.TokenHash, &s.DeviceID, &createdAt, &lastUsedAt, &expiresAt, &revokedAt, &s.RevokedReason, &rotated)
+	if err != nil {
+		return nil, translate(err)
+	}
+	if err := json.Unmarshal([]byte(rotated), &s.RotatedHashes); err != nil {
+		return nil, err
+	}
+	s.ID, s.UserID = id, mustID(userID)
+	s.CreatedAt, s.LastUsedAt, s.ExpiresAt = fromMillis(createdAt), fromMillis(lastUsedAt), fromMillis(expiresAt)
+	s.RevokedAt = fromNullMillis(revokedAt)
+	return &s, nil
+}
+
+// Rotate swaps the token hash only if it is still oldHash and the session
+// is not revoked, and adds oldHash to the rotated hashes.
+func (r *SessionRepo) Rotate(ctx context.Context, id primitive.ObjectID, oldHash, newHash string, usedAt, expiresAt time.Time) error {
+	return r.db.inTx(ctx, func(c conn) error {
+		var rotated string
+		err := c.queryRow(ctx, `SELECT rotated_hashes FROM sessions WHERE id = ?`, id.Hex()).Scan(&rotated)
+		if err != nil {
+			if errors.Is(translate(err), repositories.ErrNotFound) {
+				return repositories.ErrVersionConflict
+			}
+			return translate(err)
+		}
+		var hashes []string
+		if err := json.Unmarshal([]byte(rotated), &hashes); err != nil {
+			return err
+		}
+		next, err := json.Marshal(models.AddRotated(hashes, oldHash))
+		if err != nil {
+			return err
+		}
+		// The token_hash condition also makes sure nobody rotated in between.
+		res, err := c.exec(ctx,
+			`UPDATE sessions SET token_hash = ?, last_used_at = ?, expires_at = ?, rotated_hashes = ?
+			WHERE id = ? AND token_hash = ? AND revoked_at IS NULL`,
+			newHash, toMillis(usedAt), toMillis(expiresAt), string(next), id.Hex(), oldHash)
+		if err != nil {
+			return translate(err)
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return repositories.ErrVersionConflict
+		}
+		return nil
+	})
+}
+
+func (r *SessionRepo) Revoke(ctx context.Context, id primitive.ObjectID, reason string) error {
+	_, err := r.db.conn(ctx).exec(ctx,
+		`UPDATE sessions SET revoked_at = ?, revoked_reason = ? WHERE id = ? AND revoked_at IS NULL`,
+		toMillis(time.Now()), reason, id.Hex())
+	return translate(err)
+}
+
+func (r *SessionRepo) RevokeByUserID(ctx context.Context, userID primitive.ObjectID, reason string) error {
+	_, err := r.db.conn(ctx).exec(ctx,
+		`UPDATE sessions SET revoked_at = ?, revoked_reason = ? WHERE user_id = ? AND revoked_at IS NULL`,
+		toMillis(time.Now()), reason, userID.Hex())
+	return translate(err)
+}
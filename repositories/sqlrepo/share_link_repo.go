@@ -0,0 +1,86 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+)
+
+type ShareLinkRepo struct {
+	db *DB
+}
+
+func NewShareLinkRepo(db *DB) *ShareLinkRepo {
+	return &ShareLinkRepo{db: db}
+}
+
+const shareLinkColumns = `id, group_id, match_id, token_hash, created_by, created_at, expires_at, revoked_at`
+
+func (r *ShareLinkRepo) Create(ctx context.Context, link *models.ShareLink) error {
+	id := primitive.NewObjectID()
+	_, err := r.db.conn(ctx).exec(ctx,
+		`INSERT INTO share_links (`+shareLinkColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		id.Hex(), link.GroupID.Hex(), toNullID(link.MatchID), link.TokenHash, link.CreatedBy.Hex(),
+		toMillis(link.CreatedAt), toMillis(link.ExpiresAt), toNullMillis(link.RevokedAt))
+	if err != nil {
+		return translate(err)
+	}
+	link.ID = id
+	return nil
+}
+
+func (r *ShareLinkRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.ShareLink, error) {
+	link, err := scanShareLink(r.db.conn(ctx).queryRow(ctx,
+		`SELECT `+shareLinkColumns+` FROM share_links WHERE id = ?`, id.Hex()))
+	if err != nil {
+		return nil, translate(err)
+	}
+	return link, nil
+}
+
+func (r *ShareLinkRepo) FindByGroupID(ctx context.Context, groupID primitive.ObjectID) ([]models.ShareLink, error) {
+	rows, err := r.db.conn(ctx).query(ctx,
+		`SELECT `+shareLinkColumns+` FROM share_links WHERE group_id = ? ORDER BY created_at DESC, id DESC`, groupID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []models.ShareLink
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, *link)
+	}
+	return links, rows.Err()
+}
+
+func (r *ShareLinkRepo) Revoke(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	res, err := r.db.conn(ctx).exec(ctx, `UPDATE share_links SET revoked_at = ? WHERE id = ?`, toMillis(at), id.Hex())
+	return affectedOne(res, err)
+}
+
+func (r *ShareLinkRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	_, err := r.db.conn(ctx).exec(ctx, `DELETE FROM share_links WHERE group_id = ?`, groupID.Hex())
+	return translate(err)
+}
+
+func scanShareLink(row rowScanner) (*models.ShareLink, error) {
+	var link models.ShareLink
+	var id, groupID, createdBy string
+	var matchID sql.NullString
+	var createdAt, expiresAt int64
+	var revokedAt sql.NullInt64
+	if err := row.Scan(&id, &groupID, &matchID, &link.TokenHash, &createdBy, &createdAt, &expiresAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	link.ID, link.GroupID, link.MatchID, link.CreatedBy = mustID(id), mustID(groupID), fromNullID(matchID), mustID(createdBy)
+	link.CreatedAt, link.ExpiresAt, link.RevokedAt = fromMillis(createdAt), fromMillis(expiresAt), fromNullMillis(revokedAt)
+	return &link, nil
+}
@@ -0,0 +1,117 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+	"gully-backend/repositories/repotest"
+)
+
+func repos(db *DB) repotest.Repos {
+	return repotest.Repos{
+		Users:          NewUserRepo(db),
+		Groups:         NewGroupRepo(db),
+		Players:        NewPlayerRepo(db),
+		Matches:        NewMatchRepo(db),
+		MatchEvents:    NewMatchEventRepo(db),
+		MergeRecords:   NewMergeRecordRepo(db),
+		Sessions:       NewSessionRepo(db),
+		PasswordResets: NewPasswordResetRepo(db),
+		Invites:        NewInviteRepo(db),
+		JoinRequests:   NewJoinRequestRepo(db),
+		ShareLinks:     NewShareLinkRepo(db),
+		Tx:             db,
+	}
+}
+
+func TestSQLiteContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repos {
+		db, err := Open(context.Background(), DriverSQLite, filepath.Join(t.TempDir(), "gully.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		return repos(db)
+	})
+}
+
+// TestPostgresContract runs the contract against Postgres when
+// POSTGRES_TEST_DSN (a postgres:// URL) is set; each subtest gets its own
+// schema.
+func TestPostgresContract(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN not set")
+	}
+	ctx := context.Background()
+	admin, err := sql.Open("pgx", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.Close() })
+
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	repotest.Run(t, func(t *testing.T) repotest.Repos {
+		schema := "gully_" + primitive.NewObjectID().Hex()
+		_, err := admin.ExecContext(ctx, `CREATE SCHEMA `+schema)
+		require.NoError(t, err)
+		t.Cleanup(func() { _, _ = admin.ExecContext(ctx, `DROP SCHEMA `+schema+` CASCADE`) })
+
+		db, err := Open(ctx, DriverPostgres, dsn+sep+"search_path="+schema)
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		return repos(db)
+	})
+}
+
+func TestOpen_SchemaIsIdempotent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gully.db")
+	db, err := Open(context.Background(), DriverSQLite, path)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	db, err = Open(context.Background(), DriverSQLite, path)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+}
+
+func TestWithTransaction_RollsBack(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, DriverSQLite, filepath.Join(t.TempDir(), "gully.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	players, matches := NewPlayerRepo(db), NewMatchRepo(db)
+	groupID := primitive.NewObjectID()
+
+	var player models.Player
+	var match models.Match
+	failed := errors.New("failed")
+	err = db.WithTransaction(ctx, func(ctx context.Context) error {
+		player = models.Player{Name: "Alice", GroupID: groupID}
+		if err := players.Create(ctx, &player); err != nil {
+			return err
+		}
+		// Match writes open their own transaction, which joins this one.
+		match = models.Match{GroupID: groupID, Team1IDs: []primitive.ObjectID{player.ID}, Team1Names: []string{"Alice"}, Status: models.MatchStatusLive}
+		if err := matches.Create(ctx, &match); err != nil {
+			return err
+		}
+		return failed
+	})
+	require.ErrorIs(t, err, failed)
+
+	_, err = players.FindByID(ctx, player.ID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	_, err = matches.FindByID(ctx, match.ID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+}
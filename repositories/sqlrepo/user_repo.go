@@ -0,0 +1,69 @@
+package sqlrepo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+)
+
+type UserRepo struct {
+	db *DB
+}
+
+func NewUserRepo(db *DB) *UserRepo {
+	return &UserRepo{db: db}
+}
+
+func (r *UserRepo) Create(ctx context.Context, user *models.User) error {
+	id, createdAt := primitive.NewObjectID(), time.Now()
+	_, err := r.db.conn(ctx).exec(ctx,
+		`INSERT INTO users (id, username, password, created_at) VALUES (?, ?, ?, ?)`,
+		id.Hex(), user.Username, user.Password, toMillis(createdAt))
+	if err != nil {
+		return translate(err)
+	}
+	user.ID, user.CreatedAt = id, createdAt
+	return nil
+}
+
+func (r *UserRepo) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.findOne(ctx, `lower(username) = lower(?)`, username)
+}
+
+func (r *UserRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	return r.findOne(ctx, `id = ?`, id.Hex())
+}
+
+func (r *UserRepo) UpdatePassword(ctx context.Context, id primitive.ObjectID, hash string) error {
+	res, err := r.db.conn(ctx).exec(ctx, `UPDATE users SET password = ? WHERE id = ?`, hash, id.Hex())
+	return affectedOne(res, err)
+}
+
+func (r *UserRepo) UpdateProfile(ctx context.Context, user *models.User) error {
+	res, err := r.db.conn(ctx).exec(ctx,
+		`UPDATE users SET display_name = ?, phone = ? WHERE id = ?`, user.DisplayName, user.Phone, user.ID.Hex())
+	return affectedOne(res, err)
+}
+
+func (r *UserRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.db.conn(ctx).exec(ctx, `DELETE FROM users WHERE id = ?`, id.Hex())
+	return affectedOne(res, err)
+}
+
+func (r *UserRepo) findOne(ctx context.Context, where string, arg interface{}) (*models.User, error) {
+	var user models.User
+	var id string
+	var createdAt int64
+	err := r.db.conn(ctx).queryRow(ctx,
+		`SELECT id, username, password, created_at, display_name, phone FROM users WHERE `+where, arg).
+		Scan(&id, &user.Username, &user.Password, &createdAt, &user.DisplayName, &user.Phone)
+	if err != nil {
+		return nil, translate(err)
+	}
+	user.ID = mustID(id)
+	user.CreatedAt = fromMillis(createdAt)
+	return &user, nil
+}
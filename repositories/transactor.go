@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrNoTransactions means the MongoDB deployment is a standalone server,
+// which cannot run multi-document transactions.
+var ErrNoTransactions = errors.New("MongoDB must be a replica set or sharded cluster to run transactions; a single-node replica set will do")
+
+// MongoTransactor runs multi-document transactions.
+type MongoTransactor struct {
+	client *mongo.Client
+}
+
+// NewMongoTransactor checks that the deployment supports transactions and
+// returns ErrNoTransactions if not. Changes like deleting a group span many
+// documents, and must not be left half done.
+func NewMongoTransactor(ctx context.Context, db *mongo.Database) (*MongoTransactor, error) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return nil, err
+	}
+	if hello.SetName == "" && hello.Msg != "isdbgrid" {
+		return nil, ErrNoTransactions
+	}
+	return &MongoTransactor{client: db.Client()}, nil
+}
+
+// WithTransaction runs fn in a transaction, retrying it on transient errors.
+// Repositories join the transaction through the ctx passed to fn, and so
+// does a WithTransaction nested inside it.
+func (t *MongoTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if session := mongo.SessionFromContext(ctx); session != nil {
+		return fn(ctx)
+	}
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
@@ -7,10 +7,15 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"gully-backend/models"
 )
 
+// usernameCollation compares usernames ignoring case. It must match the
+// collation of the unique index from migration 8, so lookups can use it.
+var usernameCollation = &options.Collation{Locale: "en", Strength: 2}
+
 type UserRepo struct {
 	col *mongo.Collection
 }
@@ -28,7 +33,8 @@ func (r *UserRepo) Create(ctx context.Context, user *models.User) error {
 
 func (r *UserRepo) FindByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	err := r.col.FindOne(ctx, bson.M{"username": username}).Decode(&user)
+	err := r.col.FindOne(ctx, bson.M{"username": username},
+		options.FindOne().SetCollation(usernameCollation)).Decode(&user)
 	if err != nil {
 		return nil, err
 	}
@@ -43,3 +49,39 @@ func (r *UserRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models
 	}
 	return &user, nil
 }
+
+func (r *UserRepo) UpdatePassword(ctx context.Context, id primitive.ObjectID, hash string) error {
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"password": hash}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *UserRepo) UpdateProfile(ctx context.Context, user *models.User) error {
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{
+		"display_name": user.DisplayName,
+		"phone":        user.Phone,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *UserRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -1,58 +1,160 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 
+	"gully-backend/config"
 	"gully-backend/handlers"
 	"gully-backend/middleware"
-	ws "gully-backend/websocket"
 )
 
 func Setup(
 	r *gin.Engine,
-	jwtSecret string,
+	cfg *config.Config,
+	tokens middleware.TokenVerifier,
 	authHandler *handlers.AuthHandler,
+	userHandler *handlers.UserHandler,
 	groupHandler *handlers.GroupHandler,
 	playerHandler *handlers.PlayerHandler,
 	matchHandler *handlers.MatchHandler,
-	hub *ws.Hub,
+	statsHandler *handlers.StatsHandler,
+	shareHandler *handlers.ShareHandler,
 ) {
+	// Request limits per route group. Each group has its own buckets; a
+	// zero Limit leaves that side unlimited.
+	limits := middleware.NewMemoryRateLimitStore()
+	limit := func(group string, rule middleware.RateLimitRule) gin.HandlerFunc {
+		if !cfg.RateLimit {
+			return func(c *gin.Context) { c.Next() }
+		}
+		return middleware.RateLimit(limits, group, rule)
+	}
+	authLimit := limit("auth", middleware.RateLimitRule{
+		PerIP: middleware.Limit{Burst: 20, Per: time.Minute},
+	})
+	apiLimit := limit("api", middleware.RateLimitRule{
+		PerIP:   middleware.Limit{Burst: 600, Per: time.Minute},
+		PerUser: middleware.Limit{Burst: 300, Per: time.Minute},
+	})
+	// A rally a second is fast even for doubles; allow bursts from offline sync.
+	scoreLimit := limit("score", middleware.RateLimitRule{
+		PerUser: middleware.Limit{Burst: 60, Per: time.Minute},
+	})
+	// Join codes are short; keep guessing them slow.
+	joinLimit := limit("join", middleware.RateLimitRule{
+		PerIP:   middleware.Limit{Burst: 20, Per: time.Minute},
+		PerUser: middleware.Limit{Burst: 10, Per: time.Minute},
+	})
+	// Share tokens are long, but the routes are open to anyone.
+	shareLimit := limit("share", middleware.RateLimitRule{
+		PerIP: middleware.Limit{Burst: 60, Per: time.Minute},
+	})
+	// Exports read every match the user played in.
+	exportLimit := limit("export", middleware.RateLimitRule{
+		PerUser: middleware.Limit{Burst: 3, Per: time.Hour},
+	})
+
 	// Public routes
 	auth := r.Group("/api/auth")
+	auth.Use(authLimit)
 	{
 		auth.POST("/register", authHandler.Register)
 		auth.POST("/login", authHandler.Login)
+		auth.POST("/refresh", authHandler.Refresh)
+		auth.POST("/password-reset", authHandler.RequestPasswordReset)
+		auth.POST("/password-reset/confirm", authHandler.ResetPassword)
 	}
 
-	// WebSocket (no JWT — clients authenticate via group membership)
-	r.GET("/ws/group/:groupId", hub.HandleWebSocket)
+	// Group live feed, members only. The access token may be sent as
+	// ?token= since browsers cannot set headers on a WebSocket.
+	r.GET("/ws/group/:id", middleware.AuthMiddleware(tokens), apiLimit, groupHandler.WatchGroup)
+
+	// Share links: read-only, no login, the token is the credential.
+	r.GET("/api/share/:token", shareLimit, shareHandler.GetShared)
+	r.GET("/ws/share/:token", shareLimit, shareHandler.WatchShared)
+
+	// Retried score submissions replay the first response instead of double-counting.
+	idempotent := middleware.Idempotency(middleware.NewMemoryIdempotencyStore(), cfg.IdempotencyTTL)
 
 	// Protected routes
 	api := r.Group("/api")
-	api.Use(middleware.AuthMiddleware(jwtSecret))
+	api.Use(middleware.AuthMiddleware(tokens), apiLimit)
 	{
+		// Sessions
+		api.POST("/auth/logout", authHandler.Logout)
+		api.POST("/auth/logout-all", authHandler.LogoutAll)
+		api.POST("/auth/change-password", authHandler.ChangePassword)
+
 		// User
+		api.GET("/user/me", userHandler.GetMe)
+		api.PATCH("/user/me", userHandler.UpdateMe)
+		api.DELETE("/user/me", userHandler.DeleteMe)
+		api.GET("/user/me/export", exportLimit, userHandler.ExportMe)
 		api.GET("/user/groups", groupHandler.GetUserGroups)
 
 		// Groups
 		api.POST("/groups", groupHandler.CreateGroup)
-		api.POST("/groups/join", groupHandler.JoinGroup)
+		api.POST("/groups/join", joinLimit, groupHandler.JoinGroup)
 		api.GET("/groups/:id", groupHandler.GetGroup)
+		api.GET("/groups/:id/presence", groupHandler.GetPresence)
+		api.PATCH("/groups/:id", groupHandler.RenameGroup)
+		api.DELETE("/groups/:id", groupHandler.DeleteGroup)
+		api.POST("/groups/:id/leave", groupHandler.LeaveGroup)
+		api.DELETE("/groups/:id/members/:userId", groupHandler.RemoveMember)
+		api.POST("/groups/:id/join-code", groupHandler.RegenerateJoinCode)
+		api.PUT("/groups/:id/join-approval", groupHandler.SetJoinApproval)
+		api.GET("/groups/:id/settings", groupHandler.GetSettings)
+		api.PATCH("/groups/:id/settings", groupHandler.UpdateSettings)
+		api.GET("/groups/:id/invites", groupHandler.GetInvites)
+		api.POST("/groups/:id/invites", groupHandler.CreateInvite)
+		api.DELETE("/groups/:id/invites/:inviteId", groupHandler.RevokeInvite)
+		api.GET("/groups/:id/join-requests", groupHandler.GetJoinRequests)
+		api.POST("/groups/:id/join-requests/:requestId/approve", groupHandler.ApproveJoinRequest)
+		api.DELETE("/groups/:id/join-requests/:requestId", groupHandler.RejectJoinRequest)
+		api.GET("/groups/:id/share-links", shareHandler.GetShareLinks)
+		api.POST("/groups/:id/share-links", shareHandler.CreateShareLink)
+		api.DELETE("/groups/:id/share-links/:linkId", shareHandler.RevokeShareLink)
 
 		// Players
 		api.POST("/groups/:id/players", playerHandler.CreatePlayer)
 		api.GET("/groups/:id/players", playerHandler.GetPlayers)
+		api.PATCH("/groups/:id/players/:playerId", playerHandler.UpdatePlayer)
 		api.DELETE("/groups/:id/players/:playerId", playerHandler.DeletePlayer)
+		api.POST("/groups/:id/players/:playerId/restore", playerHandler.RestorePlayer)
+		api.GET("/groups/:id/players/merge/preview", playerHandler.PreviewMerge)
 		api.POST("/groups/:id/players/merge", playerHandler.MergePlayer)
+		api.GET("/groups/:id/players/merges", playerHandler.GetMerges)
+		api.POST("/groups/:id/players/merges/:mergeId/undo", playerHandler.UndoMerge)
+		api.POST("/groups/:id/players/:playerId/claim", playerHandler.ClaimPlayer)
+		api.POST("/groups/:id/players/:playerId/claim/approve", playerHandler.ApproveClaim)
+		api.DELETE("/groups/:id/players/:playerId/claim", playerHandler.RejectClaim)
+		api.DELETE("/groups/:id/players/:playerId/user", playerHandler.UnlinkPlayer)
 
 		// Matches
 		api.POST("/matches", matchHandler.CreateMatch)
-		api.POST("/matches/result", matchHandler.AddResult)
+		api.POST("/matches/result", idempotent, matchHandler.AddResult)
 		api.GET("/groups/:id/matches", matchHandler.GetMatches)
-		api.POST("/matches/:id/score", matchHandler.UpdateScore)
+		api.POST("/matches/:id/score", scoreLimit, idempotent, matchHandler.UpdateScore)
 		api.PUT("/matches/:id/score", matchHandler.EditScore)
-		api.POST("/matches/:id/undo", matchHandler.UndoScore)
-		api.POST("/matches/:id/finish", matchHandler.FinishMatch)
+		api.POST("/matches/:id/undo", idempotent, matchHandler.UndoScore)
+		api.POST("/matches/:id/warmup", matchHandler.WarmUpMatch)
+		api.POST("/matches/:id/start", matchHandler.StartMatch)
+		api.POST("/matches/:id/abandon", matchHandler.AbandonMatch)
+		api.POST("/matches/:id/walkover", matchHandler.WalkoverMatch)
+		api.POST("/matches/:id/pause", matchHandler.PauseMatch)
+		api.POST("/matches/:id/resume", matchHandler.ResumeMatch)
+		api.POST("/matches/:id/finish", idempotent, matchHandler.FinishMatch)
+		api.POST("/matches/:id/sync", idempotent, matchHandler.SyncMatch)
 		api.DELETE("/matches/:id", matchHandler.DeleteMatch)
+		api.GET("/matches/:id/timeline", matchHandler.GetTimeline)
+		api.POST("/matches/:id/scorer", matchHandler.ClaimScorer)
+		api.POST("/matches/:id/scorer/handover", matchHandler.HandOverScorer)
+		api.DELETE("/matches/:id/scorer", matchHandler.ReleaseScorer)
+
+		// Stats
+		api.GET("/groups/:id/stats", statsHandler.GetGroupStats)
+		api.GET("/user/stats", statsHandler.GetMyStats)
 	}
 }
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+// MaxDisplayNameLen caps display names, in characters.
+const MaxDisplayNameLen = 40
+
+// Phone numbers hold this many digits, as in E.164.
+const (
+	minPhoneDigits = 7
+	maxPhoneDigits = 15
+)
+
+var ErrInvalidProfile = errors.New("invalid profile")
+
+// ProfileUpdate is a partial edit of the user's profile; nil fields are left
+// alone and empty strings clear them.
+type ProfileUpdate struct {
+	DisplayName *string
+	Phone       *string
+}
+
+// AccountDeletion reports what deleting an account did to the user's groups.
+type AccountDeletion struct {
+	// GroupsTransferred were created by the user and now belong to the
+	// longest-standing other member.
+	GroupsTransferred []primitive.ObjectID `json:"groups_transferred"`
+	// GroupsDeleted were created by the user and had no other members.
+	GroupsDeleted     []primitive.ObjectID `json:"groups_deleted"`
+	PlayersAnonymised int                  `json:"players_anonymised"`
+
+	// GroupsLeft are the user's departures from groups that still exist,
+	// for telling their members.
+	GroupsLeft []*GroupDeparture `json:"-"`
+}
+
+// AccountService manages the signed-in user's own account.
+type AccountService struct {
+	userRepo  repositories.UserRepository
+	resetRepo repositories.PasswordResetRepository
+	groups    *GroupService
+	players   *PlayerService
+	auth      *AuthService
+	tx        repositories.Transactor
+}
+
+func NewAccountService(userRepo repositories.UserRepository, resetRepo repositories.PasswordResetRepository, groups *GroupService, players *PlayerService, auth *AuthService, tx repositories.Transactor) *AccountService {
+	return &AccountService{userRepo: userRepo, resetRepo: resetRepo, groups: groups, players: players, auth: auth, tx: tx}
+}
+
+func (s *AccountService) GetProfile(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	return s.userRepo.FindByID(ctx, userID)
+}
+
+// UpdateProfile sets the display name and phone. The display name is also
+// the name given to the user's player in groups they join from now on.
+func (s *AccountService) UpdateProfile(ctx context.Context, userID primitive.ObjectID, upd ProfileUpdate) (*models.User, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if upd.DisplayName != nil {
+		name := strings.Join(strings.Fields(*upd.DisplayName), " ")
+		if utf8.RuneCountInString(name) > MaxDisplayNameLen {
+			return nil, fmt.Errorf("%w: display name is longer than %d characters", ErrInvalidProfile, MaxDisplayNameLen)
+		}
+		user.DisplayName = name
+	}
+	if upd.Phone != nil {
+		phone, err := cleanPhone(*upd.Phone)
+		if err != nil {
+			return nil, err
+		}
+		user.Phone = phone
+	}
+	if err := s.userRepo.UpdateProfile(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// DeleteAccount removes the user after checking their password. Groups they
+// created pass to the next member, or are deleted with everything in them
+// if there is no one else. Their players are anonymised, so matches keep
+// their scores and stats for the other players. All sessions end.
+func (s *AccountService) DeleteAccount(ctx context.Context, userID primitive.ObjectID, password string) (*AccountDeletion, error) {
+	out := &AccountDeletion{GroupsTransferred: []primitive.ObjectID{}, GroupsDeleted: []primitive.ObjectID{}}
+	err := s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		user, err := s.userRepo.FindByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+			return ErrWrongPassword
+		}
+
+		groups, err := s.groups.GetUserGroups(ctx, userID)
+		if err != nil {
+			return err
+		}
+		var kept []primitive.ObjectID
+		for i := range groups {
+			g := &groups[i]
+			left, err := s.groups.removeUser(ctx, g, userID)
+			if err != nil {
+				return err
+			}
+			switch {
+			case left.Deleted:
+				out.GroupsDeleted = append(out.GroupsDeleted, g.ID)
+				continue
+			case left.NewOwner != nil:
+				out.GroupsTransferred = append(out.GroupsTransferred, g.ID)
+			}
+			out.GroupsLeft = append(out.GroupsLeft, left)
+			kept = append(kept, g.ID)
+		}
+
+		if out.PlayersAnonymised, err = s.players.forgetUser(ctx, userID, kept); err != nil {
+			return err
+		}
+		if err := s.groups.dropJoinRequests(ctx, userID); err != nil {
+			return err
+		}
+		if err := s.resetRepo.MarkUsedByUserID(ctx, userID, time.Now()); err != nil {
+			return err
+		}
+		if err := s.auth.revokeSessions(ctx, userID, models.RevokedDeleted); err != nil {
+			return err
+		}
+		return s.userRepo.Delete(ctx, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// cleanPhone drops spaces and separators and checks the digit count. A
+// leading '+' is kept.
+func cleanPhone(phone string) (string, error) {
+	phone = strings.TrimSpace(phone)
+	if phone == "" {
+		return "", nil
+	}
+	var b strings.Builder
+	for i, r := range phone {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '.':
+		default:
+			return "", fmt.Errorf("%w: phone may only contain digits, spaces, '-', '.', '(', ')' and a leading '+'", ErrInvalidProfile)
+		}
+	}
+	digits := len(strings.TrimPrefix(b.String(), "+"))
+	if digits < minPhoneDigits || digits > maxPhoneDigits {
+		return "", fmt.Errorf("%w: phone must have %d to %d digits", ErrInvalidProfile, minPhoneDigits, maxPhoneDigits)
+	}
+	return b.String(), nil
+}
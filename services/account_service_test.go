@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+	"gully-backend/repositories/memory"
+)
+
+type accountFixture struct {
+	users   *memory.UserRepo
+	groups  *memory.GroupRepo
+	players *memory.PlayerRepo
+	matches *memory.MatchRepo
+	events  *memory.MatchEventRepo
+	invites *memory.InviteRepo
+	joinReq *memory.JoinRequestRepo
+	auth    *AuthService
+	groupSv *GroupService
+	playSv  *PlayerService
+	matchSv *MatchService
+	svc     *AccountService
+	export  *ExportService
+	shares  *ShareService
+	links   *memory.ShareLinkRepo
+	merges  *memory.MergeRecordRepo
+}
+
+func newAccountFixture(t *testing.T) *accountFixture {
+	f := &accountFixture{
+		users:   memory.NewUserRepo(),
+		groups:  memory.NewGroupRepo(),
+		players: memory.NewPlayerRepo(),
+		matches: memory.NewMatchRepo(),
+		events:  memory.NewMatchEventRepo(),
+		invites: memory.NewInviteRepo(),
+		joinReq: memory.NewJoinRequestRepo(),
+		links:   memory.NewShareLinkRepo(),
+		merges:  memory.NewMergeRecordRepo(),
+	}
+	tx := memory.NewTransactor(f.users, f.groups, f.players, f.matches, f.events, f.invites, f.joinReq, f.links, f.merges)
+	f.auth = NewAuthService(f.users, memory.NewSessionRepo(), nil, NewHMACKeys("test-secret"), 0, 0)
+	f.playSv = NewPlayerService(f.players, f.matches, f.events, f.merges, tx)
+	f.matchSv = NewMatchService(f.matches, f.players, f.events, f.groups, tx)
+	f.groupSv = NewGroupService(f.groups, f.invites, f.joinReq, f.links, f.playSv, tx)
+	f.svc = NewAccountService(f.users, memory.NewPasswordResetRepo(), f.groupSv, f.playSv, f.auth, tx)
+	f.export = NewExportService(f.users, f.groups, f.players, f.matches)
+	f.shares = NewShareService(f.links, f.groups, f.matches)
+	return f
+}
+
+// member registers a user with a linked player in each group.
+func (f *accountFixture) member(t *testing.T, username string, groups ...*models.Group) (*models.User, []*models.Player) {
+	ctx := context.Background()
+	user, err := f.auth.Register(ctx, username, "password123")
+	require.NoError(t, err)
+	var players []*models.Player
+	for _, g := range groups {
+		if g.CreatedBy != user.ID {
+			_, _, err := f.groupSv.JoinGroup(ctx, g.JoinCode, user.ID, user.Username)
+			require.NoError(t, err)
+		}
+		p, err := f.playSv.CreatePlayerIfNotExists(ctx, user.ID, user.Name(), g.ID)
+		require.NoError(t, err)
+		players = append(players, p)
+	}
+	return user, players
+}
+
+func (f *accountFixture) match(t *testing.T, groupID primitive.ObjectID, team1, team2 *models.Player) *models.Match {
+	match := makeLiveMatch([]primitive.ObjectID{team1.ID}, []primitive.ObjectID{team2.ID})
+	match.GroupID = groupID
+	match.Team1Names, match.Team2Names = []string{team1.Name}, []string{team2.Name}
+	match.Score1, match.Score2 = 21, 15
+	match.Status = models.MatchStatusFinished
+	require.NoError(t, f.matches.Create(context.Background(), match))
+	return match
+}
+
+func TestUpdateProfile(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	user, _ := f.member(t, "alice")
+
+	name, phone := "  Alice   Smith ", "+44 (0)20 7946-0958"
+	got, err := f.svc.UpdateProfile(ctx, user.ID, ProfileUpdate{DisplayName: &name, Phone: &phone})
+	require.NoError(t, err)
+	assert.Equal(t, "Alice Smith", got.DisplayName)
+	assert.Equal(t, "+4402079460958", got.Phone)
+	assert.Equal(t, "Alice Smith", got.Name())
+
+	// Nil fields are left alone; empty strings clear.
+	empty := ""
+	got, err = f.svc.UpdateProfile(ctx, user.ID, ProfileUpdate{Phone: &empty})
+	require.NoError(t, err)
+	assert.Equal(t, "Alice Smith", got.DisplayName)
+	assert.Empty(t, got.Phone)
+
+	stored, err := f.svc.GetProfile(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice Smith", stored.DisplayName)
+	assert.Empty(t, stored.Phone)
+}
+
+func TestUpdateProfile_Invalid(t *testing.T) {
+	f := newAccountFixture(t)
+	user, _ := f.member(t, "alice")
+
+	long := "abcdefghij abcdefghij abcdefghij abcdefghij"
+	letters, short := "555-CALL-NOW", "12345"
+	for _, upd := range []ProfileUpdate{{DisplayName: &long}, {Phone: &letters}, {Phone: &short}} {
+		_, err := f.svc.UpdateProfile(context.Background(), user.ID, upd)
+		assert.ErrorIs(t, err, ErrInvalidProfile)
+	}
+}
+
+func TestDeleteAccount_WrongPassword(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	user, _ := f.member(t, "alice")
+
+	_, err := f.svc.DeleteAccount(ctx, user.ID, "wrong-password1")
+	assert.ErrorIs(t, err, ErrWrongPassword)
+	_, err = f.users.FindByID(ctx, user.ID)
+	assert.NoError(t, err, "nothing deleted")
+}
+
+func TestDeleteAccount(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	alice, err := f.auth.Register(ctx, "alice", "password123")
+	require.NoError(t, err)
+	shared, err := f.groupSv.CreateGroup(ctx, "Club", alice.ID)
+	require.NoError(t, err)
+	solo, err := f.groupSv.CreateGroup(ctx, "Practice", alice.ID)
+	require.NoError(t, err)
+	alicePlayers := make([]*models.Player, 0, 2)
+	for _, g := range []*models.Group{shared, solo} {
+		p, err := f.playSv.CreatePlayerIfNotExists(ctx, alice.ID, alice.Name(), g.ID)
+		require.NoError(t, err)
+		alicePlayers = append(alicePlayers, p)
+	}
+	bob, bobPlayers := f.member(t, "bob", shared)
+	carol, carolPlayers := f.member(t, "carol", shared)
+
+	played := f.match(t, shared.ID, alicePlayers[0], bobPlayers[0])
+	other := f.match(t, shared.ID, bobPlayers[0], carolPlayers[0])
+	f.match(t, solo.ID, alicePlayers[1], alicePlayers[1])
+	tokens, _, err := f.auth.Login(ctx, "alice", "password123")
+	require.NoError(t, err)
+
+	deleted, err := f.svc.DeleteAccount(ctx, alice.ID, "password123")
+	require.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{shared.ID}, deleted.GroupsTransferred)
+	assert.Equal(t, []primitive.ObjectID{solo.ID}, deleted.GroupsDeleted)
+	assert.Equal(t, 1, deleted.PlayersAnonymised)
+
+	// The shared group passes to the next member; the solo one is gone
+	// with its players and matches.
+	group, err := f.groups.FindByID(ctx, shared.ID)
+	require.NoError(t, err)
+	assert.Equal(t, bob.ID, group.CreatedBy)
+	assert.Equal(t, []primitive.ObjectID{bob.ID, carol.ID}, group.Members)
+	_, err = f.groups.FindByID(ctx, solo.ID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	soloPlayers, err := f.players.FindByGroupID(ctx, solo.ID)
+	require.NoError(t, err)
+	assert.Empty(t, soloPlayers)
+	soloMatches, err := f.matches.FindByGroupID(ctx, solo.ID)
+	require.NoError(t, err)
+	assert.Empty(t, soloMatches)
+
+	// Alice's player is anonymised but her matches stay.
+	player, err := f.players.FindByID(ctx, alicePlayers[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, AnonymousPlayerName, player.Name)
+	assert.Nil(t, player.UserID)
+	assert.True(t, player.Archived())
+	match, err := f.matches.FindByID(ctx, played.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{AnonymousPlayerName}, match.Team1Names)
+	assert.Equal(t, []string{"bob"}, match.Team2Names)
+	assert.Equal(t, 21, match.Score1)
+	untouched, err := f.matches.FindByID(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, untouched.Team1Names)
+	assert.Equal(t, []string{"carol"}, untouched.Team2Names)
+
+	// Sessions end and the username is free again.
+	_, err = f.auth.Refresh(ctx, tokens.RefreshToken)
+	assert.Error(t, err)
+	_, err = f.users.FindByID(ctx, alice.ID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	_, err = f.auth.Register(ctx, "Alice", "password456")
+	assert.NoError(t, err)
+}
+
+func TestDeleteAccount_ScrubsHistory(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	bob, err := f.auth.Register(ctx, "bob", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", bob.ID)
+	require.NoError(t, err)
+	bobPlayer, err := f.playSv.CreatePlayerIfNotExists(ctx, bob.ID, bob.Name(), group.ID)
+	require.NoError(t, err)
+	alice, alicePlayers := f.member(t, "alice", group)
+	guest, err := f.playSv.CreatePlayer(ctx, "Ally", group.ID)
+	require.NoError(t, err)
+
+	// Alice scores a match as her own player, then merges that player into
+	// the guest, which takes over her link.
+	aliceCtx := WithActor(ctx, Actor{UserID: alice.ID.Hex(), Username: alice.Username, DeviceID: "phone"})
+	match, err := f.matchSv.CreateMatch(aliceCtx, group.ID, []primitive.ObjectID{alicePlayers[0].ID}, []primitive.ObjectID{bobPlayer.ID}, "")
+	require.NoError(t, err)
+	_, err = f.matchSv.UpdateScore(aliceCtx, match.ID, 1, alicePlayers[0].ID.Hex())
+	require.NoError(t, err)
+	_, err = f.playSv.MergePlayer(aliceCtx, group.ID, guest.ID, alicePlayers[0].ID)
+	require.NoError(t, err)
+
+	_, err = f.svc.DeleteAccount(ctx, alice.ID, "password123")
+	require.NoError(t, err)
+
+	timeline, err := f.matchSv.GetTimeline(ctx, match.ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, timeline)
+	records, err := f.merges.FindByGroupID(ctx, group.ID)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	for name, v := range map[string]interface{}{"timeline": timeline, "merges": records} {
+		raw, err := json.Marshal(v)
+		require.NoError(t, err)
+		for _, leak := range []string{"alice", alice.ID.Hex(), `"Ally"`} {
+			assert.NotContains(t, string(raw), leak, name)
+		}
+		assert.Contains(t, string(raw), AnonymousPlayerName, name)
+		assert.Contains(t, string(raw), "bob", name)
+	}
+
+	// The timeline still replays to the match as it is now.
+	replayed, _, err := ReplayMatch(timeline)
+	require.NoError(t, err)
+	current, err := f.matches.FindByID(ctx, match.ID)
+	require.NoError(t, err)
+	assert.Equal(t, current.Team1Names, replayed.Team1Names)
+	assert.Equal(t, 1, replayed.Score1)
+}
+
+func TestRegister_Validation(t *testing.T) {
+	svc := NewAuthService(memory.NewUserRepo(), memory.NewSessionRepo(), nil, NewHMACKeys("test-secret"), 0, 0)
+	ctx := context.Background()
+
+	for _, name := range []string{"al", "1alice", "alice smith", "alice!", "abcdefghijklmnopqrstu"} {
+		_, err := svc.Register(ctx, name, "password123")
+		assert.ErrorIs(t, err, ErrInvalidUsername, name)
+	}
+	for _, pw := range []string{"short1", "onlyletters", "12345678", "xalice123x"} {
+		_, err := svc.Register(ctx, "alice", pw)
+		assert.ErrorIs(t, err, ErrWeakPassword, pw)
+	}
+
+	_, err := svc.Register(ctx, " alice ", "password123")
+	require.NoError(t, err)
+	_, err = svc.Register(ctx, "ALICE", "password123")
+	assert.ErrorIs(t, err, ErrUsernameTaken)
+}
@@ -0,0 +1,23 @@
+package services
+
+import "context"
+
+// Actor identifies who is making a change and from which device.
+type Actor struct {
+	UserID   string
+	Username string
+	DeviceID string
+}
+
+type actorKey struct{}
+
+// WithActor returns a context carrying the acting user.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the acting user, or the zero Actor for system changes.
+func ActorFrom(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
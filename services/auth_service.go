@@ -2,30 +2,116 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 
 	"gully-backend/models"
 	"gully-backend/repositories"
 )
 
+// Token lifetimes used when none are configured. Access tokens are checked
+// against their session on every request, so a revoked session stops
+// working at once; the short lifetime bounds a token lifted off a device.
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// jwtLeeway absorbs clock skew between instances when checking exp, nbf
+// and iat.
+const jwtLeeway = 30 * time.Second
+
+// Username length limits for new accounts.
+const (
+	MinUsernameLen = 3
+	MaxUsernameLen = 20
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.]*$`)
+
+// dummyPasswordHash is checked against when the username is unknown, so a
+// failed login takes as long whether or not the account exists.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("not anyone's password"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(fmt.Sprintf("services: dummy password hash: %v", err))
+	}
+	return hash
+})
+
+var (
+	ErrInvalidUsername    = errors.New("invalid username")
+	ErrUsernameTaken      = errors.New("username already taken")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrSessionRevoked     = errors.New("session has been revoked")
+	ErrRefreshReused      = errors.New("refresh token was already used; the session has been revoked")
+)
+
+// TokenPair is what a login or refresh hands the client.
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // access token lifetime, seconds
+}
+
+// AccessClaims identifies the user and session an access token was issued to.
+type AccessClaims struct {
+	UserID    string
+	Username  string
+	SessionID string
+}
+
 type AuthService struct {
-	userRepo  repositories.UserRepository
-	jwtSecret string
+	userRepo    repositories.UserRepository
+	sessionRepo repositories.SessionRepository
+	keys        *JWTKeys
+	accessTTL   time.Duration
+	refreshTTL  time.Duration
+	attempts    LoginAttemptStore
 }
 
-func NewAuthService(userRepo repositories.UserRepository, jwtSecret string) *AuthService {
-	return &AuthService{userRepo: userRepo, jwtSecret: jwtSecret}
+// NewAuthService creates the service; zero TTLs fall back to the defaults
+// and a nil attempts store to an in-memory one.
+func NewAuthService(userRepo repositories.UserRepository, sessionRepo repositories.SessionRepository, attempts LoginAttemptStore, keys *JWTKeys, accessTTL, refreshTTL time.Duration) *AuthService {
+	if accessTTL <= 0 {
+		accessTTL = DefaultAccessTokenTTL
+	}
+	if refreshTTL <= 0 {
+		refreshTTL = DefaultRefreshTokenTTL
+	}
+	if attempts == nil {
+		attempts = NewMemoryLoginAttempts()
+	}
+	return &AuthService{userRepo: userRepo, sessionRepo: sessionRepo, attempts: attempts, keys: keys, accessTTL: accessTTL, refreshTTL: refreshTTL}
 }
 
+// Register creates an account. Usernames follow ValidateUsername and are
+// unique ignoring case; passwords must pass the strength rules.
 func (s *AuthService) Register(ctx context.Context, username, password string) (*models.User, error) {
-	// Check if username already exists
+	username = strings.TrimSpace(username)
+	if err := ValidateUsername(username); err != nil {
+		return nil, err
+	}
+	if err := validatePassword(password, username); err != nil {
+		return nil, err
+	}
 	existing, _ := s.userRepo.FindByUsername(ctx, username)
 	if existing != nil {
-		return nil, errors.New("username already taken")
+		return nil, ErrUsernameTaken
 	}
 
 	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -38,34 +124,236 @@ func (s *AuthService) Register(ctx context.Context, username, password string) (
 		Password: string(hashed),
 	}
 	if err := s.userRepo.Create(ctx, user); err != nil {
+		if repositories.IsDuplicateKey(err) {
+			return nil, ErrUsernameTaken
+		}
 		return nil, err
 	}
 	return user, nil
 }
 
-func (s *AuthService) Login(ctx context.Context, username, password string) (string, *models.User, error) {
+// ValidateUsername checks a new username: MinUsernameLen to MaxUsernameLen
+// ASCII letters, digits, '_' and '.', starting with a letter. Accounts made
+// before these rules keep their names.
+func ValidateUsername(username string) error {
+	if len(username) < MinUsernameLen || len(username) > MaxUsernameLen {
+		return fmt.Errorf("%w: use %d to %d characters", ErrInvalidUsername, MinUsernameLen, MaxUsernameLen)
+	}
+	if !usernamePattern.MatchString(username) {
+		return fmt.Errorf("%w: use letters, digits, '_' and '.', starting with a letter", ErrInvalidUsername)
+	}
+	return nil
+}
+
+// Login checks the password and starts a session. Repeated failures lock
+// the username out for a while, whether or not the account exists, and a
+// locked username is refused even with the right password.
+func (s *AuthService) Login(ctx context.Context, username, password string) (*TokenPair, *models.User, error) {
+	key := loginKey(username)
+	if err := s.loginLocked(key); err != nil {
+		return nil, nil, err
+	}
+
 	user, err := s.userRepo.FindByUsername(ctx, username)
 	if err != nil {
-		return "", nil, errors.New("invalid credentials")
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return nil, nil, s.loginFailed(key)
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", nil, errors.New("invalid credentials")
+		return nil, nil, s.loginFailed(key)
+	}
+	s.attempts.Reset(key)
+
+	tokens, err := s.startSession(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, user, nil
+}
+
+// loginFailed counts a failed login and locks the username out once there
+// have been too many in a row.
+// loginLocked returns a LoginLockedError while key is locked out.
+func (s *AuthService) loginLocked(key string) error {
+	if until := s.attempts.LockedUntil(key); time.Now().Before(until) {
+		return &LoginLockedError{RetryAfter: time.Until(until)}
+	}
+	return nil
+}
+
+func (s *AuthService) loginFailed(key string) error {
+	lockout := lockoutFor(s.attempts.Fail(key, LoginLockoutMax))
+	if lockout == 0 {
+		return ErrInvalidCredentials
+	}
+	s.attempts.Lock(key, time.Now().Add(lockout))
+	return &LoginLockedError{RetryAfter: lockout}
+}
+
+// Refresh trades a refresh token for a new access token and a new refresh
+// token. Each refresh token works once: presenting one that was already
+// rotated means it was copied, so the session is revoked for everyone
+// holding its tokens. Any other wrong token is just invalid; session IDs
+// are guessable, so a bad secret must not be able to end a session.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	sessionHex, secret, ok := strings.Cut(refreshToken, ".")
+	sessionID, err := primitive.ObjectIDFromHex(sessionHex)
+	if !ok || err != nil || secret == "" {
+		return nil, ErrInvalidToken
+	}
+	session, err := s.sessionRepo.FindByID(ctx, sessionID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	now := time.Now()
+	if session.RevokedAt != nil {
+		return nil, ErrSessionRevoked
 	}
+	if !session.Active(now) {
+		return nil, ErrInvalidToken
+	}
+
+	oldHash := hashToken(secret)
+	if subtle.ConstantTimeCompare([]byte(oldHash), []byte(session.TokenHash)) != 1 {
+		if session.WasRotated(oldHash) {
+			return nil, s.revokeReused(ctx, session.ID)
+		}
+		return nil, ErrInvalidToken
+	}
+	refresh, newHash, err := newRefreshSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.Rotate(ctx, session.ID, oldHash, newHash, now, now.Add(s.refreshTTL)); err != nil {
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			// Someone else rotated it between our read and write.
+			return nil, s.revokeReused(ctx, session.ID)
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, session.UserID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	return s.issue(user, session.ID, refresh)
+}
+
+// Logout ends one session.
+func (s *AuthService) Logout(ctx context.Context, sessionID primitive.ObjectID) error {
+	return s.sessionRepo.Revoke(ctx, sessionID, models.RevokedLogout)
+}
 
-	token, err := s.generateJWT(user)
+// LogoutAll ends every session of the user, on all devices.
+func (s *AuthService) LogoutAll(ctx context.Context, userID primitive.ObjectID) error {
+	return s.revokeSessions(ctx, userID, models.RevokedLogoutAll)
+}
+
+func (s *AuthService) revokeSessions(ctx context.Context, userID primitive.ObjectID, reason string) error {
+	return s.sessionRepo.RevokeByUserID(ctx, userID, reason)
+}
+
+// VerifyAccessToken checks an access token's signature and expiry, and that
+// its session has not been revoked.
+func (s *AuthService) VerifyAccessToken(ctx context.Context, tokenStr string) (*AccessClaims, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, s.keys.verifyKey,
+		jwt.WithValidMethods([]string{s.keys.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithIssuer(s.keys.Issuer),
+		jwt.WithAudience(s.keys.Audience),
+		jwt.WithLeeway(jwtLeeway))
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	out := &AccessClaims{}
+	out.UserID, _ = claims["user_id"].(string)
+	out.Username, _ = claims["username"].(string)
+	out.SessionID, _ = claims["sid"].(string)
+
+	// Tokens from before sessions existed have no sid and are refused.
+	sessionID, err := primitive.ObjectIDFromHex(out.SessionID)
 	if err != nil {
-		return "", nil, err
+		return nil, ErrInvalidToken
+	}
+	session, err := s.sessionRepo.FindByID(ctx, sessionID)
+	if err != nil || session.UserID.Hex() != out.UserID {
+		return nil, ErrInvalidToken
+	}
+	if !session.Active(time.Now()) {
+		return nil, ErrSessionRevoked
 	}
-	return token, user, nil
+	return out, nil
 }
 
-func (s *AuthService) generateJWT(user *models.User) (string, error) {
+// startSession opens a session on the caller's device and issues its tokens.
+func (s *AuthService) startSession(ctx context.Context, user *models.User) (*TokenPair, error) {
+	refresh, hash, err := newRefreshSecret()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session := &models.Session{
+		UserID:     user.ID,
+		TokenHash:  hash,
+		DeviceID:   ActorFrom(ctx).DeviceID,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(s.refreshTTL),
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+	return s.issue(user, session.ID, refresh)
+}
+
+func (s *AuthService) revokeReused(ctx context.Context, sessionID primitive.ObjectID) error {
+	if err := s.sessionRepo.Revoke(ctx, sessionID, models.RevokedReuse); err != nil {
+		return err
+	}
+	return ErrRefreshReused
+}
+
+func (s *AuthService) issue(user *models.User, sessionID primitive.ObjectID, refreshSecret string) (*TokenPair, error) {
+	access, err := s.generateJWT(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: sessionID.Hex() + "." + refreshSecret,
+		ExpiresIn:    int(s.accessTTL / time.Second),
+	}, nil
+}
+
+func (s *AuthService) generateJWT(user *models.User, sessionID primitive.ObjectID) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"user_id":  user.ID.Hex(),
 		"username": user.Username,
-		"exp":      time.Now().Add(7 * 24 * time.Hour).Unix(),
+		"sid":      sessionID.Hex(),
+		"iss":      s.keys.Issuer,
+		"aud":      s.keys.Audience,
+		"iat":      now.Unix(),
+		"nbf":      now.Unix(),
+		"exp":      now.Add(s.accessTTL).Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.jwtSecret))
+	return s.keys.sign(claims)
+}
+
+// newRefreshSecret returns a random token secret and the hash stored for it.
+func newRefreshSecret() (secret, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret = base64.RawURLEncoding.EncodeToString(b)
+	return secret, hashToken(secret), nil
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
 }
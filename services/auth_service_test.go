@@ -11,11 +11,12 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	"gully-backend/models"
+	"gully-backend/repositories/memory"
 )
 
 func TestRegister_Success(t *testing.T) {
 	userRepo := new(MockUserRepo)
-	svc := NewAuthService(userRepo, "test-secret")
+	svc := NewAuthService(userRepo, memory.NewSessionRepo(), nil, NewHMACKeys("test-secret"), 0, 0)
 	ctx := context.Background()
 
 	userRepo.On("FindByUsername", ctx, "alice").Return(nil, errors.New("not found"))
@@ -34,7 +35,7 @@ func TestRegister_Success(t *testing.T) {
 
 func TestRegister_UsernameTaken(t *testing.T) {
 	userRepo := new(MockUserRepo)
-	svc := NewAuthService(userRepo, "test-secret")
+	svc := NewAuthService(userRepo, memory.NewSessionRepo(), nil, NewHMACKeys("test-secret"), 0, 0)
 	ctx := context.Background()
 
 	existing := &models.User{
@@ -53,7 +54,7 @@ func TestRegister_UsernameTaken(t *testing.T) {
 
 func TestLogin_Success(t *testing.T) {
 	userRepo := new(MockUserRepo)
-	svc := NewAuthService(userRepo, "test-secret")
+	svc := NewAuthService(userRepo, memory.NewSessionRepo(), nil, NewHMACKeys("test-secret"), 0, 0)
 	ctx := context.Background()
 
 	hashed, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
@@ -64,17 +65,18 @@ func TestLogin_Success(t *testing.T) {
 	}
 	userRepo.On("FindByUsername", ctx, "alice").Return(existingUser, nil)
 
-	token, user, err := svc.Login(ctx, "alice", "password123")
+	tokens, user, err := svc.Login(ctx, "alice", "password123")
 
 	assert.NoError(t, err)
-	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
 	assert.Equal(t, "alice", user.Username)
 	userRepo.AssertExpectations(t)
 }
 
 func TestLogin_WrongPassword(t *testing.T) {
 	userRepo := new(MockUserRepo)
-	svc := NewAuthService(userRepo, "test-secret")
+	svc := NewAuthService(userRepo, memory.NewSessionRepo(), nil, NewHMACKeys("test-secret"), 0, 0)
 	ctx := context.Background()
 
 	hashed, _ := bcrypt.GenerateFromPassword([]byte("correctpass"), bcrypt.DefaultCost)
@@ -85,10 +87,10 @@ func TestLogin_WrongPassword(t *testing.T) {
 	}
 	userRepo.On("FindByUsername", ctx, "alice").Return(existingUser, nil)
 
-	token, user, err := svc.Login(ctx, "alice", "wrongpass")
+	tokens, user, err := svc.Login(ctx, "alice", "wrongpass")
 
 	assert.Error(t, err)
-	assert.Empty(t, token)
+	assert.Nil(t, tokens)
 	assert.Nil(t, user)
 	assert.Contains(t, err.Error(), "invalid credentials")
 	userRepo.AssertExpectations(t)
@@ -96,22 +98,22 @@ func TestLogin_WrongPassword(t *testing.T) {
 
 func TestLogin_UserNotFound(t *testing.T) {
 	userRepo := new(MockUserRepo)
-	svc := NewAuthService(userRepo, "test-secret")
+	svc := NewAuthService(userRepo, memory.NewSessionRepo(), nil, NewHMACKeys("test-secret"), 0, 0)
 	ctx := context.Background()
 
 	userRepo.On("FindByUsername", ctx, "nobody").Return(nil, errors.New("not found"))
 
-	token, user, err := svc.Login(ctx, "nobody", "password123")
+	tokens, user, err := svc.Login(ctx, "nobody", "password123")
 
 	assert.Error(t, err)
-	assert.Empty(t, token)
+	assert.Nil(t, tokens)
 	assert.Nil(t, user)
 	userRepo.AssertExpectations(t)
 }
 
 func TestGenerateJWT_ContainsUserID(t *testing.T) {
 	userRepo := new(MockUserRepo)
-	svc := NewAuthService(userRepo, "test-secret")
+	svc := NewAuthService(userRepo, memory.NewSessionRepo(), nil, NewHMACKeys("test-secret"), 0, 0)
 	ctx := context.Background()
 
 	hashed, _ := bcrypt.GenerateFromPassword([]byte("pass"), bcrypt.DefaultCost)
@@ -122,13 +124,13 @@ func TestGenerateJWT_ContainsUserID(t *testing.T) {
 	}
 	userRepo.On("FindByUsername", ctx, "alice").Return(user, nil)
 
-	token, _, err := svc.Login(ctx, "alice", "pass")
+	tokens, _, err := svc.Login(ctx, "alice", "pass")
 
 	assert.NoError(t, err)
-	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, tokens.AccessToken)
 	// Token should be a valid JWT (3 parts separated by dots)
 	parts := 0
-	for _, ch := range token {
+	for _, ch := range tokens.AccessToken {
 		if ch == '.' {
 			parts++
 		}
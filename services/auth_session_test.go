@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/repositories/memory"
+)
+
+func newSessionAuth(t *testing.T) (*AuthService, *memory.SessionRepo) {
+	sessions := memory.NewSessionRepo()
+	svc := NewAuthService(memory.NewUserRepo(), sessions, nil, NewHMACKeys("test-secret"), 0, 0)
+	_, err := svc.Register(context.Background(), "alice", "password123")
+	require.NoError(t, err)
+	return svc, sessions
+}
+
+func TestRefresh_RotatesAndDetectsReuse(t *testing.T) {
+	svc, sessions := newSessionAuth(t)
+	ctx := WithActor(context.Background(), Actor{DeviceID: "phone"})
+
+	first, user, err := svc.Login(ctx, "alice", "password123")
+	require.NoError(t, err)
+	assert.Equal(t, int(DefaultAccessTokenTTL/time.Second), first.ExpiresIn)
+	claims, err := svc.VerifyAccessToken(ctx, first.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID.Hex(), claims.UserID)
+	assert.Equal(t, "alice", claims.Username)
+
+	sessionID, err := primitive.ObjectIDFromHex(claims.SessionID)
+	require.NoError(t, err)
+	session, err := sessions.FindByID(ctx, sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, "phone", session.DeviceID)
+
+	second, err := svc.Refresh(ctx, first.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	_, err = svc.VerifyAccessToken(ctx, second.AccessToken)
+	require.NoError(t, err)
+
+	// The first refresh token coming back means it was copied: the whole
+	// session goes, including the tokens just issued.
+	_, err = svc.Refresh(ctx, first.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshReused)
+	_, err = svc.Refresh(ctx, second.RefreshToken)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+	_, err = svc.VerifyAccessToken(ctx, second.AccessToken)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+}
+
+func TestRefresh_MalformedToken(t *testing.T) {
+	svc, _ := newSessionAuth(t)
+	ctx := context.Background()
+	for _, token := range []string{"", "nodot", "zz.secret", primitive.NewObjectID().Hex() + ".secret"} {
+		_, err := svc.Refresh(ctx, token)
+		assert.ErrorIs(t, err, ErrInvalidToken, token)
+	}
+}
+
+func TestRefresh_ForgedSecretDoesNotRevoke(t *testing.T) {
+	svc, _ := newSessionAuth(t)
+	ctx := context.Background()
+	tokens, _, err := svc.Login(ctx, "alice", "password123")
+	require.NoError(t, err)
+
+	// Session IDs can be guessed; a made-up secret for one is just invalid.
+	sessionHex, _, _ := strings.Cut(tokens.RefreshToken, ".")
+	_, err = svc.Refresh(ctx, sessionHex+".garbage")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = svc.VerifyAccessToken(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	next, err := svc.Refresh(ctx, tokens.RefreshToken)
+	require.NoError(t, err)
+
+	// Reuse is still caught after several rotations.
+	_, err = svc.Refresh(ctx, next.RefreshToken)
+	require.NoError(t, err)
+	_, err = svc.Refresh(ctx, tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshReused)
+}
+
+func TestLogout_RevokesOneSession(t *testing.T) {
+	svc, _ := newSessionAuth(t)
+	ctx := context.Background()
+	phone, _, err := svc.Login(ctx, "alice", "password123")
+	require.NoError(t, err)
+	tablet, _, err := svc.Login(ctx, "alice", "password123")
+	require.NoError(t, err)
+
+	claims, err := svc.VerifyAccessToken(ctx, phone.AccessToken)
+	require.NoError(t, err)
+	sessionID, _ := primitive.ObjectIDFromHex(claims.SessionID)
+	require.NoError(t, svc.Logout(ctx, sessionID))
+
+	_, err = svc.VerifyAccessToken(ctx, phone.AccessToken)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+	_, err = svc.Refresh(ctx, phone.RefreshToken)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+	_, err = svc.VerifyAccessToken(ctx, tablet.AccessToken)
+	assert.NoError(t, err)
+}
+
+func TestLogoutAll_RevokesEverySession(t *testing.T) {
+	svc, _ := newSessionAuth(t)
+	ctx := context.Background()
+	phone, user, err := svc.Login(ctx, "alice", "password123")
+	require.NoError(t, err)
+	tablet, _, err := svc.Login(ctx, "alice", "password123")
+	require.NoError(t, err)
+
+	require.NoError(t, svc.LogoutAll(ctx, user.ID))
+
+	for _, tokens := range []*TokenPair{phone, tablet} {
+		_, err = svc.VerifyAccessToken(ctx, tokens.AccessToken)
+		assert.ErrorIs(t, err, ErrSessionRevoked)
+		_, err = svc.Refresh(ctx, tokens.RefreshToken)
+		assert.ErrorIs(t, err, ErrSessionRevoked)
+	}
+}
+
+func TestVerifyAccessToken_RejectsForeignTokens(t *testing.T) {
+	svc, _ := newSessionAuth(t)
+	ctx := context.Background()
+	tokens, user, err := svc.Login(ctx, "alice", "password123")
+	require.NoError(t, err)
+	claims, err := svc.VerifyAccessToken(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+
+	sign := func(method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+		s, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		require.NoError(t, err)
+		return s
+	}
+	// Each case breaks one thing about an otherwise valid token.
+	token := func(method jwt.SigningMethod, key interface{}, edit func(jwt.MapClaims)) string {
+		now := time.Now()
+		c := jwt.MapClaims{
+			"user_id": user.ID.Hex(), "username": "alice", "sid": claims.SessionID,
+			"iss": DefaultJWTIssuer, "aud": DefaultJWTAudience,
+			"iat": now.Unix(), "nbf": now.Unix(), "exp": now.Add(time.Hour).Unix(),
+		}
+		edit(c)
+		return sign(method, key, c)
+	}
+	hs256 := func(edit func(jwt.MapClaims)) string {
+		return token(jwt.SigningMethodHS256, []byte("test-secret"), edit)
+	}
+	_, err = svc.VerifyAccessToken(ctx, hs256(func(jwt.MapClaims) {}))
+	require.NoError(t, err, "the unedited token is valid")
+
+	later := time.Now().Add(10 * time.Minute).Unix()
+	cases := map[string]string{
+		"no session (pre-session token)": hs256(func(c jwt.MapClaims) { delete(c, "sid") }),
+		"expired":                        hs256(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }),
+		"no expiry":                      hs256(func(c jwt.MapClaims) { delete(c, "exp") }),
+		"not yet valid":                  hs256(func(c jwt.MapClaims) { c["nbf"] = later }),
+		"issued in the future":           hs256(func(c jwt.MapClaims) { c["iat"] = later }),
+		"no issuer":                      hs256(func(c jwt.MapClaims) { delete(c, "iss") }),
+		"other issuer":                   hs256(func(c jwt.MapClaims) { c["iss"] = "someone-else" }),
+		"other audience":                 hs256(func(c jwt.MapClaims) { c["aud"] = "other-app" }),
+		"other user's session":           hs256(func(c jwt.MapClaims) { c["user_id"] = primitive.NewObjectID().Hex() }),
+		"other secret":                   token(jwt.SigningMethodHS256, []byte("other-secret"), func(jwt.MapClaims) {}),
+		"unsigned":                       token(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, func(jwt.MapClaims) {}),
+		"HS384":                          token(jwt.SigningMethodHS384, []byte("test-secret"), func(jwt.MapClaims) {}),
+	}
+	for name, token := range cases {
+		_, err := svc.VerifyAccessToken(ctx, token)
+		assert.ErrorIs(t, err, ErrInvalidToken, name)
+	}
+}
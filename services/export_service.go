@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+// exportedGroup is a group as it appears in an export: what it is and the
+// user's place in it, without its join code or the other members.
+type exportedGroup struct {
+	ID        primitive.ObjectID   `json:"id"`
+	Name      string               `json:"name"`
+	CreatedAt time.Time            `json:"created_at"`
+	Owner     bool                 `json:"owner"`
+	Settings  models.GroupSettings `json:"settings"`
+}
+
+// ratingsHistory stands in for the ratings history a data access request
+// may ask about: no ratings are computed, so there is none to give.
+var ratingsHistory = struct {
+	Applicable bool   `json:"applicable"`
+	Note       string `json:"note"`
+}{false, "player ratings are not computed, so no ratings history is stored"}
+
+// ExportService writes out everything stored about a user, for data access
+// requests.
+type ExportService struct {
+	userRepo   repositories.UserRepository
+	groupRepo  repositories.GroupRepository
+	playerRepo repositories.PlayerRepository
+	matchRepo  repositories.MatchRepository
+}
+
+func NewExportService(userRepo repositories.UserRepository, groupRepo repositories.GroupRepository, playerRepo repositories.PlayerRepository, matchRepo repositories.MatchRepository) *ExportService {
+	return &ExportService{userRepo: userRepo, groupRepo: groupRepo, playerRepo: playerRepo, matchRepo: matchRepo}
+}
+
+// Export writes one JSON document with the user's account (without the
+// password hash), their groups (without join codes or member lists), the
+// players linked to them and every match those players appear in, score
+// history included, plus a note that there is no ratings history. Matches are read and
+// written a page at a time, so the export never sits in memory whole.
+//
+// The user is looked up before anything is written: a missing user is an
+// error with w untouched. Later errors leave the document truncated.
+func (s *ExportService) Export(ctx context.Context, userID primitive.ObjectID, w io.Writer) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	groups, err := s.groupRepo.FindByMember(ctx, userID)
+	if err != nil {
+		return err
+	}
+	players, err := s.playerRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	field := func(prefix, name string, v any) error {
+		if _, err := io.WriteString(w, prefix+`"`+name+`":`); err != nil {
+			return err
+		}
+		return enc.Encode(v)
+	}
+	if err := field("{", "exported_at", time.Now().UTC()); err != nil {
+		return err
+	}
+	if err := field(",", "user", user); err != nil {
+		return err
+	}
+	exported := make([]exportedGroup, len(groups))
+	for i, g := range groups {
+		exported[i] = exportedGroup{
+			ID:        g.ID,
+			Name:      g.Name,
+			CreatedAt: g.CreatedAt,
+			Owner:     g.CreatedBy == userID,
+			Settings:  g.Settings.WithDefaults(),
+		}
+	}
+	if err := field(",", "groups", exported); err != nil {
+		return err
+	}
+	if err := field(",", "players", players); err != nil {
+		return err
+	}
+	if err := field(",", "ratings_history", ratingsHistory); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, `,"matches":[`); err != nil {
+		return err
+	}
+	first := true
+	for _, p := range players {
+		q := repositories.MatchQuery{GroupID: p.GroupID, PlayerID: p.ID, Ascending: true, Limit: repositories.MaxMatchPageSize}
+		for {
+			page, err := s.matchRepo.FindPage(ctx, q)
+			if err != nil {
+				return err
+			}
+			for i := range page.Matches {
+				if !first {
+					if _, err := io.WriteString(w, ","); err != nil {
+						return err
+					}
+				}
+				first = false
+				if err := enc.Encode(&page.Matches[i]); err != nil {
+					return err
+				}
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			q.Cursor = page.NextCursor
+		}
+	}
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+func TestExport(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	alice, err := f.auth.Register(ctx, "alice", "password123")
+	require.NoError(t, err)
+	club, err := f.groupSv.CreateGroup(ctx, "Club", alice.ID)
+	require.NoError(t, err)
+	alicePlayer, err := f.playSv.CreatePlayerIfNotExists(ctx, alice.ID, alice.Name(), club.ID)
+	require.NoError(t, err)
+	_, bobPlayers := f.member(t, "bob", club)
+	_, carolPlayers := f.member(t, "carol", club)
+
+	// More than a page, to check the export follows the cursor.
+	played := repositories.MaxMatchPageSize + 5
+	for i := 0; i < played; i++ {
+		f.match(t, club.ID, alicePlayer, bobPlayers[0])
+	}
+	f.match(t, club.ID, bobPlayers[0], carolPlayers[0])
+
+	var buf bytes.Buffer
+	require.NoError(t, f.export.Export(ctx, alice.ID, &buf))
+	assert.NotContains(t, buf.String(), `"password"`)
+	assert.NotContains(t, buf.String(), `"join_code"`)
+	assert.NotContains(t, buf.String(), `"members"`)
+
+	var export struct {
+		User           models.User     `json:"user"`
+		Groups         []models.Group  `json:"groups"`
+		Players        []models.Player `json:"players"`
+		RatingsHistory struct {
+			Applicable *bool `json:"applicable"`
+		} `json:"ratings_history"`
+		Matches []models.Match `json:"matches"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &export))
+	assert.Equal(t, "alice", export.User.Username)
+	require.Len(t, export.Groups, 1)
+	assert.Equal(t, club.ID, export.Groups[0].ID)
+	assert.Empty(t, export.Groups[0].JoinCode)
+	require.NotNil(t, export.RatingsHistory.Applicable)
+	assert.False(t, *export.RatingsHistory.Applicable)
+	require.Len(t, export.Players, 1)
+	assert.Equal(t, alicePlayer.ID, export.Players[0].ID)
+	require.Len(t, export.Matches, played)
+	seen := map[primitive.ObjectID]bool{}
+	for _, m := range export.Matches {
+		assert.Contains(t, m.Team1IDs, alicePlayer.ID)
+		assert.False(t, seen[m.ID], "match exported twice")
+		seen[m.ID] = true
+	}
+}
+
+func TestExport_UnknownUser(t *testing.T) {
+	f := newAccountFixture(t)
+	var buf bytes.Buffer
+	err := f.export.Export(context.Background(), primitive.NewObjectID(), &buf)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	assert.Zero(t, buf.Len(), "nothing written")
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+// Code lengths. They differ so a code alone says whether it is a group's
+// join code or an invite's.
+const (
+	JoinCodeLen   = 6
+	InviteCodeLen = 8
+)
+
+var (
+	ErrInvalidInvite       = errors.New("invalid invite")
+	ErrInviteNotFound      = errors.New("invite not found")
+	ErrInviteUnusable      = errors.New("invite has expired, been used up or been revoked")
+	ErrJoinRequestNotFound = errors.New("join request not found")
+)
+
+// InviteOptions describes a new invite. A nil ExpiresAt never expires and a
+// zero MaxUses is unlimited.
+type InviteOptions struct {
+	Name      string
+	ExpiresAt *time.Time
+	MaxUses   int
+}
+
+// ── Joining ──
+
+// JoinGroup adds the user to the group the code belongs to, either the
+// group's join code or an invite's. Joining a group you are in is a no-op.
+// Groups with join approval get a pending request instead, returned with
+// the group; asking again returns the same request. Invite uses are
+// counted when the user joins or asks to.
+func (s *GroupService) JoinGroup(ctx context.Context, code string, userID primitive.ObjectID, username string) (*models.Group, *models.JoinRequest, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	var group *models.Group
+	var request *models.JoinRequest
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		var invite *models.Invite
+		var err error
+		if len(code) == InviteCodeLen {
+			if invite, err = s.inviteRepo.FindByCode(ctx, code); err != nil {
+				return err
+			}
+			group, err = s.groupRepo.FindByID(ctx, invite.GroupID)
+		} else {
+			group, err = s.groupRepo.FindByJoinCode(ctx, code)
+		}
+		if err != nil {
+			return err
+		}
+		if group.HasMember(userID) {
+			return nil
+		}
+		if group.JoinApproval {
+			request, err = s.requestRepo.FindByGroupAndUser(ctx, group.ID, userID)
+			if !errors.Is(err, repositories.ErrNotFound) {
+				return err
+			}
+		}
+
+		now := time.Now()
+		if invite != nil {
+			if err := s.inviteRepo.Use(ctx, invite.ID, now); err != nil {
+				if errors.Is(err, repositories.ErrNotFound) {
+					return ErrInviteUnusable
+				}
+				return err
+			}
+		}
+		if group.JoinApproval {
+			request = &models.JoinRequest{GroupID: group.ID, UserID: userID, Username: username, CreatedAt: now}
+			if invite != nil {
+				request.InviteID = &invite.ID
+			}
+			return s.requestRepo.Create(ctx, request)
+		}
+		if err := s.groupRepo.AddMember(ctx, group.ID, userID); err != nil {
+			return err
+		}
+		group, err = s.groupRepo.FindByID(ctx, group.ID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return group, request, nil
+}
+
+// ── Join code and approval ──
+
+// RegenerateJoinCode gives the group a new join code; the old one stops
+// working.
+func (s *GroupService) RegenerateJoinCode(ctx context.Context, groupID, ownerID primitive.ObjectID) (*models.Group, error) {
+	group, err := s.ownedGroup(ctx, groupID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	err = withFreshCode(JoinCodeLen, func(code string) error {
+		group.JoinCode = code
+		return s.groupRepo.SetJoinCode(ctx, groupID, code)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// SetJoinApproval turns join approval on or off. Requests already pending
+// stay pending either way.
+func (s *GroupService) SetJoinApproval(ctx context.Context, groupID, ownerID primitive.ObjectID, on bool) (*models.Group, error) {
+	group, err := s.ownedGroup(ctx, groupID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.groupRepo.SetJoinApproval(ctx, groupID, on); err != nil {
+		return nil, err
+	}
+	group.JoinApproval = on
+	return group, nil
+}
+
+// ── Invites ──
+
+func (s *GroupService) CreateInvite(ctx context.Context, groupID, ownerID primitive.ObjectID, opts InviteOptions) (*models.Invite, error) {
+	name := strings.Join(strings.Fields(opts.Name), " ")
+	switch {
+	case name == "":
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidInvite)
+	case utf8.RuneCountInString(name) > MaxGroupNameLen:
+		return nil, fmt.Errorf("%w: name is longer than %d characters", ErrInvalidInvite, MaxGroupNameLen)
+	case opts.MaxUses < 0:
+		return nil, fmt.Errorf("%w: max uses cannot be negative", ErrInvalidInvite)
+	}
+	now := time.Now()
+	if opts.ExpiresAt != nil && !opts.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("%w: expiry must be in the future", ErrInvalidInvite)
+	}
+	if _, err := s.ownedGroup(ctx, groupID, ownerID); err != nil {
+		return nil, err
+	}
+
+	invite := &models.Invite{
+		GroupID:   groupID,
+		Name:      name,
+		CreatedBy: ownerID,
+		CreatedAt: now,
+		ExpiresAt: opts.ExpiresAt,
+		MaxUses:   opts.MaxUses,
+	}
+	err := withFreshCode(InviteCodeLen, func(code string) error {
+		invite.Code = code
+		return s.inviteRepo.Create(ctx, invite)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+// ListInvites returns the group's invites, revoked and used up ones too.
+func (s *GroupService) ListInvites(ctx context.Context, groupID, ownerID primitive.ObjectID) ([]models.Invite, error) {
+	if _, err := s.ownedGroup(ctx, groupID, ownerID); err != nil {
+		return nil, err
+	}
+	return s.inviteRepo.FindByGroupID(ctx, groupID)
+}
+
+// RevokeInvite stops an invite from working. Revoking it again is not an
+// error.
+func (s *GroupService) RevokeInvite(ctx context.Context, groupID, ownerID, inviteID primitive.ObjectID) (*models.Invite, error) {
+	if _, err := s.ownedGroup(ctx, groupID, ownerID); err != nil {
+		return nil, err
+	}
+	invite, err := s.inviteRepo.FindByID(ctx, inviteID)
+	if err != nil || invite.GroupID != groupID {
+		return nil, ErrInviteNotFound
+	}
+	if invite.RevokedAt != nil {
+		return invite, nil
+	}
+	now := time.Now()
+	if err := s.inviteRepo.Revoke(ctx, inviteID, now); err != nil {
+		return nil, err
+	}
+	invite.RevokedAt = &now
+	return invite, nil
+}
+
+// ── Join requests ──
+
+func (s *GroupService) ListJoinRequests(ctx context.Context, groupID, ownerID primitive.ObjectID) ([]models.JoinRequest, error) {
+	if _, err := s.ownedGroup(ctx, groupID, ownerID); err != nil {
+		return nil, err
+	}
+	return s.requestRepo.FindByGroupID(ctx, groupID)
+}
+
+// ApproveJoinRequest adds the requester to the group. It returns the group
+// and the approved request.
+func (s *GroupService) ApproveJoinRequest(ctx context.Context, groupID, ownerID, requestID primitive.ObjectID) (*models.Group, *models.JoinRequest, error) {
+	var group *models.Group
+	var request *models.JoinRequest
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		if _, err = s.ownedGroup(ctx, groupID, ownerID); err != nil {
+			return err
+		}
+		if request, err = s.joinRequest(ctx, groupID, requestID); err != nil {
+			return err
+		}
+		if err := s.groupRepo.AddMember(ctx, groupID, request.UserID); err != nil {
+			return err
+		}
+		if err := s.requestRepo.Delete(ctx, requestID); err != nil {
+			return err
+		}
+		group, err = s.groupRepo.FindByID(ctx, groupID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return group, request, nil
+}
+
+// RejectJoinRequest drops the request. The user can ask again.
+func (s *GroupService) RejectJoinRequest(ctx context.Context, groupID, ownerID, requestID primitive.ObjectID) (*models.JoinRequest, error) {
+	if _, err := s.ownedGroup(ctx, groupID, ownerID); err != nil {
+		return nil, err
+	}
+	request, err := s.joinRequest(ctx, groupID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requestRepo.Delete(ctx, requestID); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (s *GroupService) joinRequest(ctx context.Context, groupID, requestID primitive.ObjectID) (*models.JoinRequest, error) {
+	request, err := s.requestRepo.FindByID(ctx, requestID)
+	if err != nil || request.GroupID != groupID {
+		return nil, ErrJoinRequestNotFound
+	}
+	return request, nil
+}
+
+// dropJoinRequests withdraws all of the user's pending requests, for
+// account deletion.
+func (s *GroupService) dropJoinRequests(ctx context.Context, userID primitive.ObjectID) error {
+	return s.requestRepo.DeleteByUserID(ctx, userID)
+}
@@ -2,7 +2,13 @@ package services
 
 import (
 	"context"
-	"math/rand"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
@@ -12,52 +18,346 @@ import (
 
 const joinCodeChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
+// MaxGroupNameLen caps group names, in characters.
+const MaxGroupNameLen = 40
+
+var (
+	ErrInvalidGroup   = errors.New("invalid group")
+	ErrNotGroupMember = errors.New("user is not a member of the group")
+	ErrNotGroupOwner  = errors.New("only the group owner can do this")
+)
+
 type GroupService struct {
-	groupRepo repositories.GroupRepository
+	groupRepo   repositories.GroupRepository
+	inviteRepo  repositories.InviteRepository
+	requestRepo repositories.JoinRequestRepository
+	shareRepo   repositories.ShareLinkRepository
+	players     *PlayerService
+	tx          repositories.Transactor
 }
 
-func NewGroupService(groupRepo repositories.GroupRepository) *GroupService {
-	return &GroupService{groupRepo: groupRepo}
+func NewGroupService(groupRepo repositories.GroupRepository, inviteRepo repositories.InviteRepository, requestRepo repositories.JoinRequestRepository, shareRepo repositories.ShareLinkRepository, players *PlayerService, tx repositories.Transactor) *GroupService {
+	return &GroupService{groupRepo: groupRepo, inviteRepo: inviteRepo, requestRepo: requestRepo, shareRepo: shareRepo, players: players, tx: tx}
+}
+
+// GroupDeparture reports what a member leaving, or being removed, did to
+// the group.
+type GroupDeparture struct {
+	// Group is the group as it is now; nil when it was deleted.
+	Group *models.Group `json:"group,omitempty"`
+	// NewOwner is set when the owner left and the group passed on.
+	NewOwner *primitive.ObjectID `json:"new_owner,omitempty"`
+	// Deleted is set when the last member left.
+	Deleted bool `json:"deleted"`
+	// LinksRevoked are the user's share links to the group, revoked as
+	// they went, for disconnecting their viewers.
+	LinksRevoked []primitive.ObjectID `json:"-"`
 }
 
 func (s *GroupService) CreateGroup(ctx context.Context, name string, createdBy primitive.ObjectID) (*models.Group, error) {
+	name, err := cleanGroupName(name)
+	if err != nil {
+		return nil, err
+	}
 	group := &models.Group{
 		Name:      name,
-		JoinCode:  generateJoinCode(6),
 		CreatedBy: createdBy,
 		Members:   []primitive.ObjectID{createdBy},
+		Settings:  models.GroupSettings{}.WithDefaults(),
 	}
-	if err := s.groupRepo.Create(ctx, group); err != nil {
+	err = withFreshCode(JoinCodeLen, func(code string) error {
+		group.JoinCode = code
+		return s.groupRepo.Create(ctx, group)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return group, nil
 }
 
-func (s *GroupService) JoinGroup(ctx context.Context, code string, userID primitive.ObjectID) (*models.Group, error) {
-	group, err := s.groupRepo.FindByJoinCode(ctx, code)
+func (s *GroupService) GetGroup(ctx context.Context, id primitive.ObjectID) (*models.Group, error) {
+	return s.groupRepo.FindByID(ctx, id)
+}
+
+// GetMemberGroup returns the group if the user is a member, and
+// ErrNotGroupMember otherwise.
+func (s *GroupService) GetMemberGroup(ctx context.Context, groupID, userID primitive.ObjectID) (*models.Group, error) {
+	group, err := s.groupRepo.FindByID(ctx, groupID)
 	if err != nil {
 		return nil, err
 	}
-	// Add user to members (idempotent)
-	if err := s.groupRepo.AddMember(ctx, group.ID, userID); err != nil {
-		return nil, err
+	if !group.HasMember(userID) {
+		return nil, ErrNotGroupMember
 	}
-	// Re-fetch to get updated members list
-	return s.groupRepo.FindByID(ctx, group.ID)
+	return group, nil
 }
 
-func (s *GroupService) GetGroup(ctx context.Context, id primitive.ObjectID) (*models.Group, error) {
-	return s.groupRepo.FindByID(ctx, id)
+// GroupSummary is what a non-member may see of a group: never its join
+// code or member list, and only its name unless the group is public.
+type GroupSummary struct {
+	ID           primitive.ObjectID    `json:"id"`
+	Name         string                `json:"name"`
+	CreatedAt    *time.Time            `json:"created_at,omitempty"`
+	JoinApproval *bool                 `json:"join_approval,omitempty"`
+	Settings     *models.GroupSettings `json:"settings,omitempty"`
+}
+
+// GetGroupSummary returns the group without its join code or members, for
+// callers outside it. Settings and the rest are left out too unless the
+// user may see the group.
+func (s *GroupService) GetGroupSummary(ctx context.Context, groupID, userID primitive.ObjectID) (*GroupSummary, error) {
+	group, err := s.groupRepo.FindByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	summary := &GroupSummary{ID: group.ID, Name: group.Name}
+	err = s.CheckVisible(ctx, groupID, userID)
+	if errors.Is(err, ErrNotGroupMember) {
+		return summary, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	settings := group.Settings.WithDefaults()
+	summary.CreatedAt, summary.JoinApproval, summary.Settings = &group.CreatedAt, &group.JoinApproval, &settings
+	return summary, nil
 }
 
 func (s *GroupService) GetUserGroups(ctx context.Context, userID primitive.ObjectID) ([]models.Group, error) {
 	return s.groupRepo.FindByMember(ctx, userID)
 }
 
+// ── Membership ──
+
+// LeaveGroup takes the user out of the group. Their player stays as a
+// guest, with its matches. An owner leaving hands the group to the
+// longest-standing other member; the last member leaving deletes it.
+func (s *GroupService) LeaveGroup(ctx context.Context, groupID, userID primitive.ObjectID) (*GroupDeparture, error) {
+	var out *GroupDeparture
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		group, err := s.groupRepo.FindByID(ctx, groupID)
+		if err != nil {
+			return err
+		}
+		if !group.HasMember(userID) {
+			return ErrNotGroupMember
+		}
+		if out, err = s.removeUser(ctx, group, userID); err != nil {
+			return err
+		}
+		if out.Deleted {
+			return nil
+		}
+		return s.players.releaseUser(ctx, groupID, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RemoveMember is the owner taking another member out of the group. Their
+// player stays as a guest, with its matches; their share links are revoked.
+func (s *GroupService) RemoveMember(ctx context.Context, groupID, ownerID, memberID primitive.ObjectID) (*GroupDeparture, error) {
+	out := &GroupDeparture{}
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		group, err := s.ownedGroup(ctx, groupID, ownerID)
+		if err != nil {
+			return err
+		}
+		if memberID == ownerID {
+			return fmt.Errorf("%w: the owner leaves rather than removing themselves", ErrInvalidGroup)
+		}
+		if !group.HasMember(memberID) {
+			return ErrNotGroupMember
+		}
+		if err := s.groupRepo.RemoveMember(ctx, groupID, memberID); err != nil {
+			return err
+		}
+		if err := s.players.releaseUser(ctx, groupID, memberID); err != nil {
+			return err
+		}
+		if out.LinksRevoked, err = s.revokeShareLinks(ctx, groupID, memberID); err != nil {
+			return err
+		}
+		out.Group, err = s.groupRepo.FindByID(ctx, groupID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// removeUser takes the user off the member list, passing ownership on or
+// deleting the group with all its data as needed, and revokes their share
+// links. Their players are left to the caller.
+func (s *GroupService) removeUser(ctx context.Context, group *models.Group, userID primitive.ObjectID) (*GroupDeparture, error) {
+	out := &GroupDeparture{}
+	if group.CreatedBy == userID {
+		heir := nextMember(group.Members, userID)
+		if heir.IsZero() {
+			if err := s.deleteGroup(ctx, group.ID); err != nil {
+				return nil, err
+			}
+			out.Deleted = true
+			return out, nil
+		}
+		if err := s.groupRepo.SetCreator(ctx, group.ID, heir); err != nil {
+			return nil, err
+		}
+		out.NewOwner = &heir
+	}
+	if err := s.groupRepo.RemoveMember(ctx, group.ID, userID); err != nil {
+		return nil, err
+	}
+	var err error
+	if out.LinksRevoked, err = s.revokeShareLinks(ctx, group.ID, userID); err != nil {
+		return nil, err
+	}
+	if out.Group, err = s.groupRepo.FindByID(ctx, group.ID); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// revokeShareLinks revokes the live share links the user made to the
+// group and returns their IDs. Links outlive neither membership nor the
+// group.
+func (s *GroupService) revokeShareLinks(ctx context.Context, groupID, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	links, err := s.shareRepo.FindByGroupID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var revoked []primitive.ObjectID
+	for _, link := range links {
+		if link.CreatedBy != userID || !link.Usable(now) {
+			continue
+		}
+		if err := s.shareRepo.Revoke(ctx, link.ID, now); err != nil {
+			return nil, err
+		}
+		revoked = append(revoked, link.ID)
+	}
+	return revoked, nil
+}
+
+// nextMember is the first member other than userID, or the zero ID.
+func nextMember(members []primitive.ObjectID, userID primitive.ObjectID) primitive.ObjectID {
+	for _, m := range members {
+		if m != userID {
+			return m
+		}
+	}
+	return primitive.NilObjectID
+}
+
+// ── Owner actions ──
+
+// RenameGroup changes the group's name.
+func (s *GroupService) RenameGroup(ctx context.Context, groupID, ownerID primitive.ObjectID, name string) (*models.Group, error) {
+	name, err := cleanGroupName(name)
+	if err != nil {
+		return nil, err
+	}
+	group, err := s.ownedGroup(ctx, groupID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.groupRepo.Rename(ctx, groupID, name); err != nil {
+		return nil, err
+	}
+	group.Name = name
+	return group, nil
+}
+
+// DeleteGroup deletes the group with its players, matches, match events and
+// merge records.
+func (s *GroupService) DeleteGroup(ctx context.Context, groupID, ownerID primitive.ObjectID) error {
+	return s.inTx(ctx, func(ctx context.Context) error {
+		if _, err := s.ownedGroup(ctx, groupID, ownerID); err != nil {
+			return err
+		}
+		return s.deleteGroup(ctx, groupID)
+	})
+}
+
+func (s *GroupService) deleteGroup(ctx context.Context, groupID primitive.ObjectID) error {
+	if err := s.players.DeleteGroupData(ctx, groupID); err != nil {
+		return err
+	}
+	if err := s.inviteRepo.DeleteByGroupID(ctx, groupID); err != nil {
+		return err
+	}
+	if err := s.requestRepo.DeleteByGroupID(ctx, groupID); err != nil {
+		return err
+	}
+	if err := s.shareRepo.DeleteByGroupID(ctx, groupID); err != nil {
+		return err
+	}
+	return s.groupRepo.Delete(ctx, groupID)
+}
+
+// inTx runs fn in a transaction when the service has a Transactor.
+func (s *GroupService) inTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.tx == nil {
+		return fn(ctx)
+	}
+	return s.tx.WithTransaction(ctx, fn)
+}
+
+// ownedGroup loads the group and checks that userID owns it.
+func (s *GroupService) ownedGroup(ctx context.Context, groupID, userID primitive.ObjectID) (*models.Group, error) {
+	group, err := s.groupRepo.FindByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group.CreatedBy != userID {
+		return nil, ErrNotGroupOwner
+	}
+	return group, nil
+}
+
+// cleanGroupName collapses runs of whitespace and checks the length.
+func cleanGroupName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", fmt.Errorf("%w: name is required", ErrInvalidGroup)
+	}
+	if utf8.RuneCountInString(name) > MaxGroupNameLen {
+		return "", fmt.Errorf("%w: name is longer than %d characters", ErrInvalidGroup, MaxGroupNameLen)
+	}
+	return name, nil
+}
+
+// generateJoinCode draws a code from crypto/rand, so codes cannot be
+// predicted from earlier ones.
 func generateJoinCode(length int) string {
 	b := make([]byte, length)
+	alphabet := big.NewInt(int64(len(joinCodeChars)))
 	for i := range b {
-		b[i] = joinCodeChars[rand.Intn(len(joinCodeChars))]
+		n, err := rand.Int(rand.Reader, alphabet)
+		if err != nil {
+			panic(fmt.Sprintf("crypto/rand: %v", err)) // never fails on supported platforms
+		}
+		b[i] = joinCodeChars[n.Int64()]
 	}
 	return string(b)
 }
+
+// codeAttempts bounds the retries when a new code is already taken, which
+// at these lengths is very unlikely even once.
+const codeAttempts = 5
+
+// withFreshCode calls save with new codes until one is not taken. The
+// stores' unique indexes decide what is taken.
+func withFreshCode(length int, save func(code string) error) error {
+	var err error
+	for i := 0; i < codeAttempts; i++ {
+		if err = save(generateJoinCode(length)); !repositories.IsDuplicateKey(err) {
+			return err
+		}
+	}
+	return err
+}
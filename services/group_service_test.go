@@ -3,18 +3,29 @@ package services
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"gully-backend/models"
+	"gully-backend/repositories"
+	"gully-backend/repositories/memory"
 )
 
+// newMockGroupService wires a group service to a mock group repository
+// only; the tests using it never reach invites, requests or players.
+func newMockGroupService(groupRepo *MockGroupRepo) *GroupService {
+	return NewGroupService(groupRepo, nil, nil, nil, nil, memory.NewTransactor())
+}
+
 func TestCreateGroup_Success(t *testing.T) {
 	groupRepo := new(MockGroupRepo)
-	svc := NewGroupService(groupRepo)
+	svc := newMockGroupService(groupRepo)
 	ctx := context.Background()
 	userID := primitive.NewObjectID()
 
@@ -33,7 +44,7 @@ func TestCreateGroup_Success(t *testing.T) {
 
 func TestCreateGroup_RepoError(t *testing.T) {
 	groupRepo := new(MockGroupRepo)
-	svc := NewGroupService(groupRepo)
+	svc := newMockGroupService(groupRepo)
 	ctx := context.Background()
 	userID := primitive.NewObjectID()
 
@@ -48,7 +59,7 @@ func TestCreateGroup_RepoError(t *testing.T) {
 
 func TestJoinGroup_Success(t *testing.T) {
 	groupRepo := new(MockGroupRepo)
-	svc := NewGroupService(groupRepo)
+	svc := newMockGroupService(groupRepo)
 	ctx := context.Background()
 
 	groupID := primitive.NewObjectID()
@@ -66,13 +77,14 @@ func TestJoinGroup_Success(t *testing.T) {
 		Members:  []primitive.ObjectID{existing.Members[0], userID},
 	}
 
-	groupRepo.On("FindByJoinCode", ctx, "ABC123").Return(existing, nil)
-	groupRepo.On("AddMember", ctx, groupID, userID).Return(nil)
-	groupRepo.On("FindByID", ctx, groupID).Return(updated, nil)
+	groupRepo.On("FindByJoinCode", mock.Anything, "ABC123").Return(existing, nil)
+	groupRepo.On("AddMember", mock.Anything, groupID, userID).Return(nil)
+	groupRepo.On("FindByID", mock.Anything, groupID).Return(updated, nil)
 
-	result, err := svc.JoinGroup(ctx, "ABC123", userID)
+	result, request, err := svc.JoinGroup(ctx, "abc123", userID, "alice")
 
 	assert.NoError(t, err)
+	assert.Nil(t, request)
 	assert.NotNil(t, result)
 	assert.Len(t, result.Members, 2)
 	groupRepo.AssertExpectations(t)
@@ -80,12 +92,12 @@ func TestJoinGroup_Success(t *testing.T) {
 
 func TestJoinGroup_InvalidCode(t *testing.T) {
 	groupRepo := new(MockGroupRepo)
-	svc := NewGroupService(groupRepo)
+	svc := newMockGroupService(groupRepo)
 	ctx := context.Background()
 
-	groupRepo.On("FindByJoinCode", ctx, "BADCODE").Return(nil, errors.New("not found"))
+	groupRepo.On("FindByJoinCode", mock.Anything, "BADCODE").Return(nil, errors.New("not found"))
 
-	result, err := svc.JoinGroup(ctx, "BADCODE", primitive.NewObjectID())
+	result, _, err := svc.JoinGroup(ctx, "BADCODE", primitive.NewObjectID(), "alice")
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
@@ -94,7 +106,7 @@ func TestJoinGroup_InvalidCode(t *testing.T) {
 
 func TestGetGroup_Success(t *testing.T) {
 	groupRepo := new(MockGroupRepo)
-	svc := NewGroupService(groupRepo)
+	svc := newMockGroupService(groupRepo)
 	ctx := context.Background()
 
 	groupID := primitive.NewObjectID()
@@ -110,7 +122,7 @@ func TestGetGroup_Success(t *testing.T) {
 
 func TestGetUserGroups_Success(t *testing.T) {
 	groupRepo := new(MockGroupRepo)
-	svc := NewGroupService(groupRepo)
+	svc := newMockGroupService(groupRepo)
 	ctx := context.Background()
 
 	userID := primitive.NewObjectID()
@@ -146,3 +158,354 @@ func TestGenerateJoinCode_Uniqueness(t *testing.T) {
 	// With 36^6 = ~2.2 billion possibilities, 100 codes should all be unique
 	assert.Greater(t, len(codes), 90, "Most codes should be unique")
 }
+
+// ── Membership and owner actions ──
+
+func TestCreateGroup_InvalidName(t *testing.T) {
+	svc := newMockGroupService(new(MockGroupRepo))
+	for _, name := range []string{"   ", "abcdefghij abcdefghij abcdefghij abcdefghij"} {
+		_, err := svc.CreateGroup(context.Background(), name, primitive.NewObjectID())
+		assert.ErrorIs(t, err, ErrInvalidGroup)
+	}
+}
+
+func TestLeaveGroup(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+	ownerPlayer, err := f.playSv.CreatePlayerIfNotExists(ctx, owner.ID, owner.Name(), group.ID)
+	require.NoError(t, err)
+	bob, bobPlayers := f.member(t, "bob", group)
+	carol, _ := f.member(t, "carol", group)
+	f.match(t, group.ID, bobPlayers[0], ownerPlayer)
+
+	// A member leaving keeps their player, and its matches, as a guest.
+	left, err := f.groupSv.LeaveGroup(ctx, group.ID, carol.ID)
+	require.NoError(t, err)
+	assert.False(t, left.Deleted)
+	assert.Nil(t, left.NewOwner)
+	assert.Equal(t, []primitive.ObjectID{owner.ID, bob.ID}, left.Group.Members)
+	_, err = f.groupSv.LeaveGroup(ctx, group.ID, carol.ID)
+	assert.ErrorIs(t, err, ErrNotGroupMember)
+	players, err := f.players.FindByUserID(ctx, carol.ID)
+	require.NoError(t, err)
+	assert.Empty(t, players)
+
+	// The owner leaving hands the group on.
+	left, err = f.groupSv.LeaveGroup(ctx, group.ID, owner.ID)
+	require.NoError(t, err)
+	require.NotNil(t, left.NewOwner)
+	assert.Equal(t, bob.ID, *left.NewOwner)
+	assert.Equal(t, bob.ID, left.Group.CreatedBy)
+	player, err := f.players.FindByID(ctx, ownerPlayer.ID)
+	require.NoError(t, err)
+	assert.Nil(t, player.UserID)
+	assert.Equal(t, "owner", player.Name)
+
+	// The last member leaving deletes it, share links included.
+	_, _, err = f.shares.CreateShareLink(ctx, group.ID, bob.ID, nil, nil)
+	require.NoError(t, err)
+	left, err = f.groupSv.LeaveGroup(ctx, group.ID, bob.ID)
+	require.NoError(t, err)
+	assert.True(t, left.Deleted)
+	assert.Nil(t, left.Group)
+	_, err = f.groups.FindByID(ctx, group.ID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	matches, err := f.matches.FindByGroupID(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+	links, err := f.links.FindByGroupID(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Empty(t, links)
+}
+
+func TestRemoveMember(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+	bob, bobPlayers := f.member(t, "bob", group)
+	carol, _ := f.member(t, "carol", group)
+	bobLink, _, err := f.shares.CreateShareLink(ctx, group.ID, bob.ID, nil, nil)
+	require.NoError(t, err)
+	carolLink, _, err := f.shares.CreateShareLink(ctx, group.ID, carol.ID, nil, nil)
+	require.NoError(t, err)
+
+	_, err = f.groupSv.RemoveMember(ctx, group.ID, bob.ID, carol.ID)
+	assert.ErrorIs(t, err, ErrNotGroupOwner)
+	_, err = f.groupSv.RemoveMember(ctx, group.ID, owner.ID, owner.ID)
+	assert.ErrorIs(t, err, ErrInvalidGroup)
+	_, err = f.groupSv.RemoveMember(ctx, group.ID, owner.ID, primitive.NewObjectID())
+	assert.ErrorIs(t, err, ErrNotGroupMember)
+
+	got, err := f.groupSv.RemoveMember(ctx, group.ID, owner.ID, bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{owner.ID, carol.ID}, got.Group.Members)
+	player, err := f.players.FindByID(ctx, bobPlayers[0].ID)
+	require.NoError(t, err)
+	assert.Nil(t, player.UserID, "player kept as a guest")
+
+	// Their share links go with them; other members' stay.
+	assert.Equal(t, []primitive.ObjectID{bobLink.ID}, got.LinksRevoked)
+	link, err := f.links.FindByID(ctx, bobLink.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, link.RevokedAt)
+	link, err = f.links.FindByID(ctx, carolLink.ID)
+	require.NoError(t, err)
+	assert.Nil(t, link.RevokedAt)
+}
+
+func TestRenameGroup(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+	bob, _ := f.member(t, "bob", group)
+
+	_, err = f.groupSv.RenameGroup(ctx, group.ID, bob.ID, "Mine now")
+	assert.ErrorIs(t, err, ErrNotGroupOwner)
+	_, err = f.groupSv.RenameGroup(ctx, group.ID, owner.ID, " ")
+	assert.ErrorIs(t, err, ErrInvalidGroup)
+
+	got, err := f.groupSv.RenameGroup(ctx, group.ID, owner.ID, "  Sunday   Club ")
+	require.NoError(t, err)
+	assert.Equal(t, "Sunday Club", got.Name)
+	stored, err := f.groups.FindByID(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Sunday Club", stored.Name)
+}
+
+func TestDeleteGroup(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+	other, err := f.groupSv.CreateGroup(ctx, "Other", owner.ID)
+	require.NoError(t, err)
+	bob, bobPlayers := f.member(t, "bob", group, other)
+	f.match(t, group.ID, bobPlayers[0], bobPlayers[0])
+	kept := f.match(t, other.ID, bobPlayers[1], bobPlayers[1])
+
+	assert.ErrorIs(t, f.groupSv.DeleteGroup(ctx, group.ID, bob.ID), ErrNotGroupOwner)
+	require.NoError(t, f.groupSv.DeleteGroup(ctx, group.ID, owner.ID))
+	assert.ErrorIs(t, f.groupSv.DeleteGroup(ctx, group.ID, owner.ID), repositories.ErrNotFound)
+
+	players, err := f.players.FindByGroupID(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Empty(t, players)
+	matches, err := f.matches.FindByGroupID(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+	_, err = f.matches.FindByID(ctx, kept.ID)
+	assert.NoError(t, err, "other groups untouched")
+}
+
+func TestGroupService_WithoutTransactor(t *testing.T) {
+	f := newAccountFixture(t)
+	svc := NewGroupService(f.groups, f.invites, f.joinReq, f.links, f.playSv, nil)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := svc.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+	var users []*models.User
+	for _, name := range []string{"bob", "carol"} {
+		user, err := f.auth.Register(ctx, name, "password123")
+		require.NoError(t, err)
+		_, _, err = svc.JoinGroup(ctx, group.JoinCode, user.ID, user.Username)
+		require.NoError(t, err)
+		users = append(users, user)
+	}
+
+	_, err = svc.RemoveMember(ctx, group.ID, owner.ID, users[0].ID)
+	require.NoError(t, err)
+	_, err = svc.LeaveGroup(ctx, group.ID, users[1].ID)
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteGroup(ctx, group.ID, owner.ID))
+	_, err = f.groups.FindByID(ctx, group.ID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+}
+
+// ── Join codes, invites and approval ──
+
+func TestRegenerateJoinCode(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+	bob, _ := f.member(t, "bob", group)
+	old := group.JoinCode
+
+	_, err = f.groupSv.RegenerateJoinCode(ctx, group.ID, bob.ID)
+	assert.ErrorIs(t, err, ErrNotGroupOwner)
+	got, err := f.groupSv.RegenerateJoinCode(ctx, group.ID, owner.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, old, got.JoinCode)
+	assert.Len(t, got.JoinCode, JoinCodeLen)
+
+	carol, err := f.auth.Register(ctx, "carol", "password123")
+	require.NoError(t, err)
+	_, _, err = f.groupSv.JoinGroup(ctx, old, carol.ID, carol.Username)
+	assert.ErrorIs(t, err, repositories.ErrNotFound, "old code stops working")
+	joined, _, err := f.groupSv.JoinGroup(ctx, got.JoinCode, carol.ID, carol.Username)
+	require.NoError(t, err)
+	assert.True(t, joined.HasMember(carol.ID))
+}
+
+func TestInvite_MaxUses(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+
+	invite, err := f.groupSv.CreateInvite(ctx, group.ID, owner.ID, InviteOptions{Name: " WhatsApp ", MaxUses: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "WhatsApp", invite.Name)
+	assert.Len(t, invite.Code, InviteCodeLen)
+
+	for _, name := range []string{"bob", "carol"} {
+		user, err := f.auth.Register(ctx, name, "password123")
+		require.NoError(t, err)
+		joined, _, err := f.groupSv.JoinGroup(ctx, strings.ToLower(invite.Code), user.ID, user.Username)
+		require.NoError(t, err)
+		assert.True(t, joined.HasMember(user.ID))
+	}
+	// Rejoining as a member is a no-op and does not count.
+	_, _, err = f.groupSv.JoinGroup(ctx, invite.Code, owner.ID, owner.Username)
+	require.NoError(t, err)
+
+	dave, err := f.auth.Register(ctx, "dave", "password123")
+	require.NoError(t, err)
+	_, _, err = f.groupSv.JoinGroup(ctx, invite.Code, dave.ID, dave.Username)
+	assert.ErrorIs(t, err, ErrInviteUnusable)
+
+	invites, err := f.groupSv.ListInvites(ctx, group.ID, owner.ID)
+	require.NoError(t, err)
+	require.Len(t, invites, 1)
+	assert.Equal(t, 2, invites[0].Uses)
+}
+
+func TestInvite_ExpiryAndRevoke(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+	bob, err := f.auth.Register(ctx, "bob", "password123")
+	require.NoError(t, err)
+
+	past := time.Now().Add(-time.Minute)
+	for _, opts := range []InviteOptions{{Name: " "}, {Name: "x", MaxUses: -1}, {Name: "x", ExpiresAt: &past}} {
+		_, err := f.groupSv.CreateInvite(ctx, group.ID, owner.ID, opts)
+		assert.ErrorIs(t, err, ErrInvalidInvite)
+	}
+	_, err = f.groupSv.CreateInvite(ctx, group.ID, bob.ID, InviteOptions{Name: "x"})
+	assert.ErrorIs(t, err, ErrNotGroupOwner)
+
+	// Expired: stored with a past expiry, as if time had passed.
+	expired := &models.Invite{GroupID: group.ID, Code: "EXPIRED1", Name: "old", CreatedBy: owner.ID, CreatedAt: past, ExpiresAt: &past}
+	require.NoError(t, f.invites.Create(ctx, expired))
+	_, _, err = f.groupSv.JoinGroup(ctx, expired.Code, bob.ID, bob.Username)
+	assert.ErrorIs(t, err, ErrInviteUnusable)
+
+	soon := time.Now().Add(time.Hour)
+	invite, err := f.groupSv.CreateInvite(ctx, group.ID, owner.ID, InviteOptions{Name: "Flyer", ExpiresAt: &soon})
+	require.NoError(t, err)
+	revoked, err := f.groupSv.RevokeInvite(ctx, group.ID, owner.ID, invite.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, revoked.RevokedAt)
+	_, err = f.groupSv.RevokeInvite(ctx, group.ID, owner.ID, invite.ID)
+	assert.NoError(t, err, "revoking twice is fine")
+	_, err = f.groupSv.RevokeInvite(ctx, group.ID, owner.ID, primitive.NewObjectID())
+	assert.ErrorIs(t, err, ErrInviteNotFound)
+
+	_, _, err = f.groupSv.JoinGroup(ctx, invite.Code, bob.ID, bob.Username)
+	assert.ErrorIs(t, err, ErrInviteUnusable)
+	stored, err := f.groups.FindByID(ctx, group.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.HasMember(bob.ID))
+}
+
+func TestJoinApproval(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+	_, err = f.groupSv.SetJoinApproval(ctx, group.ID, owner.ID, true)
+	require.NoError(t, err)
+	bob, err := f.auth.Register(ctx, "bob", "password123")
+	require.NoError(t, err)
+	carol, err := f.auth.Register(ctx, "carol", "password123")
+	require.NoError(t, err)
+
+	got, request, err := f.groupSv.JoinGroup(ctx, group.JoinCode, bob.ID, bob.Username)
+	require.NoError(t, err)
+	require.NotNil(t, request)
+	assert.False(t, got.HasMember(bob.ID), "pending, not a member")
+	assert.Equal(t, "bob", request.Username)
+	_, again, err := f.groupSv.JoinGroup(ctx, group.JoinCode, bob.ID, bob.Username)
+	require.NoError(t, err)
+	assert.Equal(t, request.ID, again.ID, "asking twice returns the same request")
+	_, carolRequest, err := f.groupSv.JoinGroup(ctx, group.JoinCode, carol.ID, carol.Username)
+	require.NoError(t, err)
+
+	requests, err := f.groupSv.ListJoinRequests(ctx, group.ID, owner.ID)
+	require.NoError(t, err)
+	assert.Len(t, requests, 2)
+	_, _, err = f.groupSv.ApproveJoinRequest(ctx, group.ID, bob.ID, request.ID)
+	assert.ErrorIs(t, err, ErrNotGroupOwner)
+
+	approved, _, err := f.groupSv.ApproveJoinRequest(ctx, group.ID, owner.ID, request.ID)
+	require.NoError(t, err)
+	assert.True(t, approved.HasMember(bob.ID))
+	_, _, err = f.groupSv.ApproveJoinRequest(ctx, group.ID, owner.ID, request.ID)
+	assert.ErrorIs(t, err, ErrJoinRequestNotFound)
+
+	_, err = f.groupSv.RejectJoinRequest(ctx, group.ID, owner.ID, carolRequest.ID)
+	require.NoError(t, err)
+	stored, err := f.groups.FindByID(ctx, group.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.HasMember(carol.ID))
+	requests, err = f.groupSv.ListJoinRequests(ctx, group.ID, owner.ID)
+	require.NoError(t, err)
+	assert.Empty(t, requests)
+}
+
+func TestDeleteGroup_DropsInvitesAndRequests(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+	_, err = f.groupSv.SetJoinApproval(ctx, group.ID, owner.ID, true)
+	require.NoError(t, err)
+	invite, err := f.groupSv.CreateInvite(ctx, group.ID, owner.ID, InviteOptions{Name: "Flyer"})
+	require.NoError(t, err)
+	bob, err := f.auth.Register(ctx, "bob", "password123")
+	require.NoError(t, err)
+	_, request, err := f.groupSv.JoinGroup(ctx, invite.Code, bob.ID, bob.Username)
+	require.NoError(t, err)
+
+	require.NoError(t, f.groupSv.DeleteGroup(ctx, group.ID, owner.ID))
+	_, err = f.invites.FindByID(ctx, invite.ID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	_, err = f.joinReq.FindByID(ctx, request.ID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+}
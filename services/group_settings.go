@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // timezone names validate without the host's zoneinfo
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+// Limits on the free-text settings, in characters.
+const (
+	MaxDescriptionLen = 500
+	MaxVenueLen       = 80
+)
+
+var (
+	ErrInvalidSettings  = errors.New("invalid group settings")
+	ErrSettingsConflict = errors.New("group settings were changed by someone else, reload and retry")
+)
+
+// SettingsUpdate lists the settings to change. Nil fields are left alone;
+// empty strings clear the description and venue.
+type SettingsUpdate struct {
+	Description     *string
+	Sport           *string
+	HomeVenue       *string
+	Timezone        *string
+	PointsToWin     *int
+	GuestsCanScore  *bool
+	AllowBackdating *bool
+	Privacy         *string
+}
+
+// GetSettings returns the group's settings, defaults filled in. Members can
+// read them, and so can anyone when the group is public.
+func (s *GroupService) GetSettings(ctx context.Context, groupID, userID primitive.ObjectID) (*models.GroupSettings, error) {
+	group, err := s.groupRepo.FindByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	settings := group.Settings.WithDefaults()
+	if settings.Privacy != models.PrivacyPublic && !group.HasMember(userID) {
+		return nil, ErrNotGroupMember
+	}
+	return &settings, nil
+}
+
+// UpdateSettings applies the owner's changes. version is the settings
+// version the owner last read; if they have been saved since, nothing is
+// changed and ErrSettingsConflict is returned.
+func (s *GroupService) UpdateSettings(ctx context.Context, groupID, ownerID primitive.ObjectID, version int, upd SettingsUpdate) (*models.GroupSettings, error) {
+	group, err := s.ownedGroup(ctx, groupID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if group.Settings.Version != version {
+		return nil, ErrSettingsConflict
+	}
+	settings := group.Settings.WithDefaults()
+	if err := applySettings(&settings, upd); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	settings.UpdatedAt, settings.UpdatedBy = &now, &ownerID
+	if err := s.groupRepo.UpdateSettings(ctx, groupID, &settings); err != nil {
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			return nil, ErrSettingsConflict
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// CheckVisible reports whether the user may read the group's matches,
+// players and leaderboard: members always, anyone else only when the group
+// is public.
+func (s *GroupService) CheckVisible(ctx context.Context, groupID, userID primitive.ObjectID) error {
+	_, err := s.GetSettings(ctx, groupID, userID)
+	return err
+}
+
+// applySettings validates the changes and applies them to settings.
+func applySettings(settings *models.GroupSettings, upd SettingsUpdate) error {
+	if upd.Description != nil {
+		desc := strings.TrimSpace(*upd.Description)
+		if utf8.RuneCountInString(desc) > MaxDescriptionLen {
+			return fmt.Errorf("%w: description is longer than %d characters", ErrInvalidSettings, MaxDescriptionLen)
+		}
+		settings.Description = desc
+	}
+	if upd.HomeVenue != nil {
+		venue := strings.Join(strings.Fields(*upd.HomeVenue), " ")
+		if utf8.RuneCountInString(venue) > MaxVenueLen {
+			return fmt.Errorf("%w: home venue is longer than %d characters", ErrInvalidSettings, MaxVenueLen)
+		}
+		settings.HomeVenue = venue
+	}
+	if upd.Sport != nil {
+		if !models.ValidSport(*upd.Sport) {
+			return fmt.Errorf("%w: unknown sport %q", ErrInvalidSettings, *upd.Sport)
+		}
+		settings.Sport = *upd.Sport
+	}
+	if upd.Timezone != nil {
+		// LoadLocation also accepts "" and "Local", which mean nothing to
+		// a client.
+		if _, err := time.LoadLocation(*upd.Timezone); err != nil || *upd.Timezone == "" || *upd.Timezone == "Local" {
+			return fmt.Errorf("%w: unknown timezone %q", ErrInvalidSettings, *upd.Timezone)
+		}
+		settings.Timezone = *upd.Timezone
+	}
+	if upd.PointsToWin != nil {
+		if !models.ValidPointsToWin(*upd.PointsToWin) {
+			return fmt.Errorf("%w: points to win must be %d, %d or %d", ErrInvalidSettings,
+				models.PointsToWin11, models.PointsToWin15, models.PointsToWin21)
+		}
+		settings.PointsToWin = *upd.PointsToWin
+	}
+	if upd.Privacy != nil {
+		if *upd.Privacy != models.PrivacyPrivate && *upd.Privacy != models.PrivacyPublic {
+			return fmt.Errorf("%w: privacy must be %s or %s", ErrInvalidSettings, models.PrivacyPrivate, models.PrivacyPublic)
+		}
+		settings.Privacy = *upd.Privacy
+	}
+	if upd.GuestsCanScore != nil {
+		settings.GuestsCanScore = *upd.GuestsCanScore
+	}
+	if upd.AllowBackdating != nil {
+		settings.AllowBackdating = *upd.AllowBackdating
+	}
+	return nil
+}
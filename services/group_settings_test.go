@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+)
+
+func TestGroupSettings(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+	bob, _ := f.member(t, "bob", group)
+
+	settings, err := f.groupSv.GetSettings(ctx, group.ID, bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SportBadminton, settings.Sport)
+	assert.Equal(t, models.PointsToWin21, settings.PointsToWin)
+	assert.Equal(t, models.PrivacyPrivate, settings.Privacy)
+	assert.Equal(t, "UTC", settings.Timezone)
+	assert.Zero(t, settings.Version)
+
+	desc, tz, points := "  Tuesdays at the leisure centre ", "Europe/London", models.PointsToWin15
+	_, err = f.groupSv.UpdateSettings(ctx, group.ID, bob.ID, 0, SettingsUpdate{Description: &desc})
+	assert.ErrorIs(t, err, ErrNotGroupOwner)
+	got, err := f.groupSv.UpdateSettings(ctx, group.ID, owner.ID, 0, SettingsUpdate{Description: &desc, Timezone: &tz, PointsToWin: &points})
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Version)
+	assert.Equal(t, "Tuesdays at the leisure centre", got.Description)
+	assert.Equal(t, owner.ID, *got.UpdatedBy)
+
+	// Unchanged fields keep their values; a stale version changes nothing.
+	on := true
+	_, err = f.groupSv.UpdateSettings(ctx, group.ID, owner.ID, 0, SettingsUpdate{GuestsCanScore: &on})
+	assert.ErrorIs(t, err, ErrSettingsConflict)
+	got, err = f.groupSv.UpdateSettings(ctx, group.ID, owner.ID, 1, SettingsUpdate{GuestsCanScore: &on})
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.Version)
+	assert.True(t, got.GuestsCanScore)
+	assert.Equal(t, tz, got.Timezone)
+	assert.Equal(t, models.PointsToWin15, got.PointsToWin)
+
+	stored, err := f.groupSv.GetSettings(ctx, group.ID, owner.ID)
+	require.NoError(t, err)
+	assert.Equal(t, got.Version, stored.Version)
+	assert.Equal(t, got.Description, stored.Description)
+}
+
+func TestGroupSettings_Invalid(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+
+	str := func(s string) *string { return &s }
+	num := func(n int) *int { return &n }
+	for _, upd := range []SettingsUpdate{
+		{Sport: str("quidditch")},
+		{Timezone: str("Mars/Olympus_Mons")},
+		{Timezone: str("Local")},
+		{PointsToWin: num(25)},
+		{Privacy: str("secret")},
+		{HomeVenue: str(strings.Repeat("x", MaxVenueLen+1))},
+	} {
+		_, err := f.groupSv.UpdateSettings(ctx, group.ID, owner.ID, 0, upd)
+		assert.ErrorIs(t, err, ErrInvalidSettings)
+	}
+	settings, err := f.groupSv.GetSettings(ctx, group.ID, owner.ID)
+	require.NoError(t, err)
+	assert.Zero(t, settings.Version, "nothing saved")
+}
+
+func TestGroupSettings_Privacy(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+	outsider, err := f.auth.Register(ctx, "outsider", "password123")
+	require.NoError(t, err)
+
+	assert.NoError(t, f.groupSv.CheckVisible(ctx, group.ID, owner.ID))
+	assert.ErrorIs(t, f.groupSv.CheckVisible(ctx, group.ID, outsider.ID), ErrNotGroupMember)
+	_, err = f.groupSv.GetSettings(ctx, group.ID, outsider.ID)
+	assert.ErrorIs(t, err, ErrNotGroupMember)
+
+	public := models.PrivacyPublic
+	_, err = f.groupSv.UpdateSettings(ctx, group.ID, owner.ID, 0, SettingsUpdate{Privacy: &public})
+	require.NoError(t, err)
+	assert.NoError(t, f.groupSv.CheckVisible(ctx, group.ID, outsider.ID))
+}
+
+// ── Settings applied to matches ──
+
+func TestMatchSettings_PointsToWin(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+	_, players := f.member(t, "bob", group)
+	ownerPlayer, err := f.playSv.CreatePlayerIfNotExists(ctx, owner.ID, owner.Name(), group.ID)
+	require.NoError(t, err)
+	points := models.PointsToWin11
+	_, err = f.groupSv.UpdateSettings(ctx, group.ID, owner.ID, 0, SettingsUpdate{PointsToWin: &points})
+	require.NoError(t, err)
+
+	ctx = WithActor(ctx, Actor{UserID: owner.ID.Hex()})
+	match, err := f.matchSv.CreateMatch(ctx, group.ID, []primitive.ObjectID{ownerPlayer.ID}, []primitive.ObjectID{players[0].ID}, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.PointsToWin11, match.PointsToWin)
+
+	// In a game to 11 the interval comes at 6.
+	for i := 0; i < 6; i++ {
+		match, err = f.matchSv.UpdateScore(ctx, match.ID, 1, ownerPlayer.ID.Hex())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, models.MatchStatusPaused, match.Status)
+	assert.Equal(t, models.PauseReasonInterval, match.OpenPause().Reason)
+}
+
+func TestMatchSettings_GuestScoring(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+	_, players := f.member(t, "bob", group)
+	ownerPlayer, err := f.playSv.CreatePlayerIfNotExists(ctx, owner.ID, owner.Name(), group.ID)
+	require.NoError(t, err)
+	guest, err := f.auth.Register(ctx, "guest", "password123")
+	require.NoError(t, err)
+	t1, t2 := []primitive.ObjectID{ownerPlayer.ID}, []primitive.ObjectID{players[0].ID}
+	asOwner := WithActor(ctx, Actor{UserID: owner.ID.Hex()})
+	asGuest := WithActor(ctx, Actor{UserID: guest.ID.Hex()})
+
+	match, err := f.matchSv.CreateMatch(asOwner, group.ID, t1, t2, "")
+	require.NoError(t, err)
+	_, err = f.matchSv.CreateMatch(asGuest, group.ID, t1, t2, "")
+	assert.ErrorIs(t, err, ErrNotGroupMember)
+	_, err = f.matchSv.UpdateScore(asGuest, match.ID, 1, ownerPlayer.ID.Hex())
+	assert.ErrorIs(t, err, ErrNotGroupMember)
+	_, err = f.matchSv.AddResult(asGuest, group.ID, t1, t2, 21, 10, "", nil)
+	assert.ErrorIs(t, err, ErrNotGroupMember)
+
+	on := true
+	_, err = f.groupSv.UpdateSettings(ctx, group.ID, owner.ID, 0, SettingsUpdate{GuestsCanScore: &on})
+	require.NoError(t, err)
+	match, err = f.matchSv.UpdateScore(asGuest, match.ID, 1, ownerPlayer.ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, 1, match.Score1)
+	_, err = f.matchSv.UndoScore(asGuest, match.ID)
+	require.NoError(t, err)
+
+	// Guests only keep score; running matches stays with members.
+	_, err = f.matchSv.CreateMatch(asGuest, group.ID, t1, t2, "")
+	assert.ErrorIs(t, err, ErrNotGroupMember)
+	_, err = f.matchSv.EditScore(asGuest, match.ID, 5, 5)
+	assert.ErrorIs(t, err, ErrNotGroupMember)
+	_, err = f.matchSv.FinishMatch(asGuest, match.ID)
+	assert.ErrorIs(t, err, ErrNotGroupMember)
+}
+
+func TestMatchSettings_Backdating(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+	_, players := f.member(t, "bob", group)
+	ownerPlayer, err := f.playSv.CreatePlayerIfNotExists(ctx, owner.ID, owner.Name(), group.ID)
+	require.NoError(t, err)
+	t1, t2 := []primitive.ObjectID{ownerPlayer.ID}, []primitive.ObjectID{players[0].ID}
+	ctx = WithActor(ctx, Actor{UserID: owner.ID.Hex()})
+
+	recent := time.Now().Add(-20 * time.Minute)
+	match, err := f.matchSv.AddResult(ctx, group.ID, t1, t2, 21, 10, "", &recent)
+	require.NoError(t, err, "a match just played is not back-dating")
+	assert.True(t, recent.Equal(match.StartedAt))
+
+	lastWeek := time.Now().Add(-7 * 24 * time.Hour)
+	_, err = f.matchSv.AddResult(ctx, group.ID, t1, t2, 21, 10, "", &lastWeek)
+	assert.ErrorIs(t, err, ErrBackdatingDisabled)
+	tomorrow := time.Now().Add(24 * time.Hour)
+	_, err = f.matchSv.AddResult(ctx, group.ID, t1, t2, 21, 10, "", &tomorrow)
+	assert.ErrorIs(t, err, ErrInvalidResult)
+
+	on := true
+	_, err = f.groupSv.UpdateSettings(context.Background(), group.ID, owner.ID, 0, SettingsUpdate{AllowBackdating: &on})
+	require.NoError(t, err)
+	match, err = f.matchSv.AddResult(ctx, group.ID, t1, t2, 21, 10, "", &lastWeek)
+	require.NoError(t, err)
+	assert.True(t, lastWeek.Equal(*match.FinishedAt))
+	assert.Equal(t, models.PointsToWin21, match.PointsToWin)
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Defaults for the iss and aud claims of access tokens.
+const (
+	DefaultJWTIssuer   = "gully-backend"
+	DefaultJWTAudience = "gully-app"
+)
+
+// Supported signing algorithms.
+const (
+	JWTAlgHS256 = "HS256"
+	JWTAlgRS256 = "RS256"
+	JWTAlgEdDSA = "EdDSA"
+)
+
+const minRSAKeyBits = 2048
+
+// JWTKeys signs access tokens with one key and verifies them against a set
+// of keys picked by the token's kid header. Rotating a key pair means
+// signing with the new one while the old public key stays in the verify
+// set for at least the access token lifetime.
+//
+// Every token must use the configured algorithm; a token naming any other
+// one, such as HS256 signed with an RSA public key, is refused.
+type JWTKeys struct {
+	Issuer   string
+	Audience string
+
+	method  jwt.SigningMethod
+	kid     string
+	signKey interface{}
+	verify  map[string]interface{}
+}
+
+// NewHMACKeys signs and verifies with a shared secret (HS256). Tokens
+// carry no kid.
+func NewHMACKeys(secret string) *JWTKeys {
+	return &JWTKeys{
+		Issuer:   DefaultJWTIssuer,
+		Audience: DefaultJWTAudience,
+		method:   jwt.SigningMethodHS256,
+		signKey:  []byte(secret),
+		verify:   map[string]interface{}{"": []byte(secret)},
+	}
+}
+
+// ParseJWTKeys signs with a PEM private key for RS256 or EdDSA under the
+// given kid. Its public key is trusted for verification; add keys being
+// rotated out with AddVerifyKey.
+func ParseJWTKeys(alg, kid string, privatePEM []byte) (*JWTKeys, error) {
+	if kid == "" {
+		return nil, errors.New("a key id is required for " + alg)
+	}
+	keys := &JWTKeys{
+		Issuer:   DefaultJWTIssuer,
+		Audience: DefaultJWTAudience,
+		kid:      kid,
+		verify:   map[string]interface{}{},
+	}
+	var public crypto.PublicKey
+	switch alg {
+	case JWTAlgRS256:
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+		if err != nil {
+			return nil, fmt.Errorf("RS256 private key: %w", err)
+		}
+		if key.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RS256 private key: need at least %d bits, got %d", minRSAKeyBits, key.N.BitLen())
+		}
+		keys.method, keys.signKey, public = jwt.SigningMethodRS256, key, &key.PublicKey
+	case JWTAlgEdDSA:
+		key, err := jwt.ParseEdPrivateKeyFromPEM(privatePEM)
+		if err != nil {
+			return nil, fmt.Errorf("EdDSA private key: %w", err)
+		}
+		keys.method, keys.signKey, public = jwt.SigningMethodEdDSA, key, key.(ed25519.PrivateKey).Public()
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", alg)
+	}
+	keys.verify[kid] = public
+	return keys, nil
+}
+
+// AddVerifyKey trusts a PEM public key for tokens whose kid matches. The
+// key must be for the same algorithm the keys sign with.
+func (k *JWTKeys) AddVerifyKey(kid string, publicPEM []byte) error {
+	if kid == "" {
+		return errors.New("a key id is required")
+	}
+	if _, ok := k.verify[kid]; ok {
+		return fmt.Errorf("key id %q is already in use", kid)
+	}
+	var public interface{}
+	var err error
+	switch k.method {
+	case jwt.SigningMethodRS256:
+		public, err = jwt.ParseRSAPublicKeyFromPEM(publicPEM)
+	case jwt.SigningMethodEdDSA:
+		public, err = jwt.ParseEdPublicKeyFromPEM(publicPEM)
+	default:
+		return fmt.Errorf("%s keys take no extra verify keys", k.method.Alg())
+	}
+	if err != nil {
+		return fmt.Errorf("public key %q: %w", kid, err)
+	}
+	k.verify[kid] = public
+	return nil
+}
+
+// Alg is the algorithm tokens are signed and verified with.
+func (k *JWTKeys) Alg() string { return k.method.Alg() }
+
+func (k *JWTKeys) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.method, claims)
+	if k.kid != "" {
+		token.Header["kid"] = k.kid
+	}
+	return token.SignedString(k.signKey)
+}
+
+// verifyKey is the jwt.Keyfunc picking the key for a token's kid.
+func (k *JWTKeys) verifyKey(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, ok := k.verify[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return key, nil
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gully-backend/repositories/memory"
+)
+
+func pemKeys(t *testing.T, private crypto.Signer) (privatePEM, publicPEM []byte) {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	pub, err := x509.MarshalPKIXPublicKey(private.Public())
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub})
+}
+
+func newEdKey(t *testing.T) crypto.Signer {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func TestJWTKeys_RotationOverlap(t *testing.T) {
+	for _, alg := range []string{JWTAlgEdDSA, JWTAlgRS256} {
+		t.Run(alg, func(t *testing.T) {
+			newKey := func() crypto.Signer {
+				if alg == JWTAlgEdDSA {
+					return newEdKey(t)
+				}
+				key, err := rsa.GenerateKey(rand.Reader, 2048)
+				require.NoError(t, err)
+				return key
+			}
+			oldPrivate, oldPublic := pemKeys(t, newKey())
+			newPrivate, _ := pemKeys(t, newKey())
+
+			users, sessions := memory.NewUserRepo(), memory.NewSessionRepo()
+			oldKeys, err := ParseJWTKeys(alg, "2024-01", oldPrivate)
+			require.NoError(t, err)
+			before := NewAuthService(users, sessions, nil, oldKeys, 0, 0)
+			_, err = before.Register(context.Background(), "alice", "password123")
+			require.NoError(t, err)
+			ctx := context.Background()
+			oldToken, _, err := before.Login(ctx, "alice", "password123")
+			require.NoError(t, err)
+
+			header, _, err := jwt.NewParser().ParseUnverified(oldToken.AccessToken, jwt.MapClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, "2024-01", header.Header["kid"])
+			assert.Equal(t, alg, header.Header["alg"])
+
+			// The new key signs; the old one is still trusted for a while.
+			rotated, err := ParseJWTKeys(alg, "2024-02", newPrivate)
+			require.NoError(t, err)
+			require.NoError(t, rotated.AddVerifyKey("2024-01", oldPublic))
+			during := NewAuthService(users, sessions, nil, rotated, 0, 0)
+			_, err = during.VerifyAccessToken(ctx, oldToken.AccessToken)
+			assert.NoError(t, err)
+			newToken, _, err := during.Login(ctx, "alice", "password123")
+			require.NoError(t, err)
+			_, err = during.VerifyAccessToken(ctx, newToken.AccessToken)
+			assert.NoError(t, err)
+			_, err = before.VerifyAccessToken(ctx, newToken.AccessToken)
+			assert.ErrorIs(t, err, ErrInvalidToken, "unknown kid")
+
+			// Once the old key is dropped its tokens stop working.
+			after, err := ParseJWTKeys(alg, "2024-02", newPrivate)
+			require.NoError(t, err)
+			_, err = NewAuthService(users, sessions, nil, after, 0, 0).VerifyAccessToken(ctx, oldToken.AccessToken)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestJWTKeys_RejectsHMACWithPublicKey(t *testing.T) {
+	private, public := pemKeys(t, newEdKey(t))
+	keys, err := ParseJWTKeys(JWTAlgEdDSA, "k1", private)
+	require.NoError(t, err)
+	svc := NewAuthService(memory.NewUserRepo(), memory.NewSessionRepo(), nil, keys, 0, 0)
+
+	// The classic algorithm confusion: an HS256 token keyed with the
+	// public key, which anyone can read.
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "x"})
+	forged.Header["kid"] = "k1"
+	token, err := forged.SignedString(public)
+	require.NoError(t, err)
+	_, err = svc.VerifyAccessToken(context.Background(), token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestParseJWTKeys_Invalid(t *testing.T) {
+	edPrivate, edPublic := pemKeys(t, newEdKey(t))
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	smallPrivate, _ := pemKeys(t, small)
+
+	_, err = ParseJWTKeys(JWTAlgEdDSA, "", edPrivate)
+	assert.Error(t, err, "kid required")
+	_, err = ParseJWTKeys("ES256", "k1", edPrivate)
+	assert.Error(t, err, "unsupported alg")
+	_, err = ParseJWTKeys(JWTAlgRS256, "k1", edPrivate)
+	assert.Error(t, err, "key of the wrong type")
+	_, err = ParseJWTKeys(JWTAlgRS256, "k1", smallPrivate)
+	assert.Error(t, err, "RSA key too small")
+
+	keys, err := ParseJWTKeys(JWTAlgEdDSA, "k1", edPrivate)
+	require.NoError(t, err)
+	assert.Error(t, keys.AddVerifyKey("k1", edPublic), "kid already used")
+	assert.Error(t, NewHMACKeys("secret").AddVerifyKey("k2", edPublic))
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Progressive login lockout: after LoginLockoutThreshold failed logins in a
+// row for a username, it is locked for LoginLockoutBase, doubling with each
+// further failure up to LoginLockoutMax. Failures are forgotten after
+// LoginLockoutMax without another one, or on a successful login.
+const (
+	LoginLockoutThreshold = 5
+	LoginLockoutBase      = time.Minute
+	LoginLockoutMax       = time.Hour
+)
+
+var ErrLoginLocked = errors.New("too many failed logins; try again later")
+
+// LoginLockedError is returned by Login and ChangePassword while the
+// username is locked out.
+// It matches ErrLoginLocked with errors.Is.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string { return ErrLoginLocked.Error() }
+
+func (e *LoginLockedError) Unwrap() error { return ErrLoginLocked }
+
+// LoginAttemptStore counts failed logins per username. The lockout policy
+// lives in AuthService; a store only has to count and remember deadlines,
+// so a shared one can back several instances.
+type LoginAttemptStore interface {
+	// LockedUntil returns when the lockout for key ends, or the zero time.
+	LockedUntil(key string) time.Time
+	// Fail records a failed login and returns the failures in a row,
+	// starting over if the last one was more than window ago.
+	Fail(key string, window time.Duration) int
+	// Lock locks key out until the given time.
+	Lock(key string, until time.Time)
+	// Reset forgets the failures and any lockout for key.
+	Reset(key string)
+}
+
+// loginKey folds case so "Alice" and "alice" share a counter.
+func loginKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// lockoutFor is how long the given number of failures in a row locks a
+// username out; zero below the threshold.
+func lockoutFor(failures int) time.Duration {
+	if failures < LoginLockoutThreshold {
+		return 0
+	}
+	d := LoginLockoutBase
+	for i := LoginLockoutThreshold; i < failures && d < LoginLockoutMax; i++ {
+		d *= 2
+	}
+	if d > LoginLockoutMax {
+		d = LoginLockoutMax
+	}
+	return d
+}
+
+// ── Memory store ──
+
+type loginAttempts struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// MemoryLoginAttempts keeps failure counts in process memory.
+type MemoryLoginAttempts struct {
+	mu      sync.Mutex
+	entries map[string]*loginAttempts
+	sweptAt time.Time
+}
+
+func NewMemoryLoginAttempts() *MemoryLoginAttempts {
+	return &MemoryLoginAttempts{entries: make(map[string]*loginAttempts)}
+}
+
+func (s *MemoryLoginAttempts) LockedUntil(key string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok {
+		return e.lockedUntil
+	}
+	return time.Time{}
+}
+
+func (s *MemoryLoginAttempts) Fail(key string, window time.Duration) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now, window)
+	e, ok := s.entries[key]
+	if !ok {
+		e = &loginAttempts{}
+		s.entries[key] = e
+	}
+	if now.Sub(e.lastFailure) > window {
+		e.failures = 0
+	}
+	e.failures++
+	e.lastFailure = now
+	return e.failures
+}
+
+func (s *MemoryLoginAttempts) Lock(key string, until time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok {
+		e.lockedUntil = until
+		return
+	}
+	s.entries[key] = &loginAttempts{lastFailure: time.Now(), lockedUntil: until}
+}
+
+func (s *MemoryLoginAttempts) Reset(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+}
+
+// sweep drops entries idle for longer than window whose lockout is over,
+// at most once per window. Caller holds s.mu.
+func (s *MemoryLoginAttempts) sweep(now time.Time, window time.Duration) {
+	if now.Sub(s.sweptAt) < window {
+		return
+	}
+	for k, e := range s.entries {
+		if now.Sub(e.lastFailure) > window && now.After(e.lockedUntil) {
+			delete(s.entries, k)
+		}
+	}
+	s.sweptAt = now
+}
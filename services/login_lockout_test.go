@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gully-backend/repositories/memory"
+)
+
+func newLockoutAuth(t *testing.T) (*AuthService, *MemoryLoginAttempts) {
+	attempts := NewMemoryLoginAttempts()
+	svc := NewAuthService(memory.NewUserRepo(), memory.NewSessionRepo(), attempts, NewHMACKeys("test-secret"), 0, 0)
+	_, err := svc.Register(context.Background(), "alice", "password123")
+	require.NoError(t, err)
+	return svc, attempts
+}
+
+func TestLogin_LocksOutAfterRepeatedFailures(t *testing.T) {
+	svc, attempts := newLockoutAuth(t)
+	ctx := context.Background()
+
+	for i := 1; i < LoginLockoutThreshold; i++ {
+		_, _, err := svc.Login(ctx, "alice", "wrong")
+		require.ErrorIs(t, err, ErrInvalidCredentials, "failure %d", i)
+	}
+	_, _, err := svc.Login(ctx, "Alice", "wrong")
+	var locked *LoginLockedError
+	require.ErrorAs(t, err, &locked, "usernames are counted case-insensitively")
+	assert.ErrorIs(t, err, ErrLoginLocked)
+	assert.Equal(t, LoginLockoutBase, locked.RetryAfter)
+
+	// Locked out even with the right password.
+	_, _, err = svc.Login(ctx, "alice", "password123")
+	assert.ErrorIs(t, err, ErrLoginLocked)
+
+	// Once the lockout lapses, one more failure locks for twice as long.
+	attempts.Lock("alice", time.Now().Add(-time.Second))
+	_, _, err = svc.Login(ctx, "alice", "wrong")
+	require.ErrorAs(t, err, &locked)
+	assert.Equal(t, 2*LoginLockoutBase, locked.RetryAfter)
+
+	attempts.Lock("alice", time.Now().Add(-time.Second))
+	_, _, err = svc.Login(ctx, "alice", "password123")
+	require.NoError(t, err)
+	_, _, err = svc.Login(ctx, "alice", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "success starts the count over")
+}
+
+func TestLogin_UnknownUserLocksOutToo(t *testing.T) {
+	svc, _ := newLockoutAuth(t)
+	var err error
+	for i := 0; i < LoginLockoutThreshold; i++ {
+		_, _, err = svc.Login(context.Background(), "nobody", "wrong")
+	}
+	assert.ErrorIs(t, err, ErrLoginLocked)
+}
+
+func TestLockoutFor(t *testing.T) {
+	assert.Zero(t, lockoutFor(LoginLockoutThreshold-1))
+	assert.Equal(t, LoginLockoutBase, lockoutFor(LoginLockoutThreshold))
+	assert.Equal(t, 4*LoginLockoutBase, lockoutFor(LoginLockoutThreshold+2))
+	assert.Equal(t, LoginLockoutMax, lockoutFor(LoginLockoutThreshold+50))
+}
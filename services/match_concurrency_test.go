@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/repositories"
+	"gully-backend/repositories/memory"
+)
+
+// ── Concurrency tests ──
+
+func TestUpdateScore_ParallelScorers_NoLostIncrements(t *testing.T) {
+	matchRepo := memory.NewMatchRepo()
+	svc := NewMatchService(matchRepo, new(MockPlayerRepo), memory.NewMatchEventRepo(), nil, nil)
+	ctx := context.Background()
+
+	p1, p2 := newPlayerID(), newPlayerID()
+	match := makeLiveMatch([]primitive.ObjectID{p1}, []primitive.ObjectID{p2})
+	require.NoError(t, matchRepo.Create(ctx, match))
+
+	const scorers = 20
+	var (
+		wg        sync.WaitGroup
+		start     = make(chan struct{})
+		mu        sync.Mutex
+		successes = map[int]int{}
+	)
+	for i := 0; i < scorers; i++ {
+		team := i%2 + 1
+		scorer := p1
+		if team == 2 {
+			scorer = p2
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if _, err := svc.UpdateScore(ctx, match.ID, team, scorer.Hex()); err != nil {
+				assert.ErrorIs(t, err, ErrMatchConflict)
+				return
+			}
+			mu.Lock()
+			successes[team]++
+			mu.Unlock()
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	final, err := matchRepo.FindByID(ctx, match.ID)
+	require.NoError(t, err)
+	assert.Equal(t, successes[1], final.Score1)
+	assert.Equal(t, successes[2], final.Score2)
+	assert.Len(t, final.ScoreHistory, successes[1]+successes[2])
+	assert.Equal(t, 1+successes[1]+successes[2], final.Version)
+	assert.Equal(t, scorers, successes[1]+successes[2], "every scorer should succeed within the retry budget")
+}
+
+func TestUpdateScore_StaleVersion_Retries(t *testing.T) {
+	matchRepo := new(MockMatchRepo)
+	svc := NewMatchService(matchRepo, new(MockPlayerRepo), memory.NewMatchEventRepo(), nil, nil)
+	ctx := context.Background()
+
+	p1, p2 := newPlayerID(), newPlayerID()
+	match := makeLiveMatch([]primitive.ObjectID{p1}, []primitive.ObjectID{p2})
+
+	matchRepo.On("FindByID", ctx, match.ID).Return(match, nil)
+	matchRepo.On("Update", ctx, match).Return(repositories.ErrVersionConflict).Once()
+	matchRepo.On("Update", ctx, match).Return(nil).Once()
+
+	result, err := svc.UpdateScore(ctx, match.ID, 1, p1.Hex())
+
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	matchRepo.AssertNumberOfCalls(t, "FindByID", 2)
+	matchRepo.AssertNumberOfCalls(t, "Update", 2)
+}
+
+func TestUpdateScore_PersistentConflict_ReturnsErrMatchConflict(t *testing.T) {
+	matchRepo := new(MockMatchRepo)
+	svc := NewMatchService(matchRepo, new(MockPlayerRepo), memory.NewMatchEventRepo(), nil, nil)
+	ctx := context.Background()
+
+	p1, p2 := newPlayerID(), newPlayerID()
+	match := makeLiveMatch([]primitive.ObjectID{p1}, []primitive.ObjectID{p2})
+
+	matchRepo.On("FindByID", ctx, match.ID).Return(match, nil)
+	matchRepo.On("Update", ctx, match).Return(repositories.ErrVersionConflict)
+
+	_, err := svc.UpdateScore(ctx, match.ID, 1, p1.Hex())
+
+	assert.ErrorIs(t, err, ErrMatchConflict)
+	matchRepo.AssertNumberOfCalls(t, "Update", maxUpdateAttempts)
+}
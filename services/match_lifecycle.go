@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+)
+
+var errNotStarted = stateError("match has not started")
+
+// ScheduleMatch creates a match that is waiting for a court. The clock only
+// starts once StartMatch is called.
+func (s *MatchService) ScheduleMatch(ctx context.Context, groupID primitive.ObjectID, team1IDs, team2IDs []primitive.ObjectID, scheduledAt *time.Time, sessionID string) (*models.Match, error) {
+	match, err := s.newMatch(ctx, groupID, team1IDs, team2IDs, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	match.Status = models.MatchStatusScheduled
+	match.ScheduledAt = scheduledAt
+	if err := s.insertMatch(ctx, match); err != nil {
+		return nil, err
+	}
+	return match, nil
+}
+
+// WarmUpMatch marks a scheduled match as on court and warming up.
+func (s *MatchService) WarmUpMatch(ctx context.Context, matchID primitive.ObjectID) (*models.Match, error) {
+	return s.transition(ctx, matchID, models.MatchStatusWarmup, func(*models.Match, time.Time) {})
+}
+
+// StartMatch begins play and starts the clock.
+func (s *MatchService) StartMatch(ctx context.Context, matchID primitive.ObjectID) (*models.Match, error) {
+	return s.transition(ctx, matchID, models.MatchStatusLive, func(match *models.Match, now time.Time) {
+		match.StartedAt = now
+	})
+}
+
+// AbandonMatch ends a match without a result. Abandoned matches are left out
+// of results and stats.
+func (s *MatchService) AbandonMatch(ctx context.Context, matchID primitive.ObjectID, reason string) (*models.Match, error) {
+	return s.transition(ctx, matchID, models.MatchStatusAbandoned, func(match *models.Match, now time.Time) {
+		endWithoutResult(match, reason, now)
+	})
+}
+
+// WalkoverMatch awards the match to winnerTeam without (full) play, e.g. a
+// no-show before the start or a retirement during play.
+func (s *MatchService) WalkoverMatch(ctx context.Context, matchID primitive.ObjectID, winnerTeam int, reason string) (*models.Match, error) {
+	if winnerTeam != 1 && winnerTeam != 2 {
+		return nil, invalidRequest("invalid team number: %d", winnerTeam)
+	}
+	return s.transition(ctx, matchID, models.MatchStatusWalkover, func(match *models.Match, now time.Time) {
+		endWithoutResult(match, reason, now)
+		match.WinnerTeam = winnerTeam
+	})
+}
+
+// transition moves a match to a new status if the transition table allows it.
+func (s *MatchService) transition(ctx context.Context, matchID primitive.ObjectID, to string, apply func(*models.Match, time.Time)) (*models.Match, error) {
+	return s.mutateMatch(ctx, matchID, func(match *models.Match, tl *timeline) error {
+		if !models.CanTransition(match.Status, to) {
+			return stateError("cannot move match from %s to %s", match.Status, to)
+		}
+		now := time.Now()
+		if match.Status == models.MatchStatusPaused {
+			endPause(match, tl, now)
+		}
+		match.Status = to
+		apply(match, now)
+		tl.record(match, models.MatchEventStatus, nil)
+		return nil
+	})
+}
+
+// endWithoutResult stamps the end of a match that did not finish normally.
+// Only time actually played counts towards its duration.
+func endWithoutResult(match *models.Match, reason string, now time.Time) {
+	match.FinishedAt = &now
+	match.EndReason = reason
+	if !match.StartedAt.IsZero() {
+		match.DurationSecs = activeSecs(match, now)
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories/memory"
+)
+
+func TestScheduleMatch_ClockStartsOnStart(t *testing.T) {
+	matchRepo := memory.NewMatchRepo()
+	playerRepo := new(MockPlayerRepo)
+	eventRepo := memory.NewMatchEventRepo()
+	svc := NewMatchService(matchRepo, playerRepo, eventRepo, nil, nil)
+	ctx := context.Background()
+
+	p1, p2 := newPlayerID(), newPlayerID()
+	playerRepo.On("FindByID", ctx, p1).Return(&models.Player{ID: p1, Name: "Alice"}, nil)
+	playerRepo.On("FindByID", ctx, p2).Return(&models.Player{ID: p2, Name: "Bob"}, nil)
+
+	at := time.Now().Add(time.Hour)
+	match, err := svc.ScheduleMatch(ctx, primitive.NewObjectID(), []primitive.ObjectID{p1}, []primitive.ObjectID{p2}, &at, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.MatchStatusScheduled, match.Status)
+	assert.True(t, match.StartedAt.IsZero())
+
+	_, err = svc.UpdateScore(ctx, match.ID, 1, p1.Hex())
+	assert.EqualError(t, err, "match has not started")
+	_, err = svc.FinishMatch(ctx, match.ID)
+	assert.EqualError(t, err, "match has not started")
+
+	match, err = svc.WarmUpMatch(ctx, match.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.MatchStatusWarmup, match.Status)
+	assert.True(t, match.StartedAt.IsZero())
+
+	match, err = svc.StartMatch(ctx, match.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.MatchStatusLive, match.Status)
+	assert.WithinDuration(t, time.Now(), match.StartedAt, time.Second)
+
+	_, err = svc.StartMatch(ctx, match.ID)
+	assert.EqualError(t, err, "cannot move match from live to live")
+
+	events, err := svc.GetTimeline(ctx, match.ID)
+	require.NoError(t, err)
+	replayed, _, err := ReplayMatch(events)
+	require.NoError(t, err)
+	assert.Equal(t, models.MatchStatusLive, replayed.Status)
+	assert.Equal(t, match.StartedAt.Unix(), replayed.StartedAt.Unix())
+}
+
+func TestAbandonMatch_ClosesPauseAndIsTerminal(t *testing.T) {
+	svc, _, match, _ := newPauseFixture(t)
+	ctx := context.Background()
+
+	_, err := svc.PauseMatch(ctx, match.ID, models.PauseReasonInjury)
+	require.NoError(t, err)
+
+	result, err := svc.AbandonMatch(ctx, match.ID, "rain")
+	require.NoError(t, err)
+	assert.Equal(t, models.MatchStatusAbandoned, result.Status)
+	assert.Equal(t, "rain", result.EndReason)
+	assert.NotNil(t, result.FinishedAt)
+	assert.Nil(t, result.OpenPause())
+
+	_, err = svc.FinishMatch(ctx, match.ID)
+	assert.Error(t, err)
+	_, err = svc.StartMatch(ctx, match.ID)
+	assert.Error(t, err)
+}
+
+func TestWalkoverMatch_ScheduledNoShow(t *testing.T) {
+	matchRepo := memory.NewMatchRepo()
+	svc := NewMatchService(matchRepo, new(MockPlayerRepo), memory.NewMatchEventRepo(), nil, nil)
+	ctx := context.Background()
+
+	match := makeLiveMatch([]primitive.ObjectID{newPlayerID()}, []primitive.ObjectID{newPlayerID()})
+	match.Status = models.MatchStatusScheduled
+	require.NoError(t, matchRepo.Create(ctx, match))
+
+	_, err := svc.WalkoverMatch(ctx, match.ID, 3, "no-show")
+	assert.Error(t, err)
+
+	result, err := svc.WalkoverMatch(ctx, match.ID, 2, "no-show")
+	require.NoError(t, err)
+	assert.Equal(t, models.MatchStatusWalkover, result.Status)
+	assert.Equal(t, 2, result.WinnerTeam)
+	assert.Zero(t, result.DurationSecs)
+}
+
+func TestStats_WalkoverCountsAbandonedIgnored(t *testing.T) {
+	p1, p2 := newPlayerID(), newPlayerID()
+	players := []models.Player{{ID: p1, Name: "Alice"}, {ID: p2, Name: "Bob"}}
+
+	walkover := makeLiveMatch([]primitive.ObjectID{p1}, []primitive.ObjectID{p2})
+	walkover.Status = models.MatchStatusWalkover
+	walkover.WinnerTeam = 2
+	walkover.Score1 = 5
+	walkover.ScoreHistory = []models.ScoreEvent{{Team: 1, PlayerID: p1.Hex(), RallySecs: 10}}
+
+	abandoned := makeLiveMatch([]primitive.ObjectID{p1}, []primitive.ObjectID{p2})
+	abandoned.Status = models.MatchStatusAbandoned
+	abandoned.Score1 = 15
+
+	stats := computePlayerStats(players, []models.Match{*walkover, *abandoned})
+	byID := map[primitive.ObjectID]PlayerStats{}
+	for _, s := range stats {
+		byID[s.PlayerID] = s
+	}
+
+	assert.Equal(t, 1, byID[p1].Played)
+	assert.Equal(t, 1, byID[p1].Lost)
+	assert.Equal(t, 1, byID[p1].Walkovers)
+	assert.Zero(t, byID[p1].PointsScored)
+	assert.Zero(t, byID[p1].AvgRallySecs)
+	assert.Equal(t, 1, byID[p2].Won)
+}
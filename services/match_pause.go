@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+)
+
+// PauseMatch stops the clock for a break, an injury or an interval.
+func (s *MatchService) PauseMatch(ctx context.Context, matchID primitive.ObjectID, reason string) (*models.Match, error) {
+	var planned time.Duration
+	switch reason {
+	case models.PauseReasonBreak, models.PauseReasonInjury:
+	case models.PauseReasonInterval:
+		planned = models.IntervalSecs * time.Second
+	case models.PauseReasonGameInterval:
+		planned = models.GameIntervalSecs * time.Second
+	default:
+		return nil, invalidRequest("invalid pause reason: %q", reason)
+	}
+
+	return s.mutateMatch(ctx, matchID, func(match *models.Match, tl *timeline) error {
+		if match.Status == models.MatchStatusPaused {
+			return stateError("match is already paused")
+		}
+		if match.Status != models.MatchStatusLive {
+			return stateError("match is not live")
+		}
+		startPause(match, tl, reason, false, planned, time.Now())
+		return nil
+	})
+}
+
+// ResumeMatch restarts the clock after a pause.
+func (s *MatchService) ResumeMatch(ctx context.Context, matchID primitive.ObjectID) (*models.Match, error) {
+	return s.mutateMatch(ctx, matchID, func(match *models.Match, tl *timeline) error {
+		if match.Status != models.MatchStatusPaused {
+			return stateError("match is not paused")
+		}
+		endPause(match, tl, time.Now())
+		return nil
+	})
+}
+
+// startPause opens a pause; planned > 0 marks when an interval is due to end.
+func startPause(match *models.Match, tl *timeline, reason string, auto bool, planned time.Duration, now time.Time) {
+	pause := models.Pause{Reason: reason, Auto: auto, StartedAt: now}
+	if planned > 0 {
+		endsAt := now.Add(planned)
+		pause.EndsAt = &endsAt
+	}
+	match.Pauses = append(match.Pauses, pause)
+	match.Status = models.MatchStatusPaused
+	tl.record(match, models.MatchEventPause, func(e *models.MatchEvent) { e.Pause = &pause })
+}
+
+// endPause closes the open pause and adds its length to PausedSecs.
+func endPause(match *models.Match, tl *timeline, now time.Time) {
+	pause := match.OpenPause()
+	if pause == nil {
+		return
+	}
+	pause.EndedAt = &now
+	match.PausedSecs += int(now.Sub(pause.StartedAt).Seconds())
+	match.Status = models.MatchStatusLive
+	closed := *pause
+	tl.record(match, models.MatchEventResume, func(e *models.MatchEvent) { e.Pause = &closed })
+}
+
+// readyForPlay checks a rally can be recorded. An automatic interval ends as
+// soon as the next rally comes in; any other pause must be resumed first.
+func readyForPlay(match *models.Match, tl *timeline, now time.Time) error {
+	if match.Status == models.MatchStatusPaused {
+		if pause := match.OpenPause(); pause != nil && pause.Auto {
+			endPause(match, tl, now)
+			return nil
+		}
+		return stateError("match is paused")
+	}
+	if match.Status == models.MatchStatusScheduled || match.Status == models.MatchStatusWarmup {
+		return errNotStarted
+	}
+	if match.Status != models.MatchStatusLive {
+		return stateError("match is not live")
+	}
+	return nil
+}
+
+// startIntervalIfDue inserts the 60-second interval the first time the leading
+// side reaches the interval score (11 in a game to 21).
+func startIntervalIfDue(match *models.Match, tl *timeline, now time.Time) {
+	if max(match.Score1, match.Score2) != models.IntervalAt(match.PointsToWin) || match.Score1 == match.Score2 {
+		return
+	}
+	for _, p := range match.Pauses {
+		if p.Auto && p.Reason == models.PauseReasonInterval {
+			return
+		}
+	}
+	startPause(match, tl, models.PauseReasonInterval, true, models.IntervalSecs*time.Second, now)
+}
+
+// cancelInterval drops an automatic interval that has not ended yet, used when
+// the point that triggered it is undone. It returns the removed pause.
+func cancelInterval(match *models.Match) *models.Pause {
+	pause := match.OpenPause()
+	if pause == nil || !pause.Auto {
+		return nil
+	}
+	cancelled := *pause
+	match.Pauses = match.Pauses[:len(match.Pauses)-1]
+	match.Status = models.MatchStatusLive
+	return &cancelled
+}
+
+// activeSecs is the match's elapsed time minus pauses.
+func activeSecs(match *models.Match, end time.Time) int {
+	secs := int(end.Sub(match.StartedAt).Seconds()) - match.PausedSecs
+	if secs < 0 {
+		return 0
+	}
+	return secs
+}
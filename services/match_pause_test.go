@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories/memory"
+)
+
+func newPauseFixture(t *testing.T) (*MatchService, *memory.MatchEventRepo, *models.Match, primitive.ObjectID) {
+	t.Helper()
+	matchRepo := memory.NewMatchRepo()
+	eventRepo := memory.NewMatchEventRepo()
+	svc := NewMatchService(matchRepo, new(MockPlayerRepo), eventRepo, nil, nil)
+	p1, p2 := newPlayerID(), newPlayerID()
+	match := makeLiveMatch([]primitive.ObjectID{p1}, []primitive.ObjectID{p2})
+	match.StartedAt = time.Now().Add(-10 * time.Minute)
+	require.NoError(t, matchRepo.Create(context.Background(), match))
+	svc.recordCreated(context.Background(), match)
+	return svc, eventRepo, match, p1
+}
+
+func TestUpdateScore_ReachingElevenStartsInterval(t *testing.T) {
+	svc, _, match, p1 := newPauseFixture(t)
+	ctx := context.Background()
+
+	var result *models.Match
+	var err error
+	for i := 0; i < models.IntervalPoints; i++ {
+		result, err = svc.UpdateScore(ctx, match.ID, 1, p1.Hex())
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, models.MatchStatusPaused, result.Status)
+	pause := result.OpenPause()
+	require.NotNil(t, pause)
+	assert.True(t, pause.Auto)
+	assert.Equal(t, models.PauseReasonInterval, pause.Reason)
+	require.NotNil(t, pause.EndsAt)
+	assert.Equal(t, models.IntervalSecs*time.Second, pause.EndsAt.Sub(pause.StartedAt))
+
+	// The next rally ends the interval; no second interval is inserted.
+	result, err = svc.UpdateScore(ctx, match.ID, 1, p1.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, models.MatchStatusLive, result.Status)
+	assert.Nil(t, result.OpenPause())
+	assert.Len(t, result.Pauses, 1)
+}
+
+func TestUndoScore_CancelsIntervalItTriggered(t *testing.T) {
+	svc, _, match, p1 := newPauseFixture(t)
+	ctx := context.Background()
+
+	for i := 0; i < models.IntervalPoints; i++ {
+		_, err := svc.UpdateScore(ctx, match.ID, 1, p1.Hex())
+		require.NoError(t, err)
+	}
+	result, err := svc.UndoScore(ctx, match.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.MatchStatusLive, result.Status)
+	assert.Empty(t, result.Pauses)
+	assert.Equal(t, models.IntervalPoints-1, result.Score1)
+}
+
+func TestPauseMatch_BlocksScoringUntilResumed(t *testing.T) {
+	svc, _, match, p1 := newPauseFixture(t)
+	ctx := context.Background()
+
+	paused, err := svc.PauseMatch(ctx, match.ID, models.PauseReasonBreak)
+	require.NoError(t, err)
+	assert.Equal(t, models.MatchStatusPaused, paused.Status)
+
+	_, err = svc.UpdateScore(ctx, match.ID, 1, p1.Hex())
+	assert.ErrorContains(t, err, "paused")
+
+	resumed, err := svc.ResumeMatch(ctx, match.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.MatchStatusLive, resumed.Status)
+	require.NotNil(t, resumed.Pauses[0].EndedAt)
+}
+
+func TestPauseMatch_InvalidReason_Fails(t *testing.T) {
+	svc, _, match, _ := newPauseFixture(t)
+
+	_, err := svc.PauseMatch(context.Background(), match.ID, "chai")
+
+	assert.ErrorContains(t, err, "invalid pause reason")
+}
+
+func TestFinishMatch_DurationExcludesPauses(t *testing.T) {
+	matchRepo := new(MockMatchRepo)
+	svc := NewMatchService(matchRepo, new(MockPlayerRepo), memory.NewMatchEventRepo(), nil, nil)
+	ctx := context.Background()
+
+	now := time.Now()
+	breakStart, breakEnd := now.Add(-6*time.Minute), now.Add(-2*time.Minute)
+	match := makeLiveMatch([]primitive.ObjectID{newPlayerID()}, []primitive.ObjectID{newPlayerID()})
+	match.StartedAt = now.Add(-10 * time.Minute)
+	match.Pauses = []models.Pause{{Reason: models.PauseReasonBreak, StartedAt: breakStart, EndedAt: &breakEnd}}
+	match.PausedSecs = 240
+
+	matchRepo.On("FindByID", ctx, match.ID).Return(match, nil)
+	matchRepo.On("Update", ctx, match).Return(nil)
+
+	result, err := svc.FinishMatch(ctx, match.ID)
+
+	require.NoError(t, err)
+	assert.InDelta(t, 360, result.DurationSecs, 2)
+}
+
+func TestFinishMatch_WhilePaused_ClosesPause(t *testing.T) {
+	svc, eventRepo, match, _ := newPauseFixture(t)
+	ctx := context.Background()
+
+	_, err := svc.PauseMatch(ctx, match.ID, models.PauseReasonInjury)
+	require.NoError(t, err)
+	finished, err := svc.FinishMatch(ctx, match.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.MatchStatusFinished, finished.Status)
+	assert.Nil(t, finished.OpenPause())
+
+	events, err := eventRepo.FindByMatchID(ctx, match.ID)
+	require.NoError(t, err)
+	replayed, _, err := ReplayMatch(events)
+	require.NoError(t, err)
+	assert.Equal(t, finished.Pauses[0].EndedAt.Unix(), replayed.Pauses[0].EndedAt.Unix())
+	assert.Equal(t, finished.Status, replayed.Status)
+}
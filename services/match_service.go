@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,59 +13,100 @@ import (
 	"gully-backend/repositories"
 )
 
+// maxUpdateAttempts bounds how often a conflicting match update is retried.
+const maxUpdateAttempts = 8
+
+// backdateGrace is how old a result's played_at can be in groups that do
+// not allow back-dating: long enough for a match just finished.
+const backdateGrace = time.Hour
+
+var (
+	// ErrMatchConflict means the match kept changing underneath an update.
+	ErrMatchConflict = errors.New("match was updated by someone else, please retry")
+	// ErrInvalidResult means a result's details do not add up.
+	ErrInvalidResult = errors.New("invalid result")
+	// ErrBackdatingDisabled means the group does not take results dated
+	// further back than backdateGrace.
+	ErrBackdatingDisabled = errors.New("this group does not allow back-dated results")
+	// ErrInvalidMatchRequest is wrapped by match requests that can never
+	// succeed as sent, such as a rally for team 3.
+	ErrInvalidMatchRequest = errors.New("invalid match request")
+	// ErrMatchState is wrapped by match requests refused in the match's
+	// current state, such as scoring while it is paused. They may succeed
+	// once the state changes.
+	ErrMatchState = errors.New("not allowed in the match's current state")
+)
+
+// matchError carries its own message while matching kind with errors.Is.
+type matchError struct {
+	kind error
+	msg  string
+}
+
+func (e *matchError) Error() string { return e.msg }
+func (e *matchError) Unwrap() error { return e.kind }
+
+// invalidRequest returns an error wrapping ErrInvalidMatchRequest.
+func invalidRequest(format string, args ...any) error {
+	return &matchError{ErrInvalidMatchRequest, fmt.Sprintf(format, args...)}
+}
+
+// stateError returns an error wrapping ErrMatchState.
+func stateError(format string, args ...any) error {
+	return &matchError{ErrMatchState, fmt.Sprintf(format, args...)}
+}
+
 type MatchService struct {
 	matchRepo  repositories.MatchRepository
 	playerRepo repositories.PlayerRepository
+	eventRepo  repositories.MatchEventRepository
+	groupRepo  repositories.GroupRepository
+	tx         repositories.Transactor
 }
 
-func NewMatchService(matchRepo repositories.MatchRepository, playerRepo repositories.PlayerRepository) *MatchService {
-	return &MatchService{matchRepo: matchRepo, playerRepo: playerRepo}
+// NewMatchService returns a match service. Group settings are read from
+// groupRepo; when it is nil every group has the default settings and
+// anyone may score. Each match write and its timeline events are made in
+// one transaction on tx; when it is nil they are written one after the
+// other.
+func NewMatchService(matchRepo repositories.MatchRepository, playerRepo repositories.PlayerRepository, eventRepo repositories.MatchEventRepository, groupRepo repositories.GroupRepository, tx repositories.Transactor) *MatchService {
+	return &MatchService{matchRepo: matchRepo, playerRepo: playerRepo, eventRepo: eventRepo, groupRepo: groupRepo, tx: tx}
 }
 
-// CreateMatch creates a live match supporting 1v1, 1v2, or 2v2.
-func (s *MatchService) CreateMatch(ctx context.Context, groupID primitive.ObjectID, team1IDs, team2IDs []primitive.ObjectID) (*models.Match, error) {
-	if len(team1IDs) == 0 || len(team2IDs) == 0 {
-		return nil, errors.New("each team must have at least 1 player")
-	}
-	if len(team1IDs) > 2 || len(team2IDs) > 2 {
-		return nil, errors.New("each team can have at most 2 players")
-	}
-
-	// Look up player names
-	team1Names, err := s.resolvePlayerNames(ctx, team1IDs)
+// CreateMatch creates a live match supporting 1v1, 1v2, or 2v2. sessionID
+// is optional.
+func (s *MatchService) CreateMatch(ctx context.Context, groupID primitive.ObjectID, team1IDs, team2IDs []primitive.ObjectID, sessionID string) (*models.Match, error) {
+	match, err := s.newMatch(ctx, groupID, team1IDs, team2IDs, sessionID)
 	if err != nil {
-		return nil, fmt.Errorf("team 1: %w", err)
-	}
-	team2Names, err := s.resolvePlayerNames(ctx, team2IDs)
-	if err != nil {
-		return nil, fmt.Errorf("team 2: %w", err)
-	}
-
-	now := time.Now()
-	match := &models.Match{
-		GroupID:         groupID,
-		Team1IDs:        team1IDs,
-		Team2IDs:        team2IDs,
-		Team1Names:      team1Names,
-		Team2Names:      team2Names,
-		Score1:          0,
-		Score2:          0,
-		ScoreHistory:    []models.ScoreEvent{},
-		ServingTeam:     1,
-		ServingPlayerID: team1IDs[0].Hex(),
-		Team1Positions:  toHexSlice(team1IDs),
-		Team2Positions:  toHexSlice(team2IDs),
-		Status:          models.MatchStatusLive,
-		StartedAt:       now,
+		return nil, err
 	}
-	if err := s.matchRepo.Create(ctx, match); err != nil {
+	match.Status = models.MatchStatusLive
+	match.StartedAt = time.Now()
+	if err := s.insertMatch(ctx, match); err != nil {
 		return nil, err
 	}
 	return match, nil
 }
 
 // AddResult creates a finished match with final scores (for past matches).
-func (s *MatchService) AddResult(ctx context.Context, groupID primitive.ObjectID, team1IDs, team2IDs []primitive.ObjectID, score1, score2 int) (*models.Match, error) {
+// playedAt, when set, dates the match; it cannot be in the future, and
+// only groups that allow back-dating take one more than an hour old.
+func (s *MatchService) AddResult(ctx context.Context, groupID primitive.ObjectID, team1IDs, team2IDs []primitive.ObjectID, score1, score2 int, sessionID string, playedAt *time.Time) (*models.Match, error) {
+	settings, err := s.matchSettings(ctx, groupID, false)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if playedAt != nil {
+		switch {
+		case playedAt.After(now.Add(time.Minute)):
+			return nil, fmt.Errorf("%w: played_at cannot be in the future", ErrInvalidResult)
+		case playedAt.Before(now.Add(-backdateGrace)) && !settings.AllowBackdating:
+			return nil, ErrBackdatingDisabled
+		}
+		now = *playedAt
+	}
+
 	team1Names, err := s.resolvePlayerNames(ctx, team1IDs)
 	if err != nil {
 		return nil, fmt.Errorf("team 1: %w", err)
@@ -74,16 +116,19 @@ func (s *MatchService) AddResult(ctx context.Context, groupID primitive.ObjectID
 		return nil, fmt.Errorf("team 2: %w", err)
 	}
 
-	now := time.Now()
 	match := &models.Match{
 		GroupID:         groupID,
 		Team1IDs:        team1IDs,
 		Team2IDs:        team2IDs,
 		Team1Names:      team1Names,
 		Team2Names:      team2Names,
+		Type:            models.MatchTypeFor(len(team1IDs), len(team2IDs)),
+		PointsToWin:     settings.PointsToWin,
+		SessionID:       sessionID,
 		Score1:          score1,
 		Score2:          score2,
 		ScoreHistory:    []models.ScoreEvent{},
+		Pauses:          []models.Pause{},
 		ServingTeam:     0,
 		ServingPlayerID: "",
 		Team1Positions:  toHexSlice(team1IDs),
@@ -93,7 +138,7 @@ func (s *MatchService) AddResult(ctx context.Context, groupID primitive.ObjectID
 		FinishedAt:      &now,
 		DurationSecs:    0,
 	}
-	if err := s.matchRepo.Create(ctx, match); err != nil {
+	if err := s.insertMatch(ctx, match); err != nil {
 		return nil, err
 	}
 	return match, nil
@@ -103,142 +148,371 @@ func (s *MatchService) GetMatches(ctx context.Context, groupID primitive.ObjectI
 	return s.matchRepo.FindByGroupID(ctx, groupID)
 }
 
+// ListMatches returns one page of a group's matches.
+func (s *MatchService) ListMatches(ctx context.Context, q repositories.MatchQuery) (*repositories.MatchPage, error) {
+	return s.matchRepo.FindPage(ctx, q)
+}
+
 func (s *MatchService) GetMatch(ctx context.Context, id primitive.ObjectID) (*models.Match, error) {
 	return s.matchRepo.FindByID(ctx, id)
 }
 
+// Rally describes how a point was won. Only Team and PlayerID are required;
+// a let carries no team and leaves the score unchanged.
+type Rally struct {
+	Team          int
+	PlayerID      string
+	Outcome       string
+	RallySecs     int
+	FaultPlayerID string
+}
+
 // UpdateScore increments the score for the given team and records the scorer.
 // All position/serve logic is handled by the frontend from scoreHistory.
 func (s *MatchService) UpdateScore(ctx context.Context, matchID primitive.ObjectID, team int, scorerID string) (*models.Match, error) {
-	match, err := s.matchRepo.FindByID(ctx, matchID)
-	if err != nil {
-		return nil, err
+	return s.RecordRally(ctx, matchID, Rally{Team: team, PlayerID: scorerID})
+}
+
+// RecordRally appends a rally to the score history with a server timestamp,
+// awarding the point to rally.Team unless the rally was a let.
+func (s *MatchService) RecordRally(ctx context.Context, matchID primitive.ObjectID, rally Rally) (*models.Match, error) {
+	if !models.ValidOutcome(rally.Outcome) {
+		return nil, invalidRequest("invalid outcome: %q", rally.Outcome)
 	}
-	if match.Status != models.MatchStatusLive {
-		return nil, errors.New("match is not live")
+	if rally.RallySecs < 0 {
+		return nil, invalidRequest("rally duration cannot be negative")
 	}
+	let := rally.Outcome == models.OutcomeLet
 
-	event := models.ScoreEvent{
-		Team:     team,
-		PlayerID: scorerID,
-	}
+	return s.scoreMatch(ctx, matchID, func(match *models.Match, tl *timeline) error {
+		now := time.Now()
+		if err := readyForPlay(match, tl, now); err != nil {
+			return err
+		}
 
-	switch team {
-	case 1:
-		match.Score1++
-	case 2:
-		match.Score2++
-	default:
-		return nil, fmt.Errorf("invalid team number: %d", team)
-	}
+		event := models.ScoreEvent{
+			Team:          rally.Team,
+			PlayerID:      rally.PlayerID,
+			Outcome:       rally.Outcome,
+			RallySecs:     rally.RallySecs,
+			FaultPlayerID: rally.FaultPlayerID,
+			At:            now,
+			DeviceID:      ActorFrom(ctx).DeviceID,
+			Version:       match.Version + 1,
+		}
 
-	match.ScoreHistory = append(match.ScoreHistory, event)
+		switch {
+		case let:
+			// Replayed rally: logged for stats, score and serve unchanged.
+			event.Team = 0
+			event.PlayerID = ""
+		case rally.Team == 1:
+			match.Score1++
+		case rally.Team == 2:
+			match.Score2++
+		default:
+			return invalidRequest("invalid team number: %d", rally.Team)
+		}
 
-	// Simple serve tracking (frontend computes the real server)
-	match.ServingTeam = team
-	match.ServingPlayerID = scorerID
+		match.ScoreHistory = append(match.ScoreHistory, event)
 
-	if err := s.matchRepo.Update(ctx, match); err != nil {
-		return nil, err
-	}
-	return match, nil
+		// Simple serve tracking (frontend computes the real server)
+		if !let {
+			match.ServingTeam = rally.Team
+			match.ServingPlayerID = rally.PlayerID
+		}
+
+		tl.record(match, models.MatchEventPoint, func(e *models.MatchEvent) { e.Point = &event })
+		if !let {
+			startIntervalIfDue(match, tl, now)
+		}
+		return nil
+	})
 }
 
 // UndoScore reverts the last score entry.
 func (s *MatchService) UndoScore(ctx context.Context, matchID primitive.ObjectID) (*models.Match, error) {
+	return s.scoreMatch(ctx, matchID, func(match *models.Match, tl *timeline) error {
+		// Undoing the point that triggered an interval cancels the interval too.
+		cancelled := cancelInterval(match)
+		if match.Status == models.MatchStatusPaused {
+			return stateError("match is paused")
+		}
+		if match.Status != models.MatchStatusLive {
+			return stateError("match is not live")
+		}
+		if len(match.ScoreHistory) == 0 {
+			return stateError("no scores to undo")
+		}
+
+		removed := popScore(match)
+		tl.record(match, models.MatchEventUndo, func(e *models.MatchEvent) {
+			e.Pause = cancelled
+			e.Point = &removed
+		})
+		return nil
+	})
+}
+
+// FinishMatch marks the match as finished and records the duration of
+// active play, excluding pauses and intervals.
+func (s *MatchService) FinishMatch(ctx context.Context, matchID primitive.ObjectID) (*models.Match, error) {
+	return s.mutateMatch(ctx, matchID, func(match *models.Match, tl *timeline) error {
+		if match.Status == models.MatchStatusScheduled || match.Status == models.MatchStatusWarmup {
+			return errNotStarted
+		}
+		if !models.CanTransition(match.Status, models.MatchStatusFinished) {
+			return stateError("match is already finished")
+		}
+
+		now := time.Now()
+		endPause(match, tl, now)
+		match.Status = models.MatchStatusFinished
+		match.FinishedAt = &now
+		match.DurationSecs = activeSecs(match, now)
+		tl.record(match, models.MatchEventFinish, nil)
+		return nil
+	})
+}
+
+// DeleteMatch removes a match. Its timeline is kept, ending in a delete event.
+func (s *MatchService) DeleteMatch(ctx context.Context, matchID primitive.ObjectID) error {
 	match, err := s.matchRepo.FindByID(ctx, matchID)
 	if err != nil {
-		return nil, err
-	}
-	if match.Status != models.MatchStatusLive {
-		return nil, errors.New("match is not live")
-	}
-	if len(match.ScoreHistory) == 0 {
-		return nil, errors.New("no scores to undo")
+		return err
 	}
+	tl := newTimeline(match)
+	tl.record(match, models.MatchEventDelete, nil)
+	return s.inTx(ctx, func(ctx context.Context) error {
+		if err := s.matchRepo.Delete(ctx, matchID); err != nil {
+			return err
+		}
+		match.Version++
+		return appendMatchEvents(ctx, s.eventRepo, match, tl.events)
+	})
+}
 
-	last := match.ScoreHistory[len(match.ScoreHistory)-1]
-	match.ScoreHistory = match.ScoreHistory[:len(match.ScoreHistory)-1]
+// EditScore directly sets scores (admin only).
+func (s *MatchService) EditScore(ctx context.Context, matchID primitive.ObjectID, score1, score2 int) (*models.Match, error) {
+	return s.mutateMatch(ctx, matchID, func(match *models.Match, tl *timeline) error {
+		match.Score1 = score1
+		match.Score2 = score2
+		tl.record(match, models.MatchEventEdit, nil)
+		return nil
+	})
+}
 
-	switch last.Team {
-	case 1:
-		match.Score1--
-	case 2:
-		match.Score2--
-	}
+// ── Helpers ──
+
+// mutateMatch runs a read-modify-write cycle against the match. The write is
+// conditional on the version that was read; if another request got there
+// first the whole cycle is retried on fresh data, so concurrent scorers never
+// lose a point. ErrMatchConflict is returned once the retries run out.
+// Events recorded on the timeline are persisted with the write.
+// Only members of the match's group may make the change.
+func (s *MatchService) mutateMatch(ctx context.Context, matchID primitive.ObjectID, apply func(*models.Match, *timeline) error) (*models.Match, error) {
+	return s.updateMatch(ctx, matchID, false, apply)
+}
+
+// scoreMatch is mutateMatch for changes that only record or undo rallies,
+// which the group may open to guests.
+func (s *MatchService) scoreMatch(ctx context.Context, matchID primitive.ObjectID, apply func(*models.Match, *timeline) error) (*models.Match, error) {
+	return s.updateMatch(ctx, matchID, true, apply)
+}
 
-	// Restore simple serve state from previous event
-	if len(match.ScoreHistory) > 0 {
-		prev := match.ScoreHistory[len(match.ScoreHistory)-1]
-		match.ServingTeam = prev.Team
-		match.ServingPlayerID = prev.PlayerID
-	} else {
-		match.ServingTeam = 1
-		if len(match.Team1IDs) > 0 {
-			match.ServingPlayerID = match.Team1IDs[0].Hex()
+func (s *MatchService) updateMatch(ctx context.Context, matchID primitive.ObjectID, scoring bool, apply func(*models.Match, *timeline) error) (*models.Match, error) {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		if attempt > 0 {
+			if err := backoff(ctx, attempt); err != nil {
+				return nil, err
+			}
 		}
-	}
 
-	if err := s.matchRepo.Update(ctx, match); err != nil {
-		return nil, err
+		match, err := s.matchRepo.FindByID(ctx, matchID)
+		if err != nil {
+			return nil, err
+		}
+		if attempt == 0 {
+			if _, err := s.matchSettings(ctx, match.GroupID, scoring); err != nil {
+				return nil, err
+			}
+		}
+		tl := newTimeline(match)
+		if err := apply(match, tl); err != nil {
+			return nil, err
+		}
+
+		err = s.inTx(ctx, func(ctx context.Context) error {
+			if err := s.matchRepo.Update(ctx, match); err != nil {
+				return err
+			}
+			return appendMatchEvents(ctx, s.eventRepo, match, tl.events)
+		})
+		if err == nil {
+			return match, nil
+		}
+		if !errors.Is(err, repositories.ErrVersionConflict) {
+			return nil, err
+		}
 	}
-	return match, nil
+	return nil, ErrMatchConflict
 }
 
-// FinishMatch marks the match as finished and records the duration.
-func (s *MatchService) FinishMatch(ctx context.Context, matchID primitive.ObjectID) (*models.Match, error) {
-	match, err := s.matchRepo.FindByID(ctx, matchID)
-	if err != nil {
-		return nil, err
-	}
-	if match.Status != models.MatchStatusLive {
-		return nil, errors.New("match is already finished")
+// inTx runs fn in a transaction when the service has a Transactor.
+func (s *MatchService) inTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.tx == nil {
+		return fn(ctx)
 	}
+	return s.tx.WithTransaction(ctx, fn)
+}
 
-	now := time.Now()
-	match.Status = models.MatchStatusFinished
-	match.FinishedAt = &now
-	match.DurationSecs = int(now.Sub(match.StartedAt).Seconds())
+// insertMatch stores a new match along with its created event.
+func (s *MatchService) insertMatch(ctx context.Context, match *models.Match) error {
+	return s.inTx(ctx, func(ctx context.Context) error {
+		if err := s.matchRepo.Create(ctx, match); err != nil {
+			return err
+		}
+		return s.recordCreated(ctx, match)
+	})
+}
 
-	if err := s.matchRepo.Update(ctx, match); err != nil {
-		return nil, err
+// recordCreated writes the initial snapshot a match's timeline replays from.
+func (s *MatchService) recordCreated(ctx context.Context, match *models.Match) error {
+	snapshot := *match
+	snapshot.ScoreHistory = append([]models.ScoreEvent{}, match.ScoreHistory...)
+	return appendMatchEvents(ctx, s.eventRepo, match, []models.MatchEvent{{
+		Type:     models.MatchEventCreated,
+		After:    stateRef(match.State()),
+		Snapshot: &snapshot,
+	}})
+}
+
+func stateRef(s models.MatchState) *models.MatchState { return &s }
+
+// backoff sleeps for a short, jittered interval that grows with each attempt.
+func backoff(ctx context.Context, attempt int) error {
+	wait := time.Duration(rand.Intn(5*attempt)+1) * time.Millisecond
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
 	}
-	return match, nil
 }
 
-// DeleteMatch removes a match.
-func (s *MatchService) DeleteMatch(ctx context.Context, matchID primitive.ObjectID) error {
-	return s.matchRepo.Delete(ctx, matchID)
+// matchSettings loads the group's settings and checks that the acting user
+// may change its matches: members always, others only for scoring and if
+// the group lets guests score. Changes with no acting user are the
+// system's own.
+func (s *MatchService) matchSettings(ctx context.Context, groupID primitive.ObjectID, scoring bool) (models.GroupSettings, error) {
+	if s.groupRepo == nil {
+		return models.GroupSettings{}.WithDefaults(), nil
+	}
+	group, err := s.groupRepo.FindByID(ctx, groupID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return models.GroupSettings{}, invalidRequest("group not found")
+		}
+		return models.GroupSettings{}, err
+	}
+	settings := group.Settings.WithDefaults()
+	actor := ActorFrom(ctx)
+	if actor.UserID == "" || (scoring && settings.GuestsCanScore) {
+		return settings, nil
+	}
+	if userID, err := primitive.ObjectIDFromHex(actor.UserID); err != nil || !group.HasMember(userID) {
+		return models.GroupSettings{}, ErrNotGroupMember
+	}
+	return settings, nil
 }
 
-// EditScore directly sets scores (admin only).
-func (s *MatchService) EditScore(ctx context.Context, matchID primitive.ObjectID, score1, score2 int) (*models.Match, error) {
-	match, err := s.matchRepo.FindByID(ctx, matchID)
+// newMatch validates the teams and builds a match that has not started yet,
+// played to the group's default length.
+func (s *MatchService) newMatch(ctx context.Context, groupID primitive.ObjectID, team1IDs, team2IDs []primitive.ObjectID, sessionID string) (*models.Match, error) {
+	if len(team1IDs) == 0 || len(team2IDs) == 0 {
+		return nil, invalidRequest("each team must have at least 1 player")
+	}
+	if len(team1IDs) > 2 || len(team2IDs) > 2 {
+		return nil, invalidRequest("each team can have at most 2 players")
+	}
+	settings, err := s.matchSettings(ctx, groupID, false)
 	if err != nil {
 		return nil, err
 	}
-	match.Score1 = score1
-	match.Score2 = score2
-	if err := s.matchRepo.Update(ctx, match); err != nil {
-		return nil, err
+
+	// Look up player names
+	team1Names, err := s.resolvePlayerNames(ctx, team1IDs)
+	if err != nil {
+		return nil, fmt.Errorf("team 1: %w", err)
+	}
+	team2Names, err := s.resolvePlayerNames(ctx, team2IDs)
+	if err != nil {
+		return nil, fmt.Errorf("team 2: %w", err)
 	}
-	return match, nil
-}
 
-// ── Helpers ──
+	return &models.Match{
+		GroupID:         groupID,
+		Team1IDs:        team1IDs,
+		Team2IDs:        team2IDs,
+		Team1Names:      team1Names,
+		Team2Names:      team2Names,
+		Type:            models.MatchTypeFor(len(team1IDs), len(team2IDs)),
+		PointsToWin:     settings.PointsToWin,
+		SessionID:       sessionID,
+		Score1:          0,
+		Score2:          0,
+		ScoreHistory:    []models.ScoreEvent{},
+		Pauses:          []models.Pause{},
+		ServingTeam:     1,
+		ServingPlayerID: team1IDs[0].Hex(),
+		Team1Positions:  toHexSlice(team1IDs),
+		Team2Positions:  toHexSlice(team2IDs),
+	}, nil
+}
 
 func (s *MatchService) resolvePlayerNames(ctx context.Context, ids []primitive.ObjectID) ([]string, error) {
 	names := make([]string, len(ids))
 	for i, id := range ids {
 		p, err := s.playerRepo.FindByID(ctx, id)
 		if err != nil {
-			return nil, fmt.Errorf("player %s not found", id.Hex())
+			return nil, invalidRequest("player %s not found", id.Hex())
+		}
+		if p.Archived() {
+			return nil, invalidRequest("player %s is archived", p.Name)
 		}
 		names[i] = p.Name
 	}
 	return names, nil
 }
 
+// popScore removes the last rally, restores the serve state that preceded
+// it and returns the removed rally.
+func popScore(match *models.Match) models.ScoreEvent {
+	last := match.ScoreHistory[len(match.ScoreHistory)-1]
+	match.ScoreHistory = match.ScoreHistory[:len(match.ScoreHistory)-1]
+
+	switch last.Team {
+	case 1:
+		match.Score1--
+	case 2:
+		match.Score2--
+	}
+
+	// Restore simple serve state from the previous point (lets don't move the serve)
+	for i := len(match.ScoreHistory) - 1; i >= 0; i-- {
+		if prev := match.ScoreHistory[i]; prev.Team != 0 {
+			match.ServingTeam = prev.Team
+			match.ServingPlayerID = prev.PlayerID
+			return last
+		}
+	}
+	match.ServingTeam = 1
+	if len(match.Team1IDs) > 0 {
+		match.ServingPlayerID = match.Team1IDs[0].Hex()
+	}
+	return last
+}
+
 func toHexSlice(ids []primitive.ObjectID) []string {
 	s := make([]string, len(ids))
 	for i, id := range ids {
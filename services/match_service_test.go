@@ -10,6 +10,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"gully-backend/models"
+	"gully-backend/repositories/memory"
 )
 
 // ── Helpers ──
@@ -24,6 +25,7 @@ func makeLiveMatch(t1, t2 []primitive.ObjectID) *models.Match {
 		Team2IDs:        t2,
 		Team1Names:      []string{"Alice", "Bob"}[:len(t1)],
 		Team2Names:      []string{"Charlie", "Dave"}[:len(t2)],
+		Type:            models.MatchTypeFor(len(t1), len(t2)),
 		Score1:          0,
 		Score2:          0,
 		ScoreHistory:    []models.ScoreEvent{},
@@ -40,7 +42,7 @@ func makeLiveMatch(t1, t2 []primitive.ObjectID) *models.Match {
 func TestCreateMatch_1v1_Success(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1, p2 := newPlayerID(), newPlayerID()
@@ -50,7 +52,7 @@ func TestCreateMatch_1v1_Success(t *testing.T) {
 	playerRepo.On("FindByID", ctx, p2).Return(&models.Player{ID: p2, Name: "Bob"}, nil)
 	matchRepo.On("Create", ctx, mock.AnythingOfType("*models.Match")).Return(nil)
 
-	match, err := svc.CreateMatch(ctx, groupID, []primitive.ObjectID{p1}, []primitive.ObjectID{p2})
+	match, err := svc.CreateMatch(ctx, groupID, []primitive.ObjectID{p1}, []primitive.ObjectID{p2}, "")
 
 	assert.NoError(t, err)
 	assert.NotNil(t, match)
@@ -68,7 +70,7 @@ func TestCreateMatch_1v1_Success(t *testing.T) {
 func TestCreateMatch_2v2_Success(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1, p2, p3, p4 := newPlayerID(), newPlayerID(), newPlayerID(), newPlayerID()
@@ -82,11 +84,12 @@ func TestCreateMatch_2v2_Success(t *testing.T) {
 
 	match, err := svc.CreateMatch(ctx, groupID,
 		[]primitive.ObjectID{p1, p2},
-		[]primitive.ObjectID{p3, p4})
+		[]primitive.ObjectID{p3, p4}, "")
 
 	assert.NoError(t, err)
 	assert.Len(t, match.Team1IDs, 2)
 	assert.Len(t, match.Team2IDs, 2)
+	assert.Equal(t, models.MatchType2v2, match.Type)
 	assert.Equal(t, []string{"Alice", "Bob"}, match.Team1Names)
 	assert.Equal(t, []string{"Charlie", "Dave"}, match.Team2Names)
 	assert.Len(t, match.Team1Positions, 2)
@@ -97,12 +100,12 @@ func TestCreateMatch_2v2_Success(t *testing.T) {
 func TestCreateMatch_EmptyTeam_Fails(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	_, err := svc.CreateMatch(ctx, primitive.NewObjectID(),
 		[]primitive.ObjectID{},
-		[]primitive.ObjectID{newPlayerID()})
+		[]primitive.ObjectID{newPlayerID()}, "")
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "at least 1 player")
@@ -111,12 +114,12 @@ func TestCreateMatch_EmptyTeam_Fails(t *testing.T) {
 func TestCreateMatch_TooManyPlayers_Fails(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	_, err := svc.CreateMatch(ctx, primitive.NewObjectID(),
 		[]primitive.ObjectID{newPlayerID(), newPlayerID(), newPlayerID()},
-		[]primitive.ObjectID{newPlayerID()})
+		[]primitive.ObjectID{newPlayerID()}, "")
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "at most 2 players")
@@ -125,7 +128,7 @@ func TestCreateMatch_TooManyPlayers_Fails(t *testing.T) {
 func TestCreateMatch_PlayerNotFound_Fails(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1 := newPlayerID()
@@ -133,7 +136,7 @@ func TestCreateMatch_PlayerNotFound_Fails(t *testing.T) {
 
 	_, err := svc.CreateMatch(ctx, primitive.NewObjectID(),
 		[]primitive.ObjectID{p1},
-		[]primitive.ObjectID{newPlayerID()})
+		[]primitive.ObjectID{newPlayerID()}, "")
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "not found")
@@ -144,7 +147,7 @@ func TestCreateMatch_PlayerNotFound_Fails(t *testing.T) {
 func TestUpdateScore_Team1Scores(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1, p2 := newPlayerID(), newPlayerID()
@@ -169,7 +172,7 @@ func TestUpdateScore_Team1Scores(t *testing.T) {
 func TestUpdateScore_Team2Scores(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1, p2 := newPlayerID(), newPlayerID()
@@ -190,7 +193,7 @@ func TestUpdateScore_Team2Scores(t *testing.T) {
 func TestUpdateScore_InvalidTeam(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1, p2 := newPlayerID(), newPlayerID()
@@ -207,7 +210,7 @@ func TestUpdateScore_InvalidTeam(t *testing.T) {
 func TestUpdateScore_FinishedMatch_Fails(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1, p2 := newPlayerID(), newPlayerID()
@@ -227,7 +230,7 @@ func TestUpdateScore_FinishedMatch_Fails(t *testing.T) {
 func TestUpdateScore_Doubles_ConsecutiveScores_SwapPositions(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1, p2, p3, p4 := newPlayerID(), newPlayerID(), newPlayerID(), newPlayerID()
@@ -250,7 +253,7 @@ func TestUpdateScore_Doubles_ConsecutiveScores_SwapPositions(t *testing.T) {
 func TestUpdateScore_Doubles_DifferentScorers_NoSwap(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1, p2, p3, p4 := newPlayerID(), newPlayerID(), newPlayerID(), newPlayerID()
@@ -273,7 +276,7 @@ func TestUpdateScore_Doubles_DifferentScorers_NoSwap(t *testing.T) {
 func TestUpdateScore_Singles_ConsecutiveScores_NoSwap(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1, p2 := newPlayerID(), newPlayerID()
@@ -296,7 +299,7 @@ func TestUpdateScore_Singles_ConsecutiveScores_NoSwap(t *testing.T) {
 func TestUndoScore_Success(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1, p2 := newPlayerID(), newPlayerID()
@@ -323,7 +326,7 @@ func TestUndoScore_Success(t *testing.T) {
 func TestUndoScore_BackToInitial(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1, p2 := newPlayerID(), newPlayerID()
@@ -349,7 +352,7 @@ func TestUndoScore_BackToInitial(t *testing.T) {
 func TestUndoScore_NoHistory_Fails(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1, p2 := newPlayerID(), newPlayerID()
@@ -367,7 +370,7 @@ func TestUndoScore_NoHistory_Fails(t *testing.T) {
 func TestUndoScore_FinishedMatch_Fails(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1, p2 := newPlayerID(), newPlayerID()
@@ -387,7 +390,7 @@ func TestUndoScore_FinishedMatch_Fails(t *testing.T) {
 func TestFinishMatch_Success(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1, p2 := newPlayerID(), newPlayerID()
@@ -407,7 +410,7 @@ func TestFinishMatch_Success(t *testing.T) {
 func TestFinishMatch_AlreadyFinished_Fails(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1, p2 := newPlayerID(), newPlayerID()
@@ -427,7 +430,7 @@ func TestFinishMatch_AlreadyFinished_Fails(t *testing.T) {
 func TestEditScore_Success(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1, p2 := newPlayerID(), newPlayerID()
@@ -449,7 +452,7 @@ func TestEditScore_Success(t *testing.T) {
 func TestAddResult_Success(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1, p2 := newPlayerID(), newPlayerID()
@@ -462,7 +465,7 @@ func TestAddResult_Success(t *testing.T) {
 	match, err := svc.AddResult(ctx, groupID,
 		[]primitive.ObjectID{p1},
 		[]primitive.ObjectID{p2},
-		21, 18)
+		21, 18, "", nil)
 
 	assert.NoError(t, err)
 	assert.Equal(t, models.MatchStatusFinished, match.Status)
@@ -477,10 +480,12 @@ func TestAddResult_Success(t *testing.T) {
 func TestDeleteMatch_Success(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
-	matchID := primitive.NewObjectID()
+	match := makeLiveMatch([]primitive.ObjectID{newPlayerID()}, []primitive.ObjectID{newPlayerID()})
+	matchID := match.ID
+	matchRepo.On("FindByID", ctx, matchID).Return(match, nil)
 	matchRepo.On("Delete", ctx, matchID).Return(nil)
 
 	err := svc.DeleteMatch(ctx, matchID)
@@ -512,7 +517,7 @@ func TestToHexSlice_Empty(t *testing.T) {
 func TestUndoScore_Doubles_RebuildPositions(t *testing.T) {
 	matchRepo := new(MockMatchRepo)
 	playerRepo := new(MockPlayerRepo)
-	svc := NewMatchService(matchRepo, playerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
 	ctx := context.Background()
 
 	p1, p2, p3, p4 := newPlayerID(), newPlayerID(), newPlayerID(), newPlayerID()
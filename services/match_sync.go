@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+)
+
+const (
+	SyncEventScore = "score"
+	SyncEventUndo  = "undo"
+)
+
+// maxClockSkew is how far in the future a client timestamp may be before the
+// event is rejected.
+const maxClockSkew = time.Minute
+
+// SyncEvent is a rally event recorded on a device while it was offline.
+type SyncEvent struct {
+	ID       string    `json:"id"`   // client-generated, used to drop resubmitted events
+	Type     string    `json:"type"` // "score" or "undo"
+	Team     int       `json:"team"`
+	PlayerID string    `json:"player_id"`
+	At       time.Time `json:"at"`
+}
+
+// SyncConflict is a client event that was not applied, and why.
+type SyncConflict struct {
+	Event  SyncEvent `json:"event"`
+	Reason string    `json:"reason"`
+}
+
+// SyncResult is the reconciled match plus a report of what happened to each event.
+type SyncResult struct {
+	Match      *models.Match  `json:"match"`
+	Applied    int            `json:"applied"`
+	Duplicates int            `json:"duplicates"`
+	Conflicts  []SyncConflict `json:"conflicts"`
+}
+
+// SyncEvents merges a batch of offline rally events recorded since baseVersion.
+//
+// If another device recorded points after baseVersion, both devices were
+// scoring the same rallies. To avoid double counting, the server's events win:
+// client events timestamped at or before the latest remote event are reported
+// as conflicts, and only later ones are applied. Undo events only remove
+// points recorded by the same device. Events whose ID is already in the
+// history or the timeline are skipped, so a retried sync is harmless, even
+// when an undo has since removed some of its points.
+func (s *MatchService) SyncEvents(ctx context.Context, matchID primitive.ObjectID, deviceID string, baseVersion int, events []SyncEvent) (*SyncResult, error) {
+	if deviceID == "" {
+		return nil, invalidRequest("device id is required")
+	}
+	if err := validateSyncEvents(events); err != nil {
+		return nil, err
+	}
+	ordered := make([]SyncEvent, len(events))
+	copy(ordered, events)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].At.Before(ordered[j].At) })
+
+	var result *SyncResult
+	match, err := s.scoreMatch(ctx, matchID, func(match *models.Match, tl *timeline) error {
+		// A paused match still takes the batch: events from before the pause
+		// are weighed one by one in mergeSyncEvents.
+		if match.Status != models.MatchStatusPaused {
+			if err := readyForPlay(match, tl, time.Now()); err != nil {
+				return err
+			}
+		}
+		if baseVersion > match.Version {
+			return stateError("base version %d is ahead of the match (version %d)", baseVersion, match.Version)
+		}
+		recorded, err := s.eventRepo.FindByMatchID(ctx, matchID)
+		if err != nil {
+			return err
+		}
+		result = mergeSyncEvents(match, tl, deviceID, baseVersion, syncedEventIDs(recorded), ordered)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Match = match
+	return result, nil
+}
+
+func validateSyncEvents(events []SyncEvent) error {
+	if len(events) == 0 {
+		return invalidRequest("no events to sync")
+	}
+	limit := time.Now().Add(maxClockSkew)
+	for i, ev := range events {
+		switch ev.Type {
+		case SyncEventScore:
+			if ev.Team != 1 && ev.Team != 2 {
+				return invalidRequest("event %d: invalid team number: %d", i, ev.Team)
+			}
+		case SyncEventUndo:
+		default:
+			return invalidRequest("event %d: unknown type %q", i, ev.Type)
+		}
+		if ev.At.IsZero() {
+			return invalidRequest("event %d: timestamp is required", i)
+		}
+		if ev.At.After(limit) {
+			return invalidRequest("event %d: timestamp is in the future", i)
+		}
+	}
+	return nil
+}
+
+// syncedEventIDs collects the client IDs of synced events in a match's
+// timeline: points, including those undone since, and undos.
+func syncedEventIDs(recorded []models.MatchEvent) map[string]bool {
+	seen := make(map[string]bool)
+	for _, ev := range recorded {
+		if ev.EventID != "" {
+			seen[ev.EventID] = true
+		}
+		if ev.Point != nil && ev.Point.EventID != "" {
+			seen[ev.Point.EventID] = true
+		}
+	}
+	return seen
+}
+
+// mergeSyncEvents applies ordered client events to match in place, each
+// through the same checks as a live rally: one that lands inside a pause or
+// interval, or while the match is paused, is reported as a conflict, and a
+// point reaching the interval score starts the interval at its timestamp.
+// seen holds the IDs of events applied before; it is added to as events are
+// applied.
+func mergeSyncEvents(match *models.Match, tl *timeline, deviceID string, baseVersion int, seen map[string]bool, events []SyncEvent) *SyncResult {
+	result := &SyncResult{Conflicts: []SyncConflict{}}
+
+	var latestRemote time.Time
+	for _, ev := range match.ScoreHistory {
+		if ev.EventID != "" {
+			seen[ev.EventID] = true
+		}
+		if ev.Version > baseVersion && ev.DeviceID != deviceID && ev.At.After(latestRemote) {
+			latestRemote = ev.At
+		}
+	}
+
+	version := match.Version + 1
+	for _, ev := range events {
+		if ev.ID != "" && seen[ev.ID] {
+			result.Duplicates++
+			continue
+		}
+		if !latestRemote.IsZero() && !ev.At.After(latestRemote) {
+			result.Conflicts = append(result.Conflicts, SyncConflict{Event: ev, Reason: "another device recorded rallies after this point"})
+			continue
+		}
+
+		switch ev.Type {
+		case SyncEventScore:
+			if pause := pauseAt(match, ev.At); pause != nil {
+				result.Conflicts = append(result.Conflicts, SyncConflict{Event: ev, Reason: "recorded during the " + pause.Reason + " pause"})
+				continue
+			}
+			if err := readyForPlay(match, tl, ev.At); err != nil {
+				result.Conflicts = append(result.Conflicts, SyncConflict{Event: ev, Reason: err.Error()})
+				continue
+			}
+			if ev.Team == 1 {
+				match.Score1++
+			} else {
+				match.Score2++
+			}
+			point := models.ScoreEvent{
+				Team:     ev.Team,
+				PlayerID: ev.PlayerID,
+				At:       ev.At,
+				DeviceID: deviceID,
+				EventID:  ev.ID,
+				Version:  version,
+			}
+			match.ScoreHistory = append(match.ScoreHistory, point)
+			match.ServingTeam = ev.Team
+			match.ServingPlayerID = ev.PlayerID
+			tl.record(match, models.MatchEventPoint, func(e *models.MatchEvent) { e.Point = &point })
+			startIntervalIfDue(match, tl, ev.At)
+			if ev.ID != "" {
+				seen[ev.ID] = true
+			}
+		case SyncEventUndo:
+			// As with UndoScore, an undo may cancel an interval but not a pause.
+			if pause := pauseAt(match, ev.At); pause != nil && !pause.Auto {
+				result.Conflicts = append(result.Conflicts, SyncConflict{Event: ev, Reason: "recorded during the " + pause.Reason + " pause"})
+				continue
+			}
+			if pause := match.OpenPause(); pause != nil && !pause.Auto {
+				result.Conflicts = append(result.Conflicts, SyncConflict{Event: ev, Reason: "match is paused"})
+				continue
+			}
+			n := len(match.ScoreHistory)
+			if n == 0 {
+				result.Conflicts = append(result.Conflicts, SyncConflict{Event: ev, Reason: "no scores to undo"})
+				continue
+			}
+			if match.ScoreHistory[n-1].DeviceID != deviceID {
+				result.Conflicts = append(result.Conflicts, SyncConflict{Event: ev, Reason: "last point was recorded by another device"})
+				continue
+			}
+			cancelled := cancelInterval(match)
+			removed := popScore(match)
+			tl.record(match, models.MatchEventUndo, func(e *models.MatchEvent) {
+				e.EventID = ev.ID
+				e.Pause = cancelled
+				e.Point = &removed
+			})
+			if ev.ID != "" {
+				seen[ev.ID] = true
+			}
+		}
+		result.Applied++
+	}
+	return result
+}
+
+// pauseAt returns the pause the match was in at t, if any. An open interval
+// covers only its planned length; any other open pause lasts until resumed.
+func pauseAt(match *models.Match, t time.Time) *models.Pause {
+	for i := range match.Pauses {
+		p := &match.Pauses[i]
+		if t.Before(p.StartedAt) {
+			continue
+		}
+		switch {
+		case p.EndedAt != nil:
+			if t.Before(*p.EndedAt) {
+				return p
+			}
+		case p.EndsAt != nil:
+			if t.Before(*p.EndsAt) {
+				return p
+			}
+		default:
+			return p
+		}
+	}
+	return nil
+}
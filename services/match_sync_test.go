@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories/memory"
+)
+
+func newSyncFixture(t *testing.T) (*MatchService, *memory.MatchRepo, *models.Match, primitive.ObjectID, primitive.ObjectID) {
+	t.Helper()
+	matchRepo := memory.NewMatchRepo()
+	svc := NewMatchService(matchRepo, new(MockPlayerRepo), memory.NewMatchEventRepo(), nil, nil)
+	p1, p2 := newPlayerID(), newPlayerID()
+	match := makeLiveMatch([]primitive.ObjectID{p1}, []primitive.ObjectID{p2})
+	require.NoError(t, matchRepo.Create(context.Background(), match))
+	return svc, matchRepo, match, p1, p2
+}
+
+func TestSyncEvents_NoConcurrentChanges_AppliesAll(t *testing.T) {
+	svc, _, match, p1, p2 := newSyncFixture(t)
+	ctx := context.Background()
+	t0 := time.Now().Add(-5 * time.Minute)
+
+	result, err := svc.SyncEvents(ctx, match.ID, "phone-a", match.Version, []SyncEvent{
+		{ID: "a1", Type: SyncEventScore, Team: 1, PlayerID: p1.Hex(), At: t0},
+		{ID: "a2", Type: SyncEventScore, Team: 2, PlayerID: p2.Hex(), At: t0.Add(30 * time.Second)},
+		{ID: "a3", Type: SyncEventUndo, At: t0.Add(40 * time.Second)},
+		{ID: "a4", Type: SyncEventScore, Team: 1, PlayerID: p1.Hex(), At: t0.Add(60 * time.Second)},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.Applied)
+	assert.Empty(t, result.Conflicts)
+	assert.Equal(t, 2, result.Match.Score1)
+	assert.Equal(t, 0, result.Match.Score2)
+	assert.Len(t, result.Match.ScoreHistory, 2)
+	assert.Equal(t, "phone-a", result.Match.ScoreHistory[0].DeviceID)
+	assert.Equal(t, p1.Hex(), result.Match.ServingPlayerID)
+}
+
+func TestSyncEvents_OutOfOrderBatch_SortedByTimestamp(t *testing.T) {
+	svc, _, match, p1, p2 := newSyncFixture(t)
+	ctx := context.Background()
+	t0 := time.Now().Add(-5 * time.Minute)
+
+	result, err := svc.SyncEvents(ctx, match.ID, "phone-a", match.Version, []SyncEvent{
+		{ID: "a2", Type: SyncEventScore, Team: 2, PlayerID: p2.Hex(), At: t0.Add(time.Minute)},
+		{ID: "a1", Type: SyncEventScore, Team: 1, PlayerID: p1.Hex(), At: t0},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "a1", result.Match.ScoreHistory[0].EventID)
+	assert.Equal(t, 2, result.Match.ServingTeam)
+}
+
+func TestSyncEvents_OtherDeviceScored_ConflictsDropped(t *testing.T) {
+	svc, _, match, p1, p2 := newSyncFixture(t)
+	base := match.Version
+
+	// Phone B scores live while phone A is offline.
+	liveCtx := WithActor(context.Background(), Actor{DeviceID: "phone-b"})
+	_, err := svc.UpdateScore(liveCtx, match.ID, 2, p2.Hex())
+	require.NoError(t, err)
+
+	t0 := time.Now().Add(-time.Minute)
+	result, err := svc.SyncEvents(context.Background(), match.ID, "phone-a", base, []SyncEvent{
+		{ID: "a1", Type: SyncEventScore, Team: 1, PlayerID: p1.Hex(), At: t0},
+		{ID: "a2", Type: SyncEventScore, Team: 1, PlayerID: p1.Hex(), At: time.Now().Add(30 * time.Second)},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Applied)
+	require.Len(t, result.Conflicts, 1)
+	assert.Equal(t, "a1", result.Conflicts[0].Event.ID)
+	assert.Equal(t, 1, result.Match.Score1)
+	assert.Equal(t, 1, result.Match.Score2)
+}
+
+func TestSyncEvents_UndoOfOtherDevicesPoint_Conflict(t *testing.T) {
+	svc, _, match, _, p2 := newSyncFixture(t)
+
+	liveCtx := WithActor(context.Background(), Actor{DeviceID: "phone-b"})
+	scored, err := svc.UpdateScore(liveCtx, match.ID, 2, p2.Hex())
+	require.NoError(t, err)
+
+	result, err := svc.SyncEvents(context.Background(), match.ID, "phone-a", scored.Version, []SyncEvent{
+		{ID: "a1", Type: SyncEventUndo, At: time.Now()},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Applied)
+	require.Len(t, result.Conflicts, 1)
+	assert.Equal(t, 1, result.Match.Score2)
+}
+
+func TestSyncEvents_Resubmitted_SkipsDuplicates(t *testing.T) {
+	svc, _, match, p1, _ := newSyncFixture(t)
+	ctx := context.Background()
+	events := []SyncEvent{
+		{ID: "a1", Type: SyncEventScore, Team: 1, PlayerID: p1.Hex(), At: time.Now().Add(-time.Minute)},
+	}
+
+	_, err := svc.SyncEvents(ctx, match.ID, "phone-a", match.Version, events)
+	require.NoError(t, err)
+	result, err := svc.SyncEvents(ctx, match.ID, "phone-a", match.Version, events)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Applied)
+	assert.Equal(t, 1, result.Duplicates)
+	assert.Equal(t, 1, result.Match.Score1)
+}
+
+func TestSyncEvents_InvalidEvents_Fails(t *testing.T) {
+	svc, _, match, p1, _ := newSyncFixture(t)
+	ctx := context.Background()
+
+	_, err := svc.SyncEvents(ctx, match.ID, "phone-a", match.Version, []SyncEvent{
+		{Type: SyncEventScore, Team: 3, PlayerID: p1.Hex(), At: time.Now()},
+	})
+	assert.ErrorContains(t, err, "invalid team")
+
+	_, err = svc.SyncEvents(ctx, match.ID, "phone-a", match.Version, []SyncEvent{
+		{Type: SyncEventScore, Team: 1, PlayerID: p1.Hex(), At: time.Now().Add(time.Hour)},
+	})
+	assert.ErrorContains(t, err, "future")
+
+	_, err = svc.SyncEvents(ctx, match.ID, "", match.Version, []SyncEvent{
+		{Type: SyncEventScore, Team: 1, PlayerID: p1.Hex(), At: time.Now()},
+	})
+	assert.ErrorContains(t, err, "device id")
+}
+
+func TestSyncEvents_ResubmittedWithUndo_NotReapplied(t *testing.T) {
+	svc, _, match, p1, p2 := newSyncFixture(t)
+	ctx := context.Background()
+	t0 := time.Now().Add(-time.Minute)
+	events := []SyncEvent{
+		{ID: "a1", Type: SyncEventScore, Team: 1, PlayerID: p1.Hex(), At: t0},
+		{ID: "a2", Type: SyncEventScore, Team: 2, PlayerID: p2.Hex(), At: t0.Add(10 * time.Second)},
+		{ID: "a3", Type: SyncEventUndo, At: t0.Add(20 * time.Second)},
+	}
+
+	first, err := svc.SyncEvents(ctx, match.ID, "phone-a", match.Version, events)
+	require.NoError(t, err)
+	require.Equal(t, 3, first.Applied)
+
+	// The response was lost and the phone sends the same batch again: a2 is no
+	// longer in the history, but neither it nor the undo may apply twice.
+	result, err := svc.SyncEvents(ctx, match.ID, "phone-a", match.Version, events)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Applied)
+	assert.Equal(t, 3, result.Duplicates)
+	assert.Equal(t, 1, result.Match.Score1)
+	assert.Equal(t, 0, result.Match.Score2)
+	require.Len(t, result.Match.ScoreHistory, 1)
+	assert.Equal(t, "a1", result.Match.ScoreHistory[0].EventID)
+}
+
+func TestSyncEvents_CrossesInterval(t *testing.T) {
+	svc, _, match, p1, _ := newSyncFixture(t)
+	t0 := time.Now().Add(-10 * time.Minute)
+
+	var events []SyncEvent
+	for i := 0; i < models.IntervalPoints; i++ {
+		events = append(events, SyncEvent{Type: SyncEventScore, Team: 1, PlayerID: p1.Hex(), At: t0.Add(time.Duration(i) * 10 * time.Second)})
+	}
+	reached := events[len(events)-1].At
+	events = append(events,
+		SyncEvent{ID: "early", Type: SyncEventScore, Team: 1, PlayerID: p1.Hex(), At: reached.Add(20 * time.Second)},
+		SyncEvent{ID: "after", Type: SyncEventScore, Team: 1, PlayerID: p1.Hex(), At: reached.Add(models.IntervalSecs*time.Second + 10*time.Second)},
+	)
+
+	result, err := svc.SyncEvents(context.Background(), match.ID, "phone-a", match.Version, events)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.IntervalPoints+1, result.Applied)
+	require.Len(t, result.Conflicts, 1)
+	assert.Equal(t, "early", result.Conflicts[0].Event.ID)
+	assert.Equal(t, models.IntervalPoints+1, result.Match.Score1)
+
+	// The interval ran from the 11th point until the next rally.
+	require.Len(t, result.Match.Pauses, 1)
+	interval := result.Match.Pauses[0]
+	assert.Equal(t, models.PauseReasonInterval, interval.Reason)
+	assert.True(t, interval.StartedAt.Equal(reached))
+	require.NotNil(t, interval.EndedAt)
+	assert.True(t, interval.EndedAt.Equal(events[len(events)-1].At))
+	assert.Equal(t, models.MatchStatusLive, result.Match.Status)
+}
+
+func TestSyncEvents_OtherDevicePaused_Conflicts(t *testing.T) {
+	svc, _, match, p1, _ := newSyncFixture(t)
+	ctx := context.Background()
+	before := time.Now().Add(-time.Minute)
+
+	// Phone B pauses and resumes while phone A is offline.
+	_, err := svc.PauseMatch(ctx, match.ID, models.PauseReasonInjury)
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	resumed, err := svc.ResumeMatch(ctx, match.ID)
+	require.NoError(t, err)
+	pause := resumed.Pauses[0]
+
+	result, err := svc.SyncEvents(ctx, match.ID, "phone-a", match.Version, []SyncEvent{
+		{ID: "a1", Type: SyncEventScore, Team: 1, PlayerID: p1.Hex(), At: before},
+		{ID: "a2", Type: SyncEventScore, Team: 1, PlayerID: p1.Hex(), At: pause.StartedAt.Add(5 * time.Millisecond)},
+		{ID: "a3", Type: SyncEventScore, Team: 1, PlayerID: p1.Hex(), At: pause.EndedAt.Add(time.Millisecond)},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Applied)
+	require.Len(t, result.Conflicts, 1)
+	assert.Equal(t, "a2", result.Conflicts[0].Event.ID)
+	assert.Contains(t, result.Conflicts[0].Reason, "injury")
+
+	// Still paused: nothing more is taken until play resumes.
+	_, err = svc.PauseMatch(ctx, match.ID, models.PauseReasonBreak)
+	require.NoError(t, err)
+	result, err = svc.SyncEvents(ctx, match.ID, "phone-a", result.Match.Version, []SyncEvent{
+		{ID: "a4", Type: SyncEventScore, Team: 1, PlayerID: p1.Hex(), At: time.Now()},
+	})
+	require.NoError(t, err)
+	assert.Zero(t, result.Applied)
+	require.Len(t, result.Conflicts, 1)
+	assert.Equal(t, models.MatchStatusPaused, result.Match.Status)
+}
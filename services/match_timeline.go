@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+// timeline collects the audit events produced by one match update. Each
+// recorded event captures the state before and after its own step, so a batch
+// (like an offline sync) yields one event per rally.
+type timeline struct {
+	events []models.MatchEvent
+	last   models.MatchState
+}
+
+func newTimeline(match *models.Match) *timeline {
+	return &timeline{last: match.State()}
+}
+
+// record appends an event of the given type; detail fills the type-specific payload.
+func (t *timeline) record(match *models.Match, eventType string, detail func(*models.MatchEvent)) {
+	before := t.last
+	after := match.State()
+	ev := models.MatchEvent{Type: eventType, Before: &before, After: &after}
+	if detail != nil {
+		detail(&ev)
+	}
+	t.events = append(t.events, ev)
+	t.last = after
+}
+
+// appendMatchEvents stamps events with the match, actor and resulting version
+// and persists them. Callers write them in the same transaction as the match,
+// so the timeline never misses a change that was kept.
+func appendMatchEvents(ctx context.Context, repo repositories.MatchEventRepository, match *models.Match, events []models.MatchEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	actor := ActorFrom(ctx)
+	now := time.Now()
+	for i := range events {
+		events[i].MatchID = match.ID
+		events[i].GroupID = match.GroupID
+		events[i].Version = match.Version
+		events[i].Index = i
+		events[i].ActorID = actor.UserID
+		events[i].ActorName = actor.Username
+		events[i].DeviceID = actor.DeviceID
+		events[i].At = now
+	}
+	if err := repo.Append(ctx, events...); err != nil {
+		return fmt.Errorf("match %s: append %d timeline events: %w", match.ID.Hex(), len(events), err)
+	}
+	return nil
+}
+
+// GetTimeline returns every recorded change to a match in order.
+func (s *MatchService) GetTimeline(ctx context.Context, matchID primitive.ObjectID) ([]models.MatchEvent, error) {
+	return s.eventRepo.FindByMatchID(ctx, matchID)
+}
+
+// ErrNoCreatedEvent means the match predates the timeline and cannot be replayed.
+var ErrNoCreatedEvent = errors.New("timeline has no created event")
+
+// ReplayMatch rebuilds a match from its events. deleted reports whether the
+// timeline ends with the match being deleted.
+func ReplayMatch(events []models.MatchEvent) (match *models.Match, deleted bool, err error) {
+	ordered := make([]models.MatchEvent, len(events))
+	copy(ordered, events)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Version != ordered[j].Version {
+			return ordered[i].Version < ordered[j].Version
+		}
+		return ordered[i].Index < ordered[j].Index
+	})
+
+	for _, ev := range ordered {
+		if match == nil {
+			if ev.Type != models.MatchEventCreated || ev.Snapshot == nil {
+				return nil, false, ErrNoCreatedEvent
+			}
+			snapshot := *ev.Snapshot
+			snapshot.ScoreHistory = append([]models.ScoreEvent{}, snapshot.ScoreHistory...)
+			snapshot.Pauses = append([]models.Pause{}, snapshot.Pauses...)
+			match = &snapshot
+			continue
+		}
+
+		switch ev.Type {
+		case models.MatchEventPoint:
+			if ev.Point == nil {
+				return nil, false, fmt.Errorf("point event %s has no point", ev.ID.Hex())
+			}
+			match.ScoreHistory = append(match.ScoreHistory, *ev.Point)
+		case models.MatchEventUndo:
+			if len(match.ScoreHistory) == 0 {
+				return nil, false, fmt.Errorf("undo event %s with empty history", ev.ID.Hex())
+			}
+			match.ScoreHistory = match.ScoreHistory[:len(match.ScoreHistory)-1]
+			if ev.Pause != nil && len(match.Pauses) > 0 {
+				match.Pauses = match.Pauses[:len(match.Pauses)-1]
+			}
+		case models.MatchEventPause:
+			if ev.Pause != nil {
+				match.Pauses = append(match.Pauses, *ev.Pause)
+			}
+		case models.MatchEventResume:
+			if ev.Pause != nil && len(match.Pauses) > 0 {
+				match.Pauses[len(match.Pauses)-1] = *ev.Pause
+			}
+		case models.MatchEventPlayerMerge, models.MatchEventPlayerRename:
+			if ev.Merge != nil {
+				match.ReplacePlayer(ev.Merge.SourceID, ev.Merge.TargetID, ev.Merge.TargetName)
+			}
+		case models.MatchEventPlayerUnmerge:
+			if ev.Snapshot == nil {
+				return nil, false, fmt.Errorf("unmerge event %s has no snapshot", ev.ID.Hex())
+			}
+			restored := copyMatch(ev.Snapshot)
+			match = &restored
+		case models.MatchEventDelete:
+			deleted = true
+		case models.MatchEventEdit, models.MatchEventFinish, models.MatchEventStatus:
+		default:
+			return nil, false, fmt.Errorf("unknown event type %q", ev.Type)
+		}
+
+		if ev.After != nil {
+			match.ApplyState(*ev.After)
+		}
+		match.Version = ev.Version
+		match.UpdatedAt = ev.At
+	}
+
+	if match == nil {
+		return nil, false, ErrNoCreatedEvent
+	}
+	return match, deleted, nil
+}
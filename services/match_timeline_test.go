@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories/memory"
+)
+
+func TestTimeline_ReplayMatchesCurrentState(t *testing.T) {
+	matchRepo := memory.NewMatchRepo()
+	playerRepo := new(MockPlayerRepo)
+	eventRepo := memory.NewMatchEventRepo()
+	svc := NewMatchService(matchRepo, playerRepo, eventRepo, nil, nil)
+	ctx := WithActor(context.Background(), Actor{UserID: "u1", Username: "amit"})
+
+	p1, p2 := newPlayerID(), newPlayerID()
+	playerRepo.On("FindByID", ctx, p1).Return(&models.Player{ID: p1, Name: "Alice"}, nil)
+	playerRepo.On("FindByID", ctx, p2).Return(&models.Player{ID: p2, Name: "Bob"}, nil)
+
+	match, err := svc.CreateMatch(ctx, primitive.NewObjectID(), []primitive.ObjectID{p1}, []primitive.ObjectID{p2}, "")
+	require.NoError(t, err)
+	_, err = svc.UpdateScore(ctx, match.ID, 1, p1.Hex())
+	require.NoError(t, err)
+	_, err = svc.UpdateScore(ctx, match.ID, 2, p2.Hex())
+	require.NoError(t, err)
+	_, err = svc.UndoScore(ctx, match.ID)
+	require.NoError(t, err)
+	_, err = svc.EditScore(ctx, match.ID, 21, 15)
+	require.NoError(t, err)
+	current, err := svc.FinishMatch(ctx, match.ID)
+	require.NoError(t, err)
+
+	events, err := svc.GetTimeline(ctx, match.ID)
+	require.NoError(t, err)
+	types := make([]string, len(events))
+	for i, ev := range events {
+		types[i] = ev.Type
+		assert.Equal(t, "u1", ev.ActorID)
+		assert.Equal(t, "amit", ev.ActorName)
+	}
+	assert.Equal(t, []string{
+		models.MatchEventCreated, models.MatchEventPoint, models.MatchEventPoint,
+		models.MatchEventUndo, models.MatchEventEdit, models.MatchEventFinish,
+	}, types)
+
+	// "Who changed the score to 21-15?"
+	edit := events[4]
+	assert.Equal(t, 1, edit.Before.Score1)
+	assert.Equal(t, 21, edit.After.Score1)
+	assert.Equal(t, 15, edit.After.Score2)
+
+	replayed, deleted, err := ReplayMatch(events)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	assert.Equal(t, current.Score1, replayed.Score1)
+	assert.Equal(t, current.Score2, replayed.Score2)
+	assert.Equal(t, current.Status, replayed.Status)
+	assert.Equal(t, current.Version, replayed.Version)
+	assert.Equal(t, len(current.ScoreHistory), len(replayed.ScoreHistory))
+	assert.Equal(t, current.ServingPlayerID, replayed.ServingPlayerID)
+}
+
+func TestTimeline_DeleteKeepsEvents(t *testing.T) {
+	matchRepo := memory.NewMatchRepo()
+	eventRepo := memory.NewMatchEventRepo()
+	svc := NewMatchService(matchRepo, new(MockPlayerRepo), eventRepo, nil, nil)
+	ctx := context.Background()
+
+	p1, p2 := newPlayerID(), newPlayerID()
+	match := makeLiveMatch([]primitive.ObjectID{p1}, []primitive.ObjectID{p2})
+	require.NoError(t, matchRepo.Create(ctx, match))
+	require.NoError(t, svc.recordCreated(ctx, match))
+
+	require.NoError(t, svc.DeleteMatch(ctx, match.ID))
+
+	events, err := svc.GetTimeline(ctx, match.ID)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.MatchEventDelete, events[1].Type)
+
+	_, deleted, err := ReplayMatch(events)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+}
+
+// failingEvents is a timeline that cannot be written to.
+type failingEvents struct {
+	*memory.MatchEventRepo
+}
+
+func (failingEvents) Append(context.Context, ...models.MatchEvent) error {
+	return errors.New("timeline unavailable")
+}
+
+func TestTimeline_FailedAppendUndoesWrite(t *testing.T) {
+	matchRepo := memory.NewMatchRepo()
+	events := failingEvents{memory.NewMatchEventRepo()}
+	svc := NewMatchService(matchRepo, new(MockPlayerRepo), events, nil, memory.NewTransactor(matchRepo, events.MatchEventRepo))
+	ctx := context.Background()
+
+	p1, p2 := newPlayerID(), newPlayerID()
+	match := makeLiveMatch([]primitive.ObjectID{p1}, []primitive.ObjectID{p2})
+	require.NoError(t, matchRepo.Create(ctx, match))
+
+	_, err := svc.EditScore(ctx, match.ID, 5, 3)
+	require.Error(t, err)
+	require.Error(t, svc.DeleteMatch(ctx, match.ID))
+
+	got, err := matchRepo.FindByID(ctx, match.ID)
+	require.NoError(t, err)
+	assert.Equal(t, match.Version, got.Version)
+	assert.Zero(t, got.Score1)
+}
+
+func TestTimeline_PlayerMergeRecordedAndReplayed(t *testing.T) {
+	matchRepo := memory.NewMatchRepo()
+	eventRepo := memory.NewMatchEventRepo()
+	playerRepo := new(MockPlayerRepo)
+	ctx := context.Background()
+
+	p1, p2, dup := newPlayerID(), newPlayerID(), newPlayerID()
+	match := makeLiveMatch([]primitive.ObjectID{dup}, []primitive.ObjectID{p2})
+	require.NoError(t, matchRepo.Create(ctx, match))
+	require.NoError(t, NewMatchService(matchRepo, playerRepo, eventRepo, nil, nil).recordCreated(ctx, match))
+
+	playerRepo.On("FindByID", mock.Anything, p1).Return(&models.Player{ID: p1, Name: "Alice", GroupID: match.GroupID}, nil)
+	playerRepo.On("FindByID", mock.Anything, dup).Return(&models.Player{ID: dup, Name: "alice", GroupID: match.GroupID}, nil)
+	playerRepo.On("Delete", mock.Anything, dup).Return(nil)
+
+	players := NewPlayerService(playerRepo, matchRepo, eventRepo, memory.NewMergeRecordRepo(), memory.NewTransactor())
+	_, err := players.MergePlayer(ctx, match.GroupID, p1, dup)
+	require.NoError(t, err)
+
+	events, err := eventRepo.FindByMatchID(ctx, match.ID)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.MatchEventPlayerMerge, events[1].Type)
+	assert.Equal(t, dup, events[1].Merge.SourceID)
+
+	replayed, _, err := ReplayMatch(events)
+	require.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{p1}, replayed.Team1IDs)
+	assert.Equal(t, []string{"Alice"}, replayed.Team1Names)
+}
+
+func TestReplayMatch_WithoutCreated_Fails(t *testing.T) {
+	_, _, err := ReplayMatch([]models.MatchEvent{{Type: models.MatchEventPoint, Version: 2}})
+	assert.ErrorIs(t, err, ErrNoCreatedEvent)
+}
@@ -7,6 +7,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"gully-backend/models"
+	"gully-backend/repositories"
 )
 
 // ── Mock UserRepository ──
@@ -34,6 +35,21 @@ func (m *MockUserRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*mo
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserRepo) UpdatePassword(ctx context.Context, id primitive.ObjectID, hash string) error {
+	args := m.Called(ctx, id, hash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepo) UpdateProfile(ctx context.Context, user *models.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
 // ── Mock GroupRepository ──
 
 type MockGroupRepo struct{ mock.Mock }
@@ -72,6 +88,41 @@ func (m *MockGroupRepo) AddMember(ctx context.Context, groupID, userID primitive
 	return args.Error(0)
 }
 
+func (m *MockGroupRepo) RemoveMember(ctx context.Context, groupID, userID primitive.ObjectID) error {
+	args := m.Called(ctx, groupID, userID)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepo) SetCreator(ctx context.Context, groupID, userID primitive.ObjectID) error {
+	args := m.Called(ctx, groupID, userID)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepo) Rename(ctx context.Context, groupID primitive.ObjectID, name string) error {
+	args := m.Called(ctx, groupID, name)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepo) SetJoinCode(ctx context.Context, groupID primitive.ObjectID, code string) error {
+	args := m.Called(ctx, groupID, code)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepo) UpdateSettings(ctx context.Context, groupID primitive.ObjectID, settings *models.GroupSettings) error {
+	args := m.Called(ctx, groupID, settings)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepo) SetJoinApproval(ctx context.Context, groupID primitive.ObjectID, on bool) error {
+	args := m.Called(ctx, groupID, on)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
 // ── Mock PlayerRepository ──
 
 type MockPlayerRepo struct{ mock.Mock }
@@ -110,11 +161,32 @@ func (m *MockPlayerRepo) FindByNameAndGroupID(ctx context.Context, name string,
 	return args.Get(0).(*models.Player), args.Error(1)
 }
 
+func (m *MockPlayerRepo) FindByUserAndGroupID(ctx context.Context, userID, groupID primitive.ObjectID) (*models.Player, error) {
+	args := m.Called(ctx, userID, groupID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Player), args.Error(1)
+}
+
+func (m *MockPlayerRepo) FindByUserID(ctx context.Context, userID primitive.ObjectID) ([]models.Player, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Player), args.Error(1)
+}
+
 func (m *MockPlayerRepo) Update(ctx context.Context, player *models.Player) error {
 	args := m.Called(ctx, player)
 	return args.Error(0)
 }
 
+func (m *MockPlayerRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	args := m.Called(ctx, groupID)
+	return args.Error(0)
+}
+
 // ── Mock MatchRepository ──
 
 type MockMatchRepo struct{ mock.Mock }
@@ -140,6 +212,14 @@ func (m *MockMatchRepo) FindByGroupID(ctx context.Context, groupID primitive.Obj
 	return args.Get(0).([]models.Match), args.Error(1)
 }
 
+func (m *MockMatchRepo) FindPage(ctx context.Context, q repositories.MatchQuery) (*repositories.MatchPage, error) {
+	args := m.Called(ctx, q)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.MatchPage), args.Error(1)
+}
+
 func (m *MockMatchRepo) Update(ctx context.Context, match *models.Match) error {
 	args := m.Called(ctx, match)
 	return args.Error(0)
@@ -150,7 +230,15 @@ func (m *MockMatchRepo) Delete(ctx context.Context, id primitive.ObjectID) error
 	return args.Error(0)
 }
 
-func (m *MockMatchRepo) ReplacePlayerInMatches(ctx context.Context, groupID, sourceID, targetID primitive.ObjectID, sourceName, targetName string) error {
+func (m *MockMatchRepo) ReplacePlayerInMatches(ctx context.Context, groupID, sourceID, targetID primitive.ObjectID, sourceName, targetName string) ([]models.Match, error) {
 	args := m.Called(ctx, groupID, sourceID, targetID, sourceName, targetName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Match), args.Error(1)
+}
+
+func (m *MockMatchRepo) DeleteByGroupID(ctx context.Context, groupID primitive.ObjectID) error {
+	args := m.Called(ctx, groupID)
 	return args.Error(0)
 }
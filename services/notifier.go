@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"gully-backend/models"
+)
+
+// PasswordResetNotifier delivers a password reset token to its user.
+// Accounts have no email or phone number yet, so the notifiers here are
+// development stand-ins; a real one plugs in behind the same interface.
+type PasswordResetNotifier interface {
+	SendPasswordReset(ctx context.Context, user *models.User, token string, expiresAt time.Time) error
+}
+
+// LogNotifier writes reset tokens to the server log. Anyone who can read
+// the log can reset passwords: development only.
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (LogNotifier) SendPasswordReset(_ context.Context, user *models.User, token string, expiresAt time.Time) error {
+	log.Printf("password reset for %s: token %s (expires %s)", user.Username, token, expiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// FileNotifier appends reset tokens to a file, one JSON object per line, so
+// local tooling and tests can pick them up.
+type FileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func (n *FileNotifier) SendPasswordReset(_ context.Context, user *models.User, token string, expiresAt time.Time) error {
+	line, err := json.Marshal(map[string]interface{}{
+		"user_id":    user.ID.Hex(),
+		"username":   user.Username,
+		"token":      token,
+		"expires_at": expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
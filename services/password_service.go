@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+// Password length limits for changes and resets; bcrypt only reads the
+// first 72 bytes.
+const (
+	MinPasswordLen   = 8
+	MaxPasswordBytes = 72
+)
+
+// DefaultPasswordResetTTL is how long a reset token works when none is
+// configured.
+const DefaultPasswordResetTTL = time.Hour
+
+var (
+	ErrWrongPassword     = errors.New("current password is incorrect")
+	ErrWeakPassword      = errors.New("password is too weak")
+	ErrInvalidResetToken = errors.New("reset token is invalid, used or expired")
+	// ErrPasswordResetOff means the server has no way to deliver reset
+	// tokens, so password reset is turned off.
+	ErrPasswordResetOff = errors.New("password reset is not available on this server")
+)
+
+// PasswordService changes and resets passwords. Either way, every existing
+// session of the user is revoked.
+type PasswordService struct {
+	userRepo  repositories.UserRepository
+	resetRepo repositories.PasswordResetRepository
+	auth      *AuthService
+	notifier  PasswordResetNotifier
+	resetTTL  time.Duration
+}
+
+// NewPasswordService creates the service; a zero resetTTL falls back to the
+// default. A nil notifier turns password reset off.
+func NewPasswordService(userRepo repositories.UserRepository, resetRepo repositories.PasswordResetRepository, auth *AuthService, notifier PasswordResetNotifier, resetTTL time.Duration) *PasswordService {
+	if resetTTL <= 0 {
+		resetTTL = DefaultPasswordResetTTL
+	}
+	return &PasswordService{userRepo: userRepo, resetRepo: resetRepo, auth: auth, notifier: notifier, resetTTL: resetTTL}
+}
+
+// ChangePassword replaces the user's password after checking the current
+// one. All sessions end, and a new one is started for the caller's device.
+// Wrong current passwords count towards the same lockout as failed logins,
+// so a stolen access token cannot be used to guess the password.
+func (s *PasswordService) ChangePassword(ctx context.Context, userID primitive.ObjectID, current, next string) (*TokenPair, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	key := loginKey(user.Username)
+	if err := s.auth.loginLocked(key); err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(current)) != nil {
+		if err := s.auth.loginFailed(key); errors.Is(err, ErrLoginLocked) {
+			return nil, err
+		}
+		return nil, ErrWrongPassword
+	}
+	s.auth.attempts.Reset(key)
+	if current == next {
+		return nil, fmt.Errorf("%w: the new password must differ from the current one", ErrWeakPassword)
+	}
+	if err := s.setPassword(ctx, user, next); err != nil {
+		return nil, err
+	}
+	return s.auth.startSession(ctx, user)
+}
+
+// RequestPasswordReset sends the user a single-use reset token. Unknown
+// usernames succeed silently, so the endpoint cannot be used to find out
+// which accounts exist.
+func (s *PasswordService) RequestPasswordReset(ctx context.Context, username string) error {
+	if s.notifier == nil {
+		return ErrPasswordResetOff
+	}
+	user, err := s.userRepo.FindByUsername(ctx, username)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	secret, hash, err := newRefreshSecret()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	reset := &models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.resetTTL),
+	}
+	if err := s.resetRepo.Create(ctx, reset); err != nil {
+		return err
+	}
+	return s.notifier.SendPasswordReset(ctx, user, reset.ID.Hex()+"."+secret, reset.ExpiresAt)
+}
+
+// ResetPassword sets a new password with a reset token. The token, and any
+// other outstanding token of the user, stops working.
+func (s *PasswordService) ResetPassword(ctx context.Context, token, next string) error {
+	if s.notifier == nil {
+		return ErrPasswordResetOff
+	}
+	resetHex, secret, ok := strings.Cut(token, ".")
+	resetID, err := primitive.ObjectIDFromHex(resetHex)
+	if !ok || err != nil || secret == "" {
+		return ErrInvalidResetToken
+	}
+	reset, err := s.resetRepo.FindByID(ctx, resetID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	now := time.Now()
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(reset.TokenHash)) != 1 || !reset.Usable(now) {
+		return ErrInvalidResetToken
+	}
+	user, err := s.userRepo.FindByID(ctx, reset.UserID)
+	if err != nil {
+		return ErrInvalidResetToken // the account was deleted
+	}
+	if err := validatePassword(next, user.Username); err != nil {
+		return err
+	}
+
+	if err := s.resetRepo.MarkUsed(ctx, reset.ID, now); err != nil {
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	if err := s.setPassword(ctx, user, next); err != nil {
+		return err
+	}
+	return s.resetRepo.MarkUsedByUserID(ctx, user.ID, now)
+}
+
+// setPassword stores a new password and revokes every session of the user.
+func (s *PasswordService) setPassword(ctx context.Context, user *models.User, password string) error {
+	if err := validatePassword(password, user.Username); err != nil {
+		return err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, string(hashed)); err != nil {
+		return err
+	}
+	return s.auth.revokeSessions(ctx, user.ID, models.RevokedPassword)
+}
+
+// validatePassword checks the strength rules: MinPasswordLen characters to
+// MaxPasswordBytes bytes, at least one letter and one other character, and
+// not containing the username.
+func validatePassword(password, username string) error {
+	if len([]rune(password)) < MinPasswordLen {
+		return fmt.Errorf("%w: use at least %d characters", ErrWeakPassword, MinPasswordLen)
+	}
+	if len(password) > MaxPasswordBytes {
+		return fmt.Errorf("%w: use at most %d bytes", ErrWeakPassword, MaxPasswordBytes)
+	}
+	letters := 0
+	for _, r := range password {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if letters == 0 || letters == utf8.RuneCountInString(password) {
+		return fmt.Errorf("%w: mix letters with digits or symbols", ErrWeakPassword)
+	}
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return fmt.Errorf("%w: must not contain the username", ErrWeakPassword)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gully-backend/models"
+	"gully-backend/repositories/memory"
+)
+
+// capturedResets records the tokens a PasswordService sends out.
+type capturedResets struct{ tokens []string }
+
+func (c *capturedResets) SendPasswordReset(_ context.Context, _ *models.User, token string, _ time.Time) error {
+	c.tokens = append(c.tokens, token)
+	return nil
+}
+
+func newPasswordFixture(t *testing.T, resetTTL time.Duration) (*PasswordService, *AuthService, *capturedResets) {
+	users := memory.NewUserRepo()
+	auth := NewAuthService(users, memory.NewSessionRepo(), nil, NewHMACKeys("test-secret"), 0, 0)
+	_, err := auth.Register(context.Background(), "alice", "password123")
+	require.NoError(t, err)
+	notes := &capturedResets{}
+	return NewPasswordService(users, memory.NewPasswordResetRepo(), auth, notes, resetTTL), auth, notes
+}
+
+func TestChangePassword(t *testing.T) {
+	svc, auth, _ := newPasswordFixture(t, 0)
+	ctx := context.Background()
+	old, user, err := auth.Login(ctx, "alice", "password123")
+	require.NoError(t, err)
+
+	_, err = svc.ChangePassword(ctx, user.ID, "nope", "newpassword1")
+	assert.ErrorIs(t, err, ErrWrongPassword)
+	_, err = svc.ChangePassword(ctx, user.ID, "password123", "short")
+	assert.ErrorIs(t, err, ErrWeakPassword)
+	_, err = svc.ChangePassword(ctx, user.ID, "password123", "password123")
+	assert.ErrorIs(t, err, ErrWeakPassword)
+
+	fresh, err := svc.ChangePassword(ctx, user.ID, "password123", "newpassword1")
+	require.NoError(t, err)
+	_, err = auth.VerifyAccessToken(ctx, fresh.AccessToken)
+	assert.NoError(t, err)
+	_, err = auth.VerifyAccessToken(ctx, old.AccessToken)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+
+	_, _, err = auth.Login(ctx, "alice", "password123")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, _, err = auth.Login(ctx, "alice", "newpassword1")
+	assert.NoError(t, err)
+}
+
+func TestResetPassword_SingleUse(t *testing.T) {
+	svc, auth, notes := newPasswordFixture(t, 0)
+	ctx := context.Background()
+	session, _, err := auth.Login(ctx, "alice", "password123")
+	require.NoError(t, err)
+
+	require.NoError(t, svc.RequestPasswordReset(ctx, "alice"))
+	require.NoError(t, svc.RequestPasswordReset(ctx, "alice"))
+	require.Len(t, notes.tokens, 2)
+	first, second := notes.tokens[0], notes.tokens[1]
+
+	assert.ErrorIs(t, svc.ResetPassword(ctx, first, "short"), ErrWeakPassword)
+	assert.ErrorIs(t, svc.ResetPassword(ctx, first[:len(first)-1]+"x", "newpassword1"), ErrInvalidResetToken)
+	assert.ErrorIs(t, svc.ResetPassword(ctx, "garbage", "newpassword1"), ErrInvalidResetToken)
+
+	require.NoError(t, svc.ResetPassword(ctx, first, "newpassword1"))
+	assert.ErrorIs(t, svc.ResetPassword(ctx, first, "newpassword2"), ErrInvalidResetToken)
+	assert.ErrorIs(t, svc.ResetPassword(ctx, second, "newpassword2"), ErrInvalidResetToken, "other tokens are spent too")
+
+	_, err = auth.VerifyAccessToken(ctx, session.AccessToken)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+	_, _, err = auth.Login(ctx, "alice", "newpassword1")
+	assert.NoError(t, err)
+}
+
+func TestResetPassword_Expired(t *testing.T) {
+	svc, _, notes := newPasswordFixture(t, time.Nanosecond)
+	ctx := context.Background()
+	require.NoError(t, svc.RequestPasswordReset(ctx, "alice"))
+	require.Len(t, notes.tokens, 1)
+
+	time.Sleep(time.Millisecond)
+	assert.ErrorIs(t, svc.ResetPassword(ctx, notes.tokens[0], "newpassword1"), ErrInvalidResetToken)
+}
+
+func TestRequestPasswordReset_UnknownUser(t *testing.T) {
+	svc, _, notes := newPasswordFixture(t, 0)
+	assert.NoError(t, svc.RequestPasswordReset(context.Background(), "nobody"))
+	assert.Empty(t, notes.tokens)
+}
+
+func TestPasswordReset_Off(t *testing.T) {
+	users := memory.NewUserRepo()
+	auth := NewAuthService(users, memory.NewSessionRepo(), nil, NewHMACKeys("test-secret"), 0, 0)
+	_, err := auth.Register(context.Background(), "alice", "password123")
+	require.NoError(t, err)
+	svc := NewPasswordService(users, memory.NewPasswordResetRepo(), auth, nil, 0)
+
+	ctx := context.Background()
+	assert.ErrorIs(t, svc.RequestPasswordReset(ctx, "alice"), ErrPasswordResetOff)
+	assert.ErrorIs(t, svc.ResetPassword(ctx, "any.token", "newpassword1"), ErrPasswordResetOff)
+}
+
+func TestChangePassword_SharesLoginLockout(t *testing.T) {
+	svc, auth, _ := newPasswordFixture(t, 0)
+	ctx := context.Background()
+	_, user, err := auth.Login(ctx, "alice", "password123")
+	require.NoError(t, err)
+
+	for i := 1; i < LoginLockoutThreshold; i++ {
+		_, _, err := auth.Login(ctx, "alice", "wrong")
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+	_, err = svc.ChangePassword(ctx, user.ID, "still wrong", "newpassword1")
+	var locked *LoginLockedError
+	require.ErrorAs(t, err, &locked, "a wrong current password counts as a failed login")
+	assert.Equal(t, LoginLockoutBase, locked.RetryAfter)
+
+	// Locked out of both, even with the right password.
+	_, err = svc.ChangePassword(ctx, user.ID, "password123", "newpassword1")
+	assert.ErrorIs(t, err, ErrLoginLocked)
+	_, _, err = auth.Login(ctx, "alice", "password123")
+	assert.ErrorIs(t, err, ErrLoginLocked)
+}
@@ -0,0 +1,342 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+)
+
+var (
+	ErrPlayerNotFound = errors.New("player not found")
+	ErrPlayerLinked   = errors.New("player is already linked to a user")
+	ErrPlayerUnlinked = errors.New("player is not linked to a user")
+	ErrClaimPending   = errors.New("player already has a pending claim")
+	ErrNoClaim        = errors.New("player has no pending claim")
+)
+
+// GetPlayer returns a player of the group.
+func (s *PlayerService) GetPlayer(ctx context.Context, groupID, playerID primitive.ObjectID) (*models.Player, error) {
+	player, err := s.playerRepo.FindByID(ctx, playerID)
+	if err != nil || player.GroupID != groupID {
+		return nil, ErrPlayerNotFound
+	}
+	return player, nil
+}
+
+// ClaimPlayer asks for a guest player to be linked to userID. The claim waits
+// for the group creator unless approve is set, in which case it is linked at
+// once (the creator claiming a player themselves).
+func (s *PlayerService) ClaimPlayer(ctx context.Context, groupID, playerID, userID primitive.ObjectID, approve bool) (*models.Player, error) {
+	player, err := s.GetPlayer(ctx, groupID, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if player.UserID != nil {
+		return nil, ErrPlayerLinked
+	}
+	if player.ClaimedBy != nil && *player.ClaimedBy != userID {
+		return nil, ErrClaimPending
+	}
+	now := time.Now()
+	player.ClaimedBy, player.ClaimedAt = &userID, &now
+	if approve {
+		linked, _, err := s.approveClaim(ctx, player)
+		return linked, err
+	}
+	if err := s.playerRepo.Update(ctx, player); err != nil {
+		return nil, err
+	}
+	return player, nil
+}
+
+// ApproveClaim links a player to the user who claimed it. A user has one
+// player per group, so any player they were linked to before becomes a
+// guest; it is returned (or nil) so it can be merged into the claimed one.
+func (s *PlayerService) ApproveClaim(ctx context.Context, groupID, playerID primitive.ObjectID) (linked, released *models.Player, err error) {
+	player, err := s.GetPlayer(ctx, groupID, playerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.approveClaim(ctx, player)
+}
+
+func (s *PlayerService) approveClaim(ctx context.Context, player *models.Player) (linked, released *models.Player, err error) {
+	if player.ClaimedBy == nil {
+		return nil, nil, ErrNoClaim
+	}
+	userID := *player.ClaimedBy
+	err = s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		released = nil
+		if previous, err := s.playerRepo.FindByUserAndGroupID(ctx, userID, player.GroupID); err == nil && previous.ID != player.ID {
+			previous.UserID = nil
+			if err := s.playerRepo.Update(ctx, previous); err != nil {
+				return err
+			}
+			released = previous
+		}
+		player.UserID = &userID
+		player.ClaimedBy, player.ClaimedAt = nil, nil
+		return s.playerRepo.Update(ctx, player)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return player, released, nil
+}
+
+// RejectClaim drops a player's pending claim; it also serves the claimant
+// withdrawing it.
+func (s *PlayerService) RejectClaim(ctx context.Context, groupID, playerID primitive.ObjectID) (*models.Player, error) {
+	player, err := s.GetPlayer(ctx, groupID, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if player.ClaimedBy == nil {
+		return nil, ErrNoClaim
+	}
+	player.ClaimedBy, player.ClaimedAt = nil, nil
+	if err := s.playerRepo.Update(ctx, player); err != nil {
+		return nil, err
+	}
+	return player, nil
+}
+
+// UnlinkPlayer turns a linked player back into a guest. Its matches and
+// stats stay with the player.
+func (s *PlayerService) UnlinkPlayer(ctx context.Context, groupID, playerID primitive.ObjectID) (*models.Player, error) {
+	player, err := s.GetPlayer(ctx, groupID, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if player.UserID == nil {
+		return nil, ErrPlayerUnlinked
+	}
+	player.UserID = nil
+	if err := s.playerRepo.Update(ctx, player); err != nil {
+		return nil, err
+	}
+	return player, nil
+}
+
+// releaseUser is the player side of a user leaving a group: their player
+// there becomes a guest and their pending claims are dropped.
+func (s *PlayerService) releaseUser(ctx context.Context, groupID, userID primitive.ObjectID) error {
+	players, err := s.playerRepo.FindByGroupID(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	for i := range players {
+		p := &players[i]
+		linked := p.UserID != nil && *p.UserID == userID
+		claimed := p.ClaimedBy != nil && *p.ClaimedBy == userID
+		if !linked && !claimed {
+			continue
+		}
+		if linked {
+			p.UserID = nil
+		}
+		if claimed {
+			p.ClaimedBy, p.ClaimedAt = nil, nil
+		}
+		if err := s.playerRepo.Update(ctx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AnonymousPlayerName replaces the name of a deleted user's player; a
+// number is added when the group already has one.
+const AnonymousPlayerName = "Former member"
+
+// forgetUser is the player side of deleting an account. The user's linked
+// players are renamed to AnonymousPlayerName, in their matches too, and
+// archived; their matches stay for everyone else. Pending claims by the
+// user in groupIDs are dropped, and the user is scrubbed from the timeline
+// and merge records (see scrubHistory). It returns the number of players
+// anonymised.
+func (s *PlayerService) forgetUser(ctx context.Context, userID primitive.ObjectID, groupIDs []primitive.ObjectID) (int, error) {
+	linked, err := s.playerRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	for i := range linked {
+		player := &linked[i]
+		if player.Name, err = s.anonymousName(ctx, player.GroupID, player.ID); err != nil {
+			return 0, err
+		}
+		player.Nickname, player.Hand, player.Position = "", "", ""
+		player.UserID, player.ClaimedBy, player.ClaimedAt = nil, nil, nil
+		if !player.Archived() {
+			player.ArchivedAt = &now
+		}
+		if err := s.playerRepo.Update(ctx, player); err != nil {
+			return 0, err
+		}
+		if _, err := s.renameInMatches(ctx, player, ""); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := s.scrubHistory(ctx, userID, linked); err != nil {
+		return 0, err
+	}
+
+	for _, groupID := range groupIDs {
+		players, err := s.playerRepo.FindByGroupID(ctx, groupID)
+		if err != nil {
+			return 0, err
+		}
+		for i := range players {
+			if p := &players[i]; p.ClaimedBy != nil && *p.ClaimedBy == userID {
+				p.ClaimedBy, p.ClaimedAt = nil, nil
+				if err := s.playerRepo.Update(ctx, p); err != nil {
+					return 0, err
+				}
+			}
+		}
+	}
+	return len(linked), nil
+}
+
+// scrubHistory removes a deleted user's names from what is kept of the past.
+// Events they made lose their actor. Wherever the timeline and merge records
+// name one of their players (created snapshots, renames, merges, and the
+// copies kept for undoing a merge) the anonymised name is used instead,
+// also for earlier players of theirs that were merged away.
+func (s *PlayerService) scrubHistory(ctx context.Context, userID primitive.ObjectID, players []models.Player) error {
+	groups := make(map[primitive.ObjectID]map[primitive.ObjectID]string)
+	for _, p := range players {
+		if groups[p.GroupID] == nil {
+			groups[p.GroupID] = make(map[primitive.ObjectID]string)
+		}
+		groups[p.GroupID][p.ID] = p.Name
+	}
+
+	for groupID, names := range groups {
+		records, err := s.mergeRepo.FindByGroupID(ctx, groupID)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if _, ok := names[record.SourceID]; !ok && record.Source.UserID != nil && *record.Source.UserID == userID {
+				names[record.SourceID] = AnonymousPlayerName
+			}
+		}
+		for i := range records {
+			if scrubMergeRecord(&records[i], userID, names) {
+				if err := s.mergeRepo.Replace(ctx, &records[i]); err != nil {
+					return err
+				}
+			}
+		}
+
+		events, err := s.eventRepo.FindByGroupID(ctx, groupID)
+		if err != nil {
+			return err
+		}
+		for i := range events {
+			if scrubMatchEvent(&events[i], names) {
+				if err := s.eventRepo.Replace(ctx, &events[i]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	acted, err := s.eventRepo.FindByActorID(ctx, userID.Hex())
+	if err != nil {
+		return err
+	}
+	for i := range acted {
+		acted[i].ActorID, acted[i].ActorName = "", ""
+		if err := s.eventRepo.Replace(ctx, &acted[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scrubMatchEvent renames the players in names wherever ev names them. It
+// reports whether ev referred to any of them.
+func scrubMatchEvent(ev *models.MatchEvent, names map[primitive.ObjectID]string) bool {
+	found := false
+	if ev.Snapshot != nil {
+		for id, name := range names {
+			if ev.Snapshot.ReplacePlayer(id, id, name) {
+				found = true
+			}
+		}
+	}
+	if ev.Merge != nil && scrubPlayerMerge(ev.Merge, names) {
+		found = true
+	}
+	return found
+}
+
+// scrubPlayerMerge renames either side of a merge or rename that is in
+// names. Empty names, as logged for anonymised players, stay empty.
+func scrubPlayerMerge(m *models.PlayerMerge, names map[primitive.ObjectID]string) bool {
+	found := false
+	if name, ok := names[m.SourceID]; ok {
+		if m.SourceName != "" {
+			m.SourceName = name
+		}
+		found = true
+	}
+	if name, ok := names[m.TargetID]; ok {
+		m.TargetName = name
+		found = true
+	}
+	return found
+}
+
+// scrubMergeRecord renames the players in names throughout record and
+// drops the user from it and from the player it would restore.
+func scrubMergeRecord(record *models.MergeRecord, userID primitive.ObjectID, names map[primitive.ObjectID]string) bool {
+	merge := models.PlayerMerge{SourceID: record.SourceID, TargetID: record.TargetID, SourceName: record.SourceName, TargetName: record.TargetName}
+	found := scrubPlayerMerge(&merge, names)
+	record.SourceName, record.TargetName = merge.SourceName, merge.TargetName
+
+	source := &record.Source
+	if name, ok := names[source.ID]; ok {
+		source.Name = name
+		source.Nickname, source.Hand, source.Position = "", "", ""
+		found = true
+	}
+	if source.UserID != nil && *source.UserID == userID {
+		source.UserID = nil
+		found = true
+	}
+	if source.ClaimedBy != nil && *source.ClaimedBy == userID {
+		source.ClaimedBy, source.ClaimedAt = nil, nil
+		found = true
+	}
+	if record.MergedBy == userID.Hex() {
+		record.MergedBy = ""
+		found = true
+	}
+	if record.UndoneBy == userID.Hex() {
+		record.UndoneBy = ""
+		found = true
+	}
+	for i := range record.Matches {
+		for id, name := range names {
+			if record.Matches[i].Before.ReplacePlayer(id, id, name) {
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+// anonymousName returns AnonymousPlayerName, numbered if the group already
+// has a player by that name.
+func (s *PlayerService) anonymousName(ctx context.Context, groupID, playerID primitive.ObjectID) (string, error) {
+	return s.freeName(ctx, groupID, playerID, AnonymousPlayerName)
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+)
+
+func TestClaimPlayer_ApprovedByCreator(t *testing.T) {
+	f := newMergeFixture(t)
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+
+	// The user's auto-created player from joining the group.
+	own, err := f.svc.CreatePlayerIfNotExists(ctx, userID, "amit", f.groupID)
+	require.NoError(t, err)
+
+	claimed, err := f.svc.ClaimPlayer(ctx, f.groupID, f.dup.ID, userID, false)
+	require.NoError(t, err)
+	assert.Nil(t, claimed.UserID, "waits for approval")
+	require.NotNil(t, claimed.ClaimedBy)
+	assert.Equal(t, userID, *claimed.ClaimedBy)
+
+	_, err = f.svc.ClaimPlayer(ctx, f.groupID, f.dup.ID, primitive.NewObjectID(), false)
+	assert.ErrorIs(t, err, ErrClaimPending)
+
+	linked, released, err := f.svc.ApproveClaim(ctx, f.groupID, f.dup.ID)
+	require.NoError(t, err)
+	assert.True(t, linked.LinkedTo(userID))
+	assert.Nil(t, linked.ClaimedBy)
+	require.NotNil(t, released)
+	assert.Equal(t, own.ID, released.ID)
+	assert.Nil(t, released.UserID)
+
+	got, err := f.players.FindByUserAndGroupID(ctx, userID, f.groupID)
+	require.NoError(t, err)
+	assert.Equal(t, f.dup.ID, got.ID)
+
+	_, err = f.svc.ClaimPlayer(ctx, f.groupID, f.dup.ID, primitive.NewObjectID(), false)
+	assert.ErrorIs(t, err, ErrPlayerLinked)
+}
+
+func TestClaimPlayer_CreatorLinksAtOnce(t *testing.T) {
+	f := newMergeFixture(t)
+	userID := primitive.NewObjectID()
+
+	player, err := f.svc.ClaimPlayer(context.Background(), f.groupID, f.alice.ID, userID, true)
+	require.NoError(t, err)
+	assert.True(t, player.LinkedTo(userID))
+	assert.Nil(t, player.ClaimedBy)
+}
+
+func TestClaimPlayer_OtherGroup_NotFound(t *testing.T) {
+	f := newMergeFixture(t)
+	_, err := f.svc.ClaimPlayer(context.Background(), primitive.NewObjectID(), f.alice.ID, primitive.NewObjectID(), false)
+	assert.ErrorIs(t, err, ErrPlayerNotFound)
+}
+
+func TestRejectClaimAndUnlink(t *testing.T) {
+	f := newMergeFixture(t)
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+
+	_, err := f.svc.RejectClaim(ctx, f.groupID, f.alice.ID)
+	assert.ErrorIs(t, err, ErrNoClaim)
+
+	_, err = f.svc.ClaimPlayer(ctx, f.groupID, f.alice.ID, userID, false)
+	require.NoError(t, err)
+	player, err := f.svc.RejectClaim(ctx, f.groupID, f.alice.ID)
+	require.NoError(t, err)
+	assert.Nil(t, player.ClaimedBy)
+	assert.Nil(t, player.UserID)
+
+	_, err = f.svc.ClaimPlayer(ctx, f.groupID, f.alice.ID, userID, true)
+	require.NoError(t, err)
+	player, err = f.svc.UnlinkPlayer(ctx, f.groupID, f.alice.ID)
+	require.NoError(t, err)
+	assert.Nil(t, player.UserID)
+	_, err = f.svc.UnlinkPlayer(ctx, f.groupID, f.alice.ID)
+	assert.ErrorIs(t, err, ErrPlayerUnlinked)
+}
+
+func TestMergePlayer_LinkedToDifferentUsers_Refused(t *testing.T) {
+	f := newMergeFixture(t)
+	ctx := context.Background()
+	_, err := f.svc.ClaimPlayer(ctx, f.groupID, f.alice.ID, primitive.NewObjectID(), true)
+	require.NoError(t, err)
+	_, err = f.svc.ClaimPlayer(ctx, f.groupID, f.dup.ID, primitive.NewObjectID(), true)
+	require.NoError(t, err)
+
+	_, err = f.svc.MergePlayer(ctx, f.groupID, f.alice.ID, f.dup.ID)
+	assert.ErrorIs(t, err, ErrMergeLinked)
+}
+
+func TestMergePlayer_MovesLinkAndUndoRestoresIt(t *testing.T) {
+	f := newMergeFixture(t)
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	_, err := f.svc.ClaimPlayer(ctx, f.groupID, f.dup.ID, userID, true)
+	require.NoError(t, err)
+
+	record, err := f.svc.MergePlayer(ctx, f.groupID, f.alice.ID, f.dup.ID)
+	require.NoError(t, err)
+	assert.True(t, record.LinkMoved)
+	target, err := f.players.FindByID(ctx, f.alice.ID)
+	require.NoError(t, err)
+	assert.True(t, target.LinkedTo(userID))
+
+	_, restored, err := f.svc.UndoMerge(ctx, f.groupID, record.ID)
+	require.NoError(t, err)
+	assert.True(t, restored.LinkedTo(userID))
+	target, err = f.players.FindByID(ctx, f.alice.ID)
+	require.NoError(t, err)
+	assert.Nil(t, target.UserID)
+}
+
+func TestUserStats_AcrossGroups(t *testing.T) {
+	f := newMergeFixture(t)
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	_, err := f.svc.ClaimPlayer(ctx, f.groupID, f.alice.ID, userID, true)
+	require.NoError(t, err)
+	f.finishedMatch(t, f.alice, f.charlie)
+
+	// A second group where the user lost their only match.
+	otherGroup := primitive.NewObjectID()
+	other := &mergeFixture{players: f.players, matches: f.matches, events: f.events, groupID: otherGroup}
+	me := models.Player{Name: "Al", GroupID: otherGroup, UserID: &userID}
+	rival := models.Player{Name: "Zed", GroupID: otherGroup}
+	require.NoError(t, f.players.Create(ctx, &me))
+	require.NoError(t, f.players.Create(ctx, &rival))
+	other.finishedMatch(t, rival, me)
+
+	stats, err := NewStatsService(f.matches, f.players).UserStats(ctx, userID, "amit")
+	require.NoError(t, err)
+
+	require.Len(t, stats.Groups, 2)
+	byGroup := map[primitive.ObjectID]PlayerStats{}
+	for _, g := range stats.Groups {
+		byGroup[g.GroupID] = g.Stats
+	}
+	assert.Equal(t, 1, byGroup[f.groupID].Won)
+	assert.Equal(t, 1, byGroup[otherGroup].Lost)
+
+	assert.Equal(t, userID, stats.Overall.PlayerID)
+	assert.Equal(t, "amit", stats.Overall.Name)
+	assert.Equal(t, 2, stats.Overall.Played)
+	assert.Equal(t, 1, stats.Overall.Won)
+	assert.Equal(t, 1, stats.Overall.Lost)
+	assert.Equal(t, 1, stats.Overall.PointsScored)
+}
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+// MergeUndoWindow is how long after a merge it can still be undone.
+const MergeUndoWindow = 24 * time.Hour
+
+var (
+	// ErrMergeConflict means both players took part in the same match, on
+	// opposite sides or as partners; merging would put one player in a match
+	// twice.
+	ErrMergeConflict = errors.New("both players appear in the same match")
+	// ErrMergeLinked means the players are linked to different users.
+	ErrMergeLinked = errors.New("players are linked to different users")
+
+	ErrMergeNotFound    = errors.New("merge not found")
+	ErrMergeUndone      = errors.New("merge has already been undone")
+	ErrMergeUndoExpired = errors.New("merge can no longer be undone")
+	// ErrMergeStale means a merged match has changed (or been deleted) since
+	// the merge, so restoring it would lose that change.
+	ErrMergeStale = errors.New("matches changed since the merge, it can no longer be undone")
+)
+
+// MergeMatch summarises a match in a merge preview.
+type MergeMatch struct {
+	ID         primitive.ObjectID `json:"id"`
+	Status     string             `json:"status"`
+	Team1Names []string           `json:"team1_names"`
+	Team2Names []string           `json:"team2_names"`
+	Score1     int                `json:"score1"`
+	Score2     int                `json:"score2"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+// MergePreview is what a merge would do, without doing it.
+type MergePreview struct {
+	Source models.Player `json:"source"`
+	Target models.Player `json:"target"`
+
+	Matches   []MergeMatch `json:"matches"`   // rewritten from source to target
+	Conflicts []MergeMatch `json:"conflicts"` // both players took part; the merge is refused while any exist
+
+	SourceStats PlayerStats `json:"source_stats"`
+	TargetStats PlayerStats `json:"target_stats"`
+	MergedStats PlayerStats `json:"merged_stats"` // the target's stats after the merge
+}
+
+// mergePlan is the state a merge is checked and carried out against.
+type mergePlan struct {
+	source, target *models.Player
+	all            []models.Match // every match in the group
+	matches        []models.Match // referencing the source only
+	conflicts      []models.Match // referencing both players
+}
+
+func (s *PlayerService) planMerge(ctx context.Context, groupID, targetPlayerID, sourcePlayerID primitive.ObjectID) (*mergePlan, error) {
+	if targetPlayerID == sourcePlayerID {
+		return nil, errors.New("cannot merge player with itself")
+	}
+	target, err := s.playerRepo.FindByID(ctx, targetPlayerID)
+	if err != nil {
+		return nil, errors.New("target player not found")
+	}
+	source, err := s.playerRepo.FindByID(ctx, sourcePlayerID)
+	if err != nil {
+		return nil, errors.New("source player not found")
+	}
+	if target.GroupID != groupID || source.GroupID != groupID {
+		return nil, errors.New("players must belong to the group")
+	}
+	if source.UserID != nil && target.UserID != nil && *source.UserID != *target.UserID {
+		return nil, ErrMergeLinked
+	}
+
+	all, err := s.matchRepo.FindByGroupID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	plan := &mergePlan{source: source, target: target, all: all}
+	for _, m := range all {
+		if !hasPlayer(&m, sourcePlayerID) {
+			continue
+		}
+		if hasPlayer(&m, targetPlayerID) {
+			plan.conflicts = append(plan.conflicts, m)
+		} else {
+			plan.matches = append(plan.matches, m)
+		}
+	}
+	return plan, nil
+}
+
+// PreviewMerge reports the matches a merge would rewrite, any that block it,
+// and how the target's stats would change.
+func (s *PlayerService) PreviewMerge(ctx context.Context, groupID, targetPlayerID, sourcePlayerID primitive.ObjectID) (*MergePreview, error) {
+	plan, err := s.planMerge(ctx, groupID, targetPlayerID, sourcePlayerID)
+	if err != nil {
+		return nil, err
+	}
+
+	before := computePlayerStats([]models.Player{*plan.source, *plan.target}, plan.all)
+	merged := make([]models.Match, len(plan.all))
+	for i := range plan.all {
+		merged[i] = copyMatch(&plan.all[i])
+		if !hasPlayer(&merged[i], targetPlayerID) {
+			merged[i].ReplacePlayer(sourcePlayerID, targetPlayerID, plan.target.Name)
+		}
+	}
+	after := computePlayerStats([]models.Player{*plan.target}, merged)
+
+	return &MergePreview{
+		Source:      *plan.source,
+		Target:      *plan.target,
+		Matches:     summarizeMatches(plan.matches),
+		Conflicts:   summarizeMatches(plan.conflicts),
+		SourceStats: before[indexOfStats(before, sourcePlayerID)],
+		TargetStats: before[indexOfStats(before, targetPlayerID)],
+		MergedStats: after[0],
+	}, nil
+}
+
+// MergePlayer merges sourcePlayer into targetPlayer in one transaction:
+// every match reference to the source is rewritten to the target, the source
+// player is deleted, and a merge record is kept so the merge can be undone
+// within MergeUndoWindow.
+func (s *PlayerService) MergePlayer(ctx context.Context, groupID, targetPlayerID, sourcePlayerID primitive.ObjectID) (*models.MergeRecord, error) {
+	var record *models.MergeRecord
+	err := s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		plan, err := s.planMerge(ctx, groupID, targetPlayerID, sourcePlayerID)
+		if err != nil {
+			return err
+		}
+		if len(plan.conflicts) > 0 {
+			return fmt.Errorf("%w (%d matches)", ErrMergeConflict, len(plan.conflicts))
+		}
+
+		updated, err := s.matchRepo.ReplacePlayerInMatches(ctx, groupID, sourcePlayerID, targetPlayerID, plan.source.Name, plan.target.Name)
+		if err != nil {
+			return err
+		}
+		// A match written since planMerge read it would not be restored
+		// correctly on undo.
+		if len(updated) != len(plan.matches) {
+			return ErrMatchConflict
+		}
+		before := make(map[primitive.ObjectID]models.Match, len(plan.matches))
+		for _, m := range plan.matches {
+			before[m.ID] = m
+		}
+
+		now := time.Now()
+		record = &models.MergeRecord{
+			GroupID:    groupID,
+			SourceID:   sourcePlayerID,
+			TargetID:   targetPlayerID,
+			SourceName: plan.source.Name,
+			TargetName: plan.target.Name,
+			Source:     *plan.source,
+			Matches:    make([]models.MergedMatch, 0, len(updated)),
+			MergedBy:   ActorFrom(ctx).UserID,
+			MergedAt:   now,
+			UndoUntil:  now.Add(MergeUndoWindow),
+		}
+		merge := &models.PlayerMerge{
+			SourceID:   sourcePlayerID,
+			TargetID:   targetPlayerID,
+			SourceName: plan.source.Name,
+			TargetName: plan.target.Name,
+		}
+		for i := range updated {
+			prev, ok := before[updated[i].ID]
+			if !ok || updated[i].Version != prev.Version+1 {
+				return ErrMatchConflict
+			}
+			record.Matches = append(record.Matches, models.MergedMatch{MatchID: prev.ID, Before: prev, Version: updated[i].Version})
+
+			state := updated[i].State()
+			if err := appendMatchEvents(ctx, s.eventRepo, &updated[i], []models.MatchEvent{{
+				Type:   models.MatchEventPlayerMerge,
+				Before: &state,
+				After:  &state,
+				Merge:  merge,
+			}}); err != nil {
+				return err
+			}
+		}
+
+		if err := s.playerRepo.Delete(ctx, sourcePlayerID); err != nil {
+			return err
+		}
+		// A linked source hands its user over to a guest target.
+		if plan.source.UserID != nil && plan.target.UserID == nil {
+			plan.target.UserID = plan.source.UserID
+			if err := s.playerRepo.Update(ctx, plan.target); err != nil {
+				return err
+			}
+			record.LinkMoved = true
+		}
+		return s.mergeRepo.Create(ctx, record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// GetMerges returns a group's merges, newest first.
+func (s *PlayerService) GetMerges(ctx context.Context, groupID primitive.ObjectID) ([]models.MergeRecord, error) {
+	return s.mergeRepo.FindByGroupID(ctx, groupID)
+}
+
+// UndoMerge re-creates the merged-away player and puts the merged matches
+// back as they were. The player comes back under a new ID, which the restored
+// matches use. Undo is refused once the window has passed or if any of the
+// matches has changed since the merge.
+func (s *PlayerService) UndoMerge(ctx context.Context, groupID, mergeID primitive.ObjectID) (*models.MergeRecord, *models.Player, error) {
+	var record *models.MergeRecord
+	var restored models.Player
+	err := s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		record, err = s.mergeRepo.FindByID(ctx, mergeID)
+		if err != nil || record.GroupID != groupID {
+			return ErrMergeNotFound
+		}
+		if record.UndoneAt != nil {
+			return ErrMergeUndone
+		}
+		now := time.Now()
+		if now.After(record.UndoUntil) {
+			return ErrMergeUndoExpired
+		}
+
+		current := make([]*models.Match, len(record.Matches))
+		for i, mm := range record.Matches {
+			m, err := s.matchRepo.FindByID(ctx, mm.MatchID)
+			if err != nil || m.Version != mm.Version {
+				return ErrMergeStale
+			}
+			current[i] = m
+		}
+
+		if record.LinkMoved {
+			if target, err := s.playerRepo.FindByID(ctx, record.TargetID); err == nil && target.LinkedTo(*record.Source.UserID) {
+				target.UserID = nil
+				if err := s.playerRepo.Update(ctx, target); err != nil {
+					return err
+				}
+			}
+		}
+		restored = record.Source
+		restored.ID = primitive.NilObjectID
+		if err := s.playerRepo.Create(ctx, &restored); err != nil {
+			return err
+		}
+
+		merge := &models.PlayerMerge{
+			SourceID:   record.SourceID,
+			TargetID:   record.TargetID,
+			SourceName: record.SourceName,
+			TargetName: record.TargetName,
+		}
+		for i, mm := range record.Matches {
+			m := copyMatch(&mm.Before)
+			m.ReplacePlayer(record.SourceID, restored.ID, restored.Name)
+			m.Version = current[i].Version
+			if err := s.matchRepo.Update(ctx, &m); err != nil {
+				if errors.Is(err, repositories.ErrVersionConflict) {
+					return ErrMergeStale
+				}
+				return err
+			}
+
+			before, after := current[i].State(), m.State()
+			snapshot := copyMatch(&m)
+			if err := appendMatchEvents(ctx, s.eventRepo, &m, []models.MatchEvent{{
+				Type:     models.MatchEventPlayerUnmerge,
+				Before:   &before,
+				After:    &after,
+				Merge:    merge,
+				Snapshot: &snapshot,
+			}}); err != nil {
+				return err
+			}
+		}
+
+		record.UndoneAt = &now
+		record.UndoneBy = ActorFrom(ctx).UserID
+		record.RestoredID = restored.ID
+		if err := s.mergeRepo.MarkUndone(ctx, record); err != nil {
+			if errors.Is(err, repositories.ErrVersionConflict) {
+				return ErrMergeUndone
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return record, &restored, nil
+}
+
+func hasPlayer(m *models.Match, playerID primitive.ObjectID) bool {
+	for _, id := range m.Team1IDs {
+		if id == playerID {
+			return true
+		}
+	}
+	for _, id := range m.Team2IDs {
+		if id == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// copyMatch copies m along with the slices ReplacePlayer rewrites.
+func copyMatch(m *models.Match) models.Match {
+	out := *m
+	out.Team1IDs = append(m.Team1IDs[:0:0], m.Team1IDs...)
+	out.Team2IDs = append(m.Team2IDs[:0:0], m.Team2IDs...)
+	out.Team1Names = append(m.Team1Names[:0:0], m.Team1Names...)
+	out.Team2Names = append(m.Team2Names[:0:0], m.Team2Names...)
+	out.Team1Positions = append(m.Team1Positions[:0:0], m.Team1Positions...)
+	out.Team2Positions = append(m.Team2Positions[:0:0], m.Team2Positions...)
+	out.ScoreHistory = append(m.ScoreHistory[:0:0], m.ScoreHistory...)
+	out.Pauses = append(m.Pauses[:0:0], m.Pauses...)
+	return out
+}
+
+func summarizeMatches(matches []models.Match) []MergeMatch {
+	out := make([]MergeMatch, len(matches))
+	for i, m := range matches {
+		out[i] = MergeMatch{
+			ID:         m.ID,
+			Status:     m.Status,
+			Team1Names: m.Team1Names,
+			Team2Names: m.Team2Names,
+			Score1:     m.Score1,
+			Score2:     m.Score2,
+			CreatedAt:  m.CreatedAt,
+		}
+	}
+	return out
+}
+
+func indexOfStats(stats []PlayerStats, playerID primitive.ObjectID) int {
+	for i := range stats {
+		if stats[i].PlayerID == playerID {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories/memory"
+)
+
+type mergeFixture struct {
+	svc     *PlayerService
+	players *memory.PlayerRepo
+	matches *memory.MatchRepo
+	events  *memory.MatchEventRepo
+	merges  *memory.MergeRecordRepo
+
+	groupID             primitive.ObjectID
+	alice, dup, charlie models.Player
+}
+
+func newMergeFixture(t *testing.T) *mergeFixture {
+	f := &mergeFixture{
+		players: memory.NewPlayerRepo(),
+		matches: memory.NewMatchRepo(),
+		events:  memory.NewMatchEventRepo(),
+		merges:  memory.NewMergeRecordRepo(),
+		groupID: primitive.NewObjectID(),
+	}
+	f.svc = NewPlayerService(f.players, f.matches, f.events, f.merges, memory.NewTransactor())
+	ctx := context.Background()
+	for _, p := range []*models.Player{&f.alice, &f.dup, &f.charlie} {
+		p.GroupID = f.groupID
+	}
+	f.alice.Name, f.dup.Name, f.charlie.Name = "Alice", "alice", "Charlie"
+	require.NoError(t, f.players.Create(ctx, &f.alice))
+	require.NoError(t, f.players.Create(ctx, &f.dup))
+	require.NoError(t, f.players.Create(ctx, &f.charlie))
+	return f
+}
+
+// finishedMatch stores a 21-15 win for team 1, with its created event.
+func (f *mergeFixture) finishedMatch(t *testing.T, team1, team2 models.Player) *models.Match {
+	ctx := context.Background()
+	match := makeLiveMatch([]primitive.ObjectID{team1.ID}, []primitive.ObjectID{team2.ID})
+	match.GroupID = f.groupID
+	match.Team1Names, match.Team2Names = []string{team1.Name}, []string{team2.Name}
+	match.Score1, match.Score2 = 21, 15
+	match.ScoreHistory = []models.ScoreEvent{{Team: 1, PlayerID: team1.ID.Hex()}}
+	match.Status = models.MatchStatusFinished
+	require.NoError(t, f.matches.Create(ctx, match))
+	require.NoError(t, NewMatchService(f.matches, f.players, f.events, nil, nil).recordCreated(ctx, match))
+	return match
+}
+
+func TestPreviewMerge_ReportsMatchesAndStats(t *testing.T) {
+	f := newMergeFixture(t)
+	ctx := context.Background()
+	f.finishedMatch(t, f.alice, f.charlie)
+	dupMatch := f.finishedMatch(t, f.dup, f.charlie)
+
+	preview, err := f.svc.PreviewMerge(ctx, f.groupID, f.alice.ID, f.dup.ID)
+	require.NoError(t, err)
+
+	require.Len(t, preview.Matches, 1)
+	assert.Equal(t, dupMatch.ID, preview.Matches[0].ID)
+	assert.Empty(t, preview.Conflicts)
+	assert.Equal(t, 1, preview.SourceStats.Played)
+	assert.Equal(t, 1, preview.TargetStats.Played)
+	assert.Equal(t, 2, preview.MergedStats.Played)
+	assert.Equal(t, 2, preview.MergedStats.Won)
+
+	// Nothing was written.
+	_, err = f.players.FindByID(ctx, f.dup.ID)
+	assert.NoError(t, err)
+	stored, err := f.matches.FindByID(ctx, dupMatch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{f.dup.ID}, stored.Team1IDs)
+}
+
+func TestMergePlayer_OpponentsInSameMatch_Refused(t *testing.T) {
+	f := newMergeFixture(t)
+	ctx := context.Background()
+	both := f.finishedMatch(t, f.alice, f.dup)
+	f.finishedMatch(t, f.dup, f.charlie)
+
+	preview, err := f.svc.PreviewMerge(ctx, f.groupID, f.alice.ID, f.dup.ID)
+	require.NoError(t, err)
+	require.Len(t, preview.Conflicts, 1)
+	assert.Equal(t, both.ID, preview.Conflicts[0].ID)
+	assert.Len(t, preview.Matches, 1)
+
+	_, err = f.svc.MergePlayer(ctx, f.groupID, f.alice.ID, f.dup.ID)
+	assert.ErrorIs(t, err, ErrMergeConflict)
+
+	_, err = f.players.FindByID(ctx, f.dup.ID)
+	assert.NoError(t, err, "source must survive a refused merge")
+	merges, err := f.merges.FindByGroupID(ctx, f.groupID)
+	require.NoError(t, err)
+	assert.Empty(t, merges)
+}
+
+func TestMergePlayer_OtherGroup_Fails(t *testing.T) {
+	f := newMergeFixture(t)
+	_, err := f.svc.MergePlayer(context.Background(), primitive.NewObjectID(), f.alice.ID, f.dup.ID)
+	assert.EqualError(t, err, "players must belong to the group")
+}
+
+func TestMergePlayer_RecordsMerge(t *testing.T) {
+	f := newMergeFixture(t)
+	ctx := WithActor(context.Background(), Actor{UserID: "u1"})
+	match := f.finishedMatch(t, f.dup, f.charlie)
+
+	record, err := f.svc.MergePlayer(ctx, f.groupID, f.alice.ID, f.dup.ID)
+	require.NoError(t, err)
+
+	assert.False(t, record.ID.IsZero())
+	assert.Equal(t, f.dup.ID, record.Source.ID)
+	assert.Equal(t, "u1", record.MergedBy)
+	assert.WithinDuration(t, time.Now().Add(MergeUndoWindow), record.UndoUntil, time.Minute)
+	require.Len(t, record.Matches, 1)
+	assert.Equal(t, match.ID, record.Matches[0].MatchID)
+	assert.Equal(t, []primitive.ObjectID{f.dup.ID}, record.Matches[0].Before.Team1IDs)
+	assert.Equal(t, 2, record.Matches[0].Version)
+
+	stored, err := f.matches.FindByID(ctx, match.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{f.alice.ID}, stored.Team1IDs)
+	assert.Equal(t, []string{"Alice"}, stored.Team1Names)
+	assert.Equal(t, f.alice.ID.Hex(), stored.ScoreHistory[0].PlayerID)
+	_, err = f.players.FindByID(ctx, f.dup.ID)
+	assert.Error(t, err)
+
+	merges, err := f.merges.FindByGroupID(ctx, f.groupID)
+	require.NoError(t, err)
+	require.Len(t, merges, 1)
+	assert.Equal(t, record.ID, merges[0].ID)
+}
+
+func TestUndoMerge_RestoresPlayerAndMatches(t *testing.T) {
+	f := newMergeFixture(t)
+	ctx := WithActor(context.Background(), Actor{UserID: "u1"})
+	match := f.finishedMatch(t, f.dup, f.charlie)
+	record, err := f.svc.MergePlayer(ctx, f.groupID, f.alice.ID, f.dup.ID)
+	require.NoError(t, err)
+
+	undone, player, err := f.svc.UndoMerge(ctx, f.groupID, record.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, "alice", player.Name)
+	assert.Equal(t, f.groupID, player.GroupID)
+	assert.NotEqual(t, f.dup.ID, player.ID)
+	require.NotNil(t, undone.UndoneAt)
+	assert.Equal(t, player.ID, undone.RestoredID)
+
+	stored, err := f.matches.FindByID(ctx, match.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{player.ID}, stored.Team1IDs)
+	assert.Equal(t, []string{"alice"}, stored.Team1Names)
+	assert.Equal(t, player.ID.Hex(), stored.ScoreHistory[0].PlayerID)
+	assert.Equal(t, player.ID.Hex(), stored.ServingPlayerID)
+	assert.Equal(t, 3, stored.Version)
+	assert.Equal(t, 21, stored.Score1)
+
+	// The timeline still replays to the stored match.
+	events, err := f.events.FindByMatchID(ctx, match.ID)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, models.MatchEventPlayerUnmerge, events[2].Type)
+	replayed, _, err := ReplayMatch(events)
+	require.NoError(t, err)
+	assert.Equal(t, stored.Team1IDs, replayed.Team1IDs)
+	assert.Equal(t, stored.ScoreHistory, replayed.ScoreHistory)
+	assert.Equal(t, stored.Version, replayed.Version)
+
+	_, _, err = f.svc.UndoMerge(ctx, f.groupID, record.ID)
+	assert.ErrorIs(t, err, ErrMergeUndone)
+}
+
+func TestUndoMerge_MatchChangedSinceMerge_Refused(t *testing.T) {
+	f := newMergeFixture(t)
+	ctx := context.Background()
+	match := f.finishedMatch(t, f.dup, f.charlie)
+	record, err := f.svc.MergePlayer(ctx, f.groupID, f.alice.ID, f.dup.ID)
+	require.NoError(t, err)
+
+	stored, err := f.matches.FindByID(ctx, match.ID)
+	require.NoError(t, err)
+	stored.Score2 = 19
+	require.NoError(t, f.matches.Update(ctx, stored))
+
+	_, _, err = f.svc.UndoMerge(ctx, f.groupID, record.ID)
+	assert.ErrorIs(t, err, ErrMergeStale)
+	players, err := f.players.FindByGroupID(ctx, f.groupID)
+	require.NoError(t, err)
+	assert.Len(t, players, 2, "the source must not be re-created")
+}
+
+func TestUndoMerge_AfterWindow_Refused(t *testing.T) {
+	f := newMergeFixture(t)
+	ctx := context.Background()
+	record := &models.MergeRecord{
+		GroupID:   f.groupID,
+		MergedAt:  time.Now().Add(-MergeUndoWindow - time.Hour),
+		UndoUntil: time.Now().Add(-time.Hour),
+	}
+	require.NoError(t, f.merges.Create(ctx, record))
+
+	_, _, err := f.svc.UndoMerge(ctx, f.groupID, record.ID)
+	assert.ErrorIs(t, err, ErrMergeUndoExpired)
+
+	_, _, err = f.svc.UndoMerge(ctx, primitive.NewObjectID(), record.ID)
+	assert.ErrorIs(t, err, ErrMergeNotFound)
+}
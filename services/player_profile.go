@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+// MaxPlayerNameLen caps player names and nicknames, in characters.
+const MaxPlayerNameLen = 40
+
+var (
+	ErrPlayerNameTaken = errors.New("a player with this name already exists in the group")
+	ErrInvalidPlayer   = errors.New("invalid player")
+	ErrPlayerArchived  = errors.New("player is archived")
+	ErrPlayerActive    = errors.New("player is not archived")
+)
+
+// PlayerUpdate is a partial edit of a player; nil fields are left alone and
+// empty strings clear the optional ones.
+type PlayerUpdate struct {
+	Name     *string
+	Nickname *string
+	Hand     *string
+	Position *string
+
+	// RenameMatches also rewrites the player's name in the matches they
+	// played, which otherwise keep the name they had at the time.
+	RenameMatches bool
+}
+
+// UpdatePlayer applies an edit and returns the player with the number of
+// matches renamed.
+func (s *PlayerService) UpdatePlayer(ctx context.Context, groupID, playerID primitive.ObjectID, upd PlayerUpdate) (*models.Player, int, error) {
+	var player *models.Player
+	renamed := 0
+	err := s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		player, err = s.GetPlayer(ctx, groupID, playerID)
+		if err != nil {
+			return err
+		}
+		oldName := player.Name
+		if upd.Name != nil {
+			name, err := cleanPlayerName(*upd.Name)
+			if err != nil {
+				return err
+			}
+			if err := s.checkNameFree(ctx, groupID, playerID, name); err != nil {
+				return err
+			}
+			player.Name = name
+		}
+		if upd.Nickname != nil {
+			nickname := strings.Join(strings.Fields(*upd.Nickname), " ")
+			if utf8.RuneCountInString(nickname) > MaxPlayerNameLen {
+				return fmt.Errorf("%w: nickname is longer than %d characters", ErrInvalidPlayer, MaxPlayerNameLen)
+			}
+			player.Nickname = nickname
+		}
+		if upd.Hand != nil {
+			if h := *upd.Hand; h != "" && h != models.HandLeft && h != models.HandRight {
+				return fmt.Errorf("%w: hand must be %q or %q", ErrInvalidPlayer, models.HandLeft, models.HandRight)
+			}
+			player.Hand = *upd.Hand
+		}
+		if upd.Position != nil {
+			if p := *upd.Position; p != "" && p != models.PositionFront && p != models.PositionBack {
+				return fmt.Errorf("%w: position must be %q or %q", ErrInvalidPlayer, models.PositionFront, models.PositionBack)
+			}
+			player.Position = *upd.Position
+		}
+		if err := s.playerRepo.Update(ctx, player); err != nil {
+			return err
+		}
+
+		if !upd.RenameMatches || player.Name == oldName {
+			return nil
+		}
+		renamed, err = s.renameInMatches(ctx, player, oldName)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return player, renamed, nil
+}
+
+// renameInMatches writes the player's current name into their matches and
+// records a rename event on each. The event shows loggedName as the old
+// name, which may be blank to keep it out of the timeline. Each match is
+// renamed on its own, so one being scored meanwhile is retried rather
+// than failing the rename.
+func (s *PlayerService) renameInMatches(ctx context.Context, player *models.Player, loggedName string) (int, error) {
+	var matchIDs []primitive.ObjectID
+	q := repositories.MatchQuery{GroupID: player.GroupID, PlayerID: player.ID, Ascending: true, Limit: repositories.MaxMatchPageSize}
+	for {
+		page, err := s.matchRepo.FindPage(ctx, q)
+		if err != nil {
+			return 0, err
+		}
+		for _, m := range page.Matches {
+			matchIDs = append(matchIDs, m.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+
+	rename := &models.PlayerMerge{SourceID: player.ID, TargetID: player.ID, SourceName: loggedName, TargetName: player.Name}
+	renamed := 0
+	for _, id := range matchIDs {
+		match, err := s.renameInMatch(ctx, id, player)
+		if err != nil {
+			return 0, err
+		}
+		if match == nil {
+			continue
+		}
+		state := match.State()
+		if err := appendMatchEvents(ctx, s.eventRepo, match, []models.MatchEvent{{
+			Type:   models.MatchEventPlayerRename,
+			Before: &state,
+			After:  &state,
+			Merge:  rename,
+		}}); err != nil {
+			return 0, err
+		}
+		renamed++
+	}
+	return renamed, nil
+}
+
+// renameInMatch writes the player's name into one match, retrying on fresh
+// data if the match changes underneath it. It returns nil if the match has
+// gone or no longer has the player.
+func (s *PlayerService) renameInMatch(ctx context.Context, matchID primitive.ObjectID, player *models.Player) (*models.Match, error) {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		if attempt > 0 {
+			if err := backoff(ctx, attempt); err != nil {
+				return nil, err
+			}
+		}
+		match, err := s.matchRepo.FindByID(ctx, matchID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Replacing the player with itself rewrites only the names.
+		if !match.ReplacePlayer(player.ID, player.ID, player.Name) {
+			return nil, nil
+		}
+		err = s.matchRepo.Update(ctx, match)
+		if err == nil {
+			return match, nil
+		}
+		if !errors.Is(err, repositories.ErrVersionConflict) {
+			return nil, err
+		}
+	}
+	return nil, ErrMatchConflict
+}
+
+// ArchivePlayer hides a player from the group's pickers. Their matches,
+// names in them and stats are kept.
+func (s *PlayerService) ArchivePlayer(ctx context.Context, groupID, playerID primitive.ObjectID) (*models.Player, error) {
+	player, err := s.GetPlayer(ctx, groupID, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if player.Archived() {
+		return nil, ErrPlayerArchived
+	}
+	now := time.Now()
+	player.ArchivedAt = &now
+	if err := s.playerRepo.Update(ctx, player); err != nil {
+		return nil, err
+	}
+	return player, nil
+}
+
+// RestorePlayer brings an archived player back.
+func (s *PlayerService) RestorePlayer(ctx context.Context, groupID, playerID primitive.ObjectID) (*models.Player, error) {
+	player, err := s.GetPlayer(ctx, groupID, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if !player.Archived() {
+		return nil, ErrPlayerActive
+	}
+	player.ArchivedAt = nil
+	if err := s.playerRepo.Update(ctx, player); err != nil {
+		return nil, err
+	}
+	return player, nil
+}
+
+// cleanPlayerName collapses runs of whitespace and checks the length.
+func cleanPlayerName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", fmt.Errorf("%w: name is required", ErrInvalidPlayer)
+	}
+	if utf8.RuneCountInString(name) > MaxPlayerNameLen {
+		return "", fmt.Errorf("%w: name is longer than %d characters", ErrInvalidPlayer, MaxPlayerNameLen)
+	}
+	return name, nil
+}
+
+// checkNameFree fails if another player of the group, archived or not, has
+// the name in any letter case.
+func (s *PlayerService) checkNameFree(ctx context.Context, groupID, playerID primitive.ObjectID, name string) error {
+	players, err := s.playerRepo.FindByGroupID(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	for _, p := range players {
+		if p.ID != playerID && strings.EqualFold(p.Name, name) {
+			return ErrPlayerNameTaken
+		}
+	}
+	return nil
+}
+
+// freeName returns name, or if another player of the group has it the
+// first of "name 2", "name 3" and so on that is free. The name is cut short
+// where needed to leave room for the number.
+func (s *PlayerService) freeName(ctx context.Context, groupID, playerID primitive.ObjectID, name string) (string, error) {
+	players, err := s.playerRepo.FindByGroupID(ctx, groupID)
+	if err != nil {
+		return "", err
+	}
+	taken := func(candidate string) bool {
+		for _, p := range players {
+			if p.ID != playerID && strings.EqualFold(p.Name, candidate) {
+				return true
+			}
+		}
+		return false
+	}
+	candidate := name
+	for n := 2; taken(candidate); n++ {
+		suffix := fmt.Sprintf(" %d", n)
+		base := []rune(name)
+		if room := MaxPlayerNameLen - len(suffix); len(base) > room {
+			base = base[:room]
+		}
+		candidate = string(base) + suffix
+	}
+	return candidate, nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories/memory"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestUpdatePlayer_Profile(t *testing.T) {
+	f := newMergeFixture(t)
+	ctx := context.Background()
+
+	player, renamed, err := f.svc.UpdatePlayer(ctx, f.groupID, f.charlie.ID, PlayerUpdate{
+		Nickname: strPtr(" Chaz "),
+		Hand:     strPtr(models.HandLeft),
+		Position: strPtr(models.PositionBack),
+	})
+	require.NoError(t, err)
+	assert.Zero(t, renamed)
+
+	stored, err := f.players.FindByID(ctx, player.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Charlie", stored.Name)
+	assert.Equal(t, "Chaz", stored.Nickname)
+	assert.Equal(t, models.HandLeft, stored.Hand)
+	assert.Equal(t, models.PositionBack, stored.Position)
+
+	_, _, err = f.svc.UpdatePlayer(ctx, f.groupID, f.charlie.ID, PlayerUpdate{Hand: strPtr("both")})
+	assert.ErrorIs(t, err, ErrInvalidPlayer)
+	_, _, err = f.svc.UpdatePlayer(ctx, f.groupID, f.charlie.ID, PlayerUpdate{Name: strPtr("ALICE")})
+	assert.ErrorIs(t, err, ErrPlayerNameTaken)
+	_, _, err = f.svc.UpdatePlayer(ctx, primitive.NewObjectID(), f.charlie.ID, PlayerUpdate{})
+	assert.ErrorIs(t, err, ErrPlayerNotFound)
+}
+
+func TestUpdatePlayer_RenameMatches(t *testing.T) {
+	f := newMergeFixture(t)
+	ctx := context.Background()
+	match := f.finishedMatch(t, f.alice, f.charlie)
+
+	// Without rename_matches the match keeps the old name.
+	_, renamed, err := f.svc.UpdatePlayer(ctx, f.groupID, f.charlie.ID, PlayerUpdate{Name: strPtr("Charles")})
+	require.NoError(t, err)
+	assert.Zero(t, renamed)
+	stored, err := f.matches.FindByID(ctx, match.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Charlie"}, stored.Team2Names)
+
+	_, renamed, err = f.svc.UpdatePlayer(ctx, f.groupID, f.charlie.ID, PlayerUpdate{Name: strPtr("Charlie B"), RenameMatches: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, renamed)
+	stored, err = f.matches.FindByID(ctx, match.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Charlie B"}, stored.Team2Names)
+	assert.Equal(t, []primitive.ObjectID{f.charlie.ID}, stored.Team2IDs)
+	assert.Equal(t, 2, stored.Version)
+
+	events, err := f.events.FindByMatchID(ctx, match.ID)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.MatchEventPlayerRename, events[1].Type)
+	replayed, _, err := ReplayMatch(events)
+	require.NoError(t, err)
+	assert.Equal(t, stored.Team2Names, replayed.Team2Names)
+}
+
+// scoredMeanwhile is a match store where someone scores a point just before
+// the first update lands, so that update conflicts.
+type scoredMeanwhile struct {
+	*memory.MatchRepo
+	scored bool
+}
+
+func (r *scoredMeanwhile) Update(ctx context.Context, match *models.Match) error {
+	if !r.scored {
+		r.scored = true
+		other, err := r.MatchRepo.FindByID(ctx, match.ID)
+		if err != nil {
+			return err
+		}
+		other.Score1++
+		if err := r.MatchRepo.Update(ctx, other); err != nil {
+			return err
+		}
+	}
+	return r.MatchRepo.Update(ctx, match)
+}
+
+func TestUpdatePlayer_RenameMatches_RetriesConflict(t *testing.T) {
+	f := newMergeFixture(t)
+	ctx := context.Background()
+	match := f.finishedMatch(t, f.alice, f.charlie)
+	matches := &scoredMeanwhile{MatchRepo: f.matches}
+	svc := NewPlayerService(f.players, matches, f.events, f.merges, memory.NewTransactor())
+
+	_, renamed, err := svc.UpdatePlayer(ctx, f.groupID, f.charlie.ID, PlayerUpdate{Name: strPtr("Charles"), RenameMatches: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, renamed)
+	stored, err := f.matches.FindByID(ctx, match.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Charles"}, stored.Team2Names)
+	assert.Equal(t, 22, stored.Score1, "the point scored meanwhile is kept")
+	assert.Equal(t, 3, stored.Version)
+}
+
+func TestArchivePlayer_HiddenButKeepsHistory(t *testing.T) {
+	f := newMergeFixture(t)
+	ctx := context.Background()
+	f.finishedMatch(t, f.alice, f.charlie)
+
+	player, err := f.svc.ArchivePlayer(ctx, f.groupID, f.charlie.ID)
+	require.NoError(t, err)
+	assert.True(t, player.Archived())
+	_, err = f.svc.ArchivePlayer(ctx, f.groupID, f.charlie.ID)
+	assert.ErrorIs(t, err, ErrPlayerArchived)
+
+	active, err := f.svc.GetPlayers(ctx, f.groupID, false)
+	require.NoError(t, err)
+	assert.Len(t, active, 2)
+	all, err := f.svc.GetPlayers(ctx, f.groupID, true)
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	stats, err := NewStatsService(f.matches, f.players).GroupStats(ctx, f.groupID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats[indexOfStats(stats, f.charlie.ID)].Lost)
+
+	_, err = NewMatchService(f.matches, f.players, f.events, nil, nil).CreateMatch(ctx, f.groupID,
+		[]primitive.ObjectID{f.alice.ID}, []primitive.ObjectID{f.charlie.ID}, "")
+	assert.ErrorContains(t, err, "archived")
+
+	player, err = f.svc.RestorePlayer(ctx, f.groupID, f.charlie.ID)
+	require.NoError(t, err)
+	assert.False(t, player.Archived())
+	_, err = f.svc.RestorePlayer(ctx, f.groupID, f.charlie.ID)
+	assert.ErrorIs(t, err, ErrPlayerActive)
+}
+
+func TestPlayerJSON_Initials(t *testing.T) {
+	for name, want := range map[string]string{
+		"amit kumar shah": "AS",
+		"Ravi":            "R",
+		"d'souza, jo":     "DJ",
+		"":                "",
+	} {
+		body, err := json.Marshal(models.Player{Name: name})
+		require.NoError(t, err)
+		var out map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &out))
+		assert.Equal(t, want, out["initials"], name)
+		assert.Equal(t, name, out["name"])
+	}
+}
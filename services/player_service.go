@@ -2,7 +2,6 @@ package services
 
 import (
 	"context"
-	"errors"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
@@ -13,13 +12,25 @@ import (
 type PlayerService struct {
 	playerRepo repositories.PlayerRepository
 	matchRepo  repositories.MatchRepository
+	eventRepo  repositories.MatchEventRepository
+	mergeRepo  repositories.MergeRecordRepository
+	tx         repositories.Transactor
 }
 
-func NewPlayerService(playerRepo repositories.PlayerRepository, matchRepo repositories.MatchRepository) *PlayerService {
-	return &PlayerService{playerRepo: playerRepo, matchRepo: matchRepo}
+func NewPlayerService(playerRepo repositories.PlayerRepository, matchRepo repositories.MatchRepository, eventRepo repositories.MatchEventRepository, mergeRepo repositories.MergeRecordRepository, tx repositories.Transactor) *PlayerService {
+	return &PlayerService{playerRepo: playerRepo, matchRepo: matchRepo, eventRepo: eventRepo, mergeRepo: mergeRepo, tx: tx}
 }
 
+// CreatePlayer adds a guest player. Names are unique within a group,
+// ignoring case.
 func (s *PlayerService) CreatePlayer(ctx context.Context, name string, groupID primitive.ObjectID) (*models.Player, error) {
+	name, err := cleanPlayerName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkNameFree(ctx, groupID, primitive.NilObjectID, name); err != nil {
+		return nil, err
+	}
 	player := &models.Player{
 		Name:    name,
 		GroupID: groupID,
@@ -30,46 +41,57 @@ func (s *PlayerService) CreatePlayer(ctx context.Context, name string, groupID p
 	return player, nil
 }
 
-// CreatePlayerIfNotExists creates a player only if one with the same name doesn't exist in the group.
-func (s *PlayerService) CreatePlayerIfNotExists(ctx context.Context, name string, groupID primitive.ObjectID) (*models.Player, error) {
-	existing, err := s.playerRepo.FindByNameAndGroupID(ctx, name, groupID)
+// CreatePlayerIfNotExists returns the user's linked player in the group,
+// creating one with the given name if they have none. Joining never fails on
+// a taken name: the new player is numbered instead, as in "Alice 2". A
+// guest with the user's name is usually them, and can be claimed and merged.
+func (s *PlayerService) CreatePlayerIfNotExists(ctx context.Context, userID primitive.ObjectID, name string, groupID primitive.ObjectID) (*models.Player, error) {
+	existing, err := s.playerRepo.FindByUserAndGroupID(ctx, userID, groupID)
 	if err == nil && existing != nil {
 		return existing, nil // already exists
 	}
-	return s.CreatePlayer(ctx, name, groupID)
-}
-
-func (s *PlayerService) GetPlayers(ctx context.Context, groupID primitive.ObjectID) ([]models.Player, error) {
-	return s.playerRepo.FindByGroupID(ctx, groupID)
-}
-
-func (s *PlayerService) DeletePlayer(ctx context.Context, playerID primitive.ObjectID) error {
-	return s.playerRepo.Delete(ctx, playerID)
+	if name, err = s.freeName(ctx, groupID, primitive.NilObjectID, name); err != nil {
+		return nil, err
+	}
+	player := &models.Player{
+		Name:    name,
+		GroupID: groupID,
+		UserID:  &userID,
+	}
+	if err := s.playerRepo.Create(ctx, player); err != nil {
+		return nil, err
+	}
+	return player, nil
 }
 
-// MergePlayer merges sourcePlayer into targetPlayer:
-// - Replaces all references to sourcePlayer in match data with targetPlayer
-// - Deletes the sourcePlayer record
-func (s *PlayerService) MergePlayer(ctx context.Context, groupID, targetPlayerID, sourcePlayerID primitive.ObjectID) error {
-	if targetPlayerID == sourcePlayerID {
-		return errors.New("cannot merge player with itself")
+// GetPlayers lists the group's players, leaving out archived ones unless
+// includeArchived is set.
+func (s *PlayerService) GetPlayers(ctx context.Context, groupID primitive.ObjectID, includeArchived bool) ([]models.Player, error) {
+	players, err := s.playerRepo.FindByGroupID(ctx, groupID)
+	if err != nil || includeArchived {
+		return players, err
 	}
-
-	target, err := s.playerRepo.FindByID(ctx, targetPlayerID)
-	if err != nil {
-		return errors.New("target player not found")
+	active := make([]models.Player, 0, len(players))
+	for _, p := range players {
+		if !p.Archived() {
+			active = append(active, p)
+		}
 	}
+	return active, nil
+}
 
-	source, err := s.playerRepo.FindByID(ctx, sourcePlayerID)
-	if err != nil {
-		return errors.New("source player not found")
+// DeleteGroupData removes everything recorded in a group: its players,
+// matches, their timelines and merge history. The group record itself is
+// left to the caller.
+func (s *PlayerService) DeleteGroupData(ctx context.Context, groupID primitive.ObjectID) error {
+	if err := s.eventRepo.DeleteByGroupID(ctx, groupID); err != nil {
+		return err
 	}
-
-	// Replace all match references
-	if err := s.matchRepo.ReplacePlayerInMatches(ctx, groupID, sourcePlayerID, targetPlayerID, source.Name, target.Name); err != nil {
+	if err := s.matchRepo.DeleteByGroupID(ctx, groupID); err != nil {
 		return err
 	}
-
-	// Delete source player
-	return s.playerRepo.Delete(ctx, sourcePlayerID)
+	if err := s.mergeRepo.DeleteByGroupID(ctx, groupID); err != nil {
+		return err
+	}
+	return s.playerRepo.DeleteByGroupID(ctx, groupID)
 }
@@ -3,29 +3,34 @@ package services
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"gully-backend/models"
+	"gully-backend/repositories/memory"
 )
 
 func TestCreatePlayer_Success(t *testing.T) {
 	playerRepo := new(MockPlayerRepo)
 	matchRepo := new(MockMatchRepo)
-	svc := NewPlayerService(playerRepo, matchRepo)
+	svc := NewPlayerService(playerRepo, matchRepo, memory.NewMatchEventRepo(), memory.NewMergeRecordRepo(), memory.NewTransactor())
 	ctx := context.Background()
 
 	groupID := primitive.NewObjectID()
+	playerRepo.On("FindByGroupID", ctx, groupID).Return(nil, nil)
 	playerRepo.On("Create", ctx, mock.AnythingOfType("*models.Player")).Return(nil)
 
-	player, err := svc.CreatePlayer(ctx, "Alice", groupID)
+	player, err := svc.CreatePlayer(ctx, "  Alice   K ", groupID)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, player)
-	assert.Equal(t, "Alice", player.Name)
+	assert.Equal(t, "Alice K", player.Name)
 	assert.Equal(t, groupID, player.GroupID)
 	playerRepo.AssertExpectations(t)
 }
@@ -33,32 +38,55 @@ func TestCreatePlayer_Success(t *testing.T) {
 func TestCreatePlayer_RepoError(t *testing.T) {
 	playerRepo := new(MockPlayerRepo)
 	matchRepo := new(MockMatchRepo)
-	svc := NewPlayerService(playerRepo, matchRepo)
+	svc := NewPlayerService(playerRepo, matchRepo, memory.NewMatchEventRepo(), memory.NewMergeRecordRepo(), memory.NewTransactor())
 	ctx := context.Background()
 
+	groupID := primitive.NewObjectID()
+	playerRepo.On("FindByGroupID", ctx, groupID).Return(nil, nil)
 	playerRepo.On("Create", ctx, mock.AnythingOfType("*models.Player")).Return(errors.New("db error"))
 
-	player, err := svc.CreatePlayer(ctx, "Alice", primitive.NewObjectID())
+	player, err := svc.CreatePlayer(ctx, "Alice", groupID)
 
 	assert.Error(t, err)
 	assert.Nil(t, player)
 }
 
-func TestCreatePlayerIfNotExists_AlreadyExists(t *testing.T) {
+func TestCreatePlayer_NameTaken(t *testing.T) {
 	playerRepo := new(MockPlayerRepo)
 	matchRepo := new(MockMatchRepo)
-	svc := NewPlayerService(playerRepo, matchRepo)
+	svc := NewPlayerService(playerRepo, matchRepo, memory.NewMatchEventRepo(), memory.NewMergeRecordRepo(), memory.NewTransactor())
 	ctx := context.Background()
 
 	groupID := primitive.NewObjectID()
+	archivedAt := time.Now()
+	playerRepo.On("FindByGroupID", ctx, groupID).Return([]models.Player{
+		{ID: primitive.NewObjectID(), Name: "Alice", GroupID: groupID, ArchivedAt: &archivedAt},
+	}, nil)
+
+	_, err := svc.CreatePlayer(ctx, "alice", groupID)
+	assert.ErrorIs(t, err, ErrPlayerNameTaken, "archived players keep their names")
+
+	_, err = svc.CreatePlayer(ctx, "   ", groupID)
+	assert.ErrorIs(t, err, ErrInvalidPlayer)
+	playerRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestCreatePlayerIfNotExists_AlreadyExists(t *testing.T) {
+	playerRepo := new(MockPlayerRepo)
+	matchRepo := new(MockMatchRepo)
+	svc := NewPlayerService(playerRepo, matchRepo, memory.NewMatchEventRepo(), memory.NewMergeRecordRepo(), memory.NewTransactor())
+	ctx := context.Background()
+
+	groupID, userID := primitive.NewObjectID(), primitive.NewObjectID()
 	existing := &models.Player{
 		ID:      primitive.NewObjectID(),
-		Name:    "Alice",
+		Name:    "Alice K", // renamed since; the link still finds it
 		GroupID: groupID,
+		UserID:  &userID,
 	}
-	playerRepo.On("FindByNameAndGroupID", ctx, "Alice", groupID).Return(existing, nil)
+	playerRepo.On("FindByUserAndGroupID", ctx, userID, groupID).Return(existing, nil)
 
-	player, err := svc.CreatePlayerIfNotExists(ctx, "Alice", groupID)
+	player, err := svc.CreatePlayerIfNotExists(ctx, userID, "Alice", groupID)
 
 	assert.NoError(t, err)
 	assert.Equal(t, existing.ID, player.ID)
@@ -69,91 +97,80 @@ func TestCreatePlayerIfNotExists_AlreadyExists(t *testing.T) {
 func TestCreatePlayerIfNotExists_NewPlayer(t *testing.T) {
 	playerRepo := new(MockPlayerRepo)
 	matchRepo := new(MockMatchRepo)
-	svc := NewPlayerService(playerRepo, matchRepo)
+	svc := NewPlayerService(playerRepo, matchRepo, memory.NewMatchEventRepo(), memory.NewMergeRecordRepo(), memory.NewTransactor())
 	ctx := context.Background()
 
-	groupID := primitive.NewObjectID()
-	playerRepo.On("FindByNameAndGroupID", ctx, "Bob", groupID).Return(nil, errors.New("not found"))
+	groupID, userID := primitive.NewObjectID(), primitive.NewObjectID()
+	playerRepo.On("FindByUserAndGroupID", ctx, userID, groupID).Return(nil, errors.New("not found"))
+	playerRepo.On("FindByGroupID", ctx, groupID).Return(nil, nil)
 	playerRepo.On("Create", ctx, mock.AnythingOfType("*models.Player")).Return(nil)
 
-	player, err := svc.CreatePlayerIfNotExists(ctx, "Bob", groupID)
+	player, err := svc.CreatePlayerIfNotExists(ctx, userID, "Bob", groupID)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, player)
 	assert.Equal(t, "Bob", player.Name)
+	assert.True(t, player.LinkedTo(userID))
+	playerRepo.AssertNotCalled(t, "FindByNameAndGroupID", mock.Anything, mock.Anything, mock.Anything)
 	playerRepo.AssertCalled(t, "Create", ctx, mock.AnythingOfType("*models.Player"))
 }
 
+func TestCreatePlayerIfNotExists_NameTaken(t *testing.T) {
+	players := memory.NewPlayerRepo()
+	svc := NewPlayerService(players, memory.NewMatchRepo(), memory.NewMatchEventRepo(), memory.NewMergeRecordRepo(), memory.NewTransactor())
+	ctx := context.Background()
+	groupID := primitive.NewObjectID()
+	for _, name := range []string{"Bob", "bob 2"} {
+		require.NoError(t, players.Create(ctx, &models.Player{Name: name, GroupID: groupID}))
+	}
+
+	// The guest named Bob is left for the new member to claim.
+	player, err := svc.CreatePlayerIfNotExists(ctx, primitive.NewObjectID(), "bob", groupID)
+	require.NoError(t, err)
+	assert.Equal(t, "bob 3", player.Name)
+
+	long := strings.Repeat("x", MaxPlayerNameLen)
+	require.NoError(t, players.Create(ctx, &models.Player{Name: long, GroupID: groupID}))
+	player, err = svc.CreatePlayerIfNotExists(ctx, primitive.NewObjectID(), long, groupID)
+	require.NoError(t, err)
+	assert.Equal(t, long[:MaxPlayerNameLen-2]+" 2", player.Name)
+}
+
 func TestGetPlayers_Success(t *testing.T) {
 	playerRepo := new(MockPlayerRepo)
 	matchRepo := new(MockMatchRepo)
-	svc := NewPlayerService(playerRepo, matchRepo)
+	svc := NewPlayerService(playerRepo, matchRepo, memory.NewMatchEventRepo(), memory.NewMergeRecordRepo(), memory.NewTransactor())
 	ctx := context.Background()
 
 	groupID := primitive.NewObjectID()
+	archivedAt := time.Now()
 	players := []models.Player{
 		{ID: primitive.NewObjectID(), Name: "Alice", GroupID: groupID},
 		{ID: primitive.NewObjectID(), Name: "Bob", GroupID: groupID},
+		{ID: primitive.NewObjectID(), Name: "Carol", GroupID: groupID, ArchivedAt: &archivedAt},
 	}
 	playerRepo.On("FindByGroupID", ctx, groupID).Return(players, nil)
 
-	result, err := svc.GetPlayers(ctx, groupID)
-
+	result, err := svc.GetPlayers(ctx, groupID, false)
 	assert.NoError(t, err)
 	assert.Len(t, result, 2)
-}
-
-func TestDeletePlayer_Success(t *testing.T) {
-	playerRepo := new(MockPlayerRepo)
-	matchRepo := new(MockMatchRepo)
-	svc := NewPlayerService(playerRepo, matchRepo)
-	ctx := context.Background()
-
-	playerID := primitive.NewObjectID()
-	playerRepo.On("Delete", ctx, playerID).Return(nil)
-
-	err := svc.DeletePlayer(ctx, playerID)
 
+	result, err = svc.GetPlayers(ctx, groupID, true)
 	assert.NoError(t, err)
-	playerRepo.AssertExpectations(t)
+	assert.Len(t, result, 3)
 }
 
-// ── MergePlayer tests ──
-
-func TestMergePlayer_Success(t *testing.T) {
-	playerRepo := new(MockPlayerRepo)
-	matchRepo := new(MockMatchRepo)
-	svc := NewPlayerService(playerRepo, matchRepo)
-	ctx := context.Background()
-
-	groupID := primitive.NewObjectID()
-	targetID := primitive.NewObjectID()
-	sourceID := primitive.NewObjectID()
-
-	target := &models.Player{ID: targetID, Name: "Alice", GroupID: groupID}
-	source := &models.Player{ID: sourceID, Name: "Alice2", GroupID: groupID}
-
-	playerRepo.On("FindByID", ctx, targetID).Return(target, nil)
-	playerRepo.On("FindByID", ctx, sourceID).Return(source, nil)
-	matchRepo.On("ReplacePlayerInMatches", ctx, groupID, sourceID, targetID, "Alice2", "Alice").Return(nil)
-	playerRepo.On("Delete", ctx, sourceID).Return(nil)
-
-	err := svc.MergePlayer(ctx, groupID, targetID, sourceID)
-
-	assert.NoError(t, err)
-	playerRepo.AssertExpectations(t)
-	matchRepo.AssertExpectations(t)
-}
+// ── MergePlayer tests (see player_merge_test.go) ──
 
 func TestMergePlayer_SamePlayer_Fails(t *testing.T) {
 	playerRepo := new(MockPlayerRepo)
 	matchRepo := new(MockMatchRepo)
-	svc := NewPlayerService(playerRepo, matchRepo)
+	svc := NewPlayerService(playerRepo, matchRepo, memory.NewMatchEventRepo(), memory.NewMergeRecordRepo(), memory.NewTransactor())
 	ctx := context.Background()
 
 	playerID := primitive.NewObjectID()
 
-	err := svc.MergePlayer(ctx, primitive.NewObjectID(), playerID, playerID)
+	_, err := svc.MergePlayer(ctx, primitive.NewObjectID(), playerID, playerID)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "cannot merge player with itself")
@@ -162,15 +179,15 @@ func TestMergePlayer_SamePlayer_Fails(t *testing.T) {
 func TestMergePlayer_TargetNotFound(t *testing.T) {
 	playerRepo := new(MockPlayerRepo)
 	matchRepo := new(MockMatchRepo)
-	svc := NewPlayerService(playerRepo, matchRepo)
+	svc := NewPlayerService(playerRepo, matchRepo, memory.NewMatchEventRepo(), memory.NewMergeRecordRepo(), memory.NewTransactor())
 	ctx := context.Background()
 
 	targetID := primitive.NewObjectID()
 	sourceID := primitive.NewObjectID()
 
-	playerRepo.On("FindByID", ctx, targetID).Return(nil, errors.New("not found"))
+	playerRepo.On("FindByID", mock.Anything, targetID).Return(nil, errors.New("not found"))
 
-	err := svc.MergePlayer(ctx, primitive.NewObjectID(), targetID, sourceID)
+	_, err := svc.MergePlayer(ctx, primitive.NewObjectID(), targetID, sourceID)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "target player not found")
@@ -179,42 +196,18 @@ func TestMergePlayer_TargetNotFound(t *testing.T) {
 func TestMergePlayer_SourceNotFound(t *testing.T) {
 	playerRepo := new(MockPlayerRepo)
 	matchRepo := new(MockMatchRepo)
-	svc := NewPlayerService(playerRepo, matchRepo)
+	svc := NewPlayerService(playerRepo, matchRepo, memory.NewMatchEventRepo(), memory.NewMergeRecordRepo(), memory.NewTransactor())
 	ctx := context.Background()
 
 	targetID := primitive.NewObjectID()
 	sourceID := primitive.NewObjectID()
 
 	target := &models.Player{ID: targetID, Name: "Alice"}
-	playerRepo.On("FindByID", ctx, targetID).Return(target, nil)
-	playerRepo.On("FindByID", ctx, sourceID).Return(nil, errors.New("not found"))
+	playerRepo.On("FindByID", mock.Anything, targetID).Return(target, nil)
+	playerRepo.On("FindByID", mock.Anything, sourceID).Return(nil, errors.New("not found"))
 
-	err := svc.MergePlayer(ctx, primitive.NewObjectID(), targetID, sourceID)
+	_, err := svc.MergePlayer(ctx, primitive.NewObjectID(), targetID, sourceID)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "source player not found")
 }
-
-func TestMergePlayer_ReplaceError(t *testing.T) {
-	playerRepo := new(MockPlayerRepo)
-	matchRepo := new(MockMatchRepo)
-	svc := NewPlayerService(playerRepo, matchRepo)
-	ctx := context.Background()
-
-	groupID := primitive.NewObjectID()
-	targetID := primitive.NewObjectID()
-	sourceID := primitive.NewObjectID()
-
-	target := &models.Player{ID: targetID, Name: "Alice"}
-	source := &models.Player{ID: sourceID, Name: "Alice2"}
-
-	playerRepo.On("FindByID", ctx, targetID).Return(target, nil)
-	playerRepo.On("FindByID", ctx, sourceID).Return(source, nil)
-	matchRepo.On("ReplacePlayerInMatches", ctx, groupID, sourceID, targetID, "Alice2", "Alice").Return(errors.New("db error"))
-
-	err := svc.MergePlayer(ctx, groupID, targetID, sourceID)
-
-	assert.Error(t, err)
-	// Should NOT delete source if replace failed
-	playerRepo.AssertNotCalled(t, "Delete")
-}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+// Share link lifetimes. Links always expire; DefaultShareTTL applies when
+// no expiry is asked for.
+const (
+	DefaultShareTTL = 24 * time.Hour
+	MaxShareTTL     = 7 * 24 * time.Hour
+)
+
+// Share scopes, as reported in a SharedView.
+const (
+	ShareScopeGroup = "group"
+	ShareScopeMatch = "match"
+)
+
+var (
+	ErrInvalidShareLink  = errors.New("invalid share link")
+	ErrShareLinkNotFound = errors.New("share link not found, expired or revoked")
+)
+
+// activeStatuses are the match states a group share link shows: anything
+// still to be played or being played.
+var activeStatuses = []string{
+	models.MatchStatusScheduled, models.MatchStatusWarmup,
+	models.MatchStatusLive, models.MatchStatusPaused,
+}
+
+// ShareService makes share links and resolves them for people without an
+// account. What a link grants is read-only; nothing here changes a match.
+type ShareService struct {
+	shareRepo repositories.ShareLinkRepository
+	groupRepo repositories.GroupRepository
+	matchRepo repositories.MatchRepository
+}
+
+func NewShareService(shareRepo repositories.ShareLinkRepository, groupRepo repositories.GroupRepository, matchRepo repositories.MatchRepository) *ShareService {
+	return &ShareService{shareRepo: shareRepo, groupRepo: groupRepo, matchRepo: matchRepo}
+}
+
+// SharedGroup is the part of a group a share link shows: its name only. Its
+// ID, members, join code and settings stay private.
+type SharedGroup struct {
+	Name string `json:"name"`
+}
+
+// SharedMatch is a match as a share link shows it, without its group ID.
+type SharedMatch struct {
+	*models.Match
+	GroupID *struct{} `json:"group_id,omitempty"` // hides Match.GroupID
+}
+
+// SharedView is what a share link's holder sees. A match link has Match; a
+// group link has the group's active matches in Matches.
+type SharedView struct {
+	Scope     string        `json:"scope"`
+	Group     SharedGroup   `json:"group"`
+	Match     *SharedMatch  `json:"match,omitempty"`
+	Matches   []SharedMatch `json:"matches,omitempty"`
+	ExpiresAt time.Time     `json:"expires_at"`
+}
+
+// CreateShareLink makes a link to the group, or to one of its matches when
+// matchID is set, and returns it with its token. The token is only
+// returned here. A nil expiresAt means DefaultShareTTL from now.
+func (s *ShareService) CreateShareLink(ctx context.Context, groupID, userID primitive.ObjectID, matchID *primitive.ObjectID, expiresAt *time.Time) (*models.ShareLink, string, error) {
+	if _, err := s.memberGroup(ctx, groupID, userID); err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	expiry := now.Add(DefaultShareTTL)
+	if expiresAt != nil {
+		if !expiresAt.After(now) {
+			return nil, "", fmt.Errorf("%w: expiry is in the past", ErrInvalidShareLink)
+		}
+		if expiresAt.Sub(now) > MaxShareTTL {
+			return nil, "", fmt.Errorf("%w: links can last at most %s", ErrInvalidShareLink, MaxShareTTL)
+		}
+		expiry = *expiresAt
+	}
+	if matchID != nil {
+		match, err := s.matchRepo.FindByID(ctx, *matchID)
+		if err != nil || match.GroupID != groupID {
+			return nil, "", fmt.Errorf("%w: match is not in the group", ErrInvalidShareLink)
+		}
+	}
+
+	secret, hash, err := newRefreshSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	link := &models.ShareLink{
+		GroupID:   groupID,
+		MatchID:   matchID,
+		TokenHash: hash,
+		CreatedBy: userID,
+		CreatedAt: now,
+		ExpiresAt: expiry,
+	}
+	if err := s.shareRepo.Create(ctx, link); err != nil {
+		return nil, "", err
+	}
+	return link, link.ID.Hex() + "." + secret, nil
+}
+
+// ListShareLinks returns the group's links, newest first. Members only.
+func (s *ShareService) ListShareLinks(ctx context.Context, groupID, userID primitive.ObjectID) ([]models.ShareLink, error) {
+	if _, err := s.memberGroup(ctx, groupID, userID); err != nil {
+		return nil, err
+	}
+	return s.shareRepo.FindByGroupID(ctx, groupID)
+}
+
+// RevokeShareLink stops the link working. The member who made it and the
+// group owner can revoke it; revoking it again is not an error.
+func (s *ShareService) RevokeShareLink(ctx context.Context, groupID, userID, linkID primitive.ObjectID) (*models.ShareLink, error) {
+	group, err := s.memberGroup(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	link, err := s.shareRepo.FindByID(ctx, linkID)
+	if err != nil || link.GroupID != groupID {
+		return nil, ErrShareLinkNotFound
+	}
+	if link.CreatedBy != userID && group.CreatedBy != userID {
+		return nil, ErrNotGroupOwner
+	}
+	if link.RevokedAt != nil {
+		return link, nil
+	}
+	now := time.Now()
+	if err := s.shareRepo.Revoke(ctx, linkID, now); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrShareLinkNotFound
+		}
+		return nil, err
+	}
+	link.RevokedAt = &now
+	return link, nil
+}
+
+// Resolve returns the link a token belongs to while it is usable. Unknown,
+// tampered, expired and revoked tokens are all ErrShareLinkNotFound.
+func (s *ShareService) Resolve(ctx context.Context, token string) (*models.ShareLink, error) {
+	linkHex, secret, ok := strings.Cut(token, ".")
+	linkID, err := primitive.ObjectIDFromHex(linkHex)
+	if !ok || err != nil {
+		return nil, ErrShareLinkNotFound
+	}
+	link, err := s.shareRepo.FindByID(ctx, linkID)
+	if err != nil {
+		return nil, ErrShareLinkNotFound
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(link.TokenHash)) != 1 || !link.Usable(time.Now()) {
+		return nil, ErrShareLinkNotFound
+	}
+	return link, nil
+}
+
+// View returns what the link shows now. A deleted group or match ends the
+// link as if it were revoked.
+func (s *ShareService) View(ctx context.Context, link *models.ShareLink) (*SharedView, error) {
+	group, err := s.groupRepo.FindByID(ctx, link.GroupID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrShareLinkNotFound
+		}
+		return nil, err
+	}
+	view := &SharedView{
+		Scope:     ShareScopeGroup,
+		Group:     SharedGroup{Name: group.Name},
+		ExpiresAt: link.ExpiresAt,
+	}
+	if link.MatchID != nil {
+		match, err := s.matchRepo.FindByID(ctx, *link.MatchID)
+		if err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return nil, ErrShareLinkNotFound
+			}
+			return nil, err
+		}
+		view.Scope, view.Match = ShareScopeMatch, &SharedMatch{Match: match}
+		return view, nil
+	}
+	page, err := s.matchRepo.FindPage(ctx, repositories.MatchQuery{
+		GroupID:  group.ID,
+		Statuses: activeStatuses,
+		Limit:    repositories.MaxMatchPageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range page.Matches {
+		view.Matches = append(view.Matches, SharedMatch{Match: &page.Matches[i]})
+	}
+	return view, nil
+}
+
+// memberGroup loads the group and checks that userID is a member.
+func (s *ShareService) memberGroup(ctx context.Context, groupID, userID primitive.ObjectID) (*models.Group, error) {
+	group, err := s.groupRepo.FindByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if !group.HasMember(userID) {
+		return nil, ErrNotGroupMember
+	}
+	return group, nil
+}
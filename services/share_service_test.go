@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+)
+
+func TestShareLink_Match(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+	_, a := f.member(t, "alice", group)
+	_, b := f.member(t, "bob", group)
+	final := f.match(t, group.ID, a[0], b[0])
+
+	link, token, err := f.shares.CreateShareLink(ctx, group.ID, owner.ID, &final.ID, nil)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(DefaultShareTTL), link.ExpiresAt, time.Minute)
+	assert.NotContains(t, token, link.TokenHash)
+
+	got, err := f.shares.Resolve(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, link.ID, got.ID)
+	view, err := f.shares.View(ctx, got)
+	require.NoError(t, err)
+	assert.Equal(t, ShareScopeMatch, view.Scope)
+	assert.Equal(t, final.ID, view.Match.ID)
+	assert.Equal(t, "Club", view.Group.Name)
+
+	// Only the hash is stored, and the view says nothing about members.
+	stored, err := f.links.FindByID(ctx, link.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, token, stored.TokenHash)
+	body, err := json.Marshal(view)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), group.JoinCode)
+	assert.NotContains(t, string(body), group.ID.Hex())
+	assert.NotContains(t, string(body), "members")
+
+	// Deleting the match ends the link.
+	require.NoError(t, f.matches.Delete(ctx, final.ID))
+	_, err = f.shares.View(ctx, got)
+	assert.ErrorIs(t, err, ErrShareLinkNotFound)
+}
+
+func TestShareLink_GroupShowsActiveMatches(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+	_, a := f.member(t, "alice", group)
+	_, b := f.member(t, "bob", group)
+	f.match(t, group.ID, a[0], b[0]) // finished
+	live := f.match(t, group.ID, a[0], b[0])
+	live.Status = models.MatchStatusLive
+	require.NoError(t, f.matches.Update(ctx, live))
+
+	_, token, err := f.shares.CreateShareLink(ctx, group.ID, owner.ID, nil, nil)
+	require.NoError(t, err)
+	link, err := f.shares.Resolve(ctx, token)
+	require.NoError(t, err)
+	view, err := f.shares.View(ctx, link)
+	require.NoError(t, err)
+	assert.Equal(t, ShareScopeGroup, view.Scope)
+	assert.Nil(t, view.Match)
+	require.Len(t, view.Matches, 1)
+	assert.Equal(t, live.ID, view.Matches[0].ID)
+	body, err := json.Marshal(view)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), group.ID.Hex())
+}
+
+func TestShareLink_Rejected(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+	other, err := f.groupSv.CreateGroup(ctx, "Other", owner.ID)
+	require.NoError(t, err)
+	_, a := f.member(t, "alice", other)
+	_, b := f.member(t, "bob", other)
+	elsewhere := f.match(t, other.ID, a[0], b[0])
+	stranger, err := f.auth.Register(ctx, "stranger", "password123")
+	require.NoError(t, err)
+
+	_, _, err = f.shares.CreateShareLink(ctx, group.ID, stranger.ID, nil, nil)
+	assert.ErrorIs(t, err, ErrNotGroupMember)
+	_, _, err = f.shares.CreateShareLink(ctx, group.ID, owner.ID, &elsewhere.ID, nil)
+	assert.ErrorIs(t, err, ErrInvalidShareLink)
+	missing := primitive.NewObjectID()
+	_, _, err = f.shares.CreateShareLink(ctx, group.ID, owner.ID, &missing, nil)
+	assert.ErrorIs(t, err, ErrInvalidShareLink)
+
+	past, tooLong := time.Now().Add(-time.Minute), time.Now().Add(MaxShareTTL+time.Hour)
+	_, _, err = f.shares.CreateShareLink(ctx, group.ID, owner.ID, nil, &past)
+	assert.ErrorIs(t, err, ErrInvalidShareLink)
+	_, _, err = f.shares.CreateShareLink(ctx, group.ID, owner.ID, nil, &tooLong)
+	assert.ErrorIs(t, err, ErrInvalidShareLink)
+
+	link, token, err := f.shares.CreateShareLink(ctx, group.ID, owner.ID, nil, nil)
+	require.NoError(t, err)
+	for _, bad := range []string{"", "nonsense", link.ID.Hex(), link.ID.Hex() + ".wrong", primitive.NewObjectID().Hex() + token[24:]} {
+		_, err = f.shares.Resolve(ctx, bad)
+		assert.ErrorIs(t, err, ErrShareLinkNotFound, bad)
+	}
+}
+
+func TestShareLink_ExpiryAndRevoke(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+	owner, err := f.auth.Register(ctx, "owner", "password123")
+	require.NoError(t, err)
+	group, err := f.groupSv.CreateGroup(ctx, "Club", owner.ID)
+	require.NoError(t, err)
+	bob, _ := f.member(t, "bob", group)
+	carol, _ := f.member(t, "carol", group)
+
+	soon := time.Now().Add(50 * time.Millisecond)
+	_, token, err := f.shares.CreateShareLink(ctx, group.ID, bob.ID, nil, &soon)
+	require.NoError(t, err)
+	_, err = f.shares.Resolve(ctx, token)
+	require.NoError(t, err)
+	time.Sleep(60 * time.Millisecond)
+	_, err = f.shares.Resolve(ctx, token)
+	assert.ErrorIs(t, err, ErrShareLinkNotFound)
+
+	// The maker and the owner can revoke a link; other members cannot.
+	link, token, err := f.shares.CreateShareLink(ctx, group.ID, bob.ID, nil, nil)
+	require.NoError(t, err)
+	_, err = f.shares.RevokeShareLink(ctx, group.ID, carol.ID, link.ID)
+	assert.ErrorIs(t, err, ErrNotGroupOwner)
+	revoked, err := f.shares.RevokeShareLink(ctx, group.ID, owner.ID, link.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, revoked.RevokedAt)
+	_, err = f.shares.RevokeShareLink(ctx, group.ID, bob.ID, link.ID)
+	assert.NoError(t, err)
+	_, err = f.shares.Resolve(ctx, token)
+	assert.ErrorIs(t, err, ErrShareLinkNotFound)
+
+	links, err := f.shares.ListShareLinks(ctx, group.ID, carol.ID)
+	require.NoError(t, err)
+	assert.Len(t, links, 2)
+
+	// Deleting the group ends its links.
+	link, _, err = f.shares.CreateShareLink(ctx, group.ID, owner.ID, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, f.groupSv.DeleteGroup(ctx, group.ID, owner.ID))
+	_, err = f.shares.View(ctx, link)
+	assert.ErrorIs(t, err, ErrShareLinkNotFound)
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories"
+)
+
+// PlayerStats summarises a player's matches and rallies within a group.
+type PlayerStats struct {
+	PlayerID primitive.ObjectID `json:"player_id"`
+	Name     string             `json:"name"`
+
+	Played    int `json:"played"`
+	Won       int `json:"won"`
+	Lost      int `json:"lost"`
+	Walkovers int `json:"walkovers"` // included in played/won/lost; no rally stats
+
+	PointsScored    int     `json:"points_scored"`     // rallies this player finished as scorer
+	AvgRallySecs    float64 `json:"avg_rally_secs"`    // over timed rallies in their matches
+	PointsPerMinute float64 `json:"points_per_minute"` // points played per minute of match time
+
+	Errors          map[string]int `json:"errors"`            // error outcome → count
+	MostCommonError string         `json:"most_common_error"` // "" when no errors recorded
+}
+
+type StatsService struct {
+	matchRepo  repositories.MatchRepository
+	playerRepo repositories.PlayerRepository
+}
+
+func NewStatsService(matchRepo repositories.MatchRepository, playerRepo repositories.PlayerRepository) *StatsService {
+	return &StatsService{matchRepo: matchRepo, playerRepo: playerRepo}
+}
+
+// GroupStats computes stats for every player in the group, ordered by wins.
+func (s *StatsService) GroupStats(ctx context.Context, groupID primitive.ObjectID) ([]PlayerStats, error) {
+	players, err := s.playerRepo.FindByGroupID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := s.matchRepo.FindByGroupID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return computePlayerStats(players, matches), nil
+}
+
+// GroupPlayerStats is a user's stats in one group.
+type GroupPlayerStats struct {
+	GroupID primitive.ObjectID `json:"group_id"`
+	Stats   PlayerStats        `json:"stats"`
+}
+
+// UserStats is a user's stats over every group they have a linked player in.
+type UserStats struct {
+	Overall PlayerStats        `json:"overall"` // PlayerID is the user's ID
+	Groups  []GroupPlayerStats `json:"groups"`
+}
+
+// UserStats computes the stats of each player linked to the user, and
+// overall stats as if all those players were one.
+func (s *StatsService) UserStats(ctx context.Context, userID primitive.ObjectID, username string) (*UserStats, error) {
+	players, err := s.playerRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &UserStats{Groups: make([]GroupPlayerStats, 0, len(players))}
+	var all []models.Match
+	for _, p := range players {
+		matches, err := s.matchRepo.FindByGroupID(ctx, p.GroupID)
+		if err != nil {
+			return nil, err
+		}
+		out.Groups = append(out.Groups, GroupPlayerStats{
+			GroupID: p.GroupID,
+			Stats:   computePlayerStats([]models.Player{p}, matches)[0],
+		})
+		for i := range matches {
+			m := copyMatch(&matches[i])
+			m.ReplacePlayer(p.ID, userID, username)
+			all = append(all, m)
+		}
+	}
+	out.Overall = computePlayerStats([]models.Player{{ID: userID, Name: username}}, all)[0]
+	return out, nil
+}
+
+// statsAccumulator holds running totals that only become averages at the end.
+type statsAccumulator struct {
+	stats        *PlayerStats
+	rallySecs    int
+	timedRallies int
+	points       int
+	activeSecs   int
+}
+
+func computePlayerStats(players []models.Player, matches []models.Match) []PlayerStats {
+	acc := make(map[string]*statsAccumulator, len(players))
+	order := make([]*statsAccumulator, 0, len(players))
+	for _, p := range players {
+		a := &statsAccumulator{stats: &PlayerStats{PlayerID: p.ID, Name: p.Name, Errors: map[string]int{}}}
+		acc[p.ID.Hex()] = a
+		order = append(order, a)
+	}
+
+	for _, m := range matches {
+		// Abandoned and unplayed matches have no result.
+		if m.Status != models.MatchStatusFinished && m.Status != models.MatchStatusWalkover {
+			continue
+		}
+		teams := map[int][]primitive.ObjectID{1: m.Team1IDs, 2: m.Team2IDs}
+		if m.Status == models.MatchStatusWalkover {
+			countWalkover(acc, teams, m.WinnerTeam)
+			continue
+		}
+		winner := 0
+		if m.Score1 > m.Score2 {
+			winner = 1
+		} else if m.Score2 > m.Score1 {
+			winner = 2
+		}
+
+		points, rallySecs, timedRallies := 0, 0, 0
+		for _, ev := range m.ScoreHistory {
+			if ev.Team != 0 {
+				points++
+			}
+			if ev.RallySecs > 0 {
+				rallySecs += ev.RallySecs
+				timedRallies++
+			}
+			if a, ok := acc[ev.PlayerID]; ok && ev.Team != 0 {
+				a.stats.PointsScored++
+			}
+			if models.IsErrorOutcome(ev.Outcome) {
+				if a, ok := acc[faultPlayer(ev, teams)]; ok {
+					a.stats.Errors[ev.Outcome]++
+				}
+			}
+		}
+		if points == 0 {
+			// Results entered after the fact have no rally history.
+			points = m.Score1 + m.Score2
+		}
+
+		for team, ids := range teams {
+			for _, id := range ids {
+				a, ok := acc[id.Hex()]
+				if !ok {
+					continue
+				}
+				a.stats.Played++
+				switch winner {
+				case team:
+					a.stats.Won++
+				case 0:
+				default:
+					a.stats.Lost++
+				}
+				a.rallySecs += rallySecs
+				a.timedRallies += timedRallies
+				if m.DurationSecs > 0 {
+					a.points += points
+					a.activeSecs += m.DurationSecs
+				}
+			}
+		}
+	}
+
+	out := make([]PlayerStats, len(order))
+	for i, a := range order {
+		if a.timedRallies > 0 {
+			a.stats.AvgRallySecs = float64(a.rallySecs) / float64(a.timedRallies)
+		}
+		if a.activeSecs > 0 {
+			a.stats.PointsPerMinute = float64(a.points) / (float64(a.activeSecs) / 60)
+		}
+		a.stats.MostCommonError = mostCommon(a.stats.Errors)
+		out[i] = *a.stats
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Won > out[j].Won })
+	return out
+}
+
+// countWalkover records the result of a walkover. Any rallies played before a
+// retirement are left out so they do not skew rally averages.
+func countWalkover(acc map[string]*statsAccumulator, teams map[int][]primitive.ObjectID, winner int) {
+	for team, ids := range teams {
+		for _, id := range ids {
+			a, ok := acc[id.Hex()]
+			if !ok {
+				continue
+			}
+			a.stats.Played++
+			a.stats.Walkovers++
+			if team == winner {
+				a.stats.Won++
+			} else {
+				a.stats.Lost++
+			}
+		}
+	}
+}
+
+// faultPlayer returns who made the error on a rally: the recorded fault
+// player, or the only player on the side that lost the rally in singles.
+func faultPlayer(ev models.ScoreEvent, teams map[int][]primitive.ObjectID) string {
+	if ev.FaultPlayerID != "" {
+		return ev.FaultPlayerID
+	}
+	losers := teams[3-ev.Team]
+	if ev.Team != 0 && len(losers) == 1 {
+		return losers[0].Hex()
+	}
+	return ""
+}
+
+func mostCommon(counts map[string]int) string {
+	best, bestN := "", 0
+	for k, n := range counts {
+		if n > bestN || (n == bestN && k < best) {
+			best, bestN = k, n
+		}
+	}
+	return best
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"gully-backend/models"
+	"gully-backend/repositories/memory"
+)
+
+func TestGroupStats_RallyMetrics(t *testing.T) {
+	matchRepo := new(MockMatchRepo)
+	playerRepo := new(MockPlayerRepo)
+	svc := NewStatsService(matchRepo, playerRepo)
+	ctx := context.Background()
+
+	groupID := primitive.NewObjectID()
+	p1, p2 := newPlayerID(), newPlayerID()
+	match := makeLiveMatch([]primitive.ObjectID{p1}, []primitive.ObjectID{p2})
+	match.Status = models.MatchStatusFinished
+	match.Score1, match.Score2 = 2, 1
+	match.DurationSecs = 60
+	match.ScoreHistory = []models.ScoreEvent{
+		{Team: 1, PlayerID: p1.Hex(), Outcome: models.OutcomeSmashWinner, RallySecs: 10},
+		{Team: 0, Outcome: models.OutcomeLet, RallySecs: 4},
+		{Team: 2, PlayerID: p2.Hex(), Outcome: models.OutcomeNetError, RallySecs: 6},
+		{Team: 1, PlayerID: p1.Hex(), Outcome: models.OutcomeOut, RallySecs: 8},
+	}
+
+	playerRepo.On("FindByGroupID", ctx, groupID).Return([]models.Player{
+		{ID: p1, Name: "Alice"}, {ID: p2, Name: "Bob"},
+	}, nil)
+	matchRepo.On("FindByGroupID", ctx, groupID).Return([]models.Match{*match}, nil)
+
+	stats, err := svc.GroupStats(ctx, groupID)
+
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	alice, bob := stats[0], stats[1]
+	assert.Equal(t, "Alice", alice.Name)
+	assert.Equal(t, 1, alice.Won)
+	assert.Equal(t, 1, bob.Lost)
+	assert.Equal(t, 2, alice.PointsScored)
+	assert.InDelta(t, 7.0, alice.AvgRallySecs, 0.001)
+	assert.InDelta(t, 3.0, alice.PointsPerMinute, 0.001)
+	// Singles: errors are charged to the player who lost the rally.
+	assert.Equal(t, models.OutcomeNetError, alice.MostCommonError)
+	assert.Equal(t, models.OutcomeOut, bob.MostCommonError)
+}
+
+func TestRecordRally_LetDoesNotScore(t *testing.T) {
+	matchRepo := new(MockMatchRepo)
+	playerRepo := new(MockPlayerRepo)
+	svc := NewMatchService(matchRepo, playerRepo, memory.NewMatchEventRepo(), nil, nil)
+	ctx := context.Background()
+
+	p1, p2 := newPlayerID(), newPlayerID()
+	match := makeLiveMatch([]primitive.ObjectID{p1}, []primitive.ObjectID{p2})
+	match.Score2 = 1
+	match.ServingTeam = 2
+	match.ServingPlayerID = p2.Hex()
+
+	matchRepo.On("FindByID", ctx, match.ID).Return(match, nil)
+	matchRepo.On("Update", ctx, match).Return(nil)
+
+	result, err := svc.RecordRally(ctx, match.ID, Rally{Outcome: models.OutcomeLet, RallySecs: 12})
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Score1)
+	assert.Equal(t, 1, result.Score2)
+	assert.Equal(t, 2, result.ServingTeam)
+	require.Len(t, result.ScoreHistory, 1)
+	assert.Equal(t, models.OutcomeLet, result.ScoreHistory[0].Outcome)
+	assert.False(t, result.ScoreHistory[0].At.IsZero())
+}
+
+func TestRecordRally_InvalidOutcome_Fails(t *testing.T) {
+	svc := NewMatchService(new(MockMatchRepo), new(MockPlayerRepo), memory.NewMatchEventRepo(), nil, nil)
+
+	_, err := svc.RecordRally(context.Background(), primitive.NewObjectID(), Rally{Team: 1, Outcome: "lucky"})
+
+	assert.ErrorContains(t, err, "invalid outcome")
+}
+
+func TestUndoScore_SkipsLetWhenRestoringServe(t *testing.T) {
+	matchRepo := new(MockMatchRepo)
+	svc := NewMatchService(matchRepo, new(MockPlayerRepo), memory.NewMatchEventRepo(), nil, nil)
+	ctx := context.Background()
+
+	p1, p2 := newPlayerID(), newPlayerID()
+	match := makeLiveMatch([]primitive.ObjectID{p1}, []primitive.ObjectID{p2})
+	match.Score1, match.Score2 = 1, 1
+	match.ScoreHistory = []models.ScoreEvent{
+		{Team: 2, PlayerID: p2.Hex()},
+		{Team: 0, Outcome: models.OutcomeLet},
+		{Team: 1, PlayerID: p1.Hex()},
+	}
+
+	matchRepo.On("FindByID", ctx, match.ID).Return(match, nil)
+	matchRepo.On("Update", ctx, match).Return(nil)
+
+	result, err := svc.UndoScore(ctx, match.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Score1)
+	assert.Equal(t, 2, result.ServingTeam)
+	assert.Equal(t, p2.Hex(), result.ServingPlayerID)
+}
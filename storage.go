@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"gully-backend/config"
+	"gully-backend/migrations"
+	"gully-backend/repositories"
+	"gully-backend/repositories/memory"
+	"gully-backend/repositories/sqlrepo"
+)
+
+// storage is the set of repositories the server runs on.
+type storage struct {
+	users        repositories.UserRepository
+	groups       repositories.GroupRepository
+	players      repositories.PlayerRepository
+	matches      repositories.MatchRepository
+	matchEvents  repositories.MatchEventRepository
+	mergeRecords repositories.MergeRecordRepository
+	sessions     repositories.SessionRepository
+	resets       repositories.PasswordResetRepository
+	invites      repositories.InviteRepository
+	joinRequests repositories.JoinRequestRepository
+	shareLinks   repositories.ShareLinkRepository
+	tx           repositories.Transactor
+	close        func()
+}
+
+// openStorage opens the backend selected by STORAGE.
+func openStorage(cfg *config.Config) *storage {
+	switch cfg.Storage {
+	case config.StorageMemory:
+		log.Println("Using in-memory storage; data is lost on restart")
+		users, groups, players := memory.NewUserRepo(), memory.NewGroupRepo(), memory.NewPlayerRepo()
+		matches, matchEvents, mergeRecords := memory.NewMatchRepo(), memory.NewMatchEventRepo(), memory.NewMergeRecordRepo()
+		sessions, resets, invites := memory.NewSessionRepo(), memory.NewPasswordResetRepo(), memory.NewInviteRepo()
+		joinRequests, shareLinks := memory.NewJoinRequestRepo(), memory.NewShareLinkRepo()
+		return &storage{
+			users:        users,
+			groups:       groups,
+			players:      players,
+			matches:      matches,
+			matchEvents:  matchEvents,
+			mergeRecords: mergeRecords,
+			sessions:     sessions,
+			resets:       resets,
+			invites:      invites,
+			joinRequests: joinRequests,
+			shareLinks:   shareLinks,
+			tx: memory.NewTransactor(users, groups, players, matches, matchEvents, mergeRecords,
+				sessions, resets, invites, joinRequests, shareLinks),
+			close: func() {},
+		}
+	case config.StorageSQL:
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		db, err := sqlrepo.Open(ctx, cfg.SQLDriver, cfg.SQLDSN)
+		if err != nil {
+			log.Fatalf("SQL open error: %v", err)
+		}
+		log.Printf("Using %s storage", cfg.SQLDriver)
+		return &storage{
+			users:        sqlrepo.NewUserRepo(db),
+			groups:       sqlrepo.NewGroupRepo(db),
+			players:      sqlrepo.NewPlayerRepo(db),
+			matches:      sqlrepo.NewMatchRepo(db),
+			matchEvents:  sqlrepo.NewMatchEventRepo(db),
+			mergeRecords: sqlrepo.NewMergeRecordRepo(db),
+			sessions:     sqlrepo.NewSessionRepo(db),
+			resets:       sqlrepo.NewPasswordResetRepo(db),
+			invites:      sqlrepo.NewInviteRepo(db),
+			joinRequests: sqlrepo.NewJoinRequestRepo(db),
+			shareLinks:   sqlrepo.NewShareLinkRepo(db),
+			tx:           db,
+			close: func() {
+				if err := db.Close(); err != nil {
+					log.Printf("SQL close error: %v", err)
+				}
+			},
+		}
+	default:
+		db, disconnect := connectMongo(cfg)
+		if cfg.AutoMigrate {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			err := migrations.Run(ctx, db)
+			cancel()
+			if err != nil {
+				log.Fatalf("Migration error: %v", err)
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		tx, err := repositories.NewMongoTransactor(ctx, db)
+		cancel()
+		if err != nil {
+			log.Fatalf("MongoDB transactions: %v", err)
+		}
+		return &storage{
+			users:        repositories.NewUserRepo(db),
+			groups:       repositories.NewGroupRepo(db),
+			players:      repositories.NewPlayerRepo(db),
+			matches:      repositories.NewMatchRepo(db),
+			matchEvents:  repositories.NewMatchEventRepo(db),
+			mergeRecords: repositories.NewMergeRecordRepo(db),
+			sessions:     repositories.NewSessionRepo(db),
+			resets:       repositories.NewPasswordResetRepo(db),
+			invites:      repositories.NewInviteRepo(db),
+			joinRequests: repositories.NewJoinRequestRepo(db),
+			shareLinks:   repositories.NewShareLinkRepo(db),
+			tx:           tx,
+			close:        disconnect,
+		}
+	}
+}
+
+func connectMongo(cfg *config.Config) (*mongo.Database, func()) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURI))
+	if err != nil {
+		log.Fatalf("MongoDB connect error: %v", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		log.Fatalf("MongoDB ping error: %v", err)
+	}
+	log.Println("Connected to MongoDB")
+
+	disconnect := func() {
+		if err := client.Disconnect(context.Background()); err != nil {
+			log.Printf("MongoDB disconnect error: %v", err)
+		}
+	}
+	return client.Database("gullybadminton"), disconnect
+}
+
+func runMigrateCommand(cfg *config.Config, args []string) {
+	if cfg.Storage != config.StorageMongo {
+		// The memory store has no schema and SQL storage migrates on open.
+		log.Fatalf("migrate only applies to STORAGE=%s", config.StorageMongo)
+	}
+	db, disconnect := connectMongo(cfg)
+	defer disconnect()
+
+	ctx := context.Background()
+	if len(args) > 0 && args[0] == "status" {
+		applied, err := migrations.Applied(ctx, db)
+		if err != nil {
+			log.Fatalf("Migration status error: %v", err)
+		}
+		for _, r := range applied {
+			log.Printf("applied  %3d %s (%s)", r.Version, r.Name, r.AppliedAt.Format(time.RFC3339))
+		}
+		pending, err := migrations.Pending(ctx, db)
+		if err != nil {
+			log.Fatalf("Migration status error: %v", err)
+		}
+		for _, m := range pending {
+			log.Printf("pending  %3d %s", m.Version, m.Name)
+		}
+		return
+	}
+	if err := migrations.Run(ctx, db); err != nil {
+		log.Fatalf("Migration error: %v", err)
+	}
+	log.Println("Migrations up to date")
+}
@@ -16,40 +16,67 @@ var upgrader = websocket.Upgrader{
 
 // Client wraps a single WebSocket connection.
 type Client struct {
-	conn    *websocket.Conn
-	groupID string
-	hub     *Hub
+	conn *websocket.Conn
+	// writeMu serialises writes, which broadcasts make from any goroutine;
+	// a connection takes one writer at a time.
+	writeMu  sync.Mutex
+	groupID  string
+	userID   string // empty for share link viewers
+	username string
+	share    *shareScope // set for share link viewers
+	hub      *Hub
 }
 
-// Hub manages per-group WebSocket client sets.
+// Hub manages per-group WebSocket client sets, member presence and scorer locks.
 type Hub struct {
-	mu     sync.RWMutex
-	groups map[string]map[*Client]bool
+	mu       sync.RWMutex
+	groups   map[string]map[*Client]bool
+	presence map[string]map[string]*PresenceEntry // groupID → userID → entry
+	locks    map[string]*ScorerLock               // matchID → lock
 }
 
 func NewHub() *Hub {
 	return &Hub{
-		groups: make(map[string]map[*Client]bool),
+		groups:   make(map[string]map[*Client]bool),
+		presence: make(map[string]map[string]*PresenceEntry),
+		locks:    make(map[string]*ScorerLock),
 	}
 }
 
 func (h *Hub) register(client *Client) {
 	h.mu.Lock()
-	defer h.mu.Unlock()
 	if h.groups[client.groupID] == nil {
 		h.groups[client.groupID] = make(map[*Client]bool)
 	}
 	h.groups[client.groupID][client] = true
+	joined := h.addPresence(client)
+	h.mu.Unlock()
+
+	if joined != nil {
+		h.BroadcastToGroup(client.groupID, gin.H{"type": "presence_join", "user": joined})
+	}
 }
 
 func (h *Hub) unregister(client *Client) {
 	h.mu.Lock()
-	defer h.mu.Unlock()
-	if clients, ok := h.groups[client.groupID]; ok {
-		delete(clients, client)
-		if len(clients) == 0 {
-			delete(h.groups, client.groupID)
-		}
+	clients, ok := h.groups[client.groupID]
+	if !ok || !clients[client] {
+		h.mu.Unlock()
+		return
+	}
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(h.groups, client.groupID)
+	}
+	left, released := h.removePresence(client)
+	h.mu.Unlock()
+
+	if left == nil {
+		return
+	}
+	h.BroadcastToGroup(client.groupID, gin.H{"type": "presence_leave", "user": left})
+	for _, lock := range released {
+		h.BroadcastToGroup(client.groupID, gin.H{"type": "scorer_lock_released", "match_id": lock.MatchID})
 	}
 }
 
@@ -62,11 +89,25 @@ func (h *Hub) BroadcastToGroup(groupID string, message interface{}) {
 	}
 
 	h.mu.RLock()
-	clients := h.groups[groupID]
+	clients := make([]*Client, 0, len(h.groups[groupID]))
+	for client := range h.groups[groupID] {
+		clients = append(clients, client)
+	}
 	h.mu.RUnlock()
 
-	for client := range clients {
-		if err := client.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+	var event *sharedEvent
+	for _, client := range clients {
+		out := data
+		if client.share != nil {
+			if event == nil {
+				event = parseSharedEvent(data)
+			}
+			if !client.share.allows(event) {
+				continue
+			}
+			out = event.data
+		}
+		if err := client.write(out); err != nil {
 			log.Printf("write error: %v", err)
 			client.conn.Close()
 			h.unregister(client)
@@ -74,26 +115,78 @@ func (h *Hub) BroadcastToGroup(groupID string, message interface{}) {
 	}
 }
 
-// HandleWebSocket is the Gin handler for WebSocket upgrade at /ws/group/:groupId.
-func (h *Hub) HandleWebSocket(c *gin.Context) {
-	groupID := c.Param("groupId")
+// write sends one text message to the client.
+func (c *Client) write(data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// CloseGroup disconnects every client of the group, for when it is deleted.
+// Each connection unregisters as its reader stops.
+func (h *Hub) CloseGroup(groupID string) {
+	h.closeWhere(groupID, func(*Client) bool { return true })
+}
+
+// CloseMember disconnects the user's clients of the group, for when they
+// leave or are removed. Share link viewers are left alone.
+func (h *Hub) CloseMember(groupID, userID string) {
+	h.closeWhere(groupID, func(client *Client) bool {
+		return client.share == nil && client.userID == userID
+	})
+}
+
+// closeWhere disconnects the group's clients that match selects.
+func (h *Hub) closeWhere(groupID string, match func(*Client) bool) {
+	h.mu.RLock()
+	var clients []*Client
+	for client := range h.groups[groupID] {
+		if match(client) {
+			clients = append(clients, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range clients {
+		client.conn.Close()
+	}
+}
+
+// ServeGroup upgrades a member's connection to the group's live feed. The
+// caller has checked the user is a member; they show up in presence while
+// connected.
+func (h *Hub) ServeGroup(c *gin.Context, groupID, userID, username string) {
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Printf("upgrade error: %v", err)
 		return
 	}
 
-	client := &Client{conn: conn, groupID: groupID, hub: h}
+	h.serve(&Client{
+		conn:     conn,
+		groupID:  groupID,
+		userID:   userID,
+		username: username,
+		hub:      h,
+	}, nil)
+}
+
+// serve registers the client and keeps its connection open until it closes;
+// done, if set, runs after it is unregistered.
+func (h *Hub) serve(client *Client, done func()) {
 	h.register(client)
 
 	// Keep the connection alive; read messages (we only need pong/close frames).
 	go func() {
 		defer func() {
 			h.unregister(client)
-			conn.Close()
+			client.conn.Close()
+			if done != nil {
+				done()
+			}
 		}()
 		for {
-			_, _, err := conn.ReadMessage()
+			_, _, err := client.conn.ReadMessage()
 			if err != nil {
 				break
 			}
@@ -0,0 +1,183 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testServer serves the hub's sockets the way the handlers do, with the
+// identity the handlers would take from the access token or share link
+// passed in the query.
+func testServer(t *testing.T, h *Hub) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/group/:groupId", func(c *gin.Context) {
+		h.ServeGroup(c, c.Param("groupId"), c.Query("user_id"), c.Query("username"))
+	})
+	r.GET("/share/:groupId", func(c *gin.Context) {
+		expiresAt := time.Now().Add(time.Hour)
+		if ttl, err := time.ParseDuration(c.Query("ttl")); err == nil {
+			expiresAt = time.Now().Add(ttl)
+		}
+		h.ServeShare(c, c.Param("groupId"), c.Query("match_id"), c.Query("link_id"), expiresAt)
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func dial(t *testing.T, srv *httptest.Server, path string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+path, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// clients counts the group's open connections.
+func clients(h *Hub, groupID string) func() int {
+	return func() int {
+		h.mu.RLock()
+		defer h.mu.RUnlock()
+		return len(h.groups[groupID])
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	require.Eventually(t, cond, time.Second, 5*time.Millisecond)
+}
+
+// readType reads the next message and returns its type.
+func readType(t *testing.T, conn *websocket.Conn) string {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	var msg struct {
+		Type string `json:"type"`
+	}
+	require.NoError(t, json.Unmarshal(data, &msg))
+	return msg.Type
+}
+
+// assertClosed checks the server has closed the connection.
+func assertClosed(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			t.Fatal("connection still open")
+		}
+		return
+	}
+}
+
+func TestPresence_JoinAndLeave(t *testing.T) {
+	h := NewHub()
+	srv := testServer(t, h)
+
+	bob := dial(t, srv, "/group/g1?user_id=u2&username=bob")
+	waitFor(t, func() bool { return h.IsPresent("g1", "u2") })
+	readType(t, bob) // bob's own presence_join
+
+	alice := dial(t, srv, "/group/g1?user_id=u1&username=alice")
+	assert.Equal(t, "presence_join", readType(t, bob))
+	second := dial(t, srv, "/group/g1?user_id=u1&username=alice")
+	waitFor(t, func() bool { return clients(h, "g1")() == 3 })
+
+	presence := h.Presence("g1")
+	require.Len(t, presence, 2)
+	assert.Equal(t, "bob", presence[0].Username)
+	assert.Equal(t, 2, presence[1].Connections)
+
+	// Alice is present until her last connection closes.
+	second.Close()
+	waitFor(t, func() bool { return clients(h, "g1")() == 2 })
+	assert.True(t, h.IsPresent("g1", "u1"))
+	alice.Close()
+	assert.Equal(t, "presence_leave", readType(t, bob))
+	assert.False(t, h.IsPresent("g1", "u1"))
+}
+
+func TestScorerLock_RequiresConnection(t *testing.T) {
+	h := NewHub()
+	srv := testServer(t, h)
+
+	_, err := h.ClaimScorer("g1", "m1", "u1")
+	assert.ErrorIs(t, err, ErrNotPresent)
+
+	alice := dial(t, srv, "/group/g1?user_id=u1&username=alice")
+	waitFor(t, func() bool { return h.IsPresent("g1", "u1") })
+	lock, err := h.ClaimScorer("g1", "m1", "u1")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", lock.Username)
+
+	// Hand-over targets must be connected too.
+	_, err = h.HandOverScorer("m1", "u1", "u2")
+	assert.ErrorIs(t, err, ErrNotPresent)
+	dial(t, srv, "/group/g1?user_id=u2&username=bob")
+	waitFor(t, func() bool { return h.IsPresent("g1", "u2") })
+	_, err = h.ClaimScorer("g1", "m1", "u2")
+	assert.ErrorIs(t, err, ErrScorerLocked)
+	assert.ErrorIs(t, h.CheckScorer("m1", "u2"), ErrScorerLocked)
+	lock, err = h.HandOverScorer("m1", "u1", "u2")
+	require.NoError(t, err)
+	assert.Equal(t, "bob", lock.Username)
+
+	// The lock goes when its holder disconnects.
+	_, err = h.HandOverScorer("m1", "u2", "u1")
+	require.NoError(t, err)
+	alice.Close()
+	waitFor(t, func() bool { return h.ScorerLockFor("m1") == nil })
+}
+
+func TestCloseGroup(t *testing.T) {
+	h := NewHub()
+	srv := testServer(t, h)
+
+	conn := dial(t, srv, "/group/g1?user_id=u1&username=alice")
+	dial(t, srv, "/group/g2?user_id=u1&username=alice")
+	waitFor(t, func() bool { return clients(h, "g1")() == 1 && clients(h, "g2")() == 1 })
+
+	h.CloseGroup("g1")
+	assertClosed(t, conn)
+	waitFor(t, func() bool { return clients(h, "g1")() == 0 })
+	assert.False(t, h.IsPresent("g1", "u1"))
+	assert.True(t, h.IsPresent("g2", "u1"))
+}
+
+func TestCloseMember(t *testing.T) {
+	h := NewHub()
+	srv := testServer(t, h)
+
+	alice := dial(t, srv, "/group/g1?user_id=u1&username=alice")
+	bob := dial(t, srv, "/group/g1?user_id=u2&username=bob")
+	dial(t, srv, "/group/g2?user_id=u1&username=alice")
+	dial(t, srv, "/share/g1?link_id=l1")
+	waitFor(t, func() bool { return clients(h, "g1")() == 3 && clients(h, "g2")() == 1 })
+	readType(t, bob) // bob's own presence_join
+
+	h.CloseMember("g1", "u1")
+	assertClosed(t, alice)
+	assert.Equal(t, "presence_leave", readType(t, bob))
+	waitFor(t, func() bool { return clients(h, "g1")() == 2 })
+	assert.True(t, h.IsPresent("g1", "u2"))
+	assert.True(t, h.IsPresent("g2", "u1"))
+}
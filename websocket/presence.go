@@ -0,0 +1,90 @@
+package websocket
+
+import (
+	"sort"
+	"time"
+)
+
+// PresenceEntry describes a group member who currently has the app open.
+type PresenceEntry struct {
+	UserID      string    `json:"user_id"`
+	Username    string    `json:"username"`
+	Connections int       `json:"connections"` // open sockets (phone + tablet, etc.)
+	Since       time.Time `json:"since"`
+}
+
+// Presence returns the members currently connected to a group, oldest first.
+func (h *Hub) Presence(groupID string) []PresenceEntry {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	entries := make([]PresenceEntry, 0, len(h.presence[groupID]))
+	for _, e := range h.presence[groupID] {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Since.Before(entries[j].Since) })
+	return entries
+}
+
+// IsPresent reports whether the user has at least one open connection to the group.
+func (h *Hub) IsPresent(groupID, userID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, ok := h.presence[groupID][userID]
+	return ok
+}
+
+// addPresence counts a new connection. It returns the entry when this is the
+// user's first connection to the group (i.e. a join event). Caller holds h.mu.
+func (h *Hub) addPresence(client *Client) *PresenceEntry {
+	if client.userID == "" {
+		return nil
+	}
+	if h.presence[client.groupID] == nil {
+		h.presence[client.groupID] = make(map[string]*PresenceEntry)
+	}
+	if e, ok := h.presence[client.groupID][client.userID]; ok {
+		e.Connections++
+		return nil
+	}
+	e := &PresenceEntry{
+		UserID:      client.userID,
+		Username:    client.username,
+		Connections: 1,
+		Since:       time.Now(),
+	}
+	h.presence[client.groupID][client.userID] = e
+	joined := *e
+	return &joined
+}
+
+// removePresence drops a connection. When it was the user's last one, it
+// returns the leave entry plus any scorer locks that expired with it.
+// Caller holds h.mu.
+func (h *Hub) removePresence(client *Client) (*PresenceEntry, []ScorerLock) {
+	if client.userID == "" {
+		return nil, nil
+	}
+	members := h.presence[client.groupID]
+	e, ok := members[client.userID]
+	if !ok {
+		return nil, nil
+	}
+	e.Connections--
+	if e.Connections > 0 {
+		return nil, nil
+	}
+	delete(members, client.userID)
+	if len(members) == 0 {
+		delete(h.presence, client.groupID)
+	}
+
+	var released []ScorerLock
+	for matchID, lock := range h.locks {
+		if lock.GroupID == client.groupID && lock.UserID == client.userID {
+			released = append(released, *lock)
+			delete(h.locks, matchID)
+		}
+	}
+	return e, released
+}
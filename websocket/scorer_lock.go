@@ -0,0 +1,114 @@
+package websocket
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotPresent      = errors.New("user is not connected to the group")
+	ErrScorerLocked    = errors.New("match is being scored by another user")
+	ErrNotScorerHolder = errors.New("you do not hold the scorer lock")
+)
+
+// ScorerLock marks one user as the only scorer of a live match. Locks live in
+// memory and expire when the holder's last connection to the group closes.
+type ScorerLock struct {
+	MatchID    string    `json:"match_id"`
+	GroupID    string    `json:"group_id"`
+	UserID     string    `json:"user_id"`
+	Username   string    `json:"username"`
+	AcquiredAt time.Time `json:"acquired_at"`
+}
+
+// ClaimScorer gives the user the scorer lock for a match. Claiming a lock you
+// already hold is a no-op; claiming someone else's fails with ErrScorerLocked.
+// The user must be connected to the group so the lock can expire on disconnect;
+// only members can connect.
+func (h *Hub) ClaimScorer(groupID, matchID, userID string) (*ScorerLock, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	member, ok := h.presence[groupID][userID]
+	if !ok {
+		return nil, ErrNotPresent
+	}
+	if lock, ok := h.locks[matchID]; ok {
+		if lock.UserID != userID {
+			return nil, ErrScorerLocked
+		}
+		held := *lock
+		return &held, nil
+	}
+
+	lock := &ScorerLock{
+		MatchID:    matchID,
+		GroupID:    groupID,
+		UserID:     userID,
+		Username:   member.Username,
+		AcquiredAt: time.Now(),
+	}
+	h.locks[matchID] = lock
+	claimed := *lock
+	return &claimed, nil
+}
+
+// HandOverScorer passes the lock from its current holder to another connected member.
+func (h *Hub) HandOverScorer(matchID, fromUserID, toUserID string) (*ScorerLock, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	lock, ok := h.locks[matchID]
+	if !ok || lock.UserID != fromUserID {
+		return nil, ErrNotScorerHolder
+	}
+	member, ok := h.presence[lock.GroupID][toUserID]
+	if !ok {
+		return nil, ErrNotPresent
+	}
+
+	lock.UserID = toUserID
+	lock.Username = member.Username
+	lock.AcquiredAt = time.Now()
+	handed := *lock
+	return &handed, nil
+}
+
+// ReleaseScorer drops the lock. Only the holder may release unless force is set
+// (used for group creators and for matches that are finished or deleted).
+func (h *Hub) ReleaseScorer(matchID, userID string, force bool) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	lock, ok := h.locks[matchID]
+	if !ok {
+		return nil
+	}
+	if !force && lock.UserID != userID {
+		return ErrNotScorerHolder
+	}
+	delete(h.locks, matchID)
+	return nil
+}
+
+// ScorerLockFor returns the current lock on a match, or nil if nobody holds it.
+func (h *Hub) ScorerLockFor(matchID string) *ScorerLock {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	lock, ok := h.locks[matchID]
+	if !ok {
+		return nil
+	}
+	held := *lock
+	return &held
+}
+
+// CheckScorer returns ErrScorerLocked when another user holds the match's lock.
+func (h *Hub) CheckScorer(matchID, userID string) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if lock, ok := h.locks[matchID]; ok && lock.UserID != userID {
+		return ErrScorerLocked
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sharedTypes are the messages share link viewers get: match events only,
+// never membership, presence, settings or scorer locks.
+var sharedTypes = map[string]bool{
+	"match_created":  true,
+	"score_update":   true,
+	"match_status":   true,
+	"match_paused":   true,
+	"match_resumed":  true,
+	"match_finished": true,
+	"match_deleted":  true,
+}
+
+// shareScope is what a share link viewer may see.
+type shareScope struct {
+	linkID  string
+	matchID string // empty for a whole-group link
+}
+
+// sharedEvent is a broadcast as share link viewers get it. Match events
+// carry either the match or, once it is deleted, its match_id.
+type sharedEvent struct {
+	Type    string `json:"type"`
+	MatchID string `json:"match_id"`
+	Match   struct {
+		ID string `json:"id"`
+	} `json:"match"`
+
+	// data is the message without the match's group ID, which viewers are
+	// not given.
+	data []byte
+}
+
+func parseSharedEvent(data []byte) *sharedEvent {
+	var e sharedEvent
+	if err := json.Unmarshal(data, &e); err != nil {
+		return &sharedEvent{}
+	}
+	if e.MatchID == "" {
+		e.MatchID = e.Match.ID
+	}
+	if sharedTypes[e.Type] {
+		e.data = withoutGroupID(data)
+	}
+	return &e
+}
+
+// withoutGroupID drops group_id from the message's match.
+func withoutGroupID(data []byte) []byte {
+	var msg map[string]json.RawMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return data
+	}
+	var match map[string]json.RawMessage
+	if err := json.Unmarshal(msg["match"], &match); err != nil {
+		return data
+	}
+	delete(match, "group_id")
+	stripped, err := json.Marshal(match)
+	if err != nil {
+		return data
+	}
+	msg["match"] = stripped
+	if out, err := json.Marshal(msg); err == nil {
+		return out
+	}
+	return data
+}
+
+func (s *shareScope) allows(e *sharedEvent) bool {
+	if !sharedTypes[e.Type] {
+		return false
+	}
+	return s.matchID == "" || s.matchID == e.MatchID
+}
+
+// ServeShare upgrades a share link viewer's connection. The viewer gets the
+// group's match events, or one match's when matchID is set, until the link
+// expires at expiresAt or is revoked with CloseShare. Nothing they send is
+// read.
+func (h *Hub) ServeShare(c *gin.Context, groupID, matchID, linkID string, expiresAt time.Time) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("upgrade error: %v", err)
+		return
+	}
+	expiry := time.AfterFunc(time.Until(expiresAt), func() { conn.Close() })
+	h.serve(&Client{
+		conn:    conn,
+		groupID: groupID,
+		share:   &shareScope{linkID: linkID, matchID: matchID},
+		hub:     h,
+	}, func() { expiry.Stop() })
+}
+
+// CloseShare disconnects everyone watching through the share link, for when
+// it is revoked.
+func (h *Hub) CloseShare(groupID, linkID string) {
+	h.closeWhere(groupID, func(client *Client) bool {
+		return client.share != nil && client.share.linkID == linkID
+	})
+}
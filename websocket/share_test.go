@@ -0,0 +1,95 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readMessage reads the next message as a map.
+func readMessage(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	var msg map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &msg))
+	return msg
+}
+
+func TestShare_OnlyMatchEvents(t *testing.T) {
+	h := NewHub()
+	srv := testServer(t, h)
+
+	member := dial(t, srv, "/group/g1?user_id=u1&username=alice")
+	readType(t, member) // presence_join
+	group := dial(t, srv, "/share/g1?link_id=l1")
+	match := dial(t, srv, "/share/g1?link_id=l2&match_id=m1")
+	waitFor(t, func() bool { return clients(h, "g1")() == 3 })
+	assert.Len(t, h.Presence("g1"), 1, "viewers are not in presence")
+
+	match1 := gin.H{"id": "m1", "group_id": "g1", "score1": 3}
+	match2 := gin.H{"id": "m2", "group_id": "g1"}
+	for _, msg := range []gin.H{
+		{"type": "member_joined", "user_id": "u2"},
+		{"type": "join_requested", "request": gin.H{"user_id": "u3"}},
+		{"type": "settings_updated", "settings": gin.H{}},
+		{"type": "scorer_lock", "lock": gin.H{"match_id": "m1", "user_id": "u1"}},
+		{"type": "score_update", "match": match2},
+		{"type": "score_update", "match": match1},
+		{"type": "match_deleted", "match_id": "m1"},
+	} {
+		h.BroadcastToGroup("g1", msg)
+	}
+
+	// Members get everything.
+	for _, want := range []string{"member_joined", "join_requested", "settings_updated", "scorer_lock", "score_update", "score_update", "match_deleted"} {
+		assert.Equal(t, want, readType(t, member))
+	}
+
+	// A group link gets every match event, a match link only its match's,
+	// and neither gets the group ID.
+	got := readMessage(t, group)
+	assert.Equal(t, "score_update", got["type"])
+	assert.Equal(t, "m2", got["match"].(map[string]interface{})["id"])
+	assert.NotContains(t, got["match"], "group_id")
+	assert.Equal(t, "score_update", readType(t, group))
+	assert.Equal(t, "match_deleted", readType(t, group))
+
+	got = readMessage(t, match)
+	assert.Equal(t, "score_update", got["type"])
+	assert.Equal(t, "m1", got["match"].(map[string]interface{})["id"])
+	assert.Equal(t, float64(3), got["match"].(map[string]interface{})["score1"])
+	assert.NotContains(t, got["match"], "group_id")
+	assert.Equal(t, "match_deleted", readType(t, match))
+}
+
+func TestShare_ClosesAtExpiry(t *testing.T) {
+	h := NewHub()
+	srv := testServer(t, h)
+
+	conn := dial(t, srv, "/share/g1?link_id=l1&ttl=100ms")
+	waitFor(t, func() bool { return clients(h, "g1")() == 1 })
+	assertClosed(t, conn)
+	waitFor(t, func() bool { return clients(h, "g1")() == 0 })
+}
+
+func TestCloseShare(t *testing.T) {
+	h := NewHub()
+	srv := testServer(t, h)
+
+	revoked := dial(t, srv, "/share/g1?link_id=l1")
+	dial(t, srv, "/share/g1?link_id=l2")
+	member := dial(t, srv, "/group/g1?user_id=u1&username=alice")
+	waitFor(t, func() bool { return clients(h, "g1")() == 3 })
+
+	h.CloseShare("g1", "l1")
+	assertClosed(t, revoked)
+	waitFor(t, func() bool { return clients(h, "g1")() == 2 })
+	readType(t, member) // presence_join: still connected
+}